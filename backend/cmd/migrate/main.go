@@ -1,41 +1,61 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 
 	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/config"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
 )
 
 func main() {
 	if len(os.Args) < 2 {
-		log.Fatal("Usage: go run cmd/migrate/main.go [up|down]")
+		log.Fatal("Usage: go run cmd/migrate/main.go [up|down [steps]]")
 	}
 
 	cfg := config.Load()
 	direction := os.Args[1]
 
+	db, err := database.Open(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
 	switch direction {
 	case "up":
-		runMigrationsUp(cfg)
+		runMigrationsUp(db)
 	case "down":
-		runMigrationsDown(cfg)
+		steps := 1
+		if len(os.Args) > 2 {
+			steps, err = strconv.Atoi(os.Args[2])
+			if err != nil || steps < 1 {
+				log.Fatal("Invalid steps. Use a positive number")
+			}
+		}
+		runMigrationsDown(db, steps)
 	default:
 		log.Fatal("Invalid direction. Use 'up' or 'down'")
 	}
 }
 
-func runMigrationsUp(cfg *config.Config) {
-	fmt.Printf("🔄 Running migrations UP against %s\n", cfg.DatabaseURL)
-	// TODO: Implement actual migration logic
-	// This would typically use a migration library like golang-migrate
-	fmt.Println("✅ Migrations completed successfully")
+func runMigrationsUp(db *database.DB) {
+	fmt.Printf("🔄 Running migrations UP against %s\n", db.Dialect)
+	applied, err := db.Migrate(context.Background())
+	if err != nil {
+		log.Fatalf("Migration failed after %d applied: %v", applied, err)
+	}
+	fmt.Printf("✅ Migrations completed successfully (%d applied)\n", applied)
 }
 
-func runMigrationsDown(cfg *config.Config) {
-	fmt.Printf("🔄 Running migrations DOWN against %s\n", cfg.DatabaseURL)
-	// TODO: Implement actual migration logic
-	// This would typically use a migration library like golang-migrate
-	fmt.Println("✅ Migrations rollback completed successfully")
+func runMigrationsDown(db *database.DB, steps int) {
+	fmt.Printf("🔄 Running migrations DOWN against %s\n", db.Dialect)
+	reverted, err := db.Rollback(context.Background(), steps)
+	if err != nil {
+		log.Fatalf("Rollback failed after %d reverted: %v", reverted, err)
+	}
+	fmt.Printf("✅ Migrations rollback completed successfully (%d reverted)\n", reverted)
 }
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/config"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/fixtures"
+	"gopkg.in/yaml.v3"
+)
+
+func main() {
+	var (
+		sets     = flag.String("set", "", "comma-separated built-in fixture sets ("+strings.Join(fixtures.Names(), ", ")+")")
+		files    = flag.String("file", "", "comma-separated YAML or JSON fixture files")
+		generate = flag.Bool("generate", false, "generate fake users, organizations and entries")
+		seed     = flag.Uint64("seed", 1, "random seed for -generate; the same seed gives the same data")
+		users    = flag.Int("users", fixtures.DefaultOptions.Users, "users to generate")
+		orgs     = flag.Int("orgs", fixtures.DefaultOptions.Organizations, "organizations to generate")
+		entries  = flag.Int("entries", fixtures.DefaultOptions.EntriesPerUser, "entries to generate per user")
+		truncate = flag.Bool("truncate", false, "delete all existing data first")
+		force    = flag.Bool("force", false, "allow -truncate when ENV=production")
+		out      = flag.String("out", "", "write the fixtures to this .yaml or .json file instead of the database")
+	)
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run ./cmd/seed [-truncate] [-set demo] [-file a.yaml,b.json] [-generate -seed 42]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	set := &fixtures.Set{}
+	for _, name := range splitList(*sets) {
+		s, err := fixtures.Open(name)
+		if err != nil {
+			log.Fatalf("Failed to open fixture set: %v", err)
+		}
+		set.Merge(s)
+	}
+	for _, path := range splitList(*files) {
+		s, err := fixtures.ReadFile(path)
+		if err != nil {
+			log.Fatalf("Failed to read %s: %v", path, err)
+		}
+		set.Merge(s)
+	}
+	if *generate {
+		set.Merge(fixtures.Generate(*seed, fixtures.Options{Users: *users, Organizations: *orgs, EntriesPerUser: *entries}))
+	}
+	if err := set.Validate(); err != nil {
+		log.Fatalf("Invalid fixtures: %v", err)
+	}
+
+	if *out != "" {
+		writeSet(set, *out)
+		return
+	}
+	if len(set.Users) == 0 && !*truncate {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg := config.Load()
+	db, err := database.Open(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	if *truncate {
+		if cfg.Env == "production" && !*force {
+			log.Fatal("Refusing to truncate a production database without -force")
+		}
+		fmt.Printf("🧹 Truncating %s database\n", db.Dialect)
+		if err := fixtures.Truncate(ctx, db); err != nil {
+			log.Fatalf("Truncate failed: %v", err)
+		}
+	}
+	if len(set.Users) == 0 {
+		return
+	}
+
+	fmt.Printf("🌱 Seeding %s database\n", db.Dialect)
+	res, err := fixtures.Load(ctx, db, set)
+	if err != nil {
+		log.Fatalf("Seeding failed: %v", err)
+	}
+	fmt.Printf("✅ Loaded %d users, %d organizations and %d entries\n", len(res.Users), len(res.Organizations), res.Entries)
+}
+
+func writeSet(set *fixtures.Set, path string) {
+	var data []byte
+	var err error
+	switch filepath.Ext(path) {
+	case ".json":
+		data, err = json.MarshalIndent(set, "", "  ")
+	case ".yaml", ".yml":
+		data, err = yaml.Marshal(set)
+	default:
+		err = fixtures.ErrUnknownFormat
+	}
+	if err != nil {
+		log.Fatalf("Failed to encode fixtures: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		log.Fatalf("Failed to write %s: %v", path, err)
+	}
+	fmt.Printf("✅ Wrote %d users, %d organizations and %d entries to %s\n", len(set.Users), len(set.Organizations), len(set.Entries), path)
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -10,47 +10,86 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/app"
 	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/config"
-	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/handlers"
-	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/middleware"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/httpserver"
 )
 
 func main() {
 	// Load configuration
 	cfg := config.Load()
 
-	// Initialize Gin router
-	if cfg.Env == "production" {
-		gin.SetMode(gin.ReleaseMode)
+	// Connect to the database
+	db, err := database.Open(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
 	}
+	defer db.Close()
 
-	router := gin.New()
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
 
-	// Add middleware
-	router.Use(gin.Logger())
-	router.Use(gin.Recovery())
-	router.Use(middleware.CORS())
-
-	// Health check endpoint
-	router.GET("/health", handlers.HealthCheck)
+	if cfg.Env == "production" {
+		gin.SetMode(gin.ReleaseMode)
+	}
 
-	// API routes
-	api := router.Group("/api/v1")
-	{
-		api.GET("/ping", handlers.Ping)
-		// Add more routes as needed
+	// Initialize services and routes
+	application, err := app.New(backgroundCtx, cfg, db, app.Options{})
+	if err != nil {
+		log.Fatalf("Failed to initialize application: %v", err)
 	}
+	application.Start(backgroundCtx)
 
 	// Create HTTP server
 	server := &http.Server{
 		Addr:    ":" + cfg.Port,
-		Handler: router,
+		Handler: application.Router,
+	}
+
+	// Serve HTTPS when a certificate is configured; the certificate files
+	// are watched so renewals apply without a restart
+	var redirect *http.Server
+	if cfg.TLSEnabled() {
+		certs, err := httpserver.NewCertReloader(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			log.Fatalf("Failed to load TLS certificate: %v", err)
+		}
+		go certs.Watch(backgroundCtx, cfg.TLSReloadInterval)
+		server.TLSConfig, err = httpserver.TLSConfig(certs, httpserver.TLSOptions{
+			MinVersion:   cfg.TLSMinVersion,
+			CipherSuites: cfg.TLSCipherSuites,
+		})
+		if err != nil {
+			log.Fatalf("Invalid TLS configuration: %v", err)
+		}
+
+		if cfg.HTTPRedirectPort != "" {
+			redirect = &http.Server{
+				Addr:              ":" + cfg.HTTPRedirectPort,
+				Handler:           httpserver.RedirectHandler(cfg.Port),
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+			go func() {
+				log.Printf("↪️  Redirecting HTTP on port %s to HTTPS", cfg.HTTPRedirectPort)
+				if err := redirect.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+					log.Fatalf("Failed to start redirect listener: %v", err)
+				}
+			}()
+		}
 	}
 
 	// Start server in a goroutine
 	go func() {
-		log.Printf("🚀 Server starting on port %s", cfg.Port)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if server.TLSConfig != nil {
+			log.Printf("🔒 Server starting with TLS on port %s", cfg.Port)
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			log.Printf("🚀 Server starting on port %s", cfg.Port)
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Failed to start server: %v", err)
 		}
 	}()
@@ -60,11 +99,15 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Println("🛑 Shutting down server...")
+	stopBackground()
 
 	// Give outstanding requests 10 seconds to complete
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	if redirect != nil {
+		redirect.Shutdown(ctx)
+	}
 	if err := server.Shutdown(ctx); err != nil {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
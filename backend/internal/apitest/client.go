@@ -0,0 +1,180 @@
+package apitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/middleware"
+)
+
+// Client sends requests to a Server, optionally signed in. Clients are
+// immutable: the With methods return changed copies.
+type Client struct {
+	s      *Server
+	token  string
+	header http.Header
+}
+
+// Client returns an anonymous client
+func (s *Server) Client() *Client {
+	return &Client{s: s, header: http.Header{}}
+}
+
+// Login signs in through the API and returns a client sending the issued
+// access token
+func (s *Server) Login(email, password string) *Client {
+	s.t.Helper()
+	var body struct {
+		AccessToken string `json:"access_token"`
+	}
+	s.Client().Post("/api/v2/auth/login", map[string]string{"email": email, "password": password}).
+		ExpectStatus(http.StatusOK).
+		JSON(&body)
+	if body.AccessToken == "" {
+		s.t.Fatalf("login %s: no access token (is MFA required?)", email)
+	}
+	return s.Client().WithToken(body.AccessToken)
+}
+
+// Register creates an account through the API and returns a client signed
+// in as it
+func (s *Server) Register(email, name, password string) *Client {
+	s.t.Helper()
+	var body struct {
+		AccessToken string `json:"access_token"`
+	}
+	s.Client().Post("/api/v2/auth/register", map[string]string{"email": email, "name": name, "password": password}).
+		ExpectStatus(http.StatusCreated).
+		JSON(&body)
+	return s.Client().WithToken(body.AccessToken)
+}
+
+// WithToken returns a copy of c sending token as its bearer token
+func (c *Client) WithToken(token string) *Client {
+	cp := c.clone()
+	cp.token = token
+	return cp
+}
+
+// WithHeader returns a copy of c sending the header with every request
+func (c *Client) WithHeader(key, value string) *Client {
+	cp := c.clone()
+	cp.header.Set(key, value)
+	return cp
+}
+
+// WithOrg returns a copy of c acting in the organization with the slug
+func (c *Client) WithOrg(slug string) *Client {
+	return c.WithHeader(middleware.TenantHeader, slug)
+}
+
+// WithLanguage returns a copy of c preferring the language
+func (c *Client) WithLanguage(lang string) *Client {
+	return c.WithHeader("Accept-Language", lang)
+}
+
+func (c *Client) clone() *Client {
+	return &Client{s: c.s, token: c.token, header: c.header.Clone()}
+}
+
+// Get sends a GET request
+func (c *Client) Get(path string) *Response {
+	c.s.t.Helper()
+	return c.Do(http.MethodGet, path, nil)
+}
+
+// Post sends a POST request with body encoded as JSON
+func (c *Client) Post(path string, body any) *Response {
+	c.s.t.Helper()
+	return c.Do(http.MethodPost, path, body)
+}
+
+// Put sends a PUT request with body encoded as JSON
+func (c *Client) Put(path string, body any) *Response {
+	c.s.t.Helper()
+	return c.Do(http.MethodPut, path, body)
+}
+
+// Patch sends a PATCH request with body encoded as JSON
+func (c *Client) Patch(path string, body any) *Response {
+	c.s.t.Helper()
+	return c.Do(http.MethodPatch, path, body)
+}
+
+// Delete sends a DELETE request
+func (c *Client) Delete(path string) *Response {
+	c.s.t.Helper()
+	return c.Do(http.MethodDelete, path, nil)
+}
+
+// Do sends a request to path on the server. A non-nil body is encoded as
+// JSON, unless it already is a []byte. Transport errors fail the test.
+func (c *Client) Do(method, path string, body any) *Response {
+	t := c.s.t
+	t.Helper()
+
+	var reader io.Reader
+	switch b := body.(type) {
+	case nil:
+	case []byte:
+		reader = bytes.NewReader(b)
+	default:
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("encode %s %s body: %v", method, path, err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.s.URL+path, reader)
+	if err != nil {
+		t.Fatalf("build %s %s: %v", method, path, err)
+	}
+	req.Header = c.header.Clone()
+	if reader != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.s.Server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("%s %s: read body: %v", method, path, err)
+	}
+	return &Response{Response: resp, Body: data, t: t, method: method, path: path}
+}
+
+// Response is a completed response with its body read
+type Response struct {
+	*http.Response
+	Body []byte
+
+	t            testing.TB
+	method, path string
+}
+
+// ExpectStatus fails the test unless the response has the status code
+func (r *Response) ExpectStatus(code int) *Response {
+	r.t.Helper()
+	if r.StatusCode != code {
+		r.t.Fatalf("%s %s: expected status %d, got %d: %s", r.method, r.path, code, r.StatusCode, r.Body)
+	}
+	return r
+}
+
+// JSON decodes the body into v, failing the test on invalid JSON
+func (r *Response) JSON(v any) *Response {
+	r.t.Helper()
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		r.t.Fatalf("%s %s: decode body: %v: %s", r.method, r.path, err, r.Body)
+	}
+	return r
+}
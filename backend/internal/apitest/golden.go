@@ -0,0 +1,83 @@
+package apitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files with the actual responses")
+
+// Masked lists the JSON keys whose values are random on every run, such as
+// tokens and secrets. Golden replaces their values with "<key>".
+var Masked = []string{"access_token", "refresh_token", "mfa_token", "token", "secret", "recovery_codes"}
+
+// Golden compares the response status, content type and body with the
+// golden file testdata/<test name>/<name>.golden, or rewrites the file when
+// the tests run with -update. JSON bodies are indented with sorted keys,
+// and the values of Masked keys and of the extra mask keys are replaced.
+func (r *Response) Golden(name string, mask ...string) *Response {
+	r.t.Helper()
+	got := r.goldenText(append(slices.Clone(Masked), mask...))
+	path := filepath.Join("testdata", filepath.FromSlash(r.t.Name()), name+".golden")
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			r.t.Fatalf("create golden dir: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			r.t.Fatalf("write golden file: %v", err)
+		}
+		return r
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		r.t.Fatalf("read golden file (run with -update to create it): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		r.t.Errorf("%s %s: response differs from %s (run with -update to accept it)\n--- got\n%s\n--- want\n%s",
+			r.method, r.path, path, got, want)
+	}
+	return r
+}
+
+func (r *Response) goldenText(mask []string) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "HTTP %d\nContent-Type: %s\n\n", r.StatusCode, r.Header.Get("Content-Type"))
+
+	dec := json.NewDecoder(bytes.NewReader(r.Body))
+	dec.UseNumber()
+	var body any
+	if err := dec.Decode(&body); err != nil {
+		buf.Write(r.Body)
+		return buf.Bytes()
+	}
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	enc.Encode(maskValues(body, mask))
+	return buf.Bytes()
+}
+
+// maskValues replaces the non-empty values of the keys in mask, at any depth
+func maskValues(v any, mask []string) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if slices.Contains(mask, key) && value != nil && value != "" {
+				v[key] = "<" + key + ">"
+				continue
+			}
+			v[key] = maskValues(value, mask)
+		}
+	case []any:
+		for i := range v {
+			v[i] = maskValues(v[i], mask)
+		}
+	}
+	return v
+}
@@ -0,0 +1,72 @@
+package apiversion
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RewriteJSON returns an adapter that lets a version reshape the JSON body
+// produced by a shared handler, e.g. to restore a field renamed in a later
+// version. Non-JSON responses pass through untouched.
+func RewriteJSON(rewrite func(body any) any) func(gin.HandlerFunc) gin.HandlerFunc {
+	return func(next gin.HandlerFunc) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			original := c.Writer
+			capture := &captureWriter{ResponseWriter: original, status: http.StatusOK}
+			c.Writer = capture
+			next(c)
+			c.Writer = original
+
+			body := capture.buf.Bytes()
+			if strings.HasPrefix(original.Header().Get("Content-Type"), "application/json") && len(body) > 0 {
+				var decoded any
+				if err := json.Unmarshal(body, &decoded); err == nil {
+					if rewritten, err := json.Marshal(rewrite(decoded)); err == nil {
+						body = rewritten
+					}
+				}
+			}
+
+			original.Header().Del("Content-Length")
+			original.WriteHeader(capture.status)
+			original.Write(body)
+		}
+	}
+}
+
+// captureWriter buffers the response so an adapter can rewrite it
+type captureWriter struct {
+	gin.ResponseWriter
+	buf    bytes.Buffer
+	status int
+}
+
+func (w *captureWriter) WriteHeader(code int) {
+	w.status = code
+}
+
+func (w *captureWriter) WriteHeaderNow() {}
+
+func (w *captureWriter) Write(data []byte) (int, error) {
+	return w.buf.Write(data)
+}
+
+func (w *captureWriter) WriteString(s string) (int, error) {
+	return w.buf.WriteString(s)
+}
+
+func (w *captureWriter) Status() int {
+	return w.status
+}
+
+func (w *captureWriter) Size() int {
+	return w.buf.Len()
+}
+
+func (w *captureWriter) Written() bool {
+	return w.buf.Len() > 0
+}
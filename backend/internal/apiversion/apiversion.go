@@ -0,0 +1,253 @@
+// Package apiversion mounts the same handlers under several /api/<version>
+// prefixes, lets individual versions adapt a shared handler, and announces
+// deprecations with the Deprecation (RFC 9745) and Sunset (RFC 8594) headers.
+package apiversion
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VersionKey is the context key holding the name of the requested version
+const VersionKey = "apiVersion"
+
+// Version describes one mounted API version
+type Version struct {
+	// Name is the path segment, e.g. "v1"
+	Name string
+	// DeprecatedAt marks the whole version as deprecated from that moment
+	DeprecatedAt time.Time
+	// Sunset is when the version is expected to stop working
+	Sunset time.Time
+	// Link points clients at migration documentation
+	Link string
+}
+
+// Deprecated reports whether the version has been deprecated
+func (v Version) Deprecated() bool {
+	return !v.DeprecatedAt.IsZero()
+}
+
+// API owns every mounted version and the routes shared between them
+type API struct {
+	Routes
+	parent  gin.IRouter
+	prefix  string
+	metrics *Metrics
+	mounted []*Group
+}
+
+// New creates an API whose versions are mounted under prefix on parent
+func New(parent gin.IRouter, prefix string, metrics *Metrics) *API {
+	return &API{parent: parent, prefix: prefix, metrics: metrics}
+}
+
+// Mount adds a version. Mount every version before registering shared
+// routes: a route is only added to the versions mounted at that point.
+// Routes registered on the returned group exist in that version only.
+func (a *API) Mount(v Version) *Group {
+	g := &Group{
+		RouterGroup: a.parent.Group(a.prefix+"/"+v.Name, versionMiddleware(v, a.metrics)),
+		version:     v,
+	}
+	a.mounted = append(a.mounted, g)
+	a.Routes.groups = append(a.Routes.groups, g)
+	a.metrics.register(v)
+	return g
+}
+
+// Versions returns the mounted versions in mount order
+func (a *API) Versions() []Version {
+	versions := make([]Version, 0, len(a.mounted))
+	for _, g := range a.mounted {
+		versions = append(versions, g.version)
+	}
+	return versions
+}
+
+// VersionInfo is what clients are told about a version
+type VersionInfo struct {
+	Version      string     `json:"version"`
+	Deprecated   bool       `json:"deprecated"`
+	DeprecatedAt *time.Time `json:"deprecated_at,omitempty"`
+	Sunset       *time.Time `json:"sunset,omitempty"`
+	Link         string     `json:"link,omitempty"`
+}
+
+// Handler lists the mounted versions and their deprecation status as JSON.
+// It is public; usage counts are served by Metrics.Handler.
+func (a *API) Handler(c *gin.Context) {
+	list := make([]VersionInfo, 0, len(a.mounted))
+	for _, v := range a.Versions() {
+		info := VersionInfo{Version: v.Name, Deprecated: v.Deprecated(), Link: v.Link}
+		if v.Deprecated() {
+			info.DeprecatedAt = &v.DeprecatedAt
+		}
+		if !v.Sunset.IsZero() {
+			info.Sunset = &v.Sunset
+		}
+		list = append(list, info)
+	}
+	c.JSON(http.StatusOK, gin.H{"versions": list})
+}
+
+// Group is the router group of a single version
+type Group struct {
+	*gin.RouterGroup
+	version Version
+}
+
+// Version returns the version served by the group
+func (g *Group) Version() Version {
+	return g.version
+}
+
+// Routes registers handlers on a set of version groups at once
+type Routes struct {
+	groups []*Group
+	middle []gin.HandlerFunc
+	path   string
+}
+
+// Group returns a sub-route set sharing a path prefix and middleware
+func (r *Routes) Group(path string, middleware ...gin.HandlerFunc) *Routes {
+	return &Routes{
+		groups: r.groups,
+		middle: append(append([]gin.HandlerFunc(nil), r.middle...), middleware...),
+		path:   r.path + path,
+	}
+}
+
+// GET registers a shared GET handler
+func (r *Routes) GET(path string, h gin.HandlerFunc, opts ...RouteOption) {
+	r.Handle(http.MethodGet, path, h, opts...)
+}
+
+// POST registers a shared POST handler
+func (r *Routes) POST(path string, h gin.HandlerFunc, opts ...RouteOption) {
+	r.Handle(http.MethodPost, path, h, opts...)
+}
+
+// PUT registers a shared PUT handler
+func (r *Routes) PUT(path string, h gin.HandlerFunc, opts ...RouteOption) {
+	r.Handle(http.MethodPut, path, h, opts...)
+}
+
+// PATCH registers a shared PATCH handler
+func (r *Routes) PATCH(path string, h gin.HandlerFunc, opts ...RouteOption) {
+	r.Handle(http.MethodPatch, path, h, opts...)
+}
+
+// DELETE registers a shared DELETE handler
+func (r *Routes) DELETE(path string, h gin.HandlerFunc, opts ...RouteOption) {
+	r.Handle(http.MethodDelete, path, h, opts...)
+}
+
+// Handle registers h on every version in the set, applying the options of
+// each version
+func (r *Routes) Handle(method, path string, h gin.HandlerFunc, opts ...RouteOption) {
+	var cfg routeConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	for _, g := range r.groups {
+		name := g.version.Name
+		if cfg.only != nil && !cfg.only[name] {
+			continue
+		}
+
+		handler := h
+		if adapt, ok := cfg.adapters[name]; ok {
+			handler = adapt(handler)
+		}
+
+		var chain []gin.HandlerFunc
+		if d, ok := cfg.deprecations[name]; ok {
+			chain = append(chain, deprecationMiddleware(d))
+		}
+		chain = append(chain, r.middle...)
+		chain = append(chain, handler)
+		g.Handle(method, r.path+path, chain...)
+	}
+}
+
+// RouteOption customises a shared route for particular versions
+type RouteOption func(*routeConfig)
+
+type routeConfig struct {
+	only         map[string]bool
+	adapters     map[string]func(gin.HandlerFunc) gin.HandlerFunc
+	deprecations map[string]Version
+}
+
+// Only restricts the route to the named versions
+func Only(versions ...string) RouteOption {
+	return func(cfg *routeConfig) {
+		cfg.only = make(map[string]bool, len(versions))
+		for _, v := range versions {
+			cfg.only[v] = true
+		}
+	}
+}
+
+// Adapt wraps the shared handler when it is served by version, e.g. to
+// translate an old request shape or rename response fields
+func Adapt(version string, adapter func(gin.HandlerFunc) gin.HandlerFunc) RouteOption {
+	return func(cfg *routeConfig) {
+		if cfg.adapters == nil {
+			cfg.adapters = make(map[string]func(gin.HandlerFunc) gin.HandlerFunc)
+		}
+		cfg.adapters[version] = adapter
+	}
+}
+
+// Deprecate marks the route as deprecated in version even when the version
+// as a whole is not
+func Deprecate(version string, deprecatedAt, sunset time.Time, link string) RouteOption {
+	return func(cfg *routeConfig) {
+		if cfg.deprecations == nil {
+			cfg.deprecations = make(map[string]Version)
+		}
+		cfg.deprecations[version] = Version{Name: version, DeprecatedAt: deprecatedAt, Sunset: sunset, Link: link}
+	}
+}
+
+// FromContext returns the name of the version serving the request
+func FromContext(c *gin.Context) string {
+	return c.GetString(VersionKey)
+}
+
+// versionMiddleware tags the request with its version, sets the version's
+// deprecation headers and records usage
+func versionMiddleware(v Version, metrics *Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(VersionKey, v.Name)
+		if v.Deprecated() {
+			setDeprecationHeaders(c, v)
+		}
+		c.Next()
+		metrics.record(v.Name, c.Request.Method+" "+c.FullPath(), c.Writer.Status(), c.Writer.Header().Get("Deprecation") != "")
+	}
+}
+
+func deprecationMiddleware(d Version) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setDeprecationHeaders(c, d)
+		c.Next()
+	}
+}
+
+func setDeprecationHeaders(c *gin.Context, v Version) {
+	h := c.Writer.Header()
+	h.Set("Deprecation", fmt.Sprintf("@%d", v.DeprecatedAt.Unix()))
+	if !v.Sunset.IsZero() {
+		h.Set("Sunset", v.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if v.Link != "" {
+		h.Add("Link", fmt.Sprintf(`<%s>; rel="deprecation"`, v.Link))
+	}
+}
@@ -0,0 +1,186 @@
+package apiversion
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func newTestAPI() (*gin.Engine, *API, *Metrics) {
+	router := gin.New()
+	metrics := NewMetrics()
+	api := New(router, "/api", metrics)
+	return router, api, metrics
+}
+
+func do(router http.Handler, method, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	return w
+}
+
+func TestSharedHandlersMountedOnEveryVersion(t *testing.T) {
+	router, api, _ := newTestAPI()
+	api.Mount(Version{Name: "v1"})
+	api.Mount(Version{Name: "v2"})
+
+	api.GET("/ping", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"version": FromContext(c)})
+	})
+	api.GET("/new", func(c *gin.Context) { c.Status(http.StatusNoContent) }, Only("v2"))
+
+	for _, v := range []string{"v1", "v2"} {
+		w := do(router, http.MethodGet, "/api/"+v+"/ping")
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", v, w.Code)
+		}
+		var body map[string]string
+		json.Unmarshal(w.Body.Bytes(), &body)
+		if body["version"] != v {
+			t.Errorf("Expected version %q in context, got %q", v, body["version"])
+		}
+	}
+
+	if w := do(router, http.MethodGet, "/api/v1/new"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected v2-only route to be missing from v1, got %d", w.Code)
+	}
+	if w := do(router, http.MethodGet, "/api/v2/new"); w.Code != http.StatusNoContent {
+		t.Errorf("Expected v2-only route in v2, got %d", w.Code)
+	}
+}
+
+func TestAdapterRewritesResponseForOneVersion(t *testing.T) {
+	router, api, _ := newTestAPI()
+	api.Mount(Version{Name: "v1"})
+	api.Mount(Version{Name: "v2"})
+
+	// v2 renamed "entries" to "data"; v1 clients still expect the old name
+	v1Adapter := RewriteJSON(func(body any) any {
+		m := body.(map[string]any)
+		m["entries"] = m["data"]
+		delete(m, "data")
+		return m
+	})
+	api.GET("/items", func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"data": []int{1, 2}})
+	}, Adapt("v1", v1Adapter))
+
+	w := do(router, http.MethodGet, "/api/v1/items")
+	if w.Code != http.StatusCreated {
+		t.Errorf("Expected adapter to keep status 201, got %d", w.Code)
+	}
+	var v1 map[string]any
+	json.Unmarshal(w.Body.Bytes(), &v1)
+	if _, ok := v1["entries"]; !ok || v1["data"] != nil {
+		t.Errorf("Expected v1 body to use entries, got %s", w.Body.String())
+	}
+
+	var v2 map[string]any
+	json.Unmarshal(do(router, http.MethodGet, "/api/v2/items").Body.Bytes(), &v2)
+	if _, ok := v2["data"]; !ok {
+		t.Errorf("Expected v2 body to be untouched, got %v", v2)
+	}
+}
+
+func TestDeprecationHeaders(t *testing.T) {
+	router, api, _ := newTestAPI()
+	deprecatedAt := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	api.Mount(Version{Name: "v1", DeprecatedAt: deprecatedAt, Sunset: sunset, Link: "https://example.com/migrate"})
+	api.Mount(Version{Name: "v2"})
+
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	api.GET("/ping", ok)
+	api.GET("/legacy", ok, Deprecate("v2", deprecatedAt, time.Time{}, ""))
+
+	w := do(router, http.MethodGet, "/api/v1/ping")
+	if got := w.Header().Get("Deprecation"); got != "@1751328000" {
+		t.Errorf("Expected Deprecation @1751328000, got %q", got)
+	}
+	if got := w.Header().Get("Sunset"); got != "Thu, 01 Jan 2026 00:00:00 GMT" {
+		t.Errorf("Unexpected Sunset header %q", got)
+	}
+	if got := w.Header().Get("Link"); got != `<https://example.com/migrate>; rel="deprecation"` {
+		t.Errorf("Unexpected Link header %q", got)
+	}
+
+	if got := do(router, http.MethodGet, "/api/v2/ping").Header().Get("Deprecation"); got != "" {
+		t.Errorf("Expected no Deprecation header on v2, got %q", got)
+	}
+
+	w = do(router, http.MethodGet, "/api/v2/legacy")
+	if w.Header().Get("Deprecation") == "" {
+		t.Error("Expected route-level Deprecation header on v2 legacy route")
+	}
+	if w.Header().Get("Sunset") != "" {
+		t.Error("Expected no Sunset header without a sunset date")
+	}
+}
+
+func TestMetricsPerVersion(t *testing.T) {
+	router, api, metrics := newTestAPI()
+	api.Mount(Version{Name: "v1", DeprecatedAt: time.Now()})
+	api.Mount(Version{Name: "v2"})
+	api.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+	api.GET("/boom", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+
+	do(router, http.MethodGet, "/api/v1/ping")
+	do(router, http.MethodGet, "/api/v1/ping")
+	do(router, http.MethodGet, "/api/v1/boom")
+	do(router, http.MethodGet, "/api/v2/ping")
+
+	stats := metrics.Snapshot()
+	if len(stats) != 2 {
+		t.Fatalf("Expected stats for 2 versions, got %d", len(stats))
+	}
+
+	v1, v2 := stats[0], stats[1]
+	if v1.Version != "v1" || v1.Requests != 3 || v1.DeprecatedRequests != 3 || v1.Errors != 1 {
+		t.Errorf("Unexpected v1 stats: %+v", v1)
+	}
+	if v1.Routes["GET /api/v1/ping"] != 2 {
+		t.Errorf("Expected 2 hits on v1 ping, got %v", v1.Routes)
+	}
+	if v2.Requests != 1 || v2.DeprecatedRequests != 0 || v2.LastSeen == nil {
+		t.Errorf("Unexpected v2 stats: %+v", v2)
+	}
+}
+
+func TestHandlerListsVersionsWithoutUsage(t *testing.T) {
+	router, api, _ := newTestAPI()
+	sunset := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	api.Mount(Version{Name: "v1", DeprecatedAt: sunset.AddDate(0, -6, 0), Sunset: sunset, Link: "https://example.com/migrate"})
+	api.Mount(Version{Name: "v2"})
+	api.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/api/versions", api.Handler)
+	do(router, http.MethodGet, "/api/v1/ping")
+
+	w := do(router, http.MethodGet, "/api/versions")
+	var body struct {
+		Versions []map[string]any `json:"versions"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || len(body.Versions) != 2 {
+		t.Fatalf("Expected 2 versions, got %s", w.Body)
+	}
+	v1, v2 := body.Versions[0], body.Versions[1]
+	if v1["version"] != "v1" || v1["deprecated"] != true || v1["sunset"] != "2026-01-01T00:00:00Z" {
+		t.Errorf("Unexpected v1: %v", v1)
+	}
+	if v2["version"] != "v2" || v2["deprecated"] != false {
+		t.Errorf("Unexpected v2: %v", v2)
+	}
+	for _, key := range []string{"requests", "routes", "last_seen"} {
+		if _, ok := v1[key]; ok {
+			t.Errorf("Expected no %q in the public list, got %v", key, v1)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package apiversion
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics counts requests per API version so that old versions can be
+// retired once nobody calls them any more
+type Metrics struct {
+	mu       sync.Mutex
+	versions map[string]*versionUsage
+	now      func() time.Time
+}
+
+type versionUsage struct {
+	version    Version
+	requests   int64
+	deprecated int64
+	errors     int64
+	lastSeen   time.Time
+	routes     map[string]int64
+}
+
+// VersionStats is a snapshot of one version's usage
+type VersionStats struct {
+	Version            string           `json:"version"`
+	Deprecated         bool             `json:"deprecated"`
+	DeprecatedAt       *time.Time       `json:"deprecated_at,omitempty"`
+	Sunset             *time.Time       `json:"sunset,omitempty"`
+	Requests           int64            `json:"requests"`
+	DeprecatedRequests int64            `json:"deprecated_requests"`
+	Errors             int64            `json:"errors"`
+	LastSeen           *time.Time       `json:"last_seen,omitempty"`
+	Routes             map[string]int64 `json:"routes"`
+}
+
+// NewMetrics creates an empty metrics collector
+func NewMetrics() *Metrics {
+	return &Metrics{
+		versions: make(map[string]*versionUsage),
+		now:      time.Now,
+	}
+}
+
+func (m *Metrics) register(v Version) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.versions[v.Name] = &versionUsage{version: v, routes: make(map[string]int64)}
+}
+
+func (m *Metrics) record(version, route string, status int, deprecated bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u, ok := m.versions[version]
+	if !ok {
+		return
+	}
+	u.requests++
+	if deprecated {
+		u.deprecated++
+	}
+	if status >= http.StatusInternalServerError {
+		u.errors++
+	}
+	u.lastSeen = m.now()
+	u.routes[route]++
+}
+
+// Snapshot returns the usage of every registered version ordered by name
+func (m *Metrics) Snapshot() []VersionStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := make([]VersionStats, 0, len(m.versions))
+	for _, u := range m.versions {
+		s := VersionStats{
+			Version:            u.version.Name,
+			Deprecated:         u.version.Deprecated(),
+			Requests:           u.requests,
+			DeprecatedRequests: u.deprecated,
+			Errors:             u.errors,
+			Routes:             make(map[string]int64, len(u.routes)),
+		}
+		if u.version.Deprecated() {
+			deprecatedAt := u.version.DeprecatedAt
+			s.DeprecatedAt = &deprecatedAt
+		}
+		if !u.version.Sunset.IsZero() {
+			sunset := u.version.Sunset
+			s.Sunset = &sunset
+		}
+		if !u.lastSeen.IsZero() {
+			lastSeen := u.lastSeen
+			s.LastSeen = &lastSeen
+		}
+		for route, n := range u.routes {
+			s.Routes[route] = n
+		}
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Version < stats[j].Version })
+	return stats
+}
+
+// Handler serves the usage snapshot as JSON. The routes and counts are for
+// operators only, so mount it behind admin authentication.
+func (m *Metrics) Handler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"versions": m.Snapshot()})
+}
@@ -0,0 +1,160 @@
+// Package app wires the backend together: services, background workers and
+// the HTTP router with all middleware. The server command and the
+// integration tests build the same App, so tests exercise exactly what runs
+// in production.
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/auth"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/config"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/email"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/entries"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/flags"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/i18n"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/mfa"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/oidc"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/orgs"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/pagination"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/privacy"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/search"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/sessions"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/users"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/webhooks"
+)
+
+// Options adjust how the App is built; the zero value is what the server
+// uses
+type Options struct {
+	// Now replaces the wall clock of every service and repository
+	Now func() time.Time
+	// EmailSender replaces the sender chosen from the config
+	EmailSender email.Sender
+	// HTTPClient is used for requests to identity providers and webhook
+	// endpoints; nil means the services' default clients
+	HTTPClient *http.Client
+	// Quiet turns off the request log
+	Quiet bool
+}
+
+// App is the fully wired backend
+type App struct {
+	// Router serves the whole HTTP API
+	Router *gin.Engine
+
+	Config   *config.Config
+	DB       *database.DB
+	Tokens   *auth.TokenService
+	Users    *users.Repository
+	Sessions *sessions.Service
+	MFA      *mfa.Service
+	OIDC     *oidc.Service
+	Orgs     *orgs.Service
+	Entries  *entries.Repository
+	Webhooks *webhooks.Service
+	Search   *search.Service
+	Email    *email.Service
+	Privacy  *privacy.Service
+	Flags    *flags.Service
+	// FlagStore is nil when flags come from a file
+	FlagStore *flags.DBStore
+
+	bundle        *i18n.Bundle
+	emailRenderer *email.Renderer
+	pages         *pagination.Codec
+}
+
+// New builds the services and the router. ctx bounds the initial load of
+// feature flags.
+func New(ctx context.Context, cfg *config.Config, db *database.DB, opts Options) (*App, error) {
+	a := &App{
+		Config:   cfg,
+		DB:       db,
+		Tokens:   auth.NewTokenService(cfg.JWTSecret, cfg.AccessTokenTTL),
+		Users:    users.NewRepository(db),
+		Sessions: sessions.NewService(db, cfg.RefreshTokenTTL, cfg.SessionCacheTTL),
+		MFA:      mfa.NewService(db, cfg.MFAIssuer),
+		Orgs:     orgs.NewService(db, cfg.InvitationTTL),
+		Entries:  entries.NewRepository(db),
+		Webhooks: webhooks.NewService(db, opts.HTTPClient),
+		Search:   search.NewService(db),
+		pages:    pagination.NewCodec(cfg.CursorSecret),
+	}
+	a.OIDC = oidc.NewService(db, a.Users, cfg.OIDCProviders, cfg.OIDCLoginTTL, opts.HTTPClient)
+
+	var err error
+	if a.bundle, err = i18n.NewBundle(i18n.Catalogs(), cfg.DefaultLocale); err != nil {
+		return nil, fmt.Errorf("load message catalogs: %w", err)
+	}
+
+	// Emails are delivered over SMTP when SMTP_ADDR is set and logged
+	// otherwise
+	if a.emailRenderer, err = email.NewRenderer(email.Templates(), cfg.DefaultLocale); err != nil {
+		return nil, fmt.Errorf("load email templates: %w", err)
+	}
+	emailSender := opts.EmailSender
+	if emailSender == nil {
+		emailSender = email.LogSender{}
+		if cfg.SMTPAddr != "" {
+			emailSender = &email.SMTPSender{Addr: cfg.SMTPAddr, Username: cfg.SMTPUsername, Password: cfg.SMTPPassword}
+		}
+	}
+	a.Email = email.NewService(db, a.emailRenderer, emailSender, cfg.EmailFrom)
+
+	// Every module storing user data registers itself for export and deletion
+	privacyRegistry := privacy.NewRegistry()
+	privacyRegistry.Register(users.NewPrivacyModule(a.Users))
+	privacyRegistry.Register(sessions.NewPrivacyModule(db))
+	privacyRegistry.Register(oidc.NewPrivacyModule(a.OIDC))
+	privacyRegistry.Register(mfa.NewPrivacyModule(db))
+	privacyRegistry.Register(orgs.NewPrivacyModule(db))
+	privacyRegistry.Register(entries.NewPrivacyModule(db))
+	privacyRegistry.Register(webhooks.NewPrivacyModule(db))
+	privacyRegistry.Register(email.NewPrivacyModule(db))
+	a.Privacy = privacy.NewService(db, privacyRegistry, cfg.DeletionGracePeriod, cfg.ExportLinkTTL)
+
+	// Feature flags come from a config file when FLAGS_FILE is set, from
+	// the database otherwise; both are hot-reloaded
+	var flagSource flags.Source = flags.FileSource{Path: cfg.FlagsFile}
+	if cfg.FlagsFile == "" {
+		a.FlagStore = flags.NewDBStore(db)
+		flagSource = a.FlagStore
+	}
+	if a.Flags, err = flags.NewService(ctx, flagSource); err != nil {
+		return nil, fmt.Errorf("load feature flags: %w", err)
+	}
+
+	if opts.Now != nil {
+		a.SetClock(opts.Now)
+	}
+	a.Router = a.routes(opts)
+	return a, nil
+}
+
+// SetClock replaces the time source of every service and repository
+func (a *App) SetClock(now func() time.Time) {
+	a.Tokens.SetClock(now)
+	a.Users.SetClock(now)
+	a.Sessions.SetClock(now)
+	a.MFA.SetClock(now)
+	a.OIDC.SetClock(now)
+	a.Orgs.SetClock(now)
+	a.Entries.SetClock(now)
+	a.Webhooks.SetClock(now)
+	a.Email.SetClock(now)
+	a.Privacy.SetClock(now)
+}
+
+// Start runs the background workers until ctx is cancelled
+func (a *App) Start(ctx context.Context) {
+	go a.Privacy.Run(ctx, time.Minute)
+	go a.Webhooks.Run(ctx, 5*time.Second)
+	go a.Email.Run(ctx, 10*time.Second)
+	go a.Flags.Watch(ctx, a.Config.FlagsReloadInterval)
+}
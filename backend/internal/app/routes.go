@@ -0,0 +1,152 @@
+package app
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/apiversion"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/handlers"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/middleware"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/users"
+)
+
+// routes builds the router with every middleware and endpoint
+func (a *App) routes(opts Options) *gin.Engine {
+	cfg := a.Config
+	router := gin.New()
+
+	// Add middleware
+	if !opts.Quiet {
+		router.Use(gin.Logger())
+	}
+	router.Use(gin.Recovery())
+	if cfg.TLSEnabled() && cfg.HSTSMaxAge > 0 {
+		router.Use(middleware.HSTS(cfg.HSTSMaxAge, cfg.HSTSIncludeSubdomains))
+	}
+	router.Use(middleware.CORS())
+	router.Use(middleware.I18n(a.bundle, handlers.ErrorMessages, a.Users))
+
+	// Health check endpoint
+	router.GET("/health", handlers.HealthCheck)
+
+	// Email template previews
+	if cfg.Env == "development" {
+		emailHandler := handlers.NewEmailHandler(a.emailRenderer)
+		router.GET("/dev/emails", emailHandler.List)
+		router.GET("/dev/emails/:template", emailHandler.Preview)
+	}
+
+	// API routes, mounted under /api/v1 and /api/v2. Shared handlers serve
+	// both versions; use apiversion.Adapt for per-version differences.
+	apiMetrics := apiversion.NewMetrics()
+	api := apiversion.New(router, "/api", apiMetrics)
+	api.Mount(apiversion.Version{
+		Name:         "v1",
+		DeprecatedAt: cfg.APIV1DeprecatedAt,
+		Sunset:       cfg.APIV1Sunset,
+	})
+	api.Mount(apiversion.Version{Name: "v2"})
+	router.GET("/api/versions", api.Handler)
+	{
+		api.GET("/ping", handlers.Ping)
+
+		flagsHandler := handlers.NewFlagsHandler(a.Flags, a.FlagStore)
+		api.Group("", middleware.OptionalAuth(a.Tokens, a.Sessions)).GET("/flags", flagsHandler.Evaluate)
+
+		privacyHandler := handlers.NewPrivacyHandler(a.Privacy)
+		api.GET("/privacy/exports/:id/download", privacyHandler.DownloadExport)
+
+		authHandler := handlers.NewAuthHandler(a.Users, a.Sessions, a.Orgs, a.Tokens, a.MFA, cfg.MFAChallengeTTL)
+		api.POST("/auth/register", authHandler.Register)
+		api.POST("/auth/login", authHandler.Login)
+		api.POST("/auth/refresh", authHandler.Refresh)
+
+		mfaHandler := handlers.NewMFAHandler(authHandler, a.MFA)
+		api.POST("/auth/mfa/challenge", mfaHandler.Challenge)
+		api.POST("/auth/mfa/challenge/enroll", mfaHandler.ChallengeEnroll)
+
+		oidcHandler := handlers.NewOIDCHandler(authHandler, a.OIDC)
+		api.GET("/auth/oidc/providers", oidcHandler.Providers)
+		api.GET("/auth/oidc/:provider/start", oidcHandler.Start)
+		api.GET("/auth/oidc/:provider/callback", oidcHandler.Callback)
+		api.POST("/auth/oidc/:provider/callback", oidcHandler.Callback)
+
+		authed := api.Group("", middleware.Auth(a.Tokens), middleware.Session(a.Sessions))
+		authed.POST("/auth/logout", authHandler.Logout)
+		authed.GET("/sessions", authHandler.ListSessions)
+		authed.DELETE("/sessions", authHandler.RevokeOtherSessions)
+		authed.DELETE("/sessions/:id", authHandler.RevokeSession)
+		authed.POST("/auth/oidc/:provider/link", oidcHandler.Link)
+		authed.GET("/auth/identities", oidcHandler.Identities)
+		authed.DELETE("/auth/identities/:provider", oidcHandler.Unlink)
+
+		authed.GET("/mfa", mfaHandler.Status)
+		authed.POST("/mfa/enroll", mfaHandler.Enroll)
+		authed.GET("/mfa/qr.png", mfaHandler.QRCode)
+		authed.POST("/mfa/confirm", mfaHandler.Confirm)
+		authed.POST("/mfa/recovery-codes", mfaHandler.RegenerateRecoveryCodes)
+		authed.POST("/mfa/disable", mfaHandler.Disable)
+
+		authed.POST("/privacy/exports", privacyHandler.RequestExport)
+		authed.GET("/privacy/exports/:id", privacyHandler.GetExport)
+		authed.POST("/privacy/deletion", privacyHandler.RequestDeletion)
+		authed.GET("/privacy/deletion", privacyHandler.GetDeletion)
+		authed.DELETE("/privacy/deletion", privacyHandler.CancelDeletion)
+		authed.GET("/privacy/audit", privacyHandler.AuditTrail)
+
+		webhooksHandler := handlers.NewWebhooksHandler(a.Webhooks)
+		authed.GET("/webhooks", webhooksHandler.List)
+		authed.POST("/webhooks", webhooksHandler.Create)
+		authed.GET("/webhooks/:id", webhooksHandler.Get)
+		authed.PATCH("/webhooks/:id", webhooksHandler.Update)
+		authed.DELETE("/webhooks/:id", webhooksHandler.Delete)
+		authed.GET("/webhooks/:id/deliveries", webhooksHandler.Deliveries)
+		authed.POST("/webhooks/:id/deliveries/:deliveryID/redeliver", webhooksHandler.Redeliver)
+
+		localeHandler := handlers.NewLocaleHandler(a.Users, a.bundle)
+		authed.GET("/settings/locale", localeHandler.Get)
+		authed.PUT("/settings/locale", localeHandler.Update)
+
+		searchHandler := handlers.NewSearchHandler(a.Search)
+		authed.GET("/search/settings", searchHandler.GetSettings)
+		authed.PUT("/search/settings", searchHandler.UpdateSettings)
+
+		orgsHandler := handlers.NewOrgsHandler(a.Orgs, a.Users)
+		authed.POST("/orgs", orgsHandler.Create)
+		authed.GET("/orgs", orgsHandler.List)
+		authed.POST("/invitations/accept", orgsHandler.AcceptInvitation)
+
+		// Routes acting on the organization selected by the X-Org header,
+		// subdomain or token claim; repositories are scoped to it
+		scoped := authed.Group("", middleware.Tenant(a.Orgs, cfg.TenantBaseDomain))
+		scoped.GET("/org", orgsHandler.Current)
+		scoped.GET("/org/members", orgsHandler.ListMembers)
+		scoped.POST("/org/invitations", orgsHandler.Invite)
+		scoped.PUT("/org/members/:userID", orgsHandler.UpdateMemberRole)
+		scoped.DELETE("/org/members/:userID", orgsHandler.RemoveMember)
+
+		scoped.GET("/org/webhooks", webhooksHandler.List)
+		scoped.POST("/org/webhooks", webhooksHandler.Create)
+		scoped.GET("/org/webhooks/:id", webhooksHandler.Get)
+		scoped.PATCH("/org/webhooks/:id", webhooksHandler.Update)
+		scoped.DELETE("/org/webhooks/:id", webhooksHandler.Delete)
+		scoped.GET("/org/webhooks/:id/deliveries", webhooksHandler.Deliveries)
+		scoped.POST("/org/webhooks/:id/deliveries/:deliveryID/redeliver", webhooksHandler.Redeliver)
+
+		entriesHandler := handlers.NewEntriesHandler(a.Entries, a.Webhooks, a.pages)
+		scoped.GET("/entries", entriesHandler.List, apiversion.Adapt("v1", handlers.EntriesListV1))
+		scoped.POST("/entries", entriesHandler.Create)
+		scoped.GET("/entries/:id", entriesHandler.Get)
+		scoped.PUT("/entries/:id", entriesHandler.Update)
+		scoped.DELETE("/entries/:id", entriesHandler.Delete)
+		scoped.GET("/search", searchHandler.Search)
+
+		admin := authed.Group("/admin", middleware.RequireRole(users.RoleAdmin))
+		admin.GET("/versions", apiMetrics.Handler)
+		admin.GET("/flags", flagsHandler.List)
+		admin.PUT("/flags/:key", flagsHandler.Save)
+		admin.DELETE("/flags/:key", flagsHandler.Delete)
+		admin.GET("/mfa/roles", mfaHandler.RequiredRoles)
+		admin.PUT("/mfa/roles/:role", mfaHandler.SetRequired)
+		// Add more routes as needed
+	}
+	return router
+}
@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"errors"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MinPasswordLength is the minimum number of characters of a password
+const MinPasswordLength = 8
+
+// ErrWeakPassword is returned for passwords shorter than MinPasswordLength
+var ErrWeakPassword = errors.New("password must be at least 8 characters")
+
+// HashPassword returns the bcrypt hash of password
+func HashPassword(password string) (string, error) {
+	if utf8.RuneCountInString(password) < MinPasswordLength {
+		return "", ErrWeakPassword
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword reports whether password matches hash
+func CheckPassword(hash, password string) bool {
+	return hash != "" && bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestIssueAndParse(t *testing.T) {
+	svc := NewTokenService("test-secret", time.Minute)
+
+	token, err := svc.Issue(Claims{UserID: 42, Email: "user@example.com", Role: "admin"})
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+
+	claims, err := svc.Parse(token)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if claims.UserID != 42 || claims.Email != "user@example.com" || claims.Role != "admin" {
+		t.Errorf("Unexpected claims: %+v", claims)
+	}
+}
+
+func TestParseRejectsBadTokens(t *testing.T) {
+	svc := NewTokenService("test-secret", time.Minute)
+	other := NewTokenService("other-secret", time.Minute)
+	expired := NewTokenService("test-secret", -time.Minute)
+
+	foreign, _ := other.Issue(Claims{UserID: 1, Email: "a@example.com"})
+	if _, err := svc.Parse(foreign); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken for wrong signature, got %v", err)
+	}
+
+	old, _ := expired.Issue(Claims{UserID: 1, Email: "a@example.com"})
+	if _, err := svc.Parse(old); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("Expected ErrTokenExpired, got %v", err)
+	}
+
+	if _, err := svc.Parse("not-a-token"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken for garbage, got %v", err)
+	}
+}
+
+func TestChallengeTokens(t *testing.T) {
+	svc := NewTokenService("test-secret", time.Minute)
+
+	challenge, err := svc.IssueChallenge(7, time.Minute)
+	if err != nil {
+		t.Fatalf("IssueChallenge failed: %v", err)
+	}
+	if id, err := svc.ParseChallenge(challenge); err != nil || id != 7 {
+		t.Errorf("Expected user 7, got %d, %v", id, err)
+	}
+	if _, err := svc.Parse(challenge); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected challenge to be rejected as access token, got %v", err)
+	}
+
+	access, _ := svc.Issue(Claims{UserID: 7, Email: "a@example.com"})
+	if _, err := svc.ParseChallenge(access); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected access token to be rejected as challenge, got %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 )
 
 func TestLoad(t *testing.T) {
@@ -83,3 +84,74 @@ func TestGetEnvAsBool(t *testing.T) {
 		t.Errorf("Expected fallback value false, got %v", result)
 	}
 }
+
+func TestGetEnvAsDuration(t *testing.T) {
+	// Test with valid duration
+	os.Setenv("TEST_DURATION", "90m")
+	defer os.Unsetenv("TEST_DURATION")
+
+	result := getEnvAsDuration("TEST_DURATION", time.Second)
+	if result != 90*time.Minute {
+		t.Errorf("Expected 90m, got %v", result)
+	}
+
+	// Test with fallback
+	result = getEnvAsDuration("NON_EXISTENT", time.Second)
+	if result != time.Second {
+		t.Errorf("Expected fallback value 1s, got %v", result)
+	}
+}
+
+func TestGetEnvAsInterval(t *testing.T) {
+	defer os.Unsetenv("TEST_INTERVAL")
+	for value, want := range map[string]time.Duration{"5s": 5 * time.Second, "0s": time.Minute, "-1m": time.Minute} {
+		os.Setenv("TEST_INTERVAL", value)
+		if got := getEnvAsInterval("TEST_INTERVAL", time.Minute); got != want {
+			t.Errorf("getEnvAsInterval(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestGetEnvAsTime(t *testing.T) {
+	os.Setenv("TEST_DATE", "2026-01-31")
+	os.Setenv("TEST_TIMESTAMP", "2026-01-31T12:00:00Z")
+	defer os.Unsetenv("TEST_DATE")
+	defer os.Unsetenv("TEST_TIMESTAMP")
+
+	if got := getEnvAsTime("TEST_DATE"); !got.Equal(time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected 2026-01-31, got %v", got)
+	}
+	if got := getEnvAsTime("TEST_TIMESTAMP"); !got.Equal(time.Date(2026, 1, 31, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected 2026-01-31T12:00:00Z, got %v", got)
+	}
+	if got := getEnvAsTime("NON_EXISTENT"); !got.IsZero() {
+		t.Errorf("Expected zero time, got %v", got)
+	}
+}
+
+func TestLoadOIDCProviders(t *testing.T) {
+	os.Setenv("OIDC_PROVIDERS", "Google, corp, unconfigured")
+	os.Setenv("OIDC_GOOGLE_CLIENT_ID", "google-client")
+	os.Setenv("OIDC_CORP_ISSUER", "https://sso.example.com")
+	os.Setenv("OIDC_CORP_CLIENT_ID", "corp-client")
+	os.Setenv("OIDC_CORP_SCOPES", "openid email")
+	defer func() {
+		for _, key := range []string{"OIDC_PROVIDERS", "OIDC_GOOGLE_CLIENT_ID", "OIDC_CORP_ISSUER", "OIDC_CORP_CLIENT_ID", "OIDC_CORP_SCOPES"} {
+			os.Unsetenv(key)
+		}
+	}()
+
+	providers := loadOIDCProviders()
+	if len(providers) != 2 {
+		t.Fatalf("Expected 2 configured providers, got %+v", providers)
+	}
+	if providers[0].Name != "google" || providers[0].Issuer != "https://accounts.google.com" {
+		t.Errorf("Expected google with its well-known issuer, got %+v", providers[0])
+	}
+	if len(providers[0].Scopes) != 3 {
+		t.Errorf("Expected default scopes, got %v", providers[0].Scopes)
+	}
+	if providers[1].Issuer != "https://sso.example.com" || len(providers[1].Scopes) != 2 {
+		t.Errorf("Expected corp provider from env, got %+v", providers[1])
+	}
+}
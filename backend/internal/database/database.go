@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Dialect identifies the SQL flavour spoken by the connected database
+type Dialect string
+
+// Supported dialects
+const (
+	Postgres Dialect = "postgres"
+	SQLite   Dialect = "sqlite"
+)
+
+// DB wraps a connection pool together with its dialect
+type DB struct {
+	*sql.DB
+	Dialect Dialect
+}
+
+// Open connects to the database described by url and verifies the connection.
+// postgres:// and postgresql:// URLs use PostgreSQL, sqlite://<path> uses a
+// local SQLite file (sqlite://:memory: for a throwaway in-memory database).
+func Open(url string) (*DB, error) {
+	dialect, dsn, err := parseURL(url)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := sql.Open(string(dialect), dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open %s database: %w", dialect, err)
+	}
+
+	if dialect == SQLite {
+		// SQLite allows a single writer; serialising access through one
+		// connection avoids SQLITE_BUSY errors and keeps :memory: databases
+		// shared across the pool.
+		conn.SetMaxOpenConns(1)
+	} else {
+		conn.SetMaxOpenConns(25)
+		conn.SetMaxIdleConns(5)
+		conn.SetConnMaxLifetime(5 * time.Minute)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := conn.PingContext(ctx); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("ping %s database: %w", dialect, err)
+	}
+
+	return &DB{DB: conn, Dialect: dialect}, nil
+}
+
+// parseURL maps a database URL to a driver dialect and driver-specific DSN
+func parseURL(url string) (Dialect, string, error) {
+	switch {
+	case strings.HasPrefix(url, "postgres://"), strings.HasPrefix(url, "postgresql://"):
+		return Postgres, url, nil
+	case strings.HasPrefix(url, "sqlite://"):
+		path := strings.TrimPrefix(url, "sqlite://")
+		if path == "" {
+			return "", "", fmt.Errorf("sqlite url %q has no path", url)
+		}
+		sep := "?"
+		if strings.Contains(path, "?") {
+			sep = "&"
+		}
+		return SQLite, path + sep + "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)", nil
+	default:
+		return "", "", fmt.Errorf("unsupported database url %q", url)
+	}
+}
+
+// WithTx runs fn inside a transaction, committing on success and rolling back
+// when fn returns an error
+func (db *DB) WithTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// IsUniqueViolation reports whether err is a unique or primary key
+// constraint failure, for callers that map a lost insert race to a
+// domain error
+func IsUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		code := sqliteErr.Code()
+		return code == sqlite3.SQLITE_CONSTRAINT_UNIQUE || code == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+	}
+	return false
+}
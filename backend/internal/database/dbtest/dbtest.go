@@ -0,0 +1,85 @@
+// Package dbtest provides throwaway, fully migrated databases for tests.
+//
+// Tests run against SQLite by default. When TEST_DATABASE_URL names a
+// PostgreSQL server, every test gets its own schema there instead, so tests
+// can run in parallel against one server without seeing each other's data.
+package dbtest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+)
+
+// EnvURL is the environment variable selecting a PostgreSQL test server
+const EnvURL = "TEST_DATABASE_URL"
+
+// New opens a migrated database for the test and closes it when the test
+// finishes
+func New(t testing.TB) *database.DB {
+	t.Helper()
+
+	var db *database.DB
+	if serverURL := os.Getenv(EnvURL); strings.HasPrefix(serverURL, "postgres") {
+		db = newSchema(t, serverURL)
+	} else {
+		var err error
+		db, err = database.Open("sqlite://" + filepath.Join(t.TempDir(), "test.db"))
+		if err != nil {
+			t.Fatalf("open test database: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+	}
+
+	if _, err := db.Migrate(context.Background()); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+	return db
+}
+
+// newSchema creates a uniquely named schema on the PostgreSQL server and
+// connects with it as the search path; the schema is dropped afterwards
+func newSchema(t testing.TB, serverURL string) *database.DB {
+	t.Helper()
+
+	admin, err := database.Open(serverURL)
+	if err != nil {
+		t.Fatalf("open test database server: %v", err)
+	}
+	suffix := make([]byte, 6)
+	rand.Read(suffix)
+	schema := "test_" + hex.EncodeToString(suffix)
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		admin.Close()
+		t.Fatalf("create test schema: %v", err)
+	}
+
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		t.Fatalf("parse %s: %v", EnvURL, err)
+	}
+	query := u.Query()
+	query.Set("search_path", schema)
+	u.RawQuery = query.Encode()
+
+	db, err := database.Open(u.String())
+	if err != nil {
+		t.Fatalf("open test schema: %v", err)
+	}
+	// Cleanups run last-registered first: close the pool, then drop
+	t.Cleanup(func() {
+		if _, err := admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`); err != nil {
+			t.Errorf("drop test schema: %v", err)
+		}
+		admin.Close()
+	})
+	t.Cleanup(func() { db.Close() })
+	return db
+}
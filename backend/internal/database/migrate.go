@@ -0,0 +1,191 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/migrations"
+)
+
+// Migration is a single versioned schema change
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Migrations returns the migrations for the database dialect, ordered by version
+func (db *DB) Migrations() ([]Migration, error) {
+	var files fs.FS = migrations.Postgres
+	if db.Dialect == SQLite {
+		sub, err := fs.Sub(migrations.SQLite, "sqlite")
+		if err != nil {
+			return nil, err
+		}
+		files = sub
+	}
+	return loadMigrations(files)
+}
+
+// loadMigrations reads <version>_<name>.up.sql / .down.sql pairs from files
+func loadMigrations(files fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		name := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		prefix, label, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %q: expected <version>_<name>", name)
+		}
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %q: invalid version: %w", name, err)
+		}
+
+		body, err := fs.ReadFile(files, name)
+		if err != nil {
+			return nil, err
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &Migration{Version: version, Name: label}
+			byVersion[version] = m
+		}
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	result := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		result = append(result, *m)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+	return result, nil
+}
+
+// Migrate applies every migration that has not been applied yet and returns
+// how many were run
+func (db *DB) Migrate(ctx context.Context) (int, error) {
+	all, err := db.Migrations()
+	if err != nil {
+		return 0, err
+	}
+	applied, err := db.appliedVersions(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, m := range all {
+		if applied[m.Version] {
+			continue
+		}
+		err := db.WithTx(ctx, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, m.Up); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx,
+				`INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)`,
+				m.Version, m.Name, time.Now().UTC())
+			return err
+		})
+		if err != nil {
+			return count, fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// Rollback reverts the most recently applied migrations, at most steps of them,
+// and returns how many were reverted
+func (db *DB) Rollback(ctx context.Context, steps int) (int, error) {
+	all, err := db.Migrations()
+	if err != nil {
+		return 0, err
+	}
+	applied, err := db.appliedVersions(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := len(all) - 1; i >= 0 && count < steps; i-- {
+		m := all[i]
+		if !applied[m.Version] {
+			continue
+		}
+		err := db.WithTx(ctx, func(tx *sql.Tx) error {
+			if m.Down != "" {
+				if _, err := tx.ExecContext(ctx, m.Down); err != nil {
+					return err
+				}
+			}
+			_, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, m.Version)
+			return err
+		})
+		if err != nil {
+			return count, fmt.Errorf("rollback %d_%s: %w", m.Version, m.Name, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// appliedVersions creates the bookkeeping table if needed and returns the
+// set of applied migration versions
+func (db *DB) appliedVersions(ctx context.Context) (map[int64]bool, error) {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    BIGINT PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]bool)
+	for rows.Next() {
+		var version int64
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
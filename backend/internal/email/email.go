@@ -0,0 +1,43 @@
+// Package email renders and delivers transactional email.
+//
+// Emails are built from html/template and text/template files with a
+// shared layout and one variant per locale (see Templates). Service renders
+// them into an outbox table, and a background worker hands due messages to
+// a Sender, retrying temporary failures with exponential backoff. SMTPSender
+// talks to a real mail server; LogSender only logs, for development.
+package email
+
+import (
+	"context"
+	"errors"
+	"log"
+)
+
+// Predefined errors
+var (
+	ErrUnknownTemplate = errors.New("unknown email template")
+	ErrInvalidAddress  = errors.New("invalid email address")
+)
+
+// Message is a rendered email
+type Message struct {
+	From    string
+	To      string
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// Sender delivers messages
+type Sender interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// LogSender logs messages instead of sending them
+type LogSender struct{}
+
+// Send implements Sender
+func (LogSender) Send(ctx context.Context, msg *Message) error {
+	log.Printf("email: to=%s subject=%q\n%s", msg.To, msg.Subject, msg.Text)
+	return nil
+}
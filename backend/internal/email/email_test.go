@@ -0,0 +1,252 @@
+package email
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database/dbtest"
+)
+
+// fakeSMTP is an in-process SMTP server that records what it receives.
+// reply overrides the response to RCPT TO for the next messages.
+type fakeSMTP struct {
+	ln net.Listener
+
+	mu       sync.Mutex
+	received []*mail.Message
+	replies  []string
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	s := &fakeSMTP{ln: ln}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSMTP) addr() string {
+	return s.ln.Addr().String()
+}
+
+// failNext makes the next recipients be answered with the given replies
+func (s *fakeSMTP) failNext(replies ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replies = append(s.replies, replies...)
+}
+
+func (s *fakeSMTP) messages() []*mail.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*mail.Message(nil), s.received...)
+}
+
+func (s *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+	c := textproto.NewConn(conn)
+	c.PrintfLine("220 fake ESMTP")
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			return
+		}
+		verb := strings.ToUpper(strings.Fields(line + " x")[0])
+		switch verb {
+		case "EHLO", "HELO":
+			c.PrintfLine("250-fake\r\n250 8BITMIME")
+		case "MAIL", "RSET", "NOOP":
+			c.PrintfLine("250 OK")
+		case "RCPT":
+			s.mu.Lock()
+			reply := "250 OK"
+			if len(s.replies) > 0 {
+				reply, s.replies = s.replies[0], s.replies[1:]
+			}
+			s.mu.Unlock()
+			c.PrintfLine("%s", reply)
+		case "DATA":
+			c.PrintfLine("354 go ahead")
+			msg, err := mail.ReadMessage(c.DotReader())
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.received = append(s.received, msg)
+			s.mu.Unlock()
+			c.PrintfLine("250 queued")
+		case "QUIT":
+			c.PrintfLine("221 bye")
+			return
+		default:
+			c.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+func newRenderer(t *testing.T) *Renderer {
+	t.Helper()
+	r, err := NewRenderer(Templates(), "en")
+	if err != nil {
+		t.Fatalf("NewRenderer failed: %v", err)
+	}
+	return r
+}
+
+func TestEveryTemplateHasEveryLocale(t *testing.T) {
+	r := newRenderer(t)
+	for _, name := range r.Names() {
+		if got := r.Locales(name); strings.Join(got, ",") != "en,ru" {
+			t.Errorf("Expected %s in en and ru, got %v", name, got)
+		}
+		if _, ok := Samples[name]; !ok {
+			t.Errorf("Expected sample data for %s", name)
+		}
+		for _, locale := range r.Locales(name) {
+			if _, err := r.Render(name, locale, Samples[name]); err != nil {
+				t.Errorf("Render %s/%s failed: %v", name, locale, err)
+			}
+		}
+	}
+}
+
+func TestRenderLocalesAndEscaping(t *testing.T) {
+	r := newRenderer(t)
+	data := map[string]string{"Name": "<b>Ann</b>", "Link": "https://example.com/v?t=1&u=2"}
+
+	msg, err := r.Render(TemplateVerifyEmail, "ru-RU", data)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if msg.Subject != "Подтвердите email" {
+		t.Errorf("Expected Russian subject, got %q", msg.Subject)
+	}
+	if !strings.Contains(msg.HTML, `lang="ru"`) || !strings.Contains(msg.HTML, "&lt;b&gt;Ann&lt;/b&gt;") {
+		t.Errorf("Expected escaped name in Russian layout, got %s", msg.HTML)
+	}
+	if !strings.Contains(msg.Text, "<b>Ann</b>") || !strings.Contains(msg.Text, "--\n") {
+		t.Errorf("Expected unescaped text with footer, got %s", msg.Text)
+	}
+
+	if msg, _ := r.Render(TemplateVerifyEmail, "de", data); msg.Subject != "Confirm your email" {
+		t.Errorf("Expected fallback to English, got %q", msg.Subject)
+	}
+	if _, err := r.Render("nope", "en", nil); err == nil {
+		t.Errorf("Expected an error for an unknown template")
+	}
+}
+
+func TestSMTPSender(t *testing.T) {
+	server := newFakeSMTP(t)
+	sender := &SMTPSender{Addr: server.addr(), Timeout: 5 * time.Second}
+	msg := &Message{
+		From:    "Course App <no-reply@example.com>",
+		To:      "ann@example.com",
+		Subject: "Привет",
+		Text:    "plain body",
+		HTML:    "<p>html body</p>",
+	}
+	if err := sender.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	got := server.messages()
+	if len(got) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(got))
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(got[0].Header.Get("Subject"))
+	if subject != "Привет" || got[0].Header.Get("To") != "<ann@example.com>" {
+		t.Errorf("Unexpected headers %v", got[0].Header)
+	}
+	_, params, _ := mime.ParseMediaType(got[0].Header.Get("Content-Type"))
+	mr := multipart.NewReader(got[0].Body, params["boundary"])
+	var parts []string
+	for {
+		p, err := mr.NextPart()
+		if err != nil {
+			break
+		}
+		body, _ := io.ReadAll(p)
+		parts = append(parts, p.Header.Get("Content-Type")+": "+string(body))
+	}
+	if len(parts) != 2 || !strings.Contains(parts[0], "plain body") || !strings.Contains(parts[1], "<p>html body</p>") {
+		t.Errorf("Expected text and HTML parts, got %q", parts)
+	}
+}
+
+func TestQueueRetriesTemporaryFailures(t *testing.T) {
+	server := newFakeSMTP(t)
+	db := dbtest.New(t)
+	svc := NewService(db, newRenderer(t), &SMTPSender{Addr: server.addr()}, "App <no-reply@example.com>")
+	clock := time.Now().UTC()
+	svc.now = func() time.Time { return clock }
+	ctx := context.Background()
+
+	server.failNext("451 try again later")
+	id, err := svc.Send(ctx, 0, "ann@example.com", "en", TemplateReminder, Samples[TemplateReminder])
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if n, err := svc.ProcessQueue(ctx); n != 1 || err != nil {
+		t.Fatalf("Expected 1 attempt, got %d, %v", n, err)
+	}
+	if n, _ := svc.ProcessQueue(ctx); n != 0 {
+		t.Errorf("Expected retry to wait for the backoff, got %d attempts", n)
+	}
+
+	clock = clock.Add(DefaultBaseDelay)
+	if n, err := svc.ProcessQueue(ctx); n != 1 || err != nil {
+		t.Fatalf("Expected the retry, got %d, %v", n, err)
+	}
+	if got := server.messages(); len(got) != 1 {
+		t.Fatalf("Expected delivery on retry, got %d messages", len(got))
+	}
+
+	var status, body string
+	var attempts int
+	db.QueryRowContext(ctx, `SELECT status, attempts, html_body FROM email_outbox WHERE id = $1`, id).Scan(&status, &attempts, &body)
+	if status != StatusSent || attempts != 2 || body != "" {
+		t.Errorf("Expected sent after 2 attempts with the body cleared, got %s, %d, %d bytes", status, attempts, len(body))
+	}
+}
+
+func TestQueueGivesUpOnPermanentFailures(t *testing.T) {
+	server := newFakeSMTP(t)
+	db := dbtest.New(t)
+	svc := NewService(db, newRenderer(t), &SMTPSender{Addr: server.addr()}, "App <no-reply@example.com>")
+	ctx := context.Background()
+
+	server.failNext("550 no such user")
+	id, _ := svc.Send(ctx, 0, "ghost@example.com", "en", TemplatePasswordReset, Samples[TemplatePasswordReset])
+	svc.ProcessQueue(ctx)
+
+	var status, lastErr string
+	db.QueryRowContext(ctx, `SELECT status, error FROM email_outbox WHERE id = $1`, id).Scan(&status, &lastErr)
+	if status != StatusFailed || !strings.Contains(lastErr, "550") {
+		t.Errorf("Expected failed with the SMTP error, got %s, %q", status, lastErr)
+	}
+	if _, err := svc.Send(ctx, 0, "not an address", "en", TemplateReminder, nil); err != ErrInvalidAddress {
+		t.Errorf("Expected ErrInvalidAddress, got %v", err)
+	}
+}
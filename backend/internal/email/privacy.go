@@ -0,0 +1,64 @@
+package email
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/privacy"
+)
+
+// PrivacyModule exports and erases the emails sent to a user. Bodies are
+// left out of exports; sent emails no longer have them anyway.
+type PrivacyModule struct {
+	db *database.DB
+}
+
+// NewPrivacyModule creates the email privacy module
+func NewPrivacyModule(db *database.DB) *PrivacyModule {
+	return &PrivacyModule{db: db}
+}
+
+// Name implements privacy.Module
+func (m *PrivacyModule) Name() string {
+	return "emails"
+}
+
+// Export implements privacy.Module
+func (m *PrivacyModule) Export(ctx context.Context, userID int64) ([]privacy.Dataset, error) {
+	rows, err := m.db.QueryContext(ctx,
+		`SELECT id, template, to_address, subject, status, created_at, sent_at
+		 FROM email_outbox WHERE user_id = $1 ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ds := privacy.Dataset{
+		Name:    "emails",
+		Columns: []string{"id", "template", "to", "subject", "status", "created_at", "sent_at"},
+	}
+	for rows.Next() {
+		var (
+			id                            int64
+			template, to, subject, status string
+			createdAt                     sql.NullTime
+			sentAt                        sql.NullTime
+		)
+		if err := rows.Scan(&id, &template, &to, &subject, &status, &createdAt, &sentAt); err != nil {
+			return nil, err
+		}
+		var sent any
+		if sentAt.Valid {
+			sent = sentAt.Time
+		}
+		ds.Rows = append(ds.Rows, []any{id, template, to, subject, status, createdAt.Time, sent})
+	}
+	return []privacy.Dataset{ds}, rows.Err()
+}
+
+// Delete implements privacy.Module
+func (m *PrivacyModule) Delete(ctx context.Context, tx *sql.Tx, userID int64, mode privacy.DeleteMode) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM email_outbox WHERE user_id = $1`, userID)
+	return err
+}
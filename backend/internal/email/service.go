@@ -0,0 +1,176 @@
+package email
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"net/mail"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+)
+
+// Outbox statuses
+const (
+	StatusPending = "pending"
+	StatusSent    = "sent"
+	StatusFailed  = "failed"
+)
+
+// Retry policy defaults
+const (
+	DefaultMaxAttempts = 5
+	DefaultBaseDelay   = time.Minute
+	DefaultMaxDelay    = time.Hour
+)
+
+// Service queues rendered emails in the outbox and delivers them
+type Service struct {
+	db       *database.DB
+	renderer *Renderer
+	sender   Sender
+	from     string
+	now      func() time.Time
+
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+}
+
+// NewService creates an email service sending from the given address,
+// e.g. "Course App <no-reply@example.com>"
+func NewService(db *database.DB, renderer *Renderer, sender Sender, from string) *Service {
+	return &Service{
+		db:          db,
+		renderer:    renderer,
+		sender:      sender,
+		from:        from,
+		now:         func() time.Time { return time.Now().UTC() },
+		maxAttempts: DefaultMaxAttempts,
+		baseDelay:   DefaultBaseDelay,
+		maxDelay:    DefaultMaxDelay,
+	}
+}
+
+// SetClock replaces the service's time source, for tests that control time
+func (s *Service) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// Send renders the template in the recipient's locale and queues it. The
+// message is delivered by the next ProcessQueue run. userID may be zero for
+// mail not tied to an account.
+func (s *Service) Send(ctx context.Context, userID int64, to, locale, template string, data any) (int64, error) {
+	if _, err := mail.ParseAddress(to); err != nil {
+		return 0, ErrInvalidAddress
+	}
+	msg, err := s.renderer.Render(template, locale, data)
+	if err != nil {
+		return 0, err
+	}
+	now := s.now()
+	var id int64
+	err = s.db.QueryRowContext(ctx,
+		`INSERT INTO email_outbox (user_id, template, to_address, subject, html_body, text_body, status, next_attempt_at, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8) RETURNING id`,
+		sql.NullInt64{Int64: userID, Valid: userID != 0}, template, to, msg.Subject, msg.HTML, msg.Text,
+		StatusPending, now,
+	).Scan(&id)
+	return id, err
+}
+
+// dueEmail is an outbox row ready to be sent
+type dueEmail struct {
+	id       int64
+	attempts int
+	msg      Message
+}
+
+// ProcessQueue sends due emails and returns how many were attempted
+func (s *Service) ProcessQueue(ctx context.Context) (int, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, attempts, to_address, subject, html_body, text_body FROM email_outbox
+		 WHERE status = $1 AND next_attempt_at <= $2
+		 ORDER BY id LIMIT 100`,
+		StatusPending, s.now())
+	if err != nil {
+		return 0, err
+	}
+	var due []dueEmail
+	for rows.Next() {
+		d := dueEmail{msg: Message{From: s.from}}
+		if err := rows.Scan(&d.id, &d.attempts, &d.msg.To, &d.msg.Subject, &d.msg.HTML, &d.msg.Text); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		due = append(due, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for i := range due {
+		if err := s.attempt(ctx, &due[i]); err != nil {
+			return i, err
+		}
+	}
+	return len(due), nil
+}
+
+// attempt sends one email and records the outcome. Bodies are cleared once
+// the email is sent or given up on, since they may hold one-time links.
+func (s *Service) attempt(ctx context.Context, d *dueEmail) error {
+	sendErr := s.sender.Send(ctx, &d.msg)
+	d.attempts++
+	now := s.now()
+
+	if sendErr == nil {
+		_, err := s.db.ExecContext(ctx,
+			`UPDATE email_outbox SET status = $1, attempts = $2, error = '', html_body = '', text_body = '',
+			 next_attempt_at = NULL, sent_at = $3 WHERE id = $4`,
+			StatusSent, d.attempts, now, d.id)
+		return err
+	}
+
+	if permanent(sendErr) || d.attempts >= s.maxAttempts {
+		log.Printf("email: giving up on email %d after %d attempts: %v", d.id, d.attempts, sendErr)
+		_, err := s.db.ExecContext(ctx,
+			`UPDATE email_outbox SET status = $1, attempts = $2, error = $3, html_body = '', text_body = '',
+			 next_attempt_at = NULL WHERE id = $4`,
+			StatusFailed, d.attempts, sendErr.Error(), d.id)
+		return err
+	}
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE email_outbox SET attempts = $1, error = $2, next_attempt_at = $3 WHERE id = $4`,
+		d.attempts, sendErr.Error(), now.Add(s.backoff(d.attempts)), d.id)
+	return err
+}
+
+// backoff returns the delay before the next attempt after the given number
+// of failed attempts: baseDelay, doubled per attempt, capped at maxDelay
+func (s *Service) backoff(attempts int) time.Duration {
+	delay := s.baseDelay
+	for i := 1; i < attempts && delay < s.maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, s.maxDelay)
+}
+
+// Run processes the queue every interval until ctx is cancelled
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.ProcessQueue(ctx); err != nil {
+			log.Printf("email: process queue: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
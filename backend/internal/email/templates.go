@@ -0,0 +1,178 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+)
+
+//go:embed templates
+var embedded embed.FS
+
+// Templates is the built-in template tree. Every locale directory holds a
+// common.{html,txt}.tmpl defining "footer" and, per email, a
+// <name>.txt.tmpl defining "subject" and "content" and a <name>.html.tmpl
+// defining "content"; layout.{html,txt}.tmpl wrap the content.
+func Templates() fs.FS {
+	sub, err := fs.Sub(embedded, "templates")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
+
+// Template names
+const (
+	TemplateVerifyEmail   = "verify_email"
+	TemplatePasswordReset = "password_reset"
+	TemplateReminder      = "reminder"
+)
+
+// View is what templates are executed with
+type View struct {
+	Locale  string
+	Subject string
+	Data    any
+}
+
+type localized struct {
+	html *htmltemplate.Template
+	text *texttemplate.Template
+}
+
+// Renderer renders emails from a template tree
+type Renderer struct {
+	defaultLocale string
+	// templates maps name, then locale
+	templates map[string]map[string]localized
+}
+
+// NewRenderer parses every template in fsys up front so that a broken
+// template fails at startup rather than when the email is sent
+func NewRenderer(fsys fs.FS, defaultLocale string) (*Renderer, error) {
+	r := &Renderer{defaultLocale: defaultLocale, templates: map[string]map[string]localized{}}
+	locales, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range locales {
+		if !dir.IsDir() {
+			continue
+		}
+		locale := dir.Name()
+		files, err := fs.Glob(fsys, locale+"/*.txt.tmpl")
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			name := strings.TrimSuffix(path.Base(file), ".txt.tmpl")
+			if name == "common" {
+				continue
+			}
+			text, err := texttemplate.ParseFS(fsys, "layout.txt.tmpl", locale+"/common.txt.tmpl", file)
+			if err != nil {
+				return nil, err
+			}
+			html, err := htmltemplate.ParseFS(fsys, "layout.html.tmpl", locale+"/common.html.tmpl", locale+"/"+name+".html.tmpl")
+			if err != nil {
+				return nil, err
+			}
+			if text.Lookup("subject") == nil {
+				return nil, fmt.Errorf("email: %s has no subject", file)
+			}
+			if r.templates[name] == nil {
+				r.templates[name] = map[string]localized{}
+			}
+			r.templates[name][locale] = localized{html: html, text: text}
+		}
+	}
+	for name, byLocale := range r.templates {
+		if _, ok := byLocale[defaultLocale]; !ok {
+			return nil, fmt.Errorf("email: %s has no %s variant", name, defaultLocale)
+		}
+	}
+	return r, nil
+}
+
+// Names lists the available templates
+func (r *Renderer) Names() []string {
+	names := make([]string, 0, len(r.templates))
+	for name := range r.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Locales lists the locales a template is available in
+func (r *Renderer) Locales(name string) []string {
+	locales := make([]string, 0, len(r.templates[name]))
+	for locale := range r.templates[name] {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Render produces the subject and bodies of an email. locale may be a
+// language tag such as "ru-RU"; unknown locales fall back to the default.
+func (r *Renderer) Render(name, locale string, data any) (*Message, error) {
+	byLocale, ok := r.templates[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownTemplate, name)
+	}
+	locale = r.resolve(byLocale, locale)
+	t := byLocale[locale]
+	view := View{Locale: locale, Data: data}
+
+	var subject, text, html bytes.Buffer
+	if err := t.text.ExecuteTemplate(&subject, "subject", view); err != nil {
+		return nil, err
+	}
+	view.Subject = strings.TrimSpace(subject.String())
+	if err := t.text.ExecuteTemplate(&text, "layout", view); err != nil {
+		return nil, err
+	}
+	if err := t.html.ExecuteTemplate(&html, "layout", view); err != nil {
+		return nil, err
+	}
+	return &Message{Subject: view.Subject, Text: text.String(), HTML: html.String()}, nil
+}
+
+func (r *Renderer) resolve(byLocale map[string]localized, locale string) string {
+	locale = strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+	if _, ok := byLocale[locale]; ok {
+		return locale
+	}
+	if lang, _, ok := strings.Cut(locale, "-"); ok {
+		if _, ok := byLocale[lang]; ok {
+			return lang
+		}
+	}
+	return r.defaultLocale
+}
+
+// Samples holds example data for every built-in template, used by the
+// development preview
+var Samples = map[string]any{
+	TemplateVerifyEmail: map[string]string{
+		"Name": "Alex",
+		"Link": "https://example.com/verify?token=sample",
+	},
+	TemplatePasswordReset: map[string]string{
+		"Name": "Alex",
+		"Link": "https://example.com/reset?token=sample",
+	},
+	TemplateReminder: map[string]string{
+		"Name":    "Alex",
+		"Title":   "Evening journal",
+		"Message": "You haven't written anything today yet.",
+		"Link":    "https://example.com/entries/new",
+	},
+}
@@ -0,0 +1,196 @@
+package entries
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/pagination"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/tenant"
+)
+
+// Predefined errors
+var (
+	ErrNotFound   = errors.New("entry not found")
+	ErrEmptyTitle = errors.New("title cannot be empty")
+)
+
+// Entry is a journal entry written by a user inside an organization
+type Entry struct {
+	ID        int64     `json:"id"`
+	OrgID     int64     `json:"org_id"`
+	UserID    int64     `json:"user_id"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Repository stores entries. Every query is scoped to the tenant in the
+// context, so entries of other organizations are invisible.
+type Repository struct {
+	db  *tenant.DB
+	now func() time.Time
+}
+
+// NewRepository creates a new entries repository
+func NewRepository(db *database.DB) *Repository {
+	return &Repository{db: tenant.Scoped(db), now: func() time.Time { return time.Now().UTC() }}
+}
+
+// SetClock replaces the repository's time source, for tests that control
+// time
+func (r *Repository) SetClock(now func() time.Time) {
+	r.now = now
+}
+
+const entryColumns = `id, org_id, user_id, title, body, created_at, updated_at`
+
+// Create stores a new entry for the user in the current tenant. It is
+// indexed for search in the user's search language.
+func (r *Repository) Create(ctx context.Context, e *Entry) error {
+	e.Title = strings.TrimSpace(e.Title)
+	if e.Title == "" {
+		return ErrEmptyTitle
+	}
+	now := r.now()
+	err := r.db.QueryRowContext(ctx,
+		`INSERT INTO entries (org_id, user_id, title, body, language, created_at, updated_at)
+		 VALUES (@tenant, $1, $2, $3, (SELECT search_language FROM users WHERE id = $1), $4, $4)
+		 RETURNING id, org_id`,
+		e.UserID, e.Title, e.Body, now,
+	).Scan(&e.ID, &e.OrgID)
+	if err != nil {
+		return err
+	}
+	e.CreatedAt = now
+	e.UpdatedAt = now
+	return nil
+}
+
+// Get returns one of the user's entries in the current tenant
+func (r *Repository) Get(ctx context.Context, userID, id int64) (*Entry, error) {
+	e, err := scanEntry(r.db.QueryRowContext(ctx,
+		`SELECT `+entryColumns+` FROM entries WHERE id = $1 AND user_id = $2 AND org_id = @tenant`,
+		id, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return e, err
+}
+
+// ListResource is what the entries list endpoint can sort and filter by
+var ListResource = &pagination.Resource{
+	Name: "entries",
+	Fields: map[string]pagination.Field{
+		"id": {Column: "id", Type: pagination.Int, Sortable: true,
+			Filters: []pagination.Op{pagination.OpEq, pagination.OpIn}},
+		"title": {Column: "title", Type: pagination.String, Sortable: true,
+			Filters: []pagination.Op{pagination.OpEq, pagination.OpContains}},
+		"created_at": {Column: "created_at", Type: pagination.Time, Sortable: true,
+			Filters: []pagination.Op{pagination.OpLt, pagination.OpLte, pagination.OpGt, pagination.OpGte}},
+		"updated_at": {Column: "updated_at", Type: pagination.Time, Sortable: true,
+			Filters: []pagination.Op{pagination.OpLt, pagination.OpLte, pagination.OpGt, pagination.OpGte}},
+	},
+	Key:          "id",
+	DefaultSort:  "-created_at",
+	DefaultLimit: 20,
+	MaxLimit:     100,
+}
+
+// List returns the user's entries in the current tenant. With a nil query
+// all entries are returned, newest first; otherwise one page of q, plus one
+// extra row for pagination.Paginate.
+func (r *Repository) List(ctx context.Context, userID int64, q *pagination.Query) ([]Entry, error) {
+	query := `SELECT ` + entryColumns + ` FROM entries WHERE user_id = $1 AND org_id = @tenant`
+	args := []any{userID}
+	if q == nil {
+		query += ` ORDER BY created_at DESC, id DESC`
+	} else {
+		query, args = q.Apply(query, args)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []Entry{}
+	for rows.Next() {
+		e, err := scanEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *e)
+	}
+	return list, rows.Err()
+}
+
+// FieldValue returns the value of a ListResource field of e
+func FieldValue(e Entry, field string) any {
+	switch field {
+	case "id":
+		return e.ID
+	case "title":
+		return e.Title
+	case "created_at":
+		return e.CreatedAt
+	case "updated_at":
+		return e.UpdatedAt
+	}
+	return nil
+}
+
+// Update changes the title and body of one of the user's entries
+func (r *Repository) Update(ctx context.Context, e *Entry) error {
+	e.Title = strings.TrimSpace(e.Title)
+	if e.Title == "" {
+		return ErrEmptyTitle
+	}
+	now := r.now()
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE entries SET title = $1, body = $2, updated_at = $3
+		 WHERE id = $4 AND user_id = $5 AND org_id = @tenant`,
+		e.Title, e.Body, now, e.ID, e.UserID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	updated, err := r.Get(ctx, e.UserID, e.ID)
+	if err != nil {
+		return err
+	}
+	*e = *updated
+	return nil
+}
+
+// Delete removes one of the user's entries
+func (r *Repository) Delete(ctx context.Context, userID, id int64) error {
+	res, err := r.db.ExecContext(ctx,
+		`DELETE FROM entries WHERE id = $1 AND user_id = $2 AND org_id = @tenant`, id, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanEntry(row scanner) (*Entry, error) {
+	var e Entry
+	if err := row.Scan(&e.ID, &e.OrgID, &e.UserID, &e.Title, &e.Body, &e.CreatedAt, &e.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
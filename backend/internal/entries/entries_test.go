@@ -0,0 +1,126 @@
+package entries
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"slices"
+	"testing"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database/dbtest"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/pagination"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/tenant"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/users"
+)
+
+func TestTenantIsolation(t *testing.T) {
+	db := dbtest.New(t)
+	ctx := context.Background()
+
+	user := &users.User{Email: "coach@example.com"}
+	if err := users.NewRepository(db).Create(ctx, user); err != nil {
+		t.Fatalf("Create user: %v", err)
+	}
+	var orgA, orgB int64
+	for _, slug := range []string{"org-a", "org-b"} {
+		var id int64
+		if err := db.QueryRowContext(ctx,
+			`INSERT INTO organizations (name, slug) VALUES ($1, $1) RETURNING id`, slug).Scan(&id); err != nil {
+			t.Fatalf("Create org: %v", err)
+		}
+		if slug == "org-a" {
+			orgA = id
+		} else {
+			orgB = id
+		}
+	}
+	ctxA := tenant.NewContext(ctx, tenant.Tenant{OrgID: orgA})
+	ctxB := tenant.NewContext(ctx, tenant.Tenant{OrgID: orgB})
+
+	repo := NewRepository(db)
+	entry := &Entry{UserID: user.ID, Title: "Private", Body: "only for A"}
+	if err := repo.Create(ctxA, entry); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if entry.OrgID != orgA {
+		t.Errorf("Expected entry in org %d, got %d", orgA, entry.OrgID)
+	}
+
+	list, err := repo.List(ctxB, user.ID, nil)
+	if err != nil || len(list) != 0 {
+		t.Errorf("Expected no entries in org B, got %v, %v", list, err)
+	}
+	if _, err := repo.Get(ctxB, user.ID, entry.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound reading from org B, got %v", err)
+	}
+	if err := repo.Update(ctxB, &Entry{ID: entry.ID, UserID: user.ID, Title: "Hijacked"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound updating from org B, got %v", err)
+	}
+	if err := repo.Delete(ctxB, user.ID, entry.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound deleting from org B, got %v", err)
+	}
+
+	got, err := repo.Get(ctxA, user.ID, entry.ID)
+	if err != nil || got.Title != "Private" {
+		t.Errorf("Expected entry untouched in org A, got %+v, %v", got, err)
+	}
+
+	if _, err := repo.List(ctx, user.ID, nil); !errors.Is(err, tenant.ErrNoTenant) {
+		t.Errorf("Expected ErrNoTenant without a tenant, got %v", err)
+	}
+}
+
+func TestListPagination(t *testing.T) {
+	db := dbtest.New(t)
+	ctx := context.Background()
+
+	user := &users.User{Email: "writer@example.com"}
+	if err := users.NewRepository(db).Create(ctx, user); err != nil {
+		t.Fatalf("Create user: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, `INSERT INTO organizations (name, slug) VALUES ('A', 'a')`); err != nil {
+		t.Fatalf("Create org: %v", err)
+	}
+	ctx = tenant.NewContext(ctx, tenant.Tenant{OrgID: 1})
+
+	repo := NewRepository(db)
+	for _, title := range []string{"Run", "Swim", "Run", "Walk", "Run long", "Yoga", "Run"} {
+		if err := repo.Create(ctx, &Entry{UserID: user.ID, Title: title}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	codec := pagination.NewCodec("secret")
+	walk := func(params url.Values) []int64 {
+		var ids []int64
+		for {
+			q, err := codec.Parse(ListResource, params)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			list, err := repo.List(ctx, user.ID, q)
+			if err != nil {
+				t.Fatalf("List failed: %v", err)
+			}
+			page := pagination.Paginate(q, list, FieldValue)
+			for _, e := range page.Data {
+				ids = append(ids, e.ID)
+			}
+			if !page.HasMore {
+				return ids
+			}
+			params.Set("cursor", page.NextCursor)
+		}
+	}
+
+	if got, want := walk(url.Values{"limit": {"3"}}), []int64{7, 6, 5, 4, 3, 2, 1}; !slices.Equal(got, want) {
+		t.Errorf("Expected newest first %v, got %v", want, got)
+	}
+	if got, want := walk(url.Values{"limit": {"2"}, "sort": {"title,-id"}}), []int64{7, 3, 1, 5, 2, 4, 6}; !slices.Equal(got, want) {
+		t.Errorf("Expected title then id descending %v, got %v", want, got)
+	}
+	got := walk(url.Values{"limit": {"2"}, "sort": {"id"}, "filter[title][contains]": {"RUN"}})
+	if want := []int64{1, 3, 5, 7}; !slices.Equal(got, want) {
+		t.Errorf("Expected filtered %v, got %v", want, got)
+	}
+}
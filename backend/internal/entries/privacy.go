@@ -0,0 +1,54 @@
+package entries
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/privacy"
+)
+
+// PrivacyModule exports and erases journal entries across all tenants
+type PrivacyModule struct {
+	db *database.DB
+}
+
+// NewPrivacyModule creates the entries privacy module
+func NewPrivacyModule(db *database.DB) *PrivacyModule {
+	return &PrivacyModule{db: db}
+}
+
+// Name implements privacy.Module
+func (m *PrivacyModule) Name() string {
+	return "entries"
+}
+
+// Export implements privacy.Module
+func (m *PrivacyModule) Export(ctx context.Context, userID int64) ([]privacy.Dataset, error) {
+	rows, err := m.db.QueryContext(ctx,
+		`SELECT `+entryColumns+` FROM entries WHERE user_id = $1 ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ds := privacy.Dataset{
+		Name:    "entries",
+		Columns: []string{"id", "org_id", "title", "body", "created_at", "updated_at"},
+	}
+	for rows.Next() {
+		e, err := scanEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		ds.Rows = append(ds.Rows, []any{e.ID, e.OrgID, e.Title, e.Body, e.CreatedAt, e.UpdatedAt})
+	}
+	return []privacy.Dataset{ds}, rows.Err()
+}
+
+// Delete implements privacy.Module. Free-text journal entries cannot be
+// reliably anonymised, so they are removed in both modes.
+func (m *PrivacyModule) Delete(ctx context.Context, tx *sql.Tx, userID int64, mode privacy.DeleteMode) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM entries WHERE user_id = $1`, userID)
+	return err
+}
@@ -0,0 +1,202 @@
+// Package fixtures fills a database with test data.
+//
+// A Set describes users, organizations with their members and journal
+// entries. Records refer to each other by natural keys (user email,
+// organization slug) so sets can be written by hand in YAML or JSON:
+//
+//	users:
+//	  - email: anna@example.com
+//	    name: Anna
+//	    password: password123
+//	organizations:
+//	  - slug: demo
+//	    name: Demo
+//	    members:
+//	      - email: anna@example.com
+//	        role: owner
+//	entries:
+//	  - author: anna@example.com
+//	    org: demo
+//	    title: First entry
+//
+// Named sets ship with the package (see Open); Generate builds larger
+// random sets that are identical for the same seed. Load writes a set to
+// the database and Truncate empties it, for the seed command and for tests
+// alike.
+package fixtures
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/orgs"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/users"
+	"gopkg.in/yaml.v3"
+)
+
+// Predefined errors
+var (
+	ErrUnknownSet    = errors.New("unknown fixture set")
+	ErrUnknownFormat = errors.New("fixture files must be .yaml, .yml or .json")
+	ErrInvalidSet    = errors.New("invalid fixture set")
+)
+
+// DefaultPassword is used for fixture users without a password
+const DefaultPassword = "password123"
+
+// Set is a self-contained collection of fixture records
+type Set struct {
+	Users         []User         `json:"users" yaml:"users"`
+	Organizations []Organization `json:"organizations" yaml:"organizations"`
+	Entries       []Entry        `json:"entries" yaml:"entries"`
+}
+
+// User is an account; Password defaults to DefaultPassword
+type User struct {
+	Email    string `json:"email" yaml:"email"`
+	Name     string `json:"name" yaml:"name"`
+	Role     string `json:"role,omitempty" yaml:"role,omitempty"`
+	Password string `json:"password,omitempty" yaml:"password,omitempty"`
+	Locale   string `json:"locale,omitempty" yaml:"locale,omitempty"`
+}
+
+// Organization is a tenant with its members
+type Organization struct {
+	Slug    string   `json:"slug" yaml:"slug"`
+	Name    string   `json:"name" yaml:"name"`
+	Members []Member `json:"members" yaml:"members"`
+}
+
+// Member adds the user with Email to an organization
+type Member struct {
+	Email string `json:"email" yaml:"email"`
+	Role  string `json:"role" yaml:"role"`
+}
+
+// Entry is a journal entry written by Author (an email) in Org (a slug).
+// A zero CreatedAt means the time of loading.
+type Entry struct {
+	Author    string    `json:"author" yaml:"author"`
+	Org       string    `json:"org" yaml:"org"`
+	Title     string    `json:"title" yaml:"title"`
+	Body      string    `json:"body,omitempty" yaml:"body,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitzero" yaml:"created_at,omitempty"`
+}
+
+// Merge appends the records of other to s
+func (s *Set) Merge(other *Set) {
+	s.Users = append(s.Users, other.Users...)
+	s.Organizations = append(s.Organizations, other.Organizations...)
+	s.Entries = append(s.Entries, other.Entries...)
+}
+
+// Validate checks that every reference points to a record of the set
+func (s *Set) Validate() error {
+	emails := make(map[string]bool, len(s.Users))
+	for _, u := range s.Users {
+		email := normalizeEmail(u.Email)
+		if email == "" {
+			return fmt.Errorf("%w: user without email", ErrInvalidSet)
+		}
+		if emails[email] {
+			return fmt.Errorf("%w: duplicate user %s", ErrInvalidSet, email)
+		}
+		if u.Role != "" && u.Role != users.RoleUser && u.Role != users.RoleAdmin {
+			return fmt.Errorf("%w: user %s: invalid role %q", ErrInvalidSet, email, u.Role)
+		}
+		emails[email] = true
+	}
+	members := make(map[string]map[string]bool, len(s.Organizations))
+	for _, o := range s.Organizations {
+		if o.Slug == "" || members[o.Slug] != nil {
+			return fmt.Errorf("%w: missing or duplicate organization slug %q", ErrInvalidSet, o.Slug)
+		}
+		members[o.Slug] = make(map[string]bool)
+		for _, m := range o.Members {
+			if !emails[normalizeEmail(m.Email)] {
+				return fmt.Errorf("%w: organization %s: unknown member %s", ErrInvalidSet, o.Slug, m.Email)
+			}
+			if !orgs.ValidRole(m.Role) {
+				return fmt.Errorf("%w: organization %s: invalid role %q", ErrInvalidSet, o.Slug, m.Role)
+			}
+			members[o.Slug][normalizeEmail(m.Email)] = true
+		}
+	}
+	for i, e := range s.Entries {
+		if !members[e.Org][normalizeEmail(e.Author)] {
+			return fmt.Errorf("%w: entry %d: %s is not a member of organization %q", ErrInvalidSet, i, e.Author, e.Org)
+		}
+	}
+	return nil
+}
+
+// Parse decodes a set from YAML or JSON; format is a file extension such
+// as ".yaml"
+func Parse(data []byte, format string) (*Set, error) {
+	var s Set
+	switch strings.ToLower(format) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&s); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSet, err)
+		}
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&s); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSet, err)
+		}
+	default:
+		return nil, ErrUnknownFormat
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// ReadFile parses the fixture file at path
+func ReadFile(path string) (*Set, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data, filepath.Ext(path))
+}
+
+//go:embed sets
+var sets embed.FS
+
+// Names lists the built-in fixture sets
+func Names() []string {
+	entries, _ := fs.ReadDir(sets, "sets")
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), filepath.Ext(e.Name())))
+	}
+	return names
+}
+
+// Open returns the built-in fixture set with the given name
+func Open(name string) (*Set, error) {
+	for _, ext := range []string{".yaml", ".json"} {
+		data, err := sets.ReadFile("sets/" + name + ext)
+		if err == nil {
+			return Parse(data, ext)
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownSet, name)
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,126 @@
+package fixtures
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/auth"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database/dbtest"
+	"gopkg.in/yaml.v3"
+)
+
+func TestSeedDemo(t *testing.T) {
+	db := dbtest.New(t)
+	ctx := context.Background()
+	res := Seed(t, db, "demo")
+
+	if len(res.Users) != 4 || len(res.Organizations) != 2 || res.Entries != 5 {
+		t.Fatalf("Unexpected result %+v", res)
+	}
+
+	var hash, locale string
+	db.QueryRowContext(ctx, `SELECT password_hash, locale FROM users WHERE id = $1`, res.Users["maria@example.com"]).Scan(&hash, &locale)
+	if !auth.CheckPassword(hash, DefaultPassword) || locale != "ru" {
+		t.Errorf("Expected the default password and ru locale, got %q", locale)
+	}
+
+	var role string
+	db.QueryRowContext(ctx, `SELECT role FROM memberships WHERE org_id = $1 AND user_id = $2`,
+		res.Organizations["book-circle"], res.Users["james@example.com"]).Scan(&role)
+	if role != "owner" {
+		t.Errorf("Expected james to own book-circle, got %q", role)
+	}
+
+	var found int
+	db.QueryRowContext(ctx, `SELECT COUNT(*) FROM entries_fts WHERE entries_fts MATCH 'пробежка'`).Scan(&found)
+	if found != 1 {
+		t.Errorf("Expected seeded entries to be searchable, got %d matches", found)
+	}
+}
+
+func TestTruncateAndReload(t *testing.T) {
+	db := dbtest.New(t)
+	ctx := context.Background()
+	first := Seed(t, db, "demo")
+
+	if err := Truncate(ctx, db); err != nil {
+		t.Fatalf("Truncate failed: %v", err)
+	}
+	var users, migrations int
+	db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users`).Scan(&users)
+	db.QueryRowContext(ctx, `SELECT COUNT(*) FROM schema_migrations`).Scan(&migrations)
+	if users != 0 || migrations == 0 {
+		t.Errorf("Expected data gone and migrations kept, got %d users and %d migrations", users, migrations)
+	}
+
+	second := Seed(t, db, "demo")
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("Expected the same IDs after reloading, got %v and %v", first, second)
+	}
+}
+
+func TestGenerateIsDeterministic(t *testing.T) {
+	a := Generate(42, DefaultOptions)
+	b := Generate(42, DefaultOptions)
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("Expected the same set for the same seed")
+	}
+	if reflect.DeepEqual(a, Generate(43, DefaultOptions)) {
+		t.Errorf("Expected a different set for another seed")
+	}
+	if err := a.Validate(); err != nil {
+		t.Fatalf("Generated set is invalid: %v", err)
+	}
+	if len(a.Users) != 20 || len(a.Organizations) != 3 || len(a.Entries) != 160 {
+		t.Errorf("Unexpected sizes %d/%d/%d", len(a.Users), len(a.Organizations), len(a.Entries))
+	}
+
+	res, err := Load(context.Background(), dbtest.New(t), a)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if res.Entries != len(a.Entries) {
+		t.Errorf("Expected %d entries loaded, got %d", len(a.Entries), res.Entries)
+	}
+}
+
+func TestFormatsRoundTrip(t *testing.T) {
+	set := Generate(7, Options{Users: 3, Organizations: 2, EntriesPerUser: 2})
+
+	data, _ := json.Marshal(set)
+	fromJSON, err := Parse(data, ".json")
+	if err != nil || !reflect.DeepEqual(set, fromJSON) {
+		t.Errorf("JSON round trip failed: %v", err)
+	}
+	data, _ = yaml.Marshal(set)
+	fromYAML, err := Parse(data, ".yml")
+	if err != nil || !reflect.DeepEqual(set, fromYAML) {
+		t.Errorf("YAML round trip failed: %v", err)
+	}
+}
+
+func TestParseRejectsInvalidSets(t *testing.T) {
+	tests := []struct {
+		name, data string
+	}{
+		{"unknown member", `{"organizations": [{"slug": "x", "members": [{"email": "nobody@example.com", "role": "owner"}]}]}`},
+		{"bad role", `{"users": [{"email": "a@example.com"}], "organizations": [{"slug": "x", "members": [{"email": "a@example.com", "role": "boss"}]}]}`},
+		{"author outside org", `{"users": [{"email": "a@example.com"}], "organizations": [{"slug": "x"}], "entries": [{"author": "a@example.com", "org": "x", "title": "t"}]}`},
+		{"duplicate user", `{"users": [{"email": "a@example.com"}, {"email": "A@example.com"}]}`},
+		{"unknown field", `{"people": []}`},
+	}
+	for _, tt := range tests {
+		if _, err := Parse([]byte(tt.data), ".json"); !errors.Is(err, ErrInvalidSet) {
+			t.Errorf("%s: expected ErrInvalidSet, got %v", tt.name, err)
+		}
+	}
+	if _, err := Parse(nil, ".toml"); err != ErrUnknownFormat {
+		t.Errorf("Expected ErrUnknownFormat, got %v", err)
+	}
+	if _, err := Open("missing"); !errors.Is(err, ErrUnknownSet) {
+		t.Errorf("Expected ErrUnknownSet, got %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package fixtures
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/orgs"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/users"
+)
+
+// Options size a generated set
+type Options struct {
+	Users          int
+	Organizations  int
+	EntriesPerUser int
+	// Now anchors generated timestamps, which lie in the 90 days before it.
+	// It defaults to a fixed date so that output only depends on the seed.
+	Now time.Time
+}
+
+// DefaultOptions generate a small but lively data set
+var DefaultOptions = Options{Users: 20, Organizations: 3, EntriesPerUser: 8}
+
+var defaultNow = time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+
+// person is a name with the Latin spelling used for its email address
+type person struct {
+	first, last, firstLatin, lastLatin, locale string
+}
+
+var people = []person{
+	{"Anna", "Smith", "anna", "smith", "en"},
+	{"James", "Brown", "james", "brown", "en"},
+	{"Olivia", "Taylor", "olivia", "taylor", "en"},
+	{"Liam", "Wilson", "liam", "wilson", "en"},
+	{"Emma", "Clarke", "emma", "clarke", "en"},
+	{"Noah", "Evans", "noah", "evans", "en"},
+	{"Sophie", "Walker", "sophie", "walker", "en"},
+	{"Daniel", "Hughes", "daniel", "hughes", "en"},
+	{"Анна", "Иванова", "anna", "ivanova", "ru"},
+	{"Дмитрий", "Смирнов", "dmitry", "smirnov", "ru"},
+	{"Мария", "Кузнецова", "maria", "kuznetsova", "ru"},
+	{"Иван", "Попов", "ivan", "popov", "ru"},
+	{"Екатерина", "Соколова", "ekaterina", "sokolova", "ru"},
+	{"Алексей", "Лебедев", "alexey", "lebedev", "ru"},
+	{"Тимур", "Хасанов", "timur", "khasanov", "ru"},
+	{"Алия", "Галиева", "aliya", "galieva", "ru"},
+}
+
+var organizationNames = []string{
+	"Morning Pages Club", "Innopolis Cohort", "Wellness Team", "Running Buddies",
+	"Book Circle", "Mindful Mondays", "Study Group", "Night Owls",
+}
+
+var entryTitles = map[string][]string{
+	"en": {
+		"Morning run", "Reading before bed", "Gratitude list", "Weekly review",
+		"Meditation notes", "Learned something new", "Tough day", "Small wins",
+		"Plans for the weekend", "Slept well",
+	},
+	"ru": {
+		"Утренняя пробежка", "Чтение перед сном", "Список благодарностей", "Итоги недели",
+		"Заметки о медитации", "Узнал(а) новое", "Тяжёлый день", "Маленькие победы",
+		"Планы на выходные", "Хорошо выспался(ась)",
+	},
+}
+
+var entrySentences = map[string][]string{
+	"en": {
+		"Woke up early and felt rested.", "Managed to stick to the plan today.",
+		"Spent twenty minutes outside without my phone.", "Need to drink more water.",
+		"Had a great conversation with a friend.", "Finished two chapters of my book.",
+		"Work was stressful but I took breaks.", "Tried a new recipe for dinner.",
+		"Went to bed on time for once.", "Feeling motivated for tomorrow.",
+	},
+	"ru": {
+		"Проснулся(ась) рано и чувствую себя отдохнувшим(ей).", "Сегодня удалось придерживаться плана.",
+		"Двадцать минут гулял(а) без телефона.", "Нужно пить больше воды.",
+		"Был отличный разговор с другом.", "Дочитал(а) две главы книги.",
+		"На работе был стресс, но я делал(а) перерывы.", "Попробовал(а) новый рецепт на ужин.",
+		"Наконец-то лёг(легла) спать вовремя.", "Есть настрой на завтра.",
+	},
+}
+
+// Generate builds a random but realistic set. The same seed and options
+// always produce the same set. The first user is an admin, every user
+// belongs to one or two organizations and writes entries in them.
+func Generate(seed uint64, opts Options) *Set {
+	if opts.Now.IsZero() {
+		opts.Now = defaultNow
+	}
+	opts.Organizations = max(1, min(opts.Organizations, len(organizationNames)))
+	rng := rand.New(rand.NewPCG(seed, seed^0x5eed))
+	set := &Set{}
+
+	locales := make(map[string]string, opts.Users)
+	for i := range opts.Users {
+		p := people[rng.IntN(len(people))]
+		u := User{
+			Email:  fmt.Sprintf("%s.%s%d@example.com", p.firstLatin, p.lastLatin, i+1),
+			Name:   p.first + " " + p.last,
+			Locale: p.locale,
+		}
+		if i == 0 {
+			u.Role = users.RoleAdmin
+		}
+		locales[u.Email] = p.locale
+		set.Users = append(set.Users, u)
+	}
+	if len(set.Users) == 0 {
+		return set
+	}
+
+	for i, name := range organizationNames[:opts.Organizations] {
+		set.Organizations = append(set.Organizations, Organization{
+			Slug: strings.ReplaceAll(strings.ToLower(name), " ", "-"),
+			Name: name,
+			// Organization i is owned by user i, wrapping around
+			Members: []Member{{Email: set.Users[i%len(set.Users)].Email, Role: orgs.RoleOwner}},
+		})
+	}
+
+	memberOf := make(map[string][]string, len(set.Users))
+	for _, org := range set.Organizations {
+		owner := org.Members[0].Email
+		memberOf[owner] = append(memberOf[owner], org.Slug)
+	}
+	for _, u := range set.Users {
+		want := min(1+rng.IntN(2), len(set.Organizations))
+		for len(memberOf[u.Email]) < want {
+			i := rng.IntN(len(set.Organizations))
+			org := &set.Organizations[i]
+			if slices.Contains(memberOf[u.Email], org.Slug) {
+				continue
+			}
+			role := orgs.RoleMember
+			if rng.IntN(5) == 0 {
+				role = orgs.RoleAdmin
+			}
+			org.Members = append(org.Members, Member{Email: u.Email, Role: role})
+			memberOf[u.Email] = append(memberOf[u.Email], org.Slug)
+		}
+	}
+
+	for _, u := range set.Users {
+		locale := locales[u.Email]
+		for range opts.EntriesPerUser {
+			titles, sentences := entryTitles[locale], entrySentences[locale]
+			body := make([]string, 1+rng.IntN(4))
+			for i := range body {
+				body[i] = sentences[rng.IntN(len(sentences))]
+			}
+			age := time.Duration(rng.Int64N(int64(90 * 24 * time.Hour)))
+			set.Entries = append(set.Entries, Entry{
+				Author:    u.Email,
+				Org:       memberOf[u.Email][rng.IntN(len(memberOf[u.Email]))],
+				Title:     titles[rng.IntN(len(titles))],
+				Body:      strings.Join(body, " "),
+				CreatedAt: opts.Now.Add(-age).Truncate(time.Minute),
+			})
+		}
+	}
+	return set
+}
@@ -0,0 +1,184 @@
+package fixtures
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/auth"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/users"
+)
+
+// Result maps the natural keys of loaded records to their database IDs
+type Result struct {
+	// Users by email
+	Users map[string]int64
+	// Organizations by slug
+	Organizations map[string]int64
+	Entries       int
+}
+
+// Load inserts a set in one transaction. Passwords are hashed once per
+// distinct password, so large generated sets stay fast to load.
+func Load(ctx context.Context, db *database.DB, set *Set) (*Result, error) {
+	return LoadAt(ctx, db, set, time.Now())
+}
+
+// LoadAt is Load with now as the creation time of records that carry none
+func LoadAt(ctx context.Context, db *database.DB, set *Set, now time.Time) (*Result, error) {
+	if err := set.Validate(); err != nil {
+		return nil, err
+	}
+	res := &Result{Users: make(map[string]int64), Organizations: make(map[string]int64)}
+	hashes := make(map[string]string)
+	now = now.UTC()
+
+	err := db.WithTx(ctx, func(tx *sql.Tx) error {
+		for _, u := range set.Users {
+			password := u.Password
+			if password == "" {
+				password = DefaultPassword
+			}
+			hash, ok := hashes[password]
+			if !ok {
+				var err error
+				if hash, err = auth.HashPassword(password); err != nil {
+					return fmt.Errorf("user %s: %w", u.Email, err)
+				}
+				hashes[password] = hash
+			}
+			role := u.Role
+			if role == "" {
+				role = users.RoleUser
+			}
+			email := normalizeEmail(u.Email)
+			var id int64
+			if err := tx.QueryRowContext(ctx,
+				`INSERT INTO users (email, name, role, locale, password_hash, created_at, updated_at)
+				 VALUES ($1, $2, $3, $4, $5, $6, $6) RETURNING id`,
+				email, u.Name, role, u.Locale, hash, now,
+			).Scan(&id); err != nil {
+				return fmt.Errorf("user %s: %w", u.Email, err)
+			}
+			res.Users[email] = id
+		}
+
+		for _, o := range set.Organizations {
+			var id int64
+			if err := tx.QueryRowContext(ctx,
+				`INSERT INTO organizations (name, slug, created_at) VALUES ($1, $2, $3) RETURNING id`,
+				o.Name, o.Slug, now,
+			).Scan(&id); err != nil {
+				return fmt.Errorf("organization %s: %w", o.Slug, err)
+			}
+			res.Organizations[o.Slug] = id
+			for _, m := range o.Members {
+				if _, err := tx.ExecContext(ctx,
+					`INSERT INTO memberships (org_id, user_id, role, created_at) VALUES ($1, $2, $3, $4)`,
+					id, res.Users[normalizeEmail(m.Email)], m.Role, now,
+				); err != nil {
+					return fmt.Errorf("organization %s: member %s: %w", o.Slug, m.Email, err)
+				}
+			}
+		}
+
+		for i, e := range set.Entries {
+			createdAt := e.CreatedAt.UTC()
+			if e.CreatedAt.IsZero() {
+				createdAt = now
+			}
+			userID := res.Users[normalizeEmail(e.Author)]
+			if _, err := tx.ExecContext(ctx,
+				`INSERT INTO entries (org_id, user_id, title, body, language, created_at, updated_at)
+				 VALUES ($1, $2, $3, $4, (SELECT search_language FROM users WHERE id = $2), $5, $5)`,
+				res.Organizations[e.Org], userID, e.Title, e.Body, createdAt,
+			); err != nil {
+				return fmt.Errorf("entry %d: %w", i, err)
+			}
+			res.Entries++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Truncate deletes every row of every application table, leaving the
+// schema and the migration history alone, and resets ID sequences
+func Truncate(ctx context.Context, db *database.DB) error {
+	tables, err := tableNames(ctx, db)
+	if err != nil || len(tables) == 0 {
+		return err
+	}
+	if db.Dialect == database.Postgres {
+		_, err := db.ExecContext(ctx, `TRUNCATE `+strings.Join(tables, ", ")+` RESTART IDENTITY CASCADE`)
+		return err
+	}
+	return db.WithTx(ctx, func(tx *sql.Tx) error {
+		// Foreign keys are checked at commit, when every table is empty
+		if _, err := tx.ExecContext(ctx, `PRAGMA defer_foreign_keys = ON`); err != nil {
+			return err
+		}
+		for _, table := range tables {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM "`+table+`"`); err != nil {
+				return fmt.Errorf("truncate %s: %w", table, err)
+			}
+		}
+		_, err := tx.ExecContext(ctx, `DELETE FROM sqlite_sequence`)
+		return err
+	})
+}
+
+// tableNames lists the application tables. SQLite's internal tables and
+// the tables backing full-text indexes are skipped; the indexes follow
+// their content tables through triggers.
+func tableNames(ctx context.Context, db *database.DB) ([]string, error) {
+	query := `SELECT tablename FROM pg_tables WHERE schemaname = current_schema() AND tablename <> 'schema_migrations'`
+	if db.Dialect == database.SQLite {
+		query = `SELECT name FROM sqlite_master
+			WHERE type = 'table' AND name NOT LIKE 'sqlite_%' AND name <> 'schema_migrations'
+			  AND sql NOT LIKE 'CREATE VIRTUAL TABLE%'
+			  AND NOT EXISTS (SELECT 1 FROM sqlite_master v
+			                  WHERE v.sql LIKE 'CREATE VIRTUAL TABLE%' AND sqlite_master.name LIKE v.name || '_%')`
+	}
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	return tables, rows.Err()
+}
+
+// Seed loads the named built-in sets into db for a test and returns the
+// IDs of the loaded records
+func Seed(t testing.TB, db *database.DB, names ...string) *Result {
+	t.Helper()
+	set := &Set{}
+	for _, name := range names {
+		s, err := Open(name)
+		if err != nil {
+			t.Fatalf("open fixtures: %v", err)
+		}
+		set.Merge(s)
+	}
+	res, err := Load(context.Background(), db, set)
+	if err != nil {
+		t.Fatalf("load fixtures: %v", err)
+	}
+	return res
+}
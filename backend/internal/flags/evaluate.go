@@ -0,0 +1,162 @@
+package flags
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Evaluation reasons
+const (
+	ReasonDisabled    = "disabled"
+	ReasonRule        = "rule"
+	ReasonFallthrough = "fallthrough"
+	ReasonUnknown     = "unknown_flag"
+)
+
+// Subject is who a flag is evaluated for
+type Subject struct {
+	UserID int64
+	// AnonymousID buckets signed-out clients, e.g. a device identifier
+	AnonymousID string
+	Role        string
+	Platform    string
+	AppVersion  string
+}
+
+// Result is the outcome of evaluating one flag
+type Result struct {
+	Key     string `json:"key"`
+	Variant string `json:"variant"`
+	Value   any    `json:"value"`
+	Reason  string `json:"reason"`
+}
+
+// bucketKey is what percentage rollouts hash on
+func (s Subject) bucketKey() string {
+	if s.UserID != 0 {
+		return strconv.FormatInt(s.UserID, 10)
+	}
+	return s.AnonymousID
+}
+
+func (s Subject) attribute(name string) string {
+	switch name {
+	case AttrUserID:
+		if s.UserID == 0 {
+			return ""
+		}
+		return strconv.FormatInt(s.UserID, 10)
+	case AttrRole:
+		return s.Role
+	case AttrPlatform:
+		return s.Platform
+	case AttrAppVersion:
+		return s.AppVersion
+	}
+	return ""
+}
+
+// evaluate decides which variant of f the subject gets
+func evaluate(f *Flag, s Subject) Result {
+	variants := f.variants()
+	if !f.Enabled {
+		name := f.offVariant()
+		return Result{Key: f.Key, Variant: name, Value: variants[name], Reason: ReasonDisabled}
+	}
+
+	for _, rule := range f.Rules {
+		if matches(rule.Conditions, s) {
+			name := serve(f.Key, rule.Serve, s)
+			return Result{Key: f.Key, Variant: name, Value: variants[name], Reason: ReasonRule}
+		}
+	}
+
+	name := serve(f.Key, f.Fallthrough, s)
+	return Result{Key: f.Key, Variant: name, Value: variants[name], Reason: ReasonFallthrough}
+}
+
+func matches(conditions []Condition, s Subject) bool {
+	for _, c := range conditions {
+		value := s.attribute(c.Attribute)
+		var ok bool
+		switch c.Operator {
+		case OpIn:
+			ok = slices.Contains(c.Values, value)
+		case OpNotIn:
+			ok = !slices.Contains(c.Values, value)
+		case OpVersionGTE:
+			ok = value != "" && compareVersions(value, c.Values[0]) >= 0
+		case OpVersionLT:
+			ok = value != "" && compareVersions(value, c.Values[0]) < 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func serve(key string, s Serve, subject Subject) string {
+	if len(s.Rollout) == 0 {
+		if s.Variant == "" {
+			return On
+		}
+		return s.Variant
+	}
+
+	b := bucket(key, subject.bucketKey())
+	cumulative := 0
+	for _, a := range s.Rollout {
+		cumulative += a.Percent * 100
+		if b < cumulative {
+			return a.Variant
+		}
+	}
+	return s.Rollout[len(s.Rollout)-1].Variant
+}
+
+// bucket maps a subject to one of 10000 buckets, stable for a given flag so
+// that a user keeps their variant while a rollout grows, but independent
+// between flags
+func bucket(flagKey, subjectKey string) int {
+	sum := sha256.Sum256([]byte(flagKey + ":" + subjectKey))
+	return int(binary.BigEndian.Uint32(sum[:4]) % 10000)
+}
+
+// compareVersions compares dotted numeric versions such as "1.10.2",
+// ignoring any pre-release or build suffix
+func compareVersions(a, b string) int {
+	pa, pb := versionParts(a), versionParts(b)
+	for i := 0; i < max(len(pa), len(pb)); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func versionParts(v string) []int {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	var parts []int
+	for _, p := range strings.Split(v, ".") {
+		n, _ := strconv.Atoi(p)
+		parts = append(parts, n)
+	}
+	return parts
+}
@@ -0,0 +1,158 @@
+// Package flags evaluates feature flags so features can be dark-launched to
+// a subset of users without redeploying the backend.
+//
+// A flag serves one of its variants. Boolean flags have the implicit
+// variants "on" (true) and "off" (false); multivariate flags declare their
+// own. Targeting rules are checked in order and the first match decides,
+// otherwise the fallthrough serves a fixed variant or a percentage rollout.
+package flags
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Implicit variants of boolean flags
+const (
+	On  = "on"
+	Off = "off"
+)
+
+// Condition operators
+const (
+	OpIn         = "in"
+	OpNotIn      = "not_in"
+	OpVersionGTE = "version_gte"
+	OpVersionLT  = "version_lt"
+)
+
+// Subject attributes rules can target
+const (
+	AttrUserID     = "user_id"
+	AttrRole       = "role"
+	AttrPlatform   = "platform"
+	AttrAppVersion = "app_version"
+)
+
+// ErrInvalidFlag is wrapped by every validation error
+var ErrInvalidFlag = errors.New("invalid flag")
+
+// Flag is a single feature flag definition
+type Flag struct {
+	Key         string `json:"key"`
+	Description string `json:"description,omitempty"`
+	// Enabled is the kill switch: disabled flags always serve OffVariant
+	Enabled bool `json:"enabled"`
+	// Variants maps variant names to the values handed to clients. Leave
+	// empty for a boolean flag.
+	Variants map[string]any `json:"variants,omitempty"`
+	// OffVariant is served while the flag is disabled (default "off")
+	OffVariant string `json:"off_variant,omitempty"`
+	Rules      []Rule `json:"rules,omitempty"`
+	// Fallthrough is served when no rule matches (default "on")
+	Fallthrough Serve `json:"fallthrough"`
+}
+
+// Rule serves a variant to subjects matching all of its conditions
+type Rule struct {
+	Conditions []Condition `json:"conditions"`
+	Serve      Serve       `json:"serve"`
+}
+
+// Condition compares one subject attribute against a list of values
+type Condition struct {
+	Attribute string   `json:"attribute"`
+	Operator  string   `json:"op"`
+	Values    []string `json:"values"`
+}
+
+// Serve picks a variant either directly or by percentage rollout
+type Serve struct {
+	Variant string       `json:"variant,omitempty"`
+	Rollout []Allocation `json:"rollout,omitempty"`
+}
+
+// Allocation assigns a share of subjects, in percent, to a variant
+type Allocation struct {
+	Variant string `json:"variant"`
+	Percent int    `json:"percent"`
+}
+
+// variants returns the flag's variants, defaulting to on/off booleans
+func (f *Flag) variants() map[string]any {
+	if len(f.Variants) == 0 {
+		return map[string]any{On: true, Off: false}
+	}
+	return f.Variants
+}
+
+func (f *Flag) offVariant() string {
+	if f.OffVariant == "" {
+		return Off
+	}
+	return f.OffVariant
+}
+
+// Validate checks that every referenced variant exists, rollouts add up to
+// 100% and operators are known
+func (f *Flag) Validate() error {
+	if f.Key == "" {
+		return fmt.Errorf("%w: key is required", ErrInvalidFlag)
+	}
+	variants := f.variants()
+	if _, ok := variants[f.offVariant()]; !ok {
+		return fmt.Errorf("%w %q: off variant %q is not defined", ErrInvalidFlag, f.Key, f.offVariant())
+	}
+	if err := f.validateServe(f.Fallthrough, variants); err != nil {
+		return fmt.Errorf("%w %q: fallthrough: %v", ErrInvalidFlag, f.Key, err)
+	}
+	for i, rule := range f.Rules {
+		if len(rule.Conditions) == 0 {
+			return fmt.Errorf("%w %q: rule %d has no conditions", ErrInvalidFlag, f.Key, i)
+		}
+		for _, c := range rule.Conditions {
+			switch c.Operator {
+			case OpIn, OpNotIn, OpVersionGTE, OpVersionLT:
+			default:
+				return fmt.Errorf("%w %q: rule %d: unknown operator %q", ErrInvalidFlag, f.Key, i, c.Operator)
+			}
+			if len(c.Values) == 0 {
+				return fmt.Errorf("%w %q: rule %d: condition on %q has no values", ErrInvalidFlag, f.Key, i, c.Attribute)
+			}
+		}
+		if err := f.validateServe(rule.Serve, variants); err != nil {
+			return fmt.Errorf("%w %q: rule %d: %v", ErrInvalidFlag, f.Key, i, err)
+		}
+	}
+	return nil
+}
+
+func (f *Flag) validateServe(s Serve, variants map[string]any) error {
+	if len(s.Rollout) == 0 {
+		name := s.Variant
+		if name == "" {
+			name = On
+		}
+		if _, ok := variants[name]; !ok {
+			return fmt.Errorf("variant %q is not defined", name)
+		}
+		return nil
+	}
+	if s.Variant != "" {
+		return errors.New("set either variant or rollout, not both")
+	}
+	total := 0
+	for _, a := range s.Rollout {
+		if _, ok := variants[a.Variant]; !ok {
+			return fmt.Errorf("variant %q is not defined", a.Variant)
+		}
+		if a.Percent < 0 {
+			return fmt.Errorf("negative percentage for %q", a.Variant)
+		}
+		total += a.Percent
+	}
+	if total != 100 {
+		return fmt.Errorf("rollout adds up to %d%%, want 100%%", total)
+	}
+	return nil
+}
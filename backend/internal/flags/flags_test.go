@@ -0,0 +1,216 @@
+package flags
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database/dbtest"
+)
+
+type staticSource []Flag
+
+func (s staticSource) Load(ctx context.Context) ([]Flag, error) {
+	return s, nil
+}
+
+func newService(t *testing.T, list ...Flag) *Service {
+	t.Helper()
+	svc, err := NewService(context.Background(), staticSource(list))
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+	return svc
+}
+
+func TestBooleanFlag(t *testing.T) {
+	svc := newService(t,
+		Flag{Key: "new-dashboard", Enabled: true},
+		Flag{Key: "killed", Enabled: false},
+	)
+
+	if !svc.Enabled("new-dashboard", Subject{UserID: 1}) {
+		t.Error("Expected enabled flag to be on")
+	}
+	if svc.Enabled("killed", Subject{UserID: 1}) {
+		t.Error("Expected disabled flag to be off")
+	}
+	if r := svc.Evaluate("missing", Subject{}); r.Reason != ReasonUnknown || r.Value != false {
+		t.Errorf("Expected unknown flag to be off, got %+v", r)
+	}
+}
+
+func TestTargetingRules(t *testing.T) {
+	svc := newService(t, Flag{
+		Key:        "checkout",
+		Enabled:    true,
+		Variants:   map[string]any{"old": "v1", "new": "v2", "beta": "v3"},
+		OffVariant: "old",
+		Rules: []Rule{
+			{
+				Conditions: []Condition{{Attribute: AttrRole, Operator: OpIn, Values: []string{"admin"}}},
+				Serve:      Serve{Variant: "beta"},
+			},
+			{
+				Conditions: []Condition{
+					{Attribute: AttrPlatform, Operator: OpIn, Values: []string{"ios", "android"}},
+					{Attribute: AttrAppVersion, Operator: OpVersionGTE, Values: []string{"2.10.0"}},
+				},
+				Serve: Serve{Variant: "new"},
+			},
+		},
+		Fallthrough: Serve{Variant: "old"},
+	})
+
+	tests := []struct {
+		name    string
+		subject Subject
+		variant string
+	}{
+		{"admin wins first rule", Subject{Role: "admin", Platform: "web"}, "beta"},
+		{"new mobile app", Subject{Platform: "ios", AppVersion: "2.10.1"}, "new"},
+		{"version compared numerically", Subject{Platform: "android", AppVersion: "2.9.9"}, "old"},
+		{"pre-release suffix ignored", Subject{Platform: "android", AppVersion: "2.10.0-beta.1"}, "new"},
+		{"web falls through", Subject{Platform: "web", AppVersion: "3.0.0"}, "old"},
+		{"missing version never matches", Subject{Platform: "ios"}, "old"},
+	}
+	for _, tt := range tests {
+		if got := svc.Variant("checkout", tt.subject); got != tt.variant {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.variant, got)
+		}
+	}
+
+	if r := svc.Evaluate("checkout", Subject{Role: "admin"}); r.Value != "v3" || r.Reason != ReasonRule {
+		t.Errorf("Expected value v3 by rule, got %+v", r)
+	}
+}
+
+func TestPercentageRolloutIsStableAndProportional(t *testing.T) {
+	svc := newService(t, Flag{
+		Key:     "streaks",
+		Enabled: true,
+		Fallthrough: Serve{Rollout: []Allocation{
+			{Variant: On, Percent: 25},
+			{Variant: Off, Percent: 75},
+		}},
+	})
+
+	on := 0
+	const users = 10000
+	for id := int64(1); id <= users; id++ {
+		first := svc.Enabled("streaks", Subject{UserID: id})
+		if svc.Enabled("streaks", Subject{UserID: id}) != first {
+			t.Fatalf("User %d got different variants on repeated evaluation", id)
+		}
+		if first {
+			on++
+		}
+	}
+	if on < 2300 || on > 2700 {
+		t.Errorf("Expected about 25%% of users enabled, got %d of %d", on, users)
+	}
+}
+
+func TestRolloutGrowsWithoutReshuffling(t *testing.T) {
+	small := Flag{Key: "grow", Enabled: true, Fallthrough: Serve{Rollout: []Allocation{{On, 10}, {Off, 90}}}}
+	large := Flag{Key: "grow", Enabled: true, Fallthrough: Serve{Rollout: []Allocation{{On, 50}, {Off, 50}}}}
+
+	for id := int64(1); id <= 2000; id++ {
+		s := Subject{UserID: id}
+		if evaluate(&small, s).Variant == On && evaluate(&large, s).Variant != On {
+			t.Fatalf("User %d lost the feature when the rollout grew", id)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	invalid := []Flag{
+		{},
+		{Key: "x", Variants: map[string]any{"a": 1}, Fallthrough: Serve{Variant: "a"}},
+		{Key: "x", Fallthrough: Serve{Variant: "maybe"}},
+		{Key: "x", Fallthrough: Serve{Rollout: []Allocation{{On, 60}, {Off, 30}}}},
+		{Key: "x", Rules: []Rule{{Conditions: []Condition{{Attribute: AttrRole, Operator: "like", Values: []string{"a"}}}}}},
+	}
+	for i, f := range invalid {
+		if err := f.Validate(); !errors.Is(err, ErrInvalidFlag) {
+			t.Errorf("Flag %d: expected ErrInvalidFlag, got %v", i, err)
+		}
+	}
+
+	valid := Flag{Key: "x", Enabled: true}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected minimal boolean flag to be valid, got %v", err)
+	}
+}
+
+func TestFileSourceReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flags.json")
+	write := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(`[{"key": "dark-mode", "enabled": false}]`)
+	svc, err := NewService(context.Background(), FileSource{Path: path})
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+	if svc.Enabled("dark-mode", Subject{}) {
+		t.Fatal("Expected dark-mode off initially")
+	}
+
+	write(`[{"key": "dark-mode", "enabled": true}]`)
+	if err := svc.Reload(context.Background()); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if !svc.Enabled("dark-mode", Subject{}) {
+		t.Error("Expected dark-mode on after reload")
+	}
+
+	// A broken file keeps the last good flags
+	write(`[{"key": "dark-mode", "enabled": true, "fallthrough": {"variant": "nope"}}]`)
+	if err := svc.Reload(context.Background()); err == nil {
+		t.Error("Expected reload of an invalid flag to fail")
+	}
+	if !svc.Enabled("dark-mode", Subject{}) {
+		t.Error("Expected previous flags to stay in effect")
+	}
+}
+
+func TestDBStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewDBStore(dbtest.New(t))
+
+	if err := store.Save(ctx, Flag{Key: "bad", Fallthrough: Serve{Variant: "nope"}}); !errors.Is(err, ErrInvalidFlag) {
+		t.Errorf("Expected ErrInvalidFlag on save, got %v", err)
+	}
+	if err := store.Save(ctx, Flag{Key: "journal", Enabled: false}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	svc, err := NewService(ctx, store)
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+	if svc.Enabled("journal", Subject{}) {
+		t.Fatal("Expected journal off")
+	}
+
+	if err := store.Save(ctx, Flag{Key: "journal", Enabled: true}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	svc.Reload(ctx)
+	if !svc.Enabled("journal", Subject{}) {
+		t.Error("Expected journal on after update and reload")
+	}
+
+	if err := store.Delete(ctx, "journal"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := store.Delete(ctx, "journal"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package flags
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// Service evaluates flags against an in-memory snapshot that is refreshed
+// from its source, so evaluation never touches the database
+type Service struct {
+	source   Source
+	snapshot atomic.Pointer[map[string]*Flag]
+}
+
+// NewService creates a flag service and performs the initial load
+func NewService(ctx context.Context, source Source) (*Service, error) {
+	s := &Service{source: source}
+	empty := map[string]*Flag{}
+	s.snapshot.Store(&empty)
+	if err := s.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload replaces the snapshot with the source's current flags. On error,
+// including an invalid flag, the previous snapshot stays in effect.
+func (s *Service) Reload(ctx context.Context) error {
+	list, err := s.source.Load(ctx)
+	if err != nil {
+		return err
+	}
+	next := make(map[string]*Flag, len(list))
+	for i := range list {
+		f := list[i]
+		if err := f.Validate(); err != nil {
+			return err
+		}
+		next[f.Key] = &f
+	}
+	s.snapshot.Store(&next)
+	return nil
+}
+
+// Watch reloads the flags every interval until ctx is done
+func (s *Service) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Reload(ctx); err != nil {
+				log.Printf("flags: reload failed, keeping previous flags: %v", err)
+			}
+		}
+	}
+}
+
+// Evaluate returns the variant of the flag served to the subject. Unknown
+// flags evaluate to the "off" variant with value false.
+func (s *Service) Evaluate(key string, subject Subject) Result {
+	f, ok := (*s.snapshot.Load())[key]
+	if !ok {
+		return Result{Key: key, Variant: Off, Value: false, Reason: ReasonUnknown}
+	}
+	return evaluate(f, subject)
+}
+
+// Enabled reports whether a boolean flag is on for the subject
+func (s *Service) Enabled(key string, subject Subject) bool {
+	on, _ := s.Evaluate(key, subject).Value.(bool)
+	return on
+}
+
+// Variant returns the name of the variant served to the subject
+func (s *Service) Variant(key string, subject Subject) string {
+	return s.Evaluate(key, subject).Variant
+}
+
+// EvaluateAll evaluates every flag for the subject, ordered by key
+func (s *Service) EvaluateAll(subject Subject) []Result {
+	snapshot := *s.snapshot.Load()
+	results := make([]Result, 0, len(snapshot))
+	for _, f := range snapshot {
+		results = append(results, evaluate(f, subject))
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Key < results[j].Key })
+	return results
+}
+
+// Flags returns the current flag definitions, ordered by key
+func (s *Service) Flags() []Flag {
+	snapshot := *s.snapshot.Load()
+	list := make([]Flag, 0, len(snapshot))
+	for _, f := range snapshot {
+		list = append(list, *f)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Key < list[j].Key })
+	return list
+}
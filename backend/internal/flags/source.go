@@ -0,0 +1,100 @@
+package flags
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+)
+
+// ErrNotFound is returned when a flag does not exist in the store
+var ErrNotFound = errors.New("flag not found")
+
+// Source loads the complete set of flag definitions
+type Source interface {
+	Load(ctx context.Context) ([]Flag, error)
+}
+
+// FileSource reads flags from a JSON file containing an array of flags
+type FileSource struct {
+	Path string
+}
+
+// Load implements Source
+func (s FileSource) Load(ctx context.Context) ([]Flag, error) {
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	var list []Flag
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", s.Path, err)
+	}
+	return list, nil
+}
+
+// DBStore keeps flags in the feature_flags table, one JSON definition per row
+type DBStore struct {
+	db *database.DB
+}
+
+// NewDBStore creates a database-backed flag store
+func NewDBStore(db *database.DB) *DBStore {
+	return &DBStore{db: db}
+}
+
+// Load implements Source
+func (s *DBStore) Load(ctx context.Context) ([]Flag, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT key, definition FROM feature_flags ORDER BY key`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []Flag{}
+	for rows.Next() {
+		var key, definition string
+		if err := rows.Scan(&key, &definition); err != nil {
+			return nil, err
+		}
+		var f Flag
+		if err := json.Unmarshal([]byte(definition), &f); err != nil {
+			return nil, fmt.Errorf("flag %q: %w", key, err)
+		}
+		f.Key = key
+		list = append(list, f)
+	}
+	return list, rows.Err()
+}
+
+// Save validates and inserts or replaces a flag
+func (s *DBStore) Save(ctx context.Context, f Flag) error {
+	if err := f.Validate(); err != nil {
+		return err
+	}
+	definition, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO feature_flags (key, definition, updated_at) VALUES ($1, $2, $3)
+		 ON CONFLICT (key) DO UPDATE SET definition = excluded.definition, updated_at = excluded.updated_at`,
+		f.Key, string(definition), time.Now().UTC())
+	return err
+}
+
+// Delete removes a flag
+func (s *DBStore) Delete(ctx context.Context, key string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM feature_flags WHERE key = $1`, key)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
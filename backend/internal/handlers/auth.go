@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/auth"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/mfa"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/middleware"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/orgs"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/sessions"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/users"
+)
+
+// errInvalidCredentials is deliberately vague so that login does not reveal
+// which accounts exist
+var errInvalidCredentials = errors.New("invalid email or password")
+
+// AuthHandler serves sign-up, sign-in, token refresh and session management
+type AuthHandler struct {
+	users        *users.Repository
+	sessions     *sessions.Service
+	orgs         *orgs.Service
+	tokens       *auth.TokenService
+	mfa          *mfa.Service
+	challengeTTL time.Duration
+}
+
+// NewAuthHandler creates a new auth handler. Users with two-factor
+// authentication get an MFA challenge valid for challengeTTL instead of a
+// session when they sign in. Access tokens carry the user's default
+// organization from orgs.
+func NewAuthHandler(users *users.Repository, sessions *sessions.Service, orgs *orgs.Service, tokens *auth.TokenService, mfa *mfa.Service, challengeTTL time.Duration) *AuthHandler {
+	return &AuthHandler{users: users, sessions: sessions, orgs: orgs, tokens: tokens, mfa: mfa, challengeTTL: challengeTTL}
+}
+
+type registerRequest struct {
+	Email      string `json:"email"`
+	Name       string `json:"name"`
+	Password   string `json:"password"`
+	DeviceName string `json:"device_name"`
+	Platform   string `json:"platform"`
+}
+
+type loginRequest struct {
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	DeviceName string `json:"device_name"`
+	Platform   string `json:"platform"`
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type sessionView struct {
+	sessions.Session
+	Current bool `json:"current"`
+}
+
+// Register handles POST /auth/register and signs the new user in
+func (h *AuthHandler) Register(c *gin.Context) {
+	var req registerRequest
+	if err := c.ShouldBindJSON(&req); err != nil || !strings.Contains(req.Email, "@") {
+		middleware.RespondError(c, http.StatusBadRequest, errInvalidBody)
+		return
+	}
+	hash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+	user := &users.User{Email: req.Email, Name: strings.TrimSpace(req.Name), PasswordHash: hash}
+	if err := h.users.Create(c.Request.Context(), user); err != nil {
+		respondAuthError(c, err)
+		return
+	}
+	h.completeSignIn(c, http.StatusCreated, user, req.DeviceName, req.Platform)
+}
+
+// Login handles POST /auth/login
+func (h *AuthHandler) Login(c *gin.Context) {
+	var req loginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.RespondError(c, http.StatusBadRequest, errInvalidBody)
+		return
+	}
+	user, err := h.users.GetByEmail(c.Request.Context(), req.Email)
+	if err != nil && !errors.Is(err, users.ErrNotFound) {
+		respondAuthError(c, err)
+		return
+	}
+	if user == nil || user.DeletedAt != nil || !auth.CheckPassword(user.PasswordHash, req.Password) {
+		respondAuthError(c, errInvalidCredentials)
+		return
+	}
+	h.completeSignIn(c, http.StatusOK, user, req.DeviceName, req.Platform)
+}
+
+// completeSignIn is called once the first factor has been checked. It
+// signs the user in, or responds with an MFA challenge when the user has a
+// second factor or their role requires one.
+func (h *AuthHandler) completeSignIn(c *gin.Context, status int, user *users.User, deviceName, platform string) {
+	ctx := c.Request.Context()
+	enrolled, err := h.mfa.Enabled(ctx, user.ID)
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+	required, err := h.mfa.Required(ctx, user.Role)
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+	if !enrolled && !required {
+		h.signIn(c, status, user, deviceName, platform, nil)
+		return
+	}
+
+	challenge, err := h.tokens.IssueChallenge(user.ID, h.challengeTTL)
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+	c.JSON(status, gin.H{
+		"mfa_required": true,
+		"mfa_enrolled": enrolled,
+		"mfa_token":    challenge,
+		"expires_in":   int(h.challengeTTL.Seconds()),
+	})
+}
+
+// signIn creates a session and responds with its tokens, merged with extra
+func (h *AuthHandler) signIn(c *gin.Context, status int, user *users.User, deviceName, platform string, extra gin.H) {
+	if platform == "" {
+		platform = c.GetHeader(HeaderPlatform)
+	}
+	device := sessions.Device{Name: deviceName, Platform: platform, IP: c.ClientIP()}
+	sess, refresh, err := h.sessions.Create(c.Request.Context(), user.ID, device)
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+	access, err := h.issue(c.Request.Context(), user, sess.ID)
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+	body := gin.H{
+		"user":          user,
+		"session":       sess,
+		"access_token":  access,
+		"refresh_token": refresh,
+		"token_type":    "Bearer",
+		"expires_in":    int(h.tokens.TTL().Seconds()),
+	}
+	for k, v := range extra {
+		body[k] = v
+	}
+	c.JSON(status, body)
+}
+
+// Refresh handles POST /auth/refresh, exchanging a refresh token for a new
+// access and refresh token pair
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req refreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.RefreshToken == "" {
+		middleware.RespondError(c, http.StatusBadRequest, errInvalidBody)
+		return
+	}
+	sess, refresh, err := h.sessions.Refresh(c.Request.Context(), req.RefreshToken, c.ClientIP())
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+	user, err := h.users.GetByID(c.Request.Context(), sess.UserID)
+	if err != nil || user.DeletedAt != nil {
+		respondAuthError(c, sessions.ErrInvalidRefreshToken)
+		return
+	}
+	access, err := h.issue(c.Request.Context(), user, sess.ID)
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"access_token":  access,
+		"refresh_token": refresh,
+		"token_type":    "Bearer",
+		"expires_in":    int(h.tokens.TTL().Seconds()),
+	})
+}
+
+// Logout handles POST /auth/logout and revokes the current session
+func (h *AuthHandler) Logout(c *gin.Context) {
+	if err := h.sessions.Revoke(c.Request.Context(), middleware.UserID(c), middleware.SessionID(c)); err != nil {
+		respondAuthError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListSessions handles GET /sessions
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	list, err := h.sessions.List(c.Request.Context(), middleware.UserID(c))
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+	current := middleware.SessionID(c)
+	views := make([]sessionView, len(list))
+	for i, s := range list {
+		views[i] = sessionView{Session: s, Current: s.ID == current}
+	}
+	c.JSON(http.StatusOK, gin.H{"sessions": views})
+}
+
+// RevokeSession handles DELETE /sessions/:id
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		middleware.RespondError(c, http.StatusBadRequest, errInvalidSessionID)
+		return
+	}
+	if err := h.sessions.Revoke(c.Request.Context(), middleware.UserID(c), id); err != nil {
+		respondAuthError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RevokeOtherSessions handles DELETE /sessions and signs out every device
+// but the current one
+func (h *AuthHandler) RevokeOtherSessions(c *gin.Context) {
+	n, err := h.sessions.RevokeOthers(c.Request.Context(), middleware.UserID(c), middleware.SessionID(c))
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"revoked": n})
+}
+
+// issue creates an access token for the session. It names the user's
+// default organization, which the Tenant middleware falls back to when a
+// request selects none.
+func (h *AuthHandler) issue(ctx context.Context, user *users.User, sessionID int64) (string, error) {
+	orgID, err := h.orgs.DefaultOrg(ctx, user.ID)
+	if err != nil {
+		return "", err
+	}
+	return h.tokens.Issue(auth.Claims{UserID: user.ID, Email: user.Email, Role: user.Role, OrgID: orgID, SessionID: sessionID})
+}
+
+func respondAuthError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errInvalidCredentials), errors.Is(err, sessions.ErrInvalidRefreshToken):
+		middleware.RespondError(c, http.StatusUnauthorized, err)
+	case errors.Is(err, auth.ErrWeakPassword):
+		middleware.RespondError(c, http.StatusBadRequest, err)
+	case errors.Is(err, users.ErrEmailTaken):
+		middleware.RespondError(c, http.StatusConflict, err)
+	case errors.Is(err, sessions.ErrNotFound):
+		middleware.RespondError(c, http.StatusNotFound, err)
+	default:
+		log.Printf("auth: %v", err)
+		middleware.RespondError(c, http.StatusInternalServerError, middleware.ErrInternal)
+	}
+}
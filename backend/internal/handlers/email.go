@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/email"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/middleware"
+)
+
+// EmailHandler previews email templates with sample data. It is only
+// mounted in development.
+type EmailHandler struct {
+	renderer *email.Renderer
+}
+
+// NewEmailHandler creates a new email preview handler
+func NewEmailHandler(renderer *email.Renderer) *EmailHandler {
+	return &EmailHandler{renderer: renderer}
+}
+
+// List handles GET /dev/emails
+func (h *EmailHandler) List(c *gin.Context) {
+	templates := make([]gin.H, 0)
+	for _, name := range h.renderer.Names() {
+		templates = append(templates, gin.H{"name": name, "locales": h.renderer.Locales(name)})
+	}
+	c.JSON(http.StatusOK, gin.H{"templates": templates})
+}
+
+// Preview handles GET /dev/emails/:template?locale=ru&format=text and
+// renders the template with its sample data
+func (h *EmailHandler) Preview(c *gin.Context) {
+	name := c.Param("template")
+	msg, err := h.renderer.Render(name, c.Query("locale"), email.Samples[name])
+	if err != nil {
+		if errors.Is(err, email.ErrUnknownTemplate) {
+			middleware.RespondError(c, http.StatusNotFound, email.ErrUnknownTemplate)
+			return
+		}
+		log.Printf("email: %v", err)
+		middleware.RespondError(c, http.StatusInternalServerError, middleware.ErrInternal)
+		return
+	}
+
+	c.Header("X-Email-Subject", msg.Subject)
+	switch c.DefaultQuery("format", "html") {
+	case "html":
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(msg.HTML))
+	case "text":
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(msg.Text))
+	default:
+		middleware.RespondError(c, http.StatusBadRequest, errInvalidFormat)
+	}
+}
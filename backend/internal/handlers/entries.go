@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/apiversion"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/entries"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/middleware"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/pagination"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/webhooks"
+)
+
+// EntriesHandler serves the journal entry endpoints of the current tenant
+type EntriesHandler struct {
+	repo   *entries.Repository
+	events webhooks.Publisher
+	pages  *pagination.Codec
+}
+
+// NewEntriesHandler creates a new entries handler. Changes are published
+// to events.
+func NewEntriesHandler(repo *entries.Repository, events webhooks.Publisher, pages *pagination.Codec) *EntriesHandler {
+	return &EntriesHandler{repo: repo, events: events, pages: pages}
+}
+
+type entryRequest struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// List handles GET /entries with the pagination query parameters
+func (h *EntriesHandler) List(c *gin.Context) {
+	q, err := h.pages.Parse(entries.ListResource, c.Request.URL.Query())
+	if err != nil {
+		middleware.RespondError(c, http.StatusBadRequest, err)
+		return
+	}
+	list, err := h.repo.List(c.Request.Context(), middleware.UserID(c), q)
+	if err != nil {
+		respondEntriesError(c, err)
+		return
+	}
+	page := pagination.Paginate(q, list, entries.FieldValue)
+	pagination.SetLinks(c.Writer.Header(), c.Request.URL, page.NextCursor)
+	c.JSON(http.StatusOK, page)
+}
+
+// EntriesListV1 keeps the v1 response shape of GET /entries, which listed
+// entries under "entries"
+var EntriesListV1 = apiversion.RewriteJSON(func(body any) any {
+	if m, ok := body.(map[string]any); ok {
+		if data, ok := m["data"]; ok {
+			delete(m, "data")
+			m["entries"] = data
+		}
+	}
+	return body
+})
+
+// Create handles POST /entries
+func (h *EntriesHandler) Create(c *gin.Context) {
+	var req entryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.RespondError(c, http.StatusBadRequest, errInvalidBody)
+		return
+	}
+	entry := &entries.Entry{UserID: middleware.UserID(c), Title: req.Title, Body: req.Body}
+	if err := h.repo.Create(c.Request.Context(), entry); err != nil {
+		respondEntriesError(c, err)
+		return
+	}
+	h.publish(c, webhooks.EventEntryCreated, entry)
+	c.JSON(http.StatusCreated, entry)
+}
+
+// Get handles GET /entries/:id
+func (h *EntriesHandler) Get(c *gin.Context) {
+	id, ok := entryID(c)
+	if !ok {
+		return
+	}
+	entry, err := h.repo.Get(c.Request.Context(), middleware.UserID(c), id)
+	if err != nil {
+		respondEntriesError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, entry)
+}
+
+// Update handles PUT /entries/:id
+func (h *EntriesHandler) Update(c *gin.Context) {
+	id, ok := entryID(c)
+	if !ok {
+		return
+	}
+	var req entryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.RespondError(c, http.StatusBadRequest, errInvalidBody)
+		return
+	}
+	entry := &entries.Entry{ID: id, UserID: middleware.UserID(c), Title: req.Title, Body: req.Body}
+	if err := h.repo.Update(c.Request.Context(), entry); err != nil {
+		respondEntriesError(c, err)
+		return
+	}
+	h.publish(c, webhooks.EventEntryUpdated, entry)
+	c.JSON(http.StatusOK, entry)
+}
+
+// Delete handles DELETE /entries/:id
+func (h *EntriesHandler) Delete(c *gin.Context) {
+	id, ok := entryID(c)
+	if !ok {
+		return
+	}
+	if err := h.repo.Delete(c.Request.Context(), middleware.UserID(c), id); err != nil {
+		respondEntriesError(c, err)
+		return
+	}
+	h.publish(c, webhooks.EventEntryDeleted, gin.H{"id": id})
+	c.Status(http.StatusNoContent)
+}
+
+// publish notifies subscribers of a change. The change itself already
+// happened, so failures are only logged.
+func (h *EntriesHandler) publish(c *gin.Context, eventType string, data any) {
+	t, _ := middleware.CurrentTenant(c)
+	err := h.events.Publish(c.Request.Context(), webhooks.Event{
+		Type:   eventType,
+		OrgID:  t.OrgID,
+		UserID: middleware.UserID(c),
+		Data:   data,
+	})
+	if err != nil {
+		log.Printf("entries: publish %s: %v", eventType, err)
+	}
+}
+
+func entryID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		middleware.RespondError(c, http.StatusBadRequest, errInvalidEntryID)
+		return 0, false
+	}
+	return id, true
+}
+
+func respondEntriesError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, entries.ErrNotFound):
+		middleware.RespondError(c, http.StatusNotFound, err)
+	case errors.Is(err, entries.ErrEmptyTitle):
+		middleware.RespondError(c, http.StatusBadRequest, err)
+	default:
+		log.Printf("entries: %v", err)
+		middleware.RespondError(c, http.StatusInternalServerError, middleware.ErrInternal)
+	}
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/auth"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/email"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/entries"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/flags"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/i18n"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/mfa"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/middleware"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/oidc"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/orgs"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/pagination"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/privacy"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/search"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/sessions"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/users"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/webhooks"
+)
+
+// Predefined errors
+var (
+	errInvalidBody       = errors.New("invalid request body")
+	errInvalidUserID     = errors.New("invalid user id")
+	errInvalidSessionID  = errors.New("invalid session id")
+	errInvalidEntryID    = errors.New("invalid entry id")
+	errInvalidExportID   = errors.New("invalid export id")
+	errInvalidWebhookID  = errors.New("invalid webhook id")
+	errInvalidDeliveryID = errors.New("invalid delivery id")
+	errInvalidMFAToken   = errors.New("invalid or expired MFA token")
+	errLoginDenied       = errors.New("login cancelled or denied")
+	errMissingCode       = errors.New("code and state are required")
+	errSignInFailed      = errors.New("sign-in with the identity provider failed")
+	errFlagsReadOnly     = errors.New("flags are loaded from a file and are read-only")
+	errInvalidFormat     = errors.New("format must be html or text")
+	errUnsupportedLocale = errors.New("unsupported locale")
+)
+
+// ErrorMessages maps every error a handler or middleware reports to its
+// message in the i18n catalogs
+var ErrorMessages = i18n.Errors{
+	middleware.ErrInternal:         {ID: "errors.internal"},
+	middleware.ErrMissingToken:     {ID: "auth.missing_token"},
+	middleware.ErrInsufficientRole: {ID: "auth.insufficient_role"},
+	middleware.ErrNoSession:        {ID: "sessions.missing"},
+	middleware.ErrSessionRevoked:   {ID: "sessions.revoked"},
+	middleware.ErrNoTenant:         {ID: "orgs.not_selected"},
+	middleware.ErrTenantAccess:     {ID: "orgs.no_access"},
+
+	errInvalidBody:        {ID: "request.invalid_body"},
+	errInvalidUserID:      {ID: "request.invalid_user_id"},
+	errInvalidSessionID:   {ID: "request.invalid_session_id"},
+	errInvalidEntryID:     {ID: "request.invalid_entry_id"},
+	errInvalidExportID:    {ID: "request.invalid_export_id"},
+	errInvalidWebhookID:   {ID: "request.invalid_webhook_id"},
+	errInvalidDeliveryID:  {ID: "request.invalid_delivery_id"},
+	errInvalidMFAToken:    {ID: "mfa.invalid_token"},
+	errLoginDenied:        {ID: "oidc.login_denied"},
+	errMissingCode:        {ID: "oidc.missing_code"},
+	errSignInFailed:       {ID: "oidc.sign_in_failed"},
+	errFlagsReadOnly:      {ID: "flags.read_only"},
+	errInvalidFormat:      {ID: "email.invalid_format"},
+	errUnsupportedLocale:  {ID: "i18n.unsupported_locale"},
+	errInvalidCredentials: {ID: "auth.invalid_credentials"},
+
+	auth.ErrInvalidToken: {ID: "auth.invalid_token"},
+	auth.ErrTokenExpired: {ID: "auth.token_expired"},
+	auth.ErrWeakPassword: {ID: "auth.weak_password", Params: i18n.Params{"count": auth.MinPasswordLength}},
+
+	users.ErrNotFound:   {ID: "users.not_found"},
+	users.ErrEmailTaken: {ID: "users.email_taken"},
+
+	sessions.ErrNotFound:            {ID: "sessions.not_found"},
+	sessions.ErrInvalidRefreshToken: {ID: "sessions.invalid_refresh_token"},
+
+	mfa.ErrNotEnrolled:     {ID: "mfa.not_enrolled"},
+	mfa.ErrAlreadyEnabled:  {ID: "mfa.already_enabled"},
+	mfa.ErrInvalidCode:     {ID: "mfa.invalid_code"},
+	mfa.ErrCodeReused:      {ID: "mfa.invalid_code"},
+	mfa.ErrTooManyAttempts: {ID: "mfa.too_many_attempts"},
+	mfa.ErrRequired:        {ID: "mfa.required"},
+	mfa.ErrInvalidRole:     {ID: "mfa.invalid_role"},
+
+	oidc.ErrUnknownProvider:  {ID: "oidc.unknown_provider"},
+	oidc.ErrInvalidState:     {ID: "oidc.invalid_state"},
+	oidc.ErrDiscovery:        {ID: "oidc.provider_unavailable"},
+	oidc.ErrEmailNotVerified: {ID: "oidc.email_not_verified"},
+	oidc.ErrIdentityTaken:    {ID: "oidc.identity_taken"},
+	oidc.ErrIdentityNotFound: {ID: "oidc.identity_not_found"},
+	oidc.ErrLastSignInMethod: {ID: "oidc.last_sign_in_method"},
+	oidc.ErrLinkRequired:     {ID: "oidc.link_required"},
+
+	orgs.ErrNotFound:          {ID: "orgs.not_found"},
+	orgs.ErrNotMember:         {ID: "orgs.not_member"},
+	orgs.ErrForbidden:         {ID: "orgs.forbidden"},
+	orgs.ErrInvalidSlug:       {ID: "orgs.invalid_slug", Params: i18n.Params{"min": 3, "max": 40}},
+	orgs.ErrInvalidName:       {ID: "orgs.invalid_name"},
+	orgs.ErrInvalidRole:       {ID: "orgs.invalid_role"},
+	orgs.ErrSlugTaken:         {ID: "orgs.slug_taken"},
+	orgs.ErrAlreadyMember:     {ID: "orgs.already_member"},
+	orgs.ErrInvitationInvalid: {ID: "orgs.invitation_invalid"},
+	orgs.ErrEmailMismatch:     {ID: "orgs.email_mismatch"},
+	orgs.ErrLastOwner:         {ID: "orgs.last_owner"},
+
+	entries.ErrNotFound:   {ID: "entries.not_found"},
+	entries.ErrEmptyTitle: {ID: "entries.empty_title"},
+
+	pagination.ErrInvalidLimit:  {ID: "pagination.invalid_limit"},
+	pagination.ErrInvalidSort:   {ID: "pagination.invalid_sort"},
+	pagination.ErrInvalidFilter: {ID: "pagination.invalid_filter"},
+	pagination.ErrInvalidCursor: {ID: "pagination.invalid_cursor"},
+
+	search.ErrEmptyQuery:      {ID: "search.empty_query"},
+	search.ErrInvalidCursor:   {ID: "pagination.invalid_cursor"},
+	search.ErrInvalidLanguage: {ID: "search.invalid_language"},
+
+	webhooks.ErrNotFound:         {ID: "webhooks.not_found"},
+	webhooks.ErrDeliveryNotFound: {ID: "webhooks.delivery_not_found"},
+	webhooks.ErrInvalidURL:       {ID: "webhooks.invalid_url"},
+	webhooks.ErrForbiddenHost:    {ID: "webhooks.forbidden_host"},
+	webhooks.ErrInvalidEvent:     {ID: "webhooks.invalid_event"},
+
+	privacy.ErrExportNotFound:   {ID: "privacy.export_not_found"},
+	privacy.ErrExportNotReady:   {ID: "privacy.export_not_ready"},
+	privacy.ErrExportExpired:    {ID: "privacy.export_expired"},
+	privacy.ErrInvalidToken:     {ID: "privacy.invalid_token"},
+	privacy.ErrInvalidMode:      {ID: "privacy.invalid_mode"},
+	privacy.ErrDeletionPending:  {ID: "privacy.deletion_pending"},
+	privacy.ErrNoDeletionActive: {ID: "privacy.no_deletion"},
+
+	flags.ErrNotFound:    {ID: "flags.not_found"},
+	flags.ErrInvalidFlag: {ID: "flags.invalid"},
+
+	email.ErrUnknownTemplate: {ID: "email.unknown_template"},
+}
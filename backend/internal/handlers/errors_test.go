@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/i18n"
+)
+
+// TestErrorMessagesTranslated fails when an error reported by the API has
+// no message in one of the catalogs
+func TestErrorMessagesTranslated(t *testing.T) {
+	bundle, err := i18n.NewBundle(i18n.Catalogs(), "en")
+	if err != nil {
+		t.Fatalf("NewBundle failed: %v", err)
+	}
+	for err, msg := range ErrorMessages {
+		for _, locale := range bundle.Locales() {
+			if !bundle.Has(locale, msg.ID) {
+				t.Errorf("Message %q for %q is missing from %s", msg.ID, err, locale)
+			}
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/flags"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/middleware"
+)
+
+// Client headers describing the calling app, used for flag targeting
+const (
+	HeaderPlatform    = "X-Platform"
+	HeaderAppVersion  = "X-App-Version"
+	HeaderAnonymousID = "X-Anonymous-ID"
+)
+
+// FlagsHandler serves flag evaluations to clients and flag management to admins
+type FlagsHandler struct {
+	service *flags.Service
+	// store is nil when flags come from a config file and are read-only
+	store *flags.DBStore
+}
+
+// NewFlagsHandler creates a new flags handler; store may be nil
+func NewFlagsHandler(service *flags.Service, store *flags.DBStore) *FlagsHandler {
+	return &FlagsHandler{service: service, store: store}
+}
+
+// FlagSubject builds the flag evaluation subject for the current request.
+// Handlers use it together with flags.Service.Enabled to gate features.
+func FlagSubject(c *gin.Context) flags.Subject {
+	return flags.Subject{
+		UserID:      middleware.UserID(c),
+		AnonymousID: c.GetHeader(HeaderAnonymousID),
+		Role:        middleware.Role(c),
+		Platform:    c.GetHeader(HeaderPlatform),
+		AppVersion:  c.GetHeader(HeaderAppVersion),
+	}
+}
+
+// Evaluate handles GET /flags and returns every flag evaluated for the caller
+func (h *FlagsHandler) Evaluate(c *gin.Context) {
+	results := h.service.EvaluateAll(FlagSubject(c))
+	out := make(map[string]gin.H, len(results))
+	for _, r := range results {
+		out[r.Key] = gin.H{"variant": r.Variant, "value": r.Value}
+	}
+	c.JSON(http.StatusOK, gin.H{"flags": out})
+}
+
+// List handles GET /admin/flags
+func (h *FlagsHandler) List(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"flags": h.service.Flags()})
+}
+
+// Save handles PUT /admin/flags/:key
+func (h *FlagsHandler) Save(c *gin.Context) {
+	if h.store == nil {
+		middleware.RespondError(c, http.StatusConflict, errFlagsReadOnly)
+		return
+	}
+
+	var f flags.Flag
+	if err := c.ShouldBindJSON(&f); err != nil {
+		middleware.RespondError(c, http.StatusBadRequest, errInvalidBody)
+		return
+	}
+	f.Key = c.Param("key")
+
+	if err := h.store.Save(c.Request.Context(), f); err != nil {
+		h.respondError(c, err)
+		return
+	}
+	h.reload(c)
+	c.JSON(http.StatusOK, f)
+}
+
+// Delete handles DELETE /admin/flags/:key
+func (h *FlagsHandler) Delete(c *gin.Context) {
+	if h.store == nil {
+		middleware.RespondError(c, http.StatusConflict, errFlagsReadOnly)
+		return
+	}
+	if err := h.store.Delete(c.Request.Context(), c.Param("key")); err != nil {
+		h.respondError(c, err)
+		return
+	}
+	h.reload(c)
+	c.Status(http.StatusNoContent)
+}
+
+// reload applies a change immediately instead of waiting for the next poll
+func (h *FlagsHandler) reload(c *gin.Context) {
+	if err := h.service.Reload(c.Request.Context()); err != nil {
+		log.Printf("flags: reload after change: %v", err)
+	}
+}
+
+func (h *FlagsHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, flags.ErrInvalidFlag):
+		middleware.RespondError(c, http.StatusBadRequest, err)
+	case errors.Is(err, flags.ErrNotFound):
+		middleware.RespondError(c, http.StatusNotFound, err)
+	default:
+		log.Printf("flags: %v", err)
+		middleware.RespondError(c, http.StatusInternalServerError, middleware.ErrInternal)
+	}
+}
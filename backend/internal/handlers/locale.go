@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/i18n"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/middleware"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/users"
+)
+
+// LocaleHandler manages the language a user wants the API to answer in
+type LocaleHandler struct {
+	users  *users.Repository
+	bundle *i18n.Bundle
+}
+
+// NewLocaleHandler creates a new locale handler
+func NewLocaleHandler(users *users.Repository, bundle *i18n.Bundle) *LocaleHandler {
+	return &LocaleHandler{users: users, bundle: bundle}
+}
+
+type localeSettings struct {
+	Locale string `json:"locale"`
+}
+
+// Get handles GET /settings/locale. locale is the user's choice, "" when
+// Accept-Language decides, and effective the one used for this request.
+func (h *LocaleHandler) Get(c *gin.Context) {
+	locale, err := h.users.Locale(c.Request.Context(), middleware.UserID(c))
+	if err != nil {
+		respondLocaleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"locale":    locale,
+		"effective": middleware.Locale(c),
+		"locales":   h.bundle.Locales(),
+	})
+}
+
+// Update handles PUT /settings/locale; an empty locale clears the choice
+func (h *LocaleHandler) Update(c *gin.Context) {
+	var req localeSettings
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.RespondError(c, http.StatusBadRequest, errInvalidBody)
+		return
+	}
+	req.Locale = strings.ToLower(strings.TrimSpace(req.Locale))
+	if req.Locale != "" && !h.bundle.Supports(req.Locale) {
+		respondLocaleError(c, errUnsupportedLocale)
+		return
+	}
+	if err := h.users.SetLocale(c.Request.Context(), middleware.UserID(c), req.Locale); err != nil {
+		respondLocaleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, req)
+}
+
+func respondLocaleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errUnsupportedLocale):
+		middleware.RespondError(c, http.StatusBadRequest, err)
+	case errors.Is(err, users.ErrNotFound):
+		middleware.RespondError(c, http.StatusNotFound, err)
+	default:
+		log.Printf("i18n: %v", err)
+		middleware.RespondError(c, http.StatusInternalServerError, middleware.ErrInternal)
+	}
+}
@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/mfa"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/middleware"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/users"
+)
+
+// qrSize is the edge length of enrolment QR codes in pixels
+const qrSize = 256
+
+// MFAHandler serves two-factor enrolment, the sign-in step-up and the
+// admin policy
+type MFAHandler struct {
+	auth *AuthHandler
+	mfa  *mfa.Service
+}
+
+// NewMFAHandler creates a new MFA handler; sessions are issued through auth
+func NewMFAHandler(auth *AuthHandler, service *mfa.Service) *MFAHandler {
+	return &MFAHandler{auth: auth, mfa: service}
+}
+
+type mfaCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type mfaChallengeRequest struct {
+	MFAToken   string `json:"mfa_token"`
+	Code       string `json:"code"`
+	DeviceName string `json:"device_name"`
+	Platform   string `json:"platform"`
+}
+
+type mfaPolicyRequest struct {
+	Required bool `json:"required"`
+}
+
+// Challenge handles POST /auth/mfa/challenge, exchanging the MFA token from
+// a sign-in and a code for a session. Users who have to enrol first confirm
+// their enrolment here and receive their recovery codes with the session.
+func (h *MFAHandler) Challenge(c *gin.Context) {
+	var req mfaChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		middleware.RespondError(c, http.StatusBadRequest, errInvalidBody)
+		return
+	}
+	user, ok := h.challengeUser(c, req.MFAToken)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	enrolled, err := h.mfa.Enabled(ctx, user.ID)
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+	var extra gin.H
+	if enrolled {
+		err = h.mfa.Verify(ctx, user.ID, req.Code)
+	} else {
+		var codes []string
+		codes, err = h.mfa.Confirm(ctx, user.ID, req.Code)
+		extra = gin.H{"recovery_codes": codes}
+	}
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+	h.auth.signIn(c, http.StatusOK, user, req.DeviceName, req.Platform, extra)
+}
+
+// ChallengeEnroll handles POST /auth/mfa/challenge/enroll for users whose
+// role requires MFA but who have not set it up yet
+func (h *MFAHandler) ChallengeEnroll(c *gin.Context) {
+	var req mfaChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.RespondError(c, http.StatusBadRequest, errInvalidBody)
+		return
+	}
+	user, ok := h.challengeUser(c, req.MFAToken)
+	if !ok {
+		return
+	}
+	h.enroll(c, user)
+}
+
+// challengeUser resolves the user an MFA token was issued to, responding
+// with 401 when the token is invalid
+func (h *MFAHandler) challengeUser(c *gin.Context, token string) (*users.User, bool) {
+	userID, err := h.auth.tokens.ParseChallenge(token)
+	if err != nil {
+		middleware.RespondError(c, http.StatusUnauthorized, errInvalidMFAToken)
+		return nil, false
+	}
+	user, err := h.auth.users.GetByID(c.Request.Context(), userID)
+	if err != nil || user.DeletedAt != nil {
+		middleware.RespondError(c, http.StatusUnauthorized, errInvalidMFAToken)
+		return nil, false
+	}
+	return user, true
+}
+
+// Status handles GET /mfa
+func (h *MFAHandler) Status(c *gin.Context) {
+	status, err := h.mfa.Status(c.Request.Context(), middleware.UserID(c), middleware.Role(c))
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// Enroll handles POST /mfa/enroll and returns the new secret, its
+// provisioning URI and a QR code PNG
+func (h *MFAHandler) Enroll(c *gin.Context) {
+	user, err := h.auth.users.GetByID(c.Request.Context(), middleware.UserID(c))
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+	h.enroll(c, user)
+}
+
+func (h *MFAHandler) enroll(c *gin.Context, user *users.User) {
+	enrollment, err := h.mfa.Enroll(c.Request.Context(), user.ID, user.Email)
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+	png, err := mfa.QRCode(enrollment.URI, qrSize)
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+	// []byte is encoded as base64
+	c.JSON(http.StatusOK, gin.H{"secret": enrollment.Secret, "uri": enrollment.URI, "qr_png": png})
+}
+
+// QRCode handles GET /mfa/qr.png, rendering the pending enrolment
+func (h *MFAHandler) QRCode(c *gin.Context) {
+	user, err := h.auth.users.GetByID(c.Request.Context(), middleware.UserID(c))
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+	enrollment, err := h.mfa.Pending(c.Request.Context(), user.ID, user.Email)
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+	png, err := mfa.QRCode(enrollment.URI, qrSize)
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "image/png", png)
+}
+
+// Confirm handles POST /mfa/confirm and returns the recovery codes
+func (h *MFAHandler) Confirm(c *gin.Context) {
+	var req mfaCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		middleware.RespondError(c, http.StatusBadRequest, errInvalidBody)
+		return
+	}
+	codes, err := h.mfa.Confirm(c.Request.Context(), middleware.UserID(c), req.Code)
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// RegenerateRecoveryCodes handles POST /mfa/recovery-codes
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req mfaCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		middleware.RespondError(c, http.StatusBadRequest, errInvalidBody)
+		return
+	}
+	codes, err := h.mfa.RegenerateRecoveryCodes(c.Request.Context(), middleware.UserID(c), req.Code)
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// Disable handles POST /mfa/disable
+func (h *MFAHandler) Disable(c *gin.Context) {
+	var req mfaCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		middleware.RespondError(c, http.StatusBadRequest, errInvalidBody)
+		return
+	}
+	if err := h.mfa.Disable(c.Request.Context(), middleware.UserID(c), middleware.Role(c), req.Code); err != nil {
+		respondMFAError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RequiredRoles handles GET /admin/mfa/roles
+func (h *MFAHandler) RequiredRoles(c *gin.Context) {
+	roles, err := h.mfa.RequiredRoles(c.Request.Context())
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"roles": roles})
+}
+
+// SetRequired handles PUT /admin/mfa/roles/:role
+func (h *MFAHandler) SetRequired(c *gin.Context) {
+	var req mfaPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.RespondError(c, http.StatusBadRequest, errInvalidBody)
+		return
+	}
+	if err := h.mfa.SetRequired(c.Request.Context(), c.Param("role"), req.Required); err != nil {
+		respondMFAError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"role": c.Param("role"), "required": req.Required})
+}
+
+func respondMFAError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, mfa.ErrInvalidCode), errors.Is(err, mfa.ErrCodeReused):
+		middleware.RespondError(c, http.StatusUnauthorized, mfa.ErrInvalidCode)
+	case errors.Is(err, mfa.ErrTooManyAttempts):
+		middleware.RespondError(c, http.StatusTooManyRequests, err)
+	case errors.Is(err, mfa.ErrNotEnrolled), errors.Is(err, mfa.ErrInvalidRole):
+		middleware.RespondError(c, http.StatusBadRequest, err)
+	case errors.Is(err, mfa.ErrAlreadyEnabled):
+		middleware.RespondError(c, http.StatusConflict, err)
+	case errors.Is(err, mfa.ErrRequired):
+		middleware.RespondError(c, http.StatusForbidden, err)
+	default:
+		log.Printf("mfa: %v", err)
+		middleware.RespondError(c, http.StatusInternalServerError, middleware.ErrInternal)
+	}
+}
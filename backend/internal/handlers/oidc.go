@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/middleware"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/oidc"
+)
+
+// OIDCHandler serves social login through OpenID Connect providers
+type OIDCHandler struct {
+	auth *AuthHandler
+	oidc *oidc.Service
+}
+
+// NewOIDCHandler creates a new social login handler; sessions are issued
+// through auth
+func NewOIDCHandler(auth *AuthHandler, service *oidc.Service) *OIDCHandler {
+	return &OIDCHandler{auth: auth, oidc: service}
+}
+
+// oidcCallbackRequest is read from the query when the provider redirects to
+// the API, or from a JSON body when the app captures the redirect itself
+type oidcCallbackRequest struct {
+	Code       string `form:"code" json:"code"`
+	State      string `form:"state" json:"state"`
+	Error      string `form:"error" json:"error"`
+	DeviceName string `form:"device_name" json:"device_name"`
+	Platform   string `form:"platform" json:"platform"`
+}
+
+// Providers handles GET /auth/oidc/providers
+func (h *OIDCHandler) Providers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": h.oidc.Providers()})
+}
+
+// Start handles GET /auth/oidc/:provider/start
+func (h *OIDCHandler) Start(c *gin.Context) {
+	h.start(c, 0)
+}
+
+// Link handles POST /auth/oidc/:provider/link, starting a login that links
+// the provider account to the signed-in user
+func (h *OIDCHandler) Link(c *gin.Context) {
+	h.start(c, middleware.UserID(c))
+}
+
+func (h *OIDCHandler) start(c *gin.Context, linkUserID int64) {
+	authURL, state, err := h.oidc.Start(c.Request.Context(), c.Param("provider"), linkUserID)
+	if err != nil {
+		respondOIDCError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"authorization_url": authURL, "state": state})
+}
+
+// Callback handles GET and POST /auth/oidc/:provider/callback and signs the
+// user in, subject to the same MFA step-up as password logins
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	var req oidcCallbackRequest
+	if err := c.ShouldBind(&req); err != nil {
+		middleware.RespondError(c, http.StatusBadRequest, errInvalidBody)
+		return
+	}
+	if req.Error != "" {
+		middleware.RespondError(c, http.StatusUnauthorized, fmt.Errorf("%w: %s", errLoginDenied, req.Error))
+		return
+	}
+	if req.Code == "" || req.State == "" {
+		middleware.RespondError(c, http.StatusBadRequest, errMissingCode)
+		return
+	}
+	user, err := h.oidc.Finish(c.Request.Context(), c.Param("provider"), req.State, req.Code)
+	if err != nil {
+		respondOIDCError(c, err)
+		return
+	}
+	h.auth.completeSignIn(c, http.StatusOK, user, req.DeviceName, req.Platform)
+}
+
+// Identities handles GET /auth/identities
+func (h *OIDCHandler) Identities(c *gin.Context) {
+	list, err := h.oidc.Identities(c.Request.Context(), middleware.UserID(c))
+	if err != nil {
+		respondOIDCError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"identities": list})
+}
+
+// Unlink handles DELETE /auth/identities/:provider
+func (h *OIDCHandler) Unlink(c *gin.Context) {
+	if err := h.oidc.Unlink(c.Request.Context(), middleware.UserID(c), c.Param("provider")); err != nil {
+		respondOIDCError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func respondOIDCError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, oidc.ErrUnknownProvider), errors.Is(err, oidc.ErrIdentityNotFound):
+		middleware.RespondError(c, http.StatusNotFound, err)
+	case errors.Is(err, oidc.ErrInvalidState):
+		middleware.RespondError(c, http.StatusBadRequest, err)
+	case errors.Is(err, oidc.ErrExchangeFailed), errors.Is(err, oidc.ErrInvalidIDToken),
+		errors.Is(err, oidc.ErrAccountDeleted):
+		log.Printf("oidc: %v", err)
+		middleware.RespondError(c, http.StatusUnauthorized, errSignInFailed)
+	case errors.Is(err, oidc.ErrEmailNotVerified):
+		middleware.RespondError(c, http.StatusForbidden, err)
+	case errors.Is(err, oidc.ErrIdentityTaken), errors.Is(err, oidc.ErrLastSignInMethod),
+		errors.Is(err, oidc.ErrLinkRequired):
+		middleware.RespondError(c, http.StatusConflict, err)
+	case errors.Is(err, oidc.ErrDiscovery):
+		log.Printf("oidc: %v", err)
+		middleware.RespondError(c, http.StatusBadGateway, oidc.ErrDiscovery)
+	default:
+		log.Printf("oidc: %v", err)
+		middleware.RespondError(c, http.StatusInternalServerError, middleware.ErrInternal)
+	}
+}
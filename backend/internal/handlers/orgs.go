@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/middleware"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/orgs"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/users"
+)
+
+// OrgsHandler serves organization, membership and invitation endpoints
+type OrgsHandler struct {
+	service *orgs.Service
+	users   *users.Repository
+}
+
+// NewOrgsHandler creates a new organizations handler
+func NewOrgsHandler(service *orgs.Service, users *users.Repository) *OrgsHandler {
+	return &OrgsHandler{service: service, users: users}
+}
+
+type createOrgRequest struct {
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+type inviteRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+type acceptInvitationRequest struct {
+	Token string `json:"token"`
+}
+
+type updateRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// Create handles POST /orgs
+func (h *OrgsHandler) Create(c *gin.Context) {
+	var req createOrgRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.RespondError(c, http.StatusBadRequest, errInvalidBody)
+		return
+	}
+	org, err := h.service.Create(c.Request.Context(), req.Name, req.Slug, middleware.UserID(c))
+	if err != nil {
+		respondOrgsError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, org)
+}
+
+// List handles GET /orgs and returns the caller's organizations
+func (h *OrgsHandler) List(c *gin.Context) {
+	list, err := h.service.ListForUser(c.Request.Context(), middleware.UserID(c))
+	if err != nil {
+		respondOrgsError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"organizations": list})
+}
+
+// AcceptInvitation handles POST /invitations/accept. The token from the
+// invitation email is the proof of being invited; see
+// orgs.Service.AcceptInvitation.
+func (h *OrgsHandler) AcceptInvitation(c *gin.Context) {
+	var req acceptInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Token == "" {
+		middleware.RespondError(c, http.StatusBadRequest, errInvalidBody)
+		return
+	}
+	user, err := h.users.GetByID(c.Request.Context(), middleware.UserID(c))
+	if err != nil {
+		respondOrgsError(c, err)
+		return
+	}
+	membership, err := h.service.AcceptInvitation(c.Request.Context(), req.Token, user.ID, user.Email)
+	if err != nil {
+		respondOrgsError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, membership)
+}
+
+// Current handles GET /org and describes the tenant selected for the request
+func (h *OrgsHandler) Current(c *gin.Context) {
+	t, _ := middleware.CurrentTenant(c)
+	org, err := h.service.GetByID(c.Request.Context(), t.OrgID)
+	if err != nil {
+		respondOrgsError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"organization": org, "role": t.Role})
+}
+
+// ListMembers handles GET /org/members
+func (h *OrgsHandler) ListMembers(c *gin.Context) {
+	t, _ := middleware.CurrentTenant(c)
+	members, err := h.service.ListMembers(c.Request.Context(), t.OrgID)
+	if err != nil {
+		respondOrgsError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"members": members})
+}
+
+// Invite handles POST /org/invitations. The response carries the token to
+// deliver to the invitee.
+func (h *OrgsHandler) Invite(c *gin.Context) {
+	var req inviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Email == "" {
+		middleware.RespondError(c, http.StatusBadRequest, errInvalidBody)
+		return
+	}
+	if req.Role == "" {
+		req.Role = orgs.RoleMember
+	}
+
+	t, _ := middleware.CurrentTenant(c)
+	inv, token, err := h.service.Invite(c.Request.Context(), t.OrgID, middleware.UserID(c), req.Email, req.Role)
+	if err != nil {
+		respondOrgsError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"invitation": inv, "token": token})
+}
+
+// UpdateMemberRole handles PUT /org/members/:userID
+func (h *OrgsHandler) UpdateMemberRole(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("userID"), 10, 64)
+	if err != nil {
+		middleware.RespondError(c, http.StatusBadRequest, errInvalidUserID)
+		return
+	}
+	var req updateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.RespondError(c, http.StatusBadRequest, errInvalidBody)
+		return
+	}
+
+	t, _ := middleware.CurrentTenant(c)
+	if err := h.service.UpdateRole(c.Request.Context(), t.OrgID, middleware.UserID(c), userID, req.Role); err != nil {
+		respondOrgsError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RemoveMember handles DELETE /org/members/:userID
+func (h *OrgsHandler) RemoveMember(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("userID"), 10, 64)
+	if err != nil {
+		middleware.RespondError(c, http.StatusBadRequest, errInvalidUserID)
+		return
+	}
+
+	t, _ := middleware.CurrentTenant(c)
+	if err := h.service.RemoveMember(c.Request.Context(), t.OrgID, middleware.UserID(c), userID); err != nil {
+		respondOrgsError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func respondOrgsError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, orgs.ErrNotFound), errors.Is(err, orgs.ErrNotMember), errors.Is(err, users.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, orgs.ErrForbidden), errors.Is(err, orgs.ErrEmailMismatch):
+		status = http.StatusForbidden
+	case errors.Is(err, orgs.ErrSlugTaken), errors.Is(err, orgs.ErrAlreadyMember), errors.Is(err, orgs.ErrLastOwner):
+		status = http.StatusConflict
+	case errors.Is(err, orgs.ErrInvalidName), errors.Is(err, orgs.ErrInvalidSlug),
+		errors.Is(err, orgs.ErrInvalidRole), errors.Is(err, orgs.ErrInvitationInvalid):
+		status = http.StatusBadRequest
+	default:
+		log.Printf("orgs: %v", err)
+		middleware.RespondError(c, status, middleware.ErrInternal)
+		return
+	}
+	middleware.RespondError(c, status, err)
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/middleware"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/privacy"
+)
+
+// PrivacyHandler serves the data export and account deletion endpoints
+type PrivacyHandler struct {
+	service *privacy.Service
+}
+
+// NewPrivacyHandler creates a new privacy handler
+func NewPrivacyHandler(service *privacy.Service) *PrivacyHandler {
+	return &PrivacyHandler{service: service}
+}
+
+type exportResponse struct {
+	*privacy.ExportJob
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+type deletionRequest struct {
+	Mode privacy.DeleteMode `json:"mode"`
+}
+
+// RequestExport handles POST /privacy/exports. The response carries the
+// download link, which works once the export is ready and is not shown
+// again: only a hash of its token is stored.
+func (h *PrivacyHandler) RequestExport(c *gin.Context) {
+	job, err := h.service.RequestExport(c.Request.Context(), middleware.UserID(c))
+	if err != nil {
+		respondPrivacyError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, exportResponse{
+		ExportJob:   job,
+		DownloadURL: fmt.Sprintf("%s/%d/download?token=%s", c.Request.URL.Path, job.ID, job.Token),
+	})
+}
+
+// GetExport handles GET /privacy/exports/:id
+func (h *PrivacyHandler) GetExport(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		middleware.RespondError(c, http.StatusBadRequest, errInvalidExportID)
+		return
+	}
+	job, err := h.service.GetExport(c.Request.Context(), middleware.UserID(c), id)
+	if err != nil {
+		respondPrivacyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// DownloadExport handles GET /privacy/exports/:id/download. The token in the
+// query string authorises the download, so the link works outside the app.
+func (h *PrivacyHandler) DownloadExport(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		middleware.RespondError(c, http.StatusBadRequest, errInvalidExportID)
+		return
+	}
+	archive, err := h.service.Download(c.Request.Context(), id, c.Query("token"))
+	if err != nil {
+		respondPrivacyError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="export-%d.zip"`, id))
+	c.Data(http.StatusOK, "application/zip", archive)
+}
+
+// RequestDeletion handles POST /privacy/deletion
+func (h *PrivacyHandler) RequestDeletion(c *gin.Context) {
+	var req deletionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.RespondError(c, http.StatusBadRequest, errInvalidBody)
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = privacy.DeleteHard
+	}
+
+	deletion, err := h.service.RequestDeletion(c.Request.Context(), middleware.UserID(c), req.Mode)
+	if err != nil {
+		respondPrivacyError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, deletion)
+}
+
+// GetDeletion handles GET /privacy/deletion
+func (h *PrivacyHandler) GetDeletion(c *gin.Context) {
+	deletion, err := h.service.ActiveDeletion(c.Request.Context(), middleware.UserID(c))
+	if err != nil {
+		respondPrivacyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, deletion)
+}
+
+// CancelDeletion handles DELETE /privacy/deletion
+func (h *PrivacyHandler) CancelDeletion(c *gin.Context) {
+	if err := h.service.CancelDeletion(c.Request.Context(), middleware.UserID(c)); err != nil {
+		respondPrivacyError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// AuditTrail handles GET /privacy/audit
+func (h *PrivacyHandler) AuditTrail(c *gin.Context) {
+	entries, err := h.service.AuditTrail(c.Request.Context(), middleware.UserID(c))
+	if err != nil {
+		respondPrivacyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"entries": entries})
+}
+
+func respondPrivacyError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, privacy.ErrExportNotFound), errors.Is(err, privacy.ErrNoDeletionActive):
+		status = http.StatusNotFound
+	case errors.Is(err, privacy.ErrInvalidToken):
+		status = http.StatusForbidden
+	case errors.Is(err, privacy.ErrExportExpired):
+		status = http.StatusGone
+	case errors.Is(err, privacy.ErrExportNotReady), errors.Is(err, privacy.ErrDeletionPending):
+		status = http.StatusConflict
+	case errors.Is(err, privacy.ErrInvalidMode):
+		status = http.StatusBadRequest
+	default:
+		log.Printf("privacy: %v", err)
+		middleware.RespondError(c, status, middleware.ErrInternal)
+		return
+	}
+	middleware.RespondError(c, status, err)
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/middleware"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/pagination"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/search"
+)
+
+// SearchHandler serves full-text search and the per-user search settings
+type SearchHandler struct {
+	service *search.Service
+}
+
+// NewSearchHandler creates a new search handler
+func NewSearchHandler(service *search.Service) *SearchHandler {
+	return &SearchHandler{service: service}
+}
+
+type searchSettings struct {
+	Language string `json:"language"`
+}
+
+// Search handles GET /search?q=...&limit=...&cursor=...
+func (h *SearchHandler) Search(c *gin.Context) {
+	limit := search.DefaultLimit
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			middleware.RespondError(c, http.StatusBadRequest, pagination.ErrInvalidLimit)
+			return
+		}
+		limit = n
+	}
+
+	page, err := h.service.Search(c.Request.Context(), middleware.UserID(c), c.Query("q"), limit, c.Query("cursor"))
+	if err != nil {
+		respondSearchError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// GetSettings handles GET /search/settings
+func (h *SearchHandler) GetSettings(c *gin.Context) {
+	lang, err := h.service.Language(c.Request.Context(), middleware.UserID(c))
+	if err != nil {
+		respondSearchError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"language": lang, "languages": search.Languages})
+}
+
+// UpdateSettings handles PUT /search/settings
+func (h *SearchHandler) UpdateSettings(c *gin.Context) {
+	var req searchSettings
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.RespondError(c, http.StatusBadRequest, errInvalidBody)
+		return
+	}
+	if err := h.service.SetLanguage(c.Request.Context(), middleware.UserID(c), req.Language); err != nil {
+		respondSearchError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, req)
+}
+
+func respondSearchError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, search.ErrEmptyQuery), errors.Is(err, search.ErrInvalidCursor), errors.Is(err, search.ErrInvalidLanguage):
+		middleware.RespondError(c, http.StatusBadRequest, err)
+	default:
+		log.Printf("search: %v", err)
+		middleware.RespondError(c, http.StatusInternalServerError, middleware.ErrInternal)
+	}
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/middleware"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/orgs"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/webhooks"
+)
+
+// WebhooksHandler serves webhook subscriptions. Mounted behind the Tenant
+// middleware it manages the organization's webhooks, otherwise the caller's
+// personal ones.
+type WebhooksHandler struct {
+	service *webhooks.Service
+}
+
+// NewWebhooksHandler creates a new webhooks handler
+func NewWebhooksHandler(service *webhooks.Service) *WebhooksHandler {
+	return &WebhooksHandler{service: service}
+}
+
+type createWebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+type updateWebhookRequest struct {
+	URL    *string  `json:"url"`
+	Events []string `json:"events"`
+	Active *bool    `json:"active"`
+}
+
+// List handles GET /webhooks
+func (h *WebhooksHandler) List(c *gin.Context) {
+	scope, ok := webhookScope(c)
+	if !ok {
+		return
+	}
+	list, err := h.service.List(c.Request.Context(), scope)
+	if err != nil {
+		respondWebhooksError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"webhooks": list})
+}
+
+// Create handles POST /webhooks. The signing secret is only returned here.
+func (h *WebhooksHandler) Create(c *gin.Context) {
+	scope, ok := webhookScope(c)
+	if !ok {
+		return
+	}
+	var req createWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.RespondError(c, http.StatusBadRequest, errInvalidBody)
+		return
+	}
+	if len(req.Events) == 0 {
+		req.Events = []string{webhooks.EventAll}
+	}
+	w, err := h.service.Create(c.Request.Context(), scope, req.URL, req.Events)
+	if err != nil {
+		respondWebhooksError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"webhook": w, "secret": w.Secret})
+}
+
+// Get handles GET /webhooks/:id
+func (h *WebhooksHandler) Get(c *gin.Context) {
+	scope, id, ok := webhookTarget(c)
+	if !ok {
+		return
+	}
+	w, err := h.service.Get(c.Request.Context(), scope, id)
+	if err != nil {
+		respondWebhooksError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, w)
+}
+
+// Update handles PATCH /webhooks/:id
+func (h *WebhooksHandler) Update(c *gin.Context) {
+	scope, id, ok := webhookTarget(c)
+	if !ok {
+		return
+	}
+	var req updateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.RespondError(c, http.StatusBadRequest, errInvalidBody)
+		return
+	}
+	w, err := h.service.Update(c.Request.Context(), scope, id, webhooks.Changes{
+		URL:    req.URL,
+		Events: req.Events,
+		Active: req.Active,
+	})
+	if err != nil {
+		respondWebhooksError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, w)
+}
+
+// Delete handles DELETE /webhooks/:id
+func (h *WebhooksHandler) Delete(c *gin.Context) {
+	scope, id, ok := webhookTarget(c)
+	if !ok {
+		return
+	}
+	if err := h.service.Delete(c.Request.Context(), scope, id); err != nil {
+		respondWebhooksError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Deliveries handles GET /webhooks/:id/deliveries
+func (h *WebhooksHandler) Deliveries(c *gin.Context) {
+	scope, id, ok := webhookTarget(c)
+	if !ok {
+		return
+	}
+	list, err := h.service.Deliveries(c.Request.Context(), scope, id)
+	if err != nil {
+		respondWebhooksError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deliveries": list})
+}
+
+// Redeliver handles POST /webhooks/:id/deliveries/:deliveryID/redeliver
+func (h *WebhooksHandler) Redeliver(c *gin.Context) {
+	scope, id, ok := webhookTarget(c)
+	if !ok {
+		return
+	}
+	deliveryID, err := strconv.ParseInt(c.Param("deliveryID"), 10, 64)
+	if err != nil {
+		middleware.RespondError(c, http.StatusBadRequest, errInvalidDeliveryID)
+		return
+	}
+	d, err := h.service.Redeliver(c.Request.Context(), scope, id, deliveryID)
+	if err != nil {
+		respondWebhooksError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, d)
+}
+
+// webhookScope picks the organization's webhooks when a tenant is selected,
+// which only its owners and admins may manage
+func webhookScope(c *gin.Context) (webhooks.Scope, bool) {
+	scope := webhooks.Scope{UserID: middleware.UserID(c)}
+	if t, ok := middleware.CurrentTenant(c); ok {
+		if !orgs.CanManage(t.Role) {
+			middleware.RespondError(c, http.StatusForbidden, orgs.ErrForbidden)
+			return scope, false
+		}
+		scope.OrgID = t.OrgID
+	}
+	return scope, true
+}
+
+func webhookTarget(c *gin.Context) (webhooks.Scope, int64, bool) {
+	scope, ok := webhookScope(c)
+	if !ok {
+		return scope, 0, false
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		middleware.RespondError(c, http.StatusBadRequest, errInvalidWebhookID)
+		return scope, 0, false
+	}
+	return scope, id, true
+}
+
+func respondWebhooksError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, webhooks.ErrNotFound), errors.Is(err, webhooks.ErrDeliveryNotFound):
+		middleware.RespondError(c, http.StatusNotFound, err)
+	case errors.Is(err, webhooks.ErrInvalidURL), errors.Is(err, webhooks.ErrForbiddenHost),
+		errors.Is(err, webhooks.ErrInvalidEvent):
+		middleware.RespondError(c, http.StatusBadRequest, err)
+	default:
+		log.Printf("webhooks: %v", err)
+		middleware.RespondError(c, http.StatusInternalServerError, middleware.ErrInternal)
+	}
+}
@@ -0,0 +1,232 @@
+package httpserver
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/middleware"
+)
+
+// writeCert writes a self-signed certificate for localhost and 127.0.0.1
+// with the common name to cert.pem and key.pem in dir, stamped with modTime
+func writeCert(t *testing.T, dir, commonName string, modTime time.Time) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPath, keyPath := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	os.Chtimes(certPath, modTime, modTime)
+	os.Chtimes(keyPath, modTime, modTime)
+
+	cert, _ := x509.ParseCertificate(der)
+	return cert
+}
+
+// serve starts an HTTPS server with cfg, answering with the protocol used
+// and HSTS, and returns its URL
+func serve(t *testing.T, cfg *tls.Config) string {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.HSTS(24*time.Hour, true))
+	router.GET("/", func(c *gin.Context) { c.String(http.StatusOK, c.Request.Proto) })
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: router, TLSConfig: cfg}
+	go srv.ServeTLS(ln, "", "")
+	t.Cleanup(func() { srv.Close() })
+	return "https://" + ln.Addr().String() + "/"
+}
+
+// client trusts the certificates
+func client(certs ...*x509.Certificate) *http.Client {
+	pool := x509.NewCertPool()
+	for _, c := range certs {
+		pool.AddCert(c)
+	}
+	return &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: pool},
+		ForceAttemptHTTP2: true,
+	}}
+}
+
+func TestServesHTTP2AndReloadsCertificates(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Now().Add(-time.Minute)
+	first := writeCert(t, dir, "first", start)
+
+	certs, err := NewCertReloader(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"))
+	if err != nil {
+		t.Fatalf("NewCertReloader failed: %v", err)
+	}
+	cfg, err := TLSConfig(certs, TLSOptions{})
+	if err != nil {
+		t.Fatalf("TLSConfig failed: %v", err)
+	}
+	url := serve(t, cfg)
+
+	before := client(first)
+	resp, err := before.Get(url)
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.ProtoMajor != 2 {
+		t.Errorf("Expected HTTP/2, got %s", resp.Proto)
+	}
+	if got := resp.Header.Get("Strict-Transport-Security"); got != "max-age=86400; includeSubDomains" {
+		t.Errorf("Unexpected HSTS header %q", got)
+	}
+
+	if reloaded, err := certs.Reload(); reloaded || err != nil {
+		t.Errorf("Expected no reload of unchanged files, got %v, %v", reloaded, err)
+	}
+
+	// A broken key, e.g. while a renewal is half written, keeps the old
+	// certificate in use
+	os.WriteFile(filepath.Join(dir, "key.pem"), []byte("garbage"), 0o600)
+	os.Chtimes(filepath.Join(dir, "key.pem"), start.Add(time.Second), start.Add(time.Second))
+	if _, err := certs.Reload(); err == nil {
+		t.Errorf("Expected an error for a broken key")
+	}
+	if resp, err := client(first).Get(url); err != nil {
+		t.Errorf("Expected the previous certificate after a failed reload: %v", err)
+	} else {
+		resp.Body.Close()
+	}
+
+	second := writeCert(t, dir, "second", start.Add(2*time.Second))
+	if reloaded, err := certs.Reload(); !reloaded || err != nil {
+		t.Fatalf("Expected a reload, got %v, %v", reloaded, err)
+	}
+
+	// The open connection keeps working; new connections get the new
+	// certificate
+	resp, err = before.Get(url)
+	if err != nil {
+		t.Fatalf("Expected the existing connection to survive the reload: %v", err)
+	}
+	resp.Body.Close()
+	if cn := resp.TLS.PeerCertificates[0].Subject.CommonName; cn != "first" {
+		t.Errorf("Expected the existing connection to keep the first certificate, got %q", cn)
+	}
+	resp, err = client(second).Get(url)
+	if err != nil {
+		t.Fatalf("GET with the new certificate failed: %v", err)
+	}
+	resp.Body.Close()
+	if cn := resp.TLS.PeerCertificates[0].Subject.CommonName; cn != "second" {
+		t.Errorf("Expected the second certificate, got %q", cn)
+	}
+}
+
+func TestMinVersion(t *testing.T) {
+	dir := t.TempDir()
+	cert := writeCert(t, dir, "localhost", time.Now())
+	certs, err := NewCertReloader(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := TLSConfig(certs, TLSOptions{MinVersion: "1.3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := serve(t, cfg)
+
+	old := client(cert)
+	old.Transport.(*http.Transport).TLSClientConfig.MaxVersion = tls.VersionTLS12
+	if _, err := old.Get(url); err == nil {
+		t.Errorf("Expected a TLS 1.2 client to be rejected")
+	}
+	resp, err := client(cert).Get(url)
+	if err != nil {
+		t.Fatalf("Expected a TLS 1.3 client to connect: %v", err)
+	}
+	resp.Body.Close()
+	if resp.TLS.Version != tls.VersionTLS13 {
+		t.Errorf("Expected TLS 1.3, got %x", resp.TLS.Version)
+	}
+}
+
+func TestTLSOptionsValidation(t *testing.T) {
+	if _, err := ParseTLSVersion("1.1"); !errors.Is(err, ErrUnknownTLSVersion) {
+		t.Errorf("Expected ErrUnknownTLSVersion for 1.1, got %v", err)
+	}
+	if v, err := ParseTLSVersion("TLS1.3"); err != nil || v != tls.VersionTLS13 {
+		t.Errorf("Expected TLS 1.3, got %x, %v", v, err)
+	}
+	if _, err := ParseCipherSuites([]string{"TLS_RSA_WITH_RC4_128_SHA"}); !errors.Is(err, ErrUnknownCipherSuite) {
+		t.Errorf("Expected insecure suites to be rejected, got %v", err)
+	}
+
+	certs := &CertReloader{}
+	if _, err := TLSConfig(certs, TLSOptions{CipherSuites: []string{"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384"}}); err != ErrHTTP2CipherMissing {
+		t.Errorf("Expected ErrHTTP2CipherMissing, got %v", err)
+	}
+	cfg, err := TLSConfig(certs, TLSOptions{CipherSuites: []string{
+		"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384", "tls_ecdhe_ecdsa_with_aes_128_gcm_sha256",
+	}})
+	if err != nil || len(cfg.CipherSuites) != 2 {
+		t.Errorf("Expected two cipher suites, got %v, %v", cfg, err)
+	}
+}
+
+func TestRedirectHandler(t *testing.T) {
+	tests := []struct {
+		method, url, port string
+		status            int
+		location          string
+	}{
+		{"GET", "http://example.com/entries?page=2", "443", http.StatusMovedPermanently, "https://example.com/entries?page=2"},
+		{"GET", "http://example.com:8080/", "8443", http.StatusMovedPermanently, "https://example.com:8443/"},
+		{"POST", "http://example.com/api/v2/auth/login", "443", http.StatusPermanentRedirect, "https://example.com/api/v2/auth/login"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		RedirectHandler(tt.port).ServeHTTP(w, httptest.NewRequest(tt.method, tt.url, nil))
+		if w.Code != tt.status || w.Header().Get("Location") != tt.location {
+			t.Errorf("%s %s: expected %d to %s, got %d to %s", tt.method, tt.url, tt.status, tt.location, w.Code, w.Header().Get("Location"))
+		}
+	}
+}
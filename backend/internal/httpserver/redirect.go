@@ -0,0 +1,28 @@
+package httpserver
+
+import (
+	"net"
+	"net/http"
+)
+
+// RedirectHandler redirects every request to the same URL over HTTPS on
+// httpsPort. The port is left out of the URL when it is 443.
+func RedirectHandler(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != "" && httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		}
+		target := "https://" + host + r.URL.RequestURI()
+
+		// 308 keeps the method and body of non-GET requests
+		status := http.StatusMovedPermanently
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			status = http.StatusPermanentRedirect
+		}
+		http.Redirect(w, r, target, status)
+	})
+}
@@ -0,0 +1,91 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/tls"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// CertReloader serves a certificate loaded from files and re-reads them
+// when they change. Every TLS handshake asks for the current certificate,
+// so a renewal takes effect for new connections while open ones continue
+// undisturbed.
+type CertReloader struct {
+	certFile, keyFile string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	certMod time.Time
+	keyMod  time.Time
+}
+
+// NewCertReloader loads the certificate and key pair from the PEM files
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	r := &CertReloader{certFile: certFile, keyFile: keyFile}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// Reload re-reads the files if either was modified since the last
+// successful load and reports whether the certificate was replaced. On
+// error, for example while only one of the files has been rewritten, the
+// previous certificate stays in use and the next Reload tries again.
+func (r *CertReloader) Reload() (bool, error) {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return false, err
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.RLock()
+	unchanged := r.cert != nil && certInfo.ModTime().Equal(r.certMod) && keyInfo.ModTime().Equal(r.keyMod)
+	r.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, err
+	}
+	r.mu.Lock()
+	r.cert = &cert
+	r.certMod = certInfo.ModTime()
+	r.keyMod = keyInfo.ModTime()
+	r.mu.Unlock()
+	return true, nil
+}
+
+// Watch checks the files for changes every interval until ctx is done
+func (r *CertReloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := r.Reload()
+			if err != nil {
+				log.Printf("httpserver: certificate reload failed, keeping previous certificate: %v", err)
+			} else if reloaded {
+				log.Printf("httpserver: reloaded certificate from %s", r.certFile)
+			}
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Package httpserver holds what it takes to serve the API directly to the
+// internet without a reverse proxy: TLS with certificates that are reloaded
+// when they change on disk, HTTP/2, and a plain-HTTP listener redirecting
+// to HTTPS.
+package httpserver
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Predefined errors
+var (
+	ErrUnknownTLSVersion  = errors.New("unknown TLS version")
+	ErrUnknownCipherSuite = errors.New("unknown or insecure cipher suite")
+	ErrHTTP2CipherMissing = errors.New("cipher suites must include TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 or TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 for HTTP/2")
+)
+
+// TLSOptions configure the server side of TLS
+type TLSOptions struct {
+	// MinVersion is "1.2" or "1.3"; empty means 1.2
+	MinVersion string
+	// CipherSuites are TLS 1.2 suite names such as
+	// "TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384"; empty means Go's defaults
+	CipherSuites []string
+}
+
+// TLSConfig builds a TLS config serving the certificates of certs, with
+// HTTP/2 preferred over HTTP/1.1
+func TLSConfig(certs *CertReloader, opts TLSOptions) (*tls.Config, error) {
+	minVersion, err := ParseTLSVersion(opts.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	suites, err := ParseCipherSuites(opts.CipherSuites)
+	if err != nil {
+		return nil, err
+	}
+	// The HTTP/2 spec requires these, and net/http refuses to enable HTTP/2
+	// without one of them
+	if len(suites) > 0 && minVersion < tls.VersionTLS13 &&
+		!slices.Contains(suites, tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256) &&
+		!slices.Contains(suites, tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256) {
+		return nil, ErrHTTP2CipherMissing
+	}
+	return &tls.Config{
+		MinVersion:     minVersion,
+		CipherSuites:   suites,
+		NextProtos:     []string{"h2", "http/1.1"},
+		GetCertificate: certs.GetCertificate,
+	}, nil
+}
+
+// ParseTLSVersion maps "1.2" and "1.3" to their protocol constants; empty
+// means 1.2. Older versions are not accepted.
+func ParseTLSVersion(s string) (uint16, error) {
+	switch strings.TrimPrefix(strings.ToLower(strings.TrimSpace(s)), "tls") {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("%w: %q", ErrUnknownTLSVersion, s)
+}
+
+// ParseCipherSuites maps suite names to their IDs. Only suites Go considers
+// secure are accepted.
+func ParseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	known := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := known[strings.ToUpper(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownCipherSuite, name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
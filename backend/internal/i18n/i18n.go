@@ -0,0 +1,214 @@
+// Package i18n translates user-facing messages.
+//
+// Messages live in JSON catalogs, one per locale, keyed by message ID:
+//
+//	{
+//	  "auth.weak_password": {
+//	    "one":   "Password must be at least {count} character",
+//	    "other": "Password must be at least {count} characters"
+//	  },
+//	  "users.not_found": "User not found"
+//	}
+//
+// A message is either a plain string or a set of plural forms selected by
+// the "count" parameter according to the locale's plural rules (see
+// PluralForms). {name} placeholders are replaced by parameters. Missing
+// messages fall back to the default locale and then to the ID itself.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Predefined errors
+var (
+	ErrNoCatalogs      = errors.New("no message catalogs")
+	ErrInvalidCatalog  = errors.New("invalid message catalog")
+	ErrUnknownLanguage = errors.New("no plural rules for language")
+)
+
+//go:embed locales
+var catalogs embed.FS
+
+// Catalogs returns the built-in message catalogs
+func Catalogs() fs.FS {
+	sub, _ := fs.Sub(catalogs, "locales")
+	return sub
+}
+
+// Params are the values substituted into a message's placeholders
+type Params map[string]any
+
+// message is one catalog entry: either text, or plural forms keyed by
+// plural category
+type message struct {
+	text   string
+	plural map[string]string
+}
+
+func (m *message) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &m.text); err == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, &m.plural); err != nil || len(m.plural) == 0 {
+		return errors.New("message must be a string or an object of plural forms")
+	}
+	return nil
+}
+
+// Bundle holds the catalogs of every supported locale
+type Bundle struct {
+	defaultLocale string
+	catalogs      map[string]map[string]message
+}
+
+// NewBundle loads every <locale>.json catalog in fsys. The default locale
+// must be among them.
+func NewBundle(fsys fs.FS, defaultLocale string) (*Bundle, error) {
+	files, err := fs.Glob(fsys, "*.json")
+	if err != nil {
+		return nil, err
+	}
+	b := &Bundle{defaultLocale: defaultLocale, catalogs: make(map[string]map[string]message)}
+	for _, file := range files {
+		locale := strings.ToLower(strings.TrimSuffix(path.Base(file), ".json"))
+		if _, ok := pluralRules[baseLanguage(locale)]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownLanguage, locale)
+		}
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		catalog := make(map[string]message)
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidCatalog, file, err)
+		}
+		b.catalogs[locale] = catalog
+	}
+	if _, ok := b.catalogs[defaultLocale]; !ok {
+		return nil, fmt.Errorf("%w for default locale %q", ErrNoCatalogs, defaultLocale)
+	}
+	return b, nil
+}
+
+// DefaultLocale returns the locale used when nothing better matches
+func (b *Bundle) DefaultLocale() string {
+	return b.defaultLocale
+}
+
+// Locales lists the supported locales
+func (b *Bundle) Locales() []string {
+	locales := make([]string, 0, len(b.catalogs))
+	for locale := range b.catalogs {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Supports reports whether locale has a catalog
+func (b *Bundle) Supports(locale string) bool {
+	_, ok := b.catalogs[locale]
+	return ok
+}
+
+// IDs lists the message IDs of a locale's catalog
+func (b *Bundle) IDs(locale string) []string {
+	ids := make([]string, 0, len(b.catalogs[locale]))
+	for id := range b.catalogs[locale] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Has reports whether locale's catalog defines the message
+func (b *Bundle) Has(locale, id string) bool {
+	_, ok := b.catalogs[locale][id]
+	return ok
+}
+
+// Translate renders a message in locale. Plural messages are selected by
+// params["count"]; unknown placeholders are left as they are.
+func (b *Bundle) Translate(locale, id string, params Params) string {
+	m, ok := b.catalogs[locale][id]
+	if !ok {
+		locale = b.defaultLocale
+		if m, ok = b.catalogs[locale][id]; !ok {
+			return id
+		}
+	}
+	text := m.text
+	if m.plural != nil {
+		// Non-integer counts select Other, which languages with
+		// integer-only forms in their catalogs (ru) may leave out
+		forms := PluralForms(locale)
+		for _, form := range []string{PluralForm(locale, params["count"]), Other, forms[len(forms)-1]} {
+			if text, ok = m.plural[form]; ok {
+				break
+			}
+		}
+	}
+	return substitute(text, params)
+}
+
+var placeholder = regexp.MustCompile(`\{(\w+)\}`)
+
+func substitute(text string, params Params) string {
+	if len(params) == 0 {
+		return text
+	}
+	return placeholder.ReplaceAllStringFunc(text, func(match string) string {
+		if value, ok := params[match[1:len(match)-1]]; ok {
+			return fmt.Sprint(value)
+		}
+		return match
+	})
+}
+
+// placeholders returns the placeholder names used by a message, sorted
+func placeholders(m message) []string {
+	seen := make(map[string]bool)
+	texts := []string{m.text}
+	for _, text := range m.plural {
+		texts = append(texts, text)
+	}
+	for _, text := range texts {
+		for _, match := range placeholder.FindAllStringSubmatch(text, -1) {
+			seen[match[1]] = true
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Message identifies a catalog message together with its parameters
+type Message struct {
+	ID     string
+	Params Params
+}
+
+// Errors maps sentinel errors to the messages describing them to users
+type Errors map[error]Message
+
+// Lookup returns the message of err or of the closest error it wraps
+func (e Errors) Lookup(err error) (Message, bool) {
+	for ; err != nil; err = errors.Unwrap(err) {
+		if m, ok := e[err]; ok {
+			return m, true
+		}
+	}
+	return Message{}, false
+}
@@ -0,0 +1,152 @@
+package i18n
+
+import (
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func newBundle(t *testing.T) *Bundle {
+	t.Helper()
+	b, err := NewBundle(Catalogs(), "en")
+	if err != nil {
+		t.Fatalf("NewBundle failed: %v", err)
+	}
+	return b
+}
+
+// TestCatalogsComplete fails when a message is missing from a catalog, lacks
+// a plural form its language needs or uses different placeholders
+func TestCatalogsComplete(t *testing.T) {
+	b := newBundle(t)
+	if got := b.Locales(); !slices.Equal(got, []string{"en", "ru"}) {
+		t.Fatalf("Expected en and ru catalogs, got %v", got)
+	}
+
+	all := make(map[string]bool)
+	for _, locale := range b.Locales() {
+		for _, id := range b.IDs(locale) {
+			all[id] = true
+		}
+	}
+	for id := range all {
+		reference, ok := b.catalogs[b.defaultLocale][id]
+		if !ok {
+			t.Errorf("Message %q is missing from the default catalog", id)
+			continue
+		}
+		for _, locale := range b.Locales() {
+			m, ok := b.catalogs[locale][id]
+			if !ok {
+				t.Errorf("Message %q is missing from %s", id, locale)
+				continue
+			}
+			if (m.plural == nil) != (reference.plural == nil) {
+				t.Errorf("Message %q is plural in one catalog but not in %s", id, locale)
+			}
+			if m.plural != nil {
+				for _, form := range PluralForms(locale) {
+					if _, ok := m.plural[form]; !ok {
+						t.Errorf("Message %q in %s lacks the %q plural form", id, locale, form)
+					}
+				}
+			}
+			if got, want := placeholders(m), placeholders(reference); !slices.Equal(got, want) {
+				t.Errorf("Message %q in %s uses placeholders %v, expected %v", id, locale, got, want)
+			}
+		}
+	}
+}
+
+func TestPluralForm(t *testing.T) {
+	tests := []struct {
+		locale string
+		count  any
+		want   string
+	}{
+		{"en", 1, One},
+		{"en", 0, Other},
+		{"en", 2, Other},
+		{"en", 1.5, Other},
+		{"ru", 1, One},
+		{"ru", 21, One},
+		{"ru", 11, Many},
+		{"ru", 2, Few},
+		{"ru", 24, Few},
+		{"ru", 12, Many},
+		{"ru", 5, Many},
+		{"ru", 100, Many},
+		{"ru-RU", int64(101), One},
+		{"ru", 2.5, Other},
+		{"de", 1, Other},
+	}
+	for _, tt := range tests {
+		if got := PluralForm(tt.locale, tt.count); got != tt.want {
+			t.Errorf("PluralForm(%s, %v): expected %s, got %s", tt.locale, tt.count, tt.want, got)
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	b, err := NewBundle(fstest.MapFS{
+		"en.json": {Data: []byte(`{"greet": "Hi {name}", "files": {"one": "{count} file", "other": "{count} files"}, "only_en": "English"}`)},
+		"ru.json": {Data: []byte(`{"greet": "Привет, {name}", "files": {"one": "{count} файл", "few": "{count} файла", "many": "{count} файлов"}}`)},
+	}, "en")
+	if err != nil {
+		t.Fatalf("NewBundle failed: %v", err)
+	}
+
+	tests := []struct {
+		locale, id string
+		params     Params
+		want       string
+	}{
+		{"ru", "greet", Params{"name": "Аня"}, "Привет, Аня"},
+		{"en", "greet", nil, "Hi {name}"},
+		{"en", "files", Params{"count": 1}, "1 file"},
+		{"en", "files", Params{"count": 3}, "3 files"},
+		{"ru", "files", Params{"count": 3}, "3 файла"},
+		{"ru", "files", Params{"count": 11}, "11 файлов"},
+		{"ru", "files", Params{"count": 1.5}, "1.5 файлов"},
+		{"ru", "only_en", nil, "English"},
+		{"ru", "missing", nil, "missing"},
+	}
+	for _, tt := range tests {
+		if got := b.Translate(tt.locale, tt.id, tt.params); got != tt.want {
+			t.Errorf("Translate(%s, %s): expected %q, got %q", tt.locale, tt.id, tt.want, got)
+		}
+	}
+}
+
+func TestNewBundleRejectsBadCatalogs(t *testing.T) {
+	if _, err := NewBundle(fstest.MapFS{"en.json": {Data: []byte(`{"x": 1}`)}}, "en"); err == nil {
+		t.Errorf("Expected an error for a non-string message")
+	}
+	if _, err := NewBundle(fstest.MapFS{"xx.json": {Data: []byte(`{}`)}}, "xx"); err == nil {
+		t.Errorf("Expected an error for a language without plural rules")
+	}
+	if _, err := NewBundle(fstest.MapFS{"ru.json": {Data: []byte(`{}`)}}, "en"); err == nil {
+		t.Errorf("Expected an error without a default catalog")
+	}
+}
+
+func TestMatch(t *testing.T) {
+	b := newBundle(t)
+	tests := []struct {
+		header, want string
+	}{
+		{"", "en"},
+		{"ru", "ru"},
+		{"ru-RU,ru;q=0.9,en;q=0.8", "ru"},
+		{"de-DE,de;q=0.9,ru;q=0.5", "ru"},
+		{"en;q=0.3, ru_RU;q=0.7", "ru"},
+		{"ru;q=0, en", "en"},
+		{"fr, *;q=0.1", "en"},
+		{"ru;q=abc", "en"},
+	}
+	for _, tt := range tests {
+		if got := b.Match(tt.header); got != tt.want {
+			t.Errorf("Match(%q): expected %s, got %s", tt.header, tt.want, got)
+		}
+	}
+}
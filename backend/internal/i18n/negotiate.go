@@ -0,0 +1,47 @@
+package i18n
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Match picks the supported locale that best fits an Accept-Language
+// header such as "ru-RU,ru;q=0.9,en;q=0.8". Language ranges are tried by
+// descending quality, each first as given and then by its base language.
+// The default locale is returned when nothing matches.
+func (b *Bundle) Match(acceptLanguage string) string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var ranges []weighted
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if tag = strings.ToLower(strings.ReplaceAll(tag, "_", "-")); tag != "" && q > 0 {
+			ranges = append(ranges, weighted{tag, q})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+
+	for _, r := range ranges {
+		if r.tag == "*" {
+			return b.defaultLocale
+		}
+		if b.Supports(r.tag) {
+			return r.tag
+		}
+		if lang := baseLanguage(r.tag); b.Supports(lang) {
+			return lang
+		}
+	}
+	return b.defaultLocale
+}
@@ -0,0 +1,85 @@
+package i18n
+
+import "strings"
+
+// Plural categories, as defined by CLDR
+const (
+	One   = "one"
+	Few   = "few"
+	Many  = "many"
+	Other = "other"
+)
+
+// pluralRule picks the plural category of a non-negative integer
+type pluralRule struct {
+	forms  []string
+	choose func(n int64) string
+}
+
+// pluralRules are the CLDR cardinal rules for integers, by language
+var pluralRules = map[string]pluralRule{
+	"en": {
+		forms: []string{One, Other},
+		choose: func(n int64) string {
+			if n == 1 {
+				return One
+			}
+			return Other
+		},
+	},
+	"ru": {
+		forms: []string{One, Few, Many},
+		choose: func(n int64) string {
+			switch mod10, mod100 := n%10, n%100; {
+			case mod10 == 1 && mod100 != 11:
+				return One
+			case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+				return Few
+			default:
+				return Many
+			}
+		},
+	},
+}
+
+// PluralForms lists the plural categories a locale's messages must define
+func PluralForms(locale string) []string {
+	return pluralRules[baseLanguage(locale)].forms
+}
+
+// PluralForm returns the plural category of count in locale. Counts that
+// are not integers select Other.
+func PluralForm(locale string, count any) string {
+	rule, ok := pluralRules[baseLanguage(locale)]
+	if !ok {
+		return Other
+	}
+	var n int64
+	switch v := count.(type) {
+	case int:
+		n = int64(v)
+	case int32:
+		n = int64(v)
+	case int64:
+		n = v
+	case uint:
+		n = int64(v)
+	case float64:
+		if v != float64(int64(v)) {
+			return Other
+		}
+		n = int64(v)
+	default:
+		return Other
+	}
+	if n < 0 {
+		n = -n
+	}
+	return rule.choose(n)
+}
+
+// baseLanguage returns the language subtag of a locale, e.g. "ru" for "ru-RU"
+func baseLanguage(locale string) string {
+	lang, _, _ := strings.Cut(strings.ToLower(strings.ReplaceAll(locale, "_", "-")), "-")
+	return lang
+}
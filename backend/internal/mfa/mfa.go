@@ -0,0 +1,421 @@
+// Package mfa provides two-factor authentication with TOTP authenticator
+// apps and single-use recovery codes.
+//
+// Enrolment is two-step: Enroll stores a pending secret and returns the
+// provisioning URI, and Confirm enables it once the user proves their app
+// produces valid codes, handing out a fresh set of recovery codes. Codes
+// are accepted one step either side of the current one, and never twice:
+// the last accepted step is remembered, so a code observed by someone else
+// cannot be replayed. Repeated failures lock verification for a while.
+//
+// Admins can require MFA for all users of a role; such users must enrol
+// before they can finish signing in and cannot disable it.
+package mfa
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+)
+
+// Predefined errors
+var (
+	ErrNotEnrolled     = errors.New("two-factor authentication is not set up")
+	ErrAlreadyEnabled  = errors.New("two-factor authentication is already enabled")
+	ErrInvalidCode     = errors.New("invalid verification code")
+	ErrCodeReused      = errors.New("verification code already used")
+	ErrTooManyAttempts = errors.New("too many failed attempts, try again later")
+	ErrRequired        = errors.New("two-factor authentication is required for your role")
+	ErrInvalidRole     = errors.New("invalid role")
+)
+
+const (
+	// RecoveryCodeCount is how many recovery codes are issued at a time
+	RecoveryCodeCount = 10
+	// maxFailures consecutive wrong codes lock verification for lockout
+	maxFailures = 5
+	lockout     = 5 * time.Minute
+)
+
+// Enrollment is a pending TOTP secret to be added to an authenticator app
+type Enrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// Status describes a user's two-factor setup
+type Status struct {
+	Enabled           bool `json:"enabled"`
+	Pending           bool `json:"pending"`
+	Required          bool `json:"required"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+// Service manages TOTP factors, recovery codes and the per-role policy
+type Service struct {
+	db     *database.DB
+	issuer string
+	now    func() time.Time
+}
+
+// NewService creates an MFA service; issuer is the name authenticator apps
+// show next to the account
+func NewService(db *database.DB, issuer string) *Service {
+	return &Service{db: db, issuer: issuer, now: func() time.Time { return time.Now().UTC() }}
+}
+
+// SetClock replaces the service's time source, for tests that control time
+func (s *Service) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// Enroll starts or restarts TOTP enrolment for the user; account is shown
+// in the authenticator app, usually the email address
+func (s *Service) Enroll(ctx context.Context, userID int64, account string) (*Enrollment, error) {
+	secret, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.db.ExecContext(ctx,
+		`INSERT INTO mfa_totp (user_id, secret, created_at) VALUES ($1, $2, $3)
+		 ON CONFLICT (user_id) DO UPDATE SET secret = excluded.secret, last_used_step = 0,
+		     failures = 0, locked_until = NULL, created_at = excluded.created_at
+		 WHERE mfa_totp.enabled_at IS NULL`,
+		userID, secret, s.now())
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrAlreadyEnabled
+	}
+	return &Enrollment{Secret: secret, URI: provisioningURI(s.issuer, account, secret)}, nil
+}
+
+// Pending returns the user's unconfirmed enrolment
+func (s *Service) Pending(ctx context.Context, userID int64, account string) (*Enrollment, error) {
+	f, err := s.factor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if f.enabled {
+		return nil, ErrAlreadyEnabled
+	}
+	return &Enrollment{Secret: f.secret, URI: provisioningURI(s.issuer, account, f.secret)}, nil
+}
+
+// Confirm enables a pending enrolment given a valid code from the app and
+// returns the user's recovery codes
+func (s *Service) Confirm(ctx context.Context, userID int64, input string) ([]string, error) {
+	f, err := s.factor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if f.enabled {
+		return nil, ErrAlreadyEnabled
+	}
+	if s.locked(f) {
+		return nil, ErrTooManyAttempts
+	}
+	if err := s.checkTOTP(ctx, userID, f, input); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE mfa_totp SET enabled_at = $1 WHERE user_id = $2`, s.now(), userID); err != nil {
+		return nil, err
+	}
+	codes, err := s.replaceRecoveryCodes(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return codes, tx.Commit()
+}
+
+// Verify checks a TOTP code or an unused recovery code for a user with MFA
+// enabled. Recovery codes are consumed.
+func (s *Service) Verify(ctx context.Context, userID int64, input string) error {
+	f, err := s.factor(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !f.enabled {
+		return ErrNotEnrolled
+	}
+	if s.locked(f) {
+		return ErrTooManyAttempts
+	}
+
+	input = strings.TrimSpace(input)
+	if len(strings.ReplaceAll(input, " ", "")) == digits {
+		return s.checkTOTP(ctx, userID, f, input)
+	}
+
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE mfa_recovery_codes SET used_at = $1 WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL`,
+		s.now(), userID, hashRecoveryCode(userID, input))
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return s.fail(ctx, userID, ErrInvalidCode)
+	}
+	return s.succeed(ctx, userID)
+}
+
+// checkTOTP validates a code against the factor and records its step so it
+// cannot be used again
+func (s *Service) checkTOTP(ctx context.Context, userID int64, f *factor, input string) error {
+	st := match(f.secret, input, s.now())
+	if st == 0 {
+		return s.fail(ctx, userID, ErrInvalidCode)
+	}
+	if st <= f.lastUsedStep {
+		return s.fail(ctx, userID, ErrCodeReused)
+	}
+	// The condition makes two concurrent uses of one code race safely
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE mfa_totp SET last_used_step = $1, failures = 0, locked_until = NULL
+		 WHERE user_id = $2 AND last_used_step < $1`,
+		st, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return s.fail(ctx, userID, ErrCodeReused)
+	}
+	return nil
+}
+
+func (s *Service) locked(f *factor) bool {
+	return f.lockedUntil != nil && s.now().Before(*f.lockedUntil)
+}
+
+// fail counts a failed attempt, locking verification after too many, and
+// returns cause
+func (s *Service) fail(ctx context.Context, userID int64, cause error) error {
+	var failures int
+	err := s.db.QueryRowContext(ctx,
+		`UPDATE mfa_totp SET failures = failures + 1 WHERE user_id = $1 RETURNING failures`, userID,
+	).Scan(&failures)
+	if err != nil {
+		return err
+	}
+	if failures >= maxFailures {
+		if _, err := s.db.ExecContext(ctx,
+			`UPDATE mfa_totp SET failures = 0, locked_until = $1 WHERE user_id = $2`,
+			s.now().Add(lockout), userID); err != nil {
+			return err
+		}
+	}
+	return cause
+}
+
+func (s *Service) succeed(ctx context.Context, userID int64) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE mfa_totp SET failures = 0, locked_until = NULL WHERE user_id = $1`, userID)
+	return err
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes after checking
+// a current code
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, userID int64, input string) ([]string, error) {
+	if err := s.Verify(ctx, userID, input); err != nil {
+		return nil, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	codes, err := s.replaceRecoveryCodes(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return codes, tx.Commit()
+}
+
+// Disable turns MFA off after checking a current code, unless the user's
+// role requires it
+func (s *Service) Disable(ctx context.Context, userID int64, role, input string) error {
+	required, err := s.Required(ctx, role)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrRequired
+	}
+	if err := s.Verify(ctx, userID, input); err != nil {
+		return err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := deleteFactors(ctx, tx, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Enabled reports whether the user has confirmed a TOTP factor
+func (s *Service) Enabled(ctx context.Context, userID int64) (bool, error) {
+	f, err := s.factor(ctx, userID)
+	if errors.Is(err, ErrNotEnrolled) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return f.enabled, nil
+}
+
+// Status returns the user's two-factor setup
+func (s *Service) Status(ctx context.Context, userID int64, role string) (*Status, error) {
+	st := &Status{}
+	f, err := s.factor(ctx, userID)
+	switch {
+	case err == nil:
+		st.Enabled, st.Pending = f.enabled, !f.enabled
+	case !errors.Is(err, ErrNotEnrolled):
+		return nil, err
+	}
+	if st.Required, err = s.Required(ctx, role); err != nil {
+		return nil, err
+	}
+	err = s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM mfa_recovery_codes WHERE user_id = $1 AND used_at IS NULL`, userID,
+	).Scan(&st.RecoveryCodesLeft)
+	return st, err
+}
+
+// Required reports whether users of the role must use MFA
+func (s *Service) Required(ctx context.Context, role string) (bool, error) {
+	var n int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM mfa_required_roles WHERE role = $1`, role).Scan(&n)
+	return n > 0, err
+}
+
+// RequiredRoles lists the roles MFA is enforced for
+func (s *Service) RequiredRoles(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT role FROM mfa_required_roles ORDER BY role`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []string{}
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+// SetRequired enforces or stops enforcing MFA for a role
+func (s *Service) SetRequired(ctx context.Context, role string, required bool) error {
+	role = strings.TrimSpace(role)
+	if role == "" {
+		return ErrInvalidRole
+	}
+	var err error
+	if required {
+		_, err = s.db.ExecContext(ctx,
+			`INSERT INTO mfa_required_roles (role, created_at) VALUES ($1, $2) ON CONFLICT (role) DO NOTHING`,
+			role, s.now())
+	} else {
+		_, err = s.db.ExecContext(ctx, `DELETE FROM mfa_required_roles WHERE role = $1`, role)
+	}
+	return err
+}
+
+// factor is a stored TOTP secret
+type factor struct {
+	secret       string
+	lastUsedStep int64
+	enabled      bool
+	lockedUntil  *time.Time
+}
+
+func (s *Service) factor(ctx context.Context, userID int64) (*factor, error) {
+	var (
+		f         factor
+		enabledAt *time.Time
+	)
+	err := s.db.QueryRowContext(ctx,
+		`SELECT secret, last_used_step, enabled_at, locked_until FROM mfa_totp WHERE user_id = $1`, userID,
+	).Scan(&f.secret, &f.lastUsedStep, &enabledAt, &f.lockedUntil)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotEnrolled
+	}
+	if err != nil {
+		return nil, err
+	}
+	f.enabled = enabledAt != nil
+	return &f, nil
+}
+
+func (s *Service) replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID int64) ([]string, error) {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return nil, err
+	}
+	codes := make([]string, RecoveryCodeCount)
+	for i := range codes {
+		c, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = c
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO mfa_recovery_codes (user_id, code_hash, created_at) VALUES ($1, $2, $3)`,
+			userID, hashRecoveryCode(userID, c), s.now()); err != nil {
+			return nil, err
+		}
+	}
+	return codes, nil
+}
+
+func deleteFactors(ctx context.Context, tx *sql.Tx, userID int64) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `DELETE FROM mfa_totp WHERE user_id = $1`, userID)
+	return err
+}
+
+// newRecoveryCode returns a code like "k3vqa-7m2xd" carrying 50 random bits
+func newRecoveryCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	const alphabet = "abcdefghijkmnpqrstuvwxyz23456789"
+	for i := range b {
+		b[i] = alphabet[int(b[i])%len(alphabet)]
+	}
+	return fmt.Sprintf("%s-%s", b[:5], b[5:]), nil
+}
+
+// hashRecoveryCode normalises the code as users tend to retype it and
+// binds the hash to the user
+func hashRecoveryCode(userID int64, input string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(input))
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d:%s", userID, normalized)))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,236 @@
+package mfa
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database/dbtest"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/users"
+)
+
+func TestCodeMatchesRFC6238(t *testing.T) {
+	// RFC 6238 appendix B, SHA-1 with the ASCII key "12345678901234567890",
+	// truncated to six digits
+	secret := b32.EncodeToString([]byte("12345678901234567890"))
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tt := range tests {
+		got, err := code(secret, step(time.Unix(tt.unix, 0)))
+		if err != nil || got != tt.want {
+			t.Errorf("At %d expected %s, got %s, %v", tt.unix, tt.want, got, err)
+		}
+	}
+}
+
+func TestMatchDriftWindow(t *testing.T) {
+	secret, _ := newSecret()
+	now := time.Unix(1_700_000_000, 0)
+	for _, drift := range []int64{-1, 0, 1} {
+		c, _ := code(secret, step(now)+drift)
+		if match(secret, c, now) != step(now)+drift {
+			t.Errorf("Expected code with drift %d to match", drift)
+		}
+	}
+	c, _ := code(secret, step(now)+2)
+	if match(secret, c, now) != 0 {
+		t.Errorf("Expected code two steps ahead to be rejected")
+	}
+}
+
+func TestProvisioningURIAndQRCode(t *testing.T) {
+	uri := provisioningURI("Habit Journal", "ann@example.com", "JBSWY3DPEHPK3PXP")
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "otpauth" || u.Host != "totp" {
+		t.Fatalf("Unexpected URI %q: %v", uri, err)
+	}
+	if u.Path != "/Habit Journal:ann@example.com" || u.Query().Get("secret") != "JBSWY3DPEHPK3PXP" {
+		t.Errorf("Unexpected label or secret in %q", uri)
+	}
+	png, err := QRCode(uri, 256)
+	if err != nil || !bytes.HasPrefix(png, []byte("\x89PNG")) {
+		t.Errorf("Expected a PNG, got %d bytes, %v", len(png), err)
+	}
+}
+
+type fixture struct {
+	service *Service
+	userID  int64
+	clock   time.Time
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	db := dbtest.New(t)
+	user := &users.User{Email: "ann@example.com"}
+	if err := users.NewRepository(db).Create(context.Background(), user); err != nil {
+		t.Fatalf("Create user: %v", err)
+	}
+	f := &fixture{service: NewService(db, "Habit Journal"), userID: user.ID, clock: time.Now().UTC()}
+	f.service.now = func() time.Time { return f.clock }
+	return f
+}
+
+// enable enrols and confirms TOTP and returns the secret and recovery codes
+func (f *fixture) enable(t *testing.T) (string, []string) {
+	t.Helper()
+	ctx := context.Background()
+	enrollment, err := f.service.Enroll(ctx, f.userID, "ann@example.com")
+	if err != nil {
+		t.Fatalf("Enroll failed: %v", err)
+	}
+	codes, err := f.service.Confirm(ctx, f.userID, f.code(enrollment.Secret, 0))
+	if err != nil {
+		t.Fatalf("Confirm failed: %v", err)
+	}
+	return enrollment.Secret, codes
+}
+
+func (f *fixture) code(secret string, drift int64) string {
+	c, _ := code(secret, step(f.clock)+drift)
+	return c
+}
+
+func TestEnrolment(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	if enabled, _ := f.service.Enabled(ctx, f.userID); enabled {
+		t.Fatalf("Expected MFA to be off initially")
+	}
+	first, err := f.service.Enroll(ctx, f.userID, "ann@example.com")
+	if err != nil {
+		t.Fatalf("Enroll failed: %v", err)
+	}
+	if _, err := f.service.Confirm(ctx, f.userID, f.code(first.Secret, 5)); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("Expected ErrInvalidCode, got %v", err)
+	}
+
+	// Restarting enrolment replaces the pending secret
+	second, _ := f.service.Enroll(ctx, f.userID, "ann@example.com")
+	if pending, _ := f.service.Pending(ctx, f.userID, "ann@example.com"); pending.Secret != second.Secret || second.Secret == first.Secret {
+		t.Errorf("Expected the new secret to be pending")
+	}
+
+	codes, err := f.service.Confirm(ctx, f.userID, f.code(second.Secret, 0))
+	if err != nil || len(codes) != RecoveryCodeCount {
+		t.Fatalf("Expected %d recovery codes, got %v, %v", RecoveryCodeCount, codes, err)
+	}
+	if _, err := f.service.Enroll(ctx, f.userID, "ann@example.com"); !errors.Is(err, ErrAlreadyEnabled) {
+		t.Errorf("Expected ErrAlreadyEnabled, got %v", err)
+	}
+	status, err := f.service.Status(ctx, f.userID, users.RoleUser)
+	if err != nil || !status.Enabled || status.Pending || status.RecoveryCodesLeft != RecoveryCodeCount {
+		t.Errorf("Unexpected status %+v, %v", status, err)
+	}
+}
+
+func TestVerifyPreventsReplay(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	secret, _ := f.enable(t)
+
+	// The code used to confirm enrolment cannot be used again
+	if err := f.service.Verify(ctx, f.userID, f.code(secret, 0)); !errors.Is(err, ErrCodeReused) {
+		t.Errorf("Expected ErrCodeReused for the confirmation code, got %v", err)
+	}
+
+	f.clock = f.clock.Add(period * time.Second)
+	c := f.code(secret, 0)
+	if err := f.service.Verify(ctx, f.userID, c[:3]+" "+c[3:]); err != nil {
+		t.Fatalf("Expected spaced code to verify, got %v", err)
+	}
+	if err := f.service.Verify(ctx, f.userID, c); !errors.Is(err, ErrCodeReused) {
+		t.Errorf("Expected ErrCodeReused, got %v", err)
+	}
+	// An older code inside the drift window is rejected too
+	if err := f.service.Verify(ctx, f.userID, f.code(secret, -1)); !errors.Is(err, ErrCodeReused) {
+		t.Errorf("Expected ErrCodeReused for an earlier step, got %v", err)
+	}
+	if err := f.service.Verify(ctx, f.userID, f.code(secret, 1)); err != nil {
+		t.Errorf("Expected a later step within the window to verify, got %v", err)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	secret, codes := f.enable(t)
+
+	if err := f.service.Verify(ctx, f.userID, strings.ToUpper(codes[0])); err != nil {
+		t.Fatalf("Expected recovery code to verify regardless of case, got %v", err)
+	}
+	if err := f.service.Verify(ctx, f.userID, codes[0]); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("Expected used recovery code to be rejected, got %v", err)
+	}
+	if status, _ := f.service.Status(ctx, f.userID, users.RoleUser); status.RecoveryCodesLeft != RecoveryCodeCount-1 {
+		t.Errorf("Expected %d codes left, got %d", RecoveryCodeCount-1, status.RecoveryCodesLeft)
+	}
+
+	f.clock = f.clock.Add(period * time.Second)
+	fresh, err := f.service.RegenerateRecoveryCodes(ctx, f.userID, f.code(secret, 0))
+	if err != nil || len(fresh) != RecoveryCodeCount {
+		t.Fatalf("Regenerate failed: %v, %v", fresh, err)
+	}
+	if err := f.service.Verify(ctx, f.userID, codes[1]); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("Expected old recovery codes to be replaced, got %v", err)
+	}
+}
+
+func TestLockoutAfterFailures(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	secret, _ := f.enable(t)
+
+	for range maxFailures {
+		f.service.Verify(ctx, f.userID, "wrong-code")
+	}
+	f.clock = f.clock.Add(period * time.Second)
+	if err := f.service.Verify(ctx, f.userID, f.code(secret, 0)); !errors.Is(err, ErrTooManyAttempts) {
+		t.Errorf("Expected ErrTooManyAttempts, got %v", err)
+	}
+	f.clock = f.clock.Add(lockout)
+	if err := f.service.Verify(ctx, f.userID, f.code(secret, 0)); err != nil {
+		t.Errorf("Expected verification after the lockout, got %v", err)
+	}
+}
+
+func TestRequiredRoles(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	secret, _ := f.enable(t)
+
+	if err := f.service.SetRequired(ctx, users.RoleAdmin, true); err != nil {
+		t.Fatalf("SetRequired failed: %v", err)
+	}
+	f.service.SetRequired(ctx, users.RoleAdmin, true)
+	if roles, _ := f.service.RequiredRoles(ctx); len(roles) != 1 || roles[0] != users.RoleAdmin {
+		t.Errorf("Expected [admin], got %v", roles)
+	}
+	if err := f.service.Disable(ctx, f.userID, users.RoleAdmin, f.code(secret, 1)); !errors.Is(err, ErrRequired) {
+		t.Errorf("Expected ErrRequired, got %v", err)
+	}
+
+	if err := f.service.Disable(ctx, f.userID, users.RoleUser, f.code(secret, 1)); err != nil {
+		t.Fatalf("Disable failed: %v", err)
+	}
+	if enabled, _ := f.service.Enabled(ctx, f.userID); enabled {
+		t.Errorf("Expected MFA to be off after disabling")
+	}
+
+	f.service.SetRequired(ctx, users.RoleAdmin, false)
+	if required, _ := f.service.Required(ctx, users.RoleAdmin); required {
+		t.Errorf("Expected admin role to no longer require MFA")
+	}
+}
@@ -0,0 +1,66 @@
+package mfa
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/privacy"
+)
+
+// PrivacyModule exports and erases a user's second factors. Secrets and
+// code hashes are never exported.
+type PrivacyModule struct {
+	db *database.DB
+}
+
+// NewPrivacyModule creates the mfa privacy module
+func NewPrivacyModule(db *database.DB) *PrivacyModule {
+	return &PrivacyModule{db: db}
+}
+
+// Name implements privacy.Module
+func (m *PrivacyModule) Name() string {
+	return "mfa"
+}
+
+// Export implements privacy.Module
+func (m *PrivacyModule) Export(ctx context.Context, userID int64) ([]privacy.Dataset, error) {
+	ds := privacy.Dataset{
+		Name:    "mfa",
+		Columns: []string{"method", "created_at", "enabled_at", "used_at"},
+	}
+	rows, err := m.db.QueryContext(ctx,
+		`SELECT 'totp', created_at, enabled_at, NULL FROM mfa_totp WHERE user_id = $1
+		 UNION ALL
+		 SELECT 'recovery_code', created_at, NULL, used_at FROM mfa_recovery_codes WHERE user_id = $1`,
+		userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			method            string
+			createdAt         sql.NullTime
+			enabledAt, usedAt sql.NullTime
+		)
+		if err := rows.Scan(&method, &createdAt, &enabledAt, &usedAt); err != nil {
+			return nil, err
+		}
+		ds.Rows = append(ds.Rows, []any{method, nullTime(createdAt), nullTime(enabledAt), nullTime(usedAt)})
+	}
+	return []privacy.Dataset{ds}, rows.Err()
+}
+
+// Delete implements privacy.Module
+func (m *PrivacyModule) Delete(ctx context.Context, tx *sql.Tx, userID int64, mode privacy.DeleteMode) error {
+	return deleteFactors(ctx, tx, userID)
+}
+
+func nullTime(t sql.NullTime) any {
+	if !t.Valid {
+		return nil
+	}
+	return t.Time
+}
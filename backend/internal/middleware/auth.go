@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/auth"
+)
+
+// Predefined errors
+var (
+	ErrMissingToken     = errors.New("missing bearer token")
+	ErrInsufficientRole = errors.New("insufficient role")
+)
+
+// Context keys set by Auth
+const (
+	UserIDKey = "userID"
+	RoleKey   = "role"
+	ClaimsKey = "claims"
+)
+
+// Auth middleware rejects requests without a valid bearer access token and
+// stores the authenticated user in the context
+func Auth(tokens *auth.TokenService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenString, ok := bearerToken(c)
+		if !ok {
+			AbortWithError(c, http.StatusUnauthorized, ErrMissingToken)
+			return
+		}
+
+		claims, err := tokens.Parse(tokenString)
+		if err != nil {
+			AbortWithError(c, http.StatusUnauthorized, err)
+			return
+		}
+
+		setClaims(c, claims)
+		c.Next()
+	}
+}
+
+// OptionalAuth stores the user in the context when a valid bearer token
+// with an active session is present and lets anonymous requests through
+// otherwise, so a signed-out token is treated as no token
+func OptionalAuth(tokens *auth.TokenService, sessions SessionChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if tokenString, ok := bearerToken(c); ok {
+			if claims, err := tokens.Parse(tokenString); err == nil && sessionActive(c, sessions, claims) {
+				setClaims(c, claims)
+			}
+		}
+		c.Next()
+	}
+}
+
+// sessionActive reports whether the session of claims is active. Errors
+// count as inactive: the request goes on anonymously.
+func sessionActive(c *gin.Context, sessions SessionChecker, claims *auth.Claims) bool {
+	if claims.SessionID == 0 {
+		return false
+	}
+	active, err := sessions.Active(c.Request.Context(), claims.UserID, claims.SessionID)
+	if err != nil {
+		log.Printf("sessions: %v", err)
+		return false
+	}
+	return active
+}
+
+// RequireRole rejects authenticated users whose role is not one of roles.
+// It must run after Auth.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := Role(c)
+		for _, r := range roles {
+			if role == r {
+				c.Next()
+				return
+			}
+		}
+		AbortWithError(c, http.StatusForbidden, ErrInsufficientRole)
+	}
+}
+
+// UserID returns the authenticated user's ID, or 0 outside Auth
+func UserID(c *gin.Context) int64 {
+	return c.GetInt64(UserIDKey)
+}
+
+// Role returns the authenticated user's role, or "" outside Auth
+func Role(c *gin.Context) string {
+	return c.GetString(RoleKey)
+}
+
+func bearerToken(c *gin.Context) (string, bool) {
+	tokenString, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	return tokenString, ok && tokenString != ""
+}
+
+func setClaims(c *gin.Context, claims *auth.Claims) {
+	c.Set(UserIDKey, claims.UserID)
+	c.Set(RoleKey, claims.Role)
+	c.Set(ClaimsKey, claims)
+}
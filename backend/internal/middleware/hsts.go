@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HSTS tells browsers to use HTTPS only, for maxAge. The header is only
+// sent on HTTPS responses, as browsers ignore it over plain HTTP.
+func HSTS(maxAge time.Duration, includeSubdomains bool) gin.HandlerFunc {
+	value := "max-age=" + strconv.FormatInt(int64(maxAge.Seconds()), 10)
+	if includeSubdomains {
+		value += "; includeSubDomains"
+	}
+	return func(c *gin.Context) {
+		if c.Request.TLS != nil {
+			c.Header("Strict-Transport-Security", value)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/i18n"
+)
+
+// ErrInternal is reported to clients for unexpected failures, which are
+// logged instead of shown
+var ErrInternal = errors.New("internal server error")
+
+// Context keys set by I18n
+const (
+	LocaleKey    = "locale"
+	localizerKey = "localizer"
+)
+
+// LocaleStore returns the locale a user chose in their profile, or ""
+type LocaleStore interface {
+	Locale(ctx context.Context, userID int64) (string, error)
+}
+
+type localizer struct {
+	bundle   *i18n.Bundle
+	messages i18n.Errors
+	store    LocaleStore
+}
+
+// I18n middleware lets handlers answer in the caller's language with
+// Translate and RespondError. The locale is resolved on first use: the one
+// in the signed-in user's profile wins, then Accept-Language, then the
+// bundle's default. messages maps errors to catalog messages.
+func I18n(bundle *i18n.Bundle, messages i18n.Errors, store LocaleStore) gin.HandlerFunc {
+	l := &localizer{bundle: bundle, messages: messages, store: store}
+	return func(c *gin.Context) {
+		c.Set(localizerKey, l)
+		c.Header("Vary", "Accept-Language")
+		c.Next()
+	}
+}
+
+// Locale returns the locale of the current request, or "" outside I18n
+func Locale(c *gin.Context) string {
+	if locale := c.GetString(LocaleKey); locale != "" {
+		return locale
+	}
+	l, ok := localizerOf(c)
+	if !ok {
+		return ""
+	}
+
+	var locale string
+	if userID := UserID(c); userID != 0 && l.store != nil {
+		preferred, err := l.store.Locale(c.Request.Context(), userID)
+		if err != nil {
+			log.Printf("i18n: %v", err)
+		} else if l.bundle.Supports(preferred) {
+			locale = preferred
+		}
+	}
+	if locale == "" {
+		locale = l.bundle.Match(c.GetHeader("Accept-Language"))
+	}
+	c.Set(LocaleKey, locale)
+	c.Header("Content-Language", locale)
+	return locale
+}
+
+// Translate renders a catalog message in the request's locale
+func Translate(c *gin.Context, id string, params i18n.Params) string {
+	l, ok := localizerOf(c)
+	if !ok {
+		return id
+	}
+	return l.bundle.Translate(Locale(c), id, params)
+}
+
+// RespondError writes err as {"error": <localized message>, "code": <id>}.
+// When err wraps a known error with more context, that context is added
+// untranslated as "detail". Errors without a message are written as is.
+func RespondError(c *gin.Context, status int, err error) {
+	c.JSON(status, errorBody(c, err))
+}
+
+// AbortWithError is RespondError for middleware: it also stops the chain
+func AbortWithError(c *gin.Context, status int, err error) {
+	c.AbortWithStatusJSON(status, errorBody(c, err))
+}
+
+func errorBody(c *gin.Context, err error) gin.H {
+	l, ok := localizerOf(c)
+	if !ok {
+		return gin.H{"error": err.Error()}
+	}
+	msg, ok := l.messages.Lookup(err)
+	if !ok {
+		return gin.H{"error": err.Error()}
+	}
+	body := gin.H{
+		"error": l.bundle.Translate(Locale(c), msg.ID, msg.Params),
+		"code":  msg.ID,
+	}
+	if _, exact := l.messages[err]; !exact {
+		body["detail"] = err.Error()
+	}
+	return body
+}
+
+func localizerOf(c *gin.Context) (*localizer, bool) {
+	value, ok := c.Get(localizerKey)
+	if !ok {
+		return nil, false
+	}
+	l, ok := value.(*localizer)
+	return l, ok
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/auth"
+)
+
+// Predefined errors
+var (
+	ErrNoSession      = errors.New("token has no session")
+	ErrSessionRevoked = errors.New("session revoked")
+)
+
+// SessionChecker reports whether a session is still active
+type SessionChecker interface {
+	Active(ctx context.Context, userID, sessionID int64) (bool, error)
+}
+
+// Session middleware rejects access tokens that are not tied to a session
+// or whose session has been revoked or has expired. It must run after Auth.
+func Session(checker SessionChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessionID := SessionID(c)
+		if sessionID == 0 {
+			AbortWithError(c, http.StatusUnauthorized, ErrNoSession)
+			return
+		}
+
+		active, err := checker.Active(c.Request.Context(), UserID(c), sessionID)
+		if err != nil {
+			log.Printf("sessions: %v", err)
+			AbortWithError(c, http.StatusInternalServerError, ErrInternal)
+			return
+		}
+		if !active {
+			AbortWithError(c, http.StatusUnauthorized, ErrSessionRevoked)
+			return
+		}
+		c.Next()
+	}
+}
+
+// SessionID returns the session of the authenticated access token, or 0
+func SessionID(c *gin.Context) int64 {
+	if value, ok := c.Get(ClaimsKey); ok {
+		if claims, ok := value.(*auth.Claims); ok {
+			return claims.SessionID
+		}
+	}
+	return 0
+}
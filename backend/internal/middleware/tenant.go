@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/auth"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/tenant"
+)
+
+// TenantHeader selects the organization by slug or ID
+const TenantHeader = "X-Org"
+
+// TenantKey is the context key holding the resolved tenant.Tenant
+const TenantKey = "tenant"
+
+// Predefined errors
+var (
+	// ErrTenantAccess is returned by a TenantResolver when the organization
+	// does not exist or the user is not a member of it
+	ErrTenantAccess = errors.New("no access to organization")
+	ErrNoTenant     = errors.New("no organization selected")
+)
+
+// TenantResolver looks up an organization reference (slug or ID) and checks
+// that the user belongs to it
+type TenantResolver interface {
+	ResolveTenant(ctx context.Context, ref string, userID int64) (tenant.Tenant, error)
+}
+
+// Tenant middleware resolves the organization a request acts on and stores
+// it in both the gin and the request context, where tenant-scoped
+// repositories pick it up. The organization is taken from, in order, the
+// X-Org header, the subdomain of baseDomain and the token's org claim.
+// It must run after Auth.
+func Tenant(resolver TenantResolver, baseDomain string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ref := tenantRef(c, baseDomain)
+		if ref == "" {
+			AbortWithError(c, http.StatusBadRequest, ErrNoTenant)
+			return
+		}
+
+		t, err := resolver.ResolveTenant(c.Request.Context(), ref, UserID(c))
+		if err != nil {
+			// Unknown organizations and foreign ones look the same so that
+			// slugs of other tenants cannot be probed
+			AbortWithError(c, http.StatusForbidden, ErrTenantAccess)
+			return
+		}
+
+		c.Set(TenantKey, t)
+		c.Request = c.Request.WithContext(tenant.NewContext(c.Request.Context(), t))
+		c.Next()
+	}
+}
+
+// CurrentTenant returns the tenant resolved by the Tenant middleware
+func CurrentTenant(c *gin.Context) (tenant.Tenant, bool) {
+	return tenant.FromContext(c.Request.Context())
+}
+
+func tenantRef(c *gin.Context, baseDomain string) string {
+	if ref := strings.TrimSpace(c.GetHeader(TenantHeader)); ref != "" {
+		return ref
+	}
+
+	if baseDomain != "" {
+		host := c.Request.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if sub, ok := strings.CutSuffix(strings.ToLower(host), "."+strings.ToLower(baseDomain)); ok && sub != "" && !strings.Contains(sub, ".") {
+			return sub
+		}
+	}
+
+	if value, ok := c.Get(ClaimsKey); ok {
+		if claims, ok := value.(*auth.Claims); ok && claims.OrgID != 0 {
+			return strconv.FormatInt(claims.OrgID, 10)
+		}
+	}
+	return ""
+}
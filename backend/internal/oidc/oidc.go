@@ -0,0 +1,285 @@
+// Package oidc implements "Sign in with ..." through OpenID Connect.
+//
+// A login starts with Start, which records a single-use state together with
+// a PKCE verifier and a nonce and returns the provider's authorization URL.
+// The provider redirects back with a code, which Finish exchanges for an ID
+// token. The token is verified against the provider's published keys and
+// mapped to a local account: an identity seen before signs in its user, a
+// verified email matching an existing account without a password links to
+// it, and a new email creates a new account. Signed-in users can also link a provider
+// explicitly, regardless of email.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/config"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/users"
+)
+
+// Predefined errors
+var (
+	ErrUnknownProvider  = errors.New("unknown identity provider")
+	ErrInvalidState     = errors.New("invalid or expired login state")
+	ErrDiscovery        = errors.New("identity provider unavailable")
+	ErrExchangeFailed   = errors.New("authorization code exchange failed")
+	ErrInvalidIDToken   = errors.New("invalid ID token")
+	ErrEmailNotVerified = errors.New("identity provider did not verify the email address")
+	ErrIdentityTaken    = errors.New("identity is linked to another account")
+	ErrIdentityNotFound = errors.New("identity not linked")
+	ErrLastSignInMethod = errors.New("cannot unlink the only way to sign in")
+	ErrAccountDeleted   = errors.New("account deleted")
+	ErrLinkRequired     = errors.New("an account with this email already exists; sign in and link the provider from your account")
+)
+
+// LinkedIdentity is a provider account linked to a local user
+type LinkedIdentity struct {
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"-"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Service runs social logins against the configured providers
+type Service struct {
+	db        *database.DB
+	users     *users.Repository
+	providers map[string]*Provider
+	loginTTL  time.Duration
+	now       func() time.Time
+}
+
+// NewService creates the service for the configured providers. client is
+// used for all provider requests; nil means a default client.
+func NewService(db *database.DB, userRepo *users.Repository, providers []config.OIDCProvider, loginTTL time.Duration, client *http.Client) *Service {
+	s := &Service{
+		db:        db,
+		users:     userRepo,
+		providers: make(map[string]*Provider, len(providers)),
+		loginTTL:  loginTTL,
+		now:       func() time.Time { return time.Now().UTC() },
+	}
+	for _, cfg := range providers {
+		s.providers[cfg.Name] = NewProvider(cfg, client)
+	}
+	return s
+}
+
+// SetClock replaces the time source of the service and its providers, for
+// tests that control time
+func (s *Service) SetClock(now func() time.Time) {
+	s.now = now
+	for _, p := range s.providers {
+		p.now = now
+	}
+}
+
+// Providers returns the names of the configured providers
+func (s *Service) Providers() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Start begins a login with the provider and returns the URL to send the
+// user to and the state the provider will echo back. A non-zero linkUserID
+// links the provider account to that user instead of signing in by email.
+func (s *Service) Start(ctx context.Context, provider string, linkUserID int64) (authURL, state string, err error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return "", "", ErrUnknownProvider
+	}
+	state, verifier, nonce := randomString(), randomString(), randomString()
+	authURL, err = p.AuthURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return "", "", err
+	}
+
+	now := s.now()
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM oidc_logins WHERE expires_at < $1`, now); err != nil {
+		return "", "", err
+	}
+	var userID sql.NullInt64
+	if linkUserID != 0 {
+		userID = sql.NullInt64{Int64: linkUserID, Valid: true}
+	}
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO oidc_logins (state, provider, user_id, code_verifier, nonce, expires_at)
+		 VALUES ($1, $2, $3, $4, $5, $6)`,
+		state, provider, userID, verifier, nonce, now.Add(s.loginTTL))
+	if err != nil {
+		return "", "", err
+	}
+	return authURL, state, nil
+}
+
+// Finish completes a login started with Start and returns the local user
+// the provider account belongs to, linking or creating it as needed
+func (s *Service) Finish(ctx context.Context, provider, state, code string) (*users.User, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	// Deleting the state up front makes it single-use even if the rest fails
+	var (
+		linkUserID      sql.NullInt64
+		verifier, nonce string
+		expiresAt       time.Time
+	)
+	err := s.db.QueryRowContext(ctx,
+		`DELETE FROM oidc_logins WHERE state = $1 AND provider = $2
+		 RETURNING user_id, code_verifier, nonce, expires_at`,
+		state, provider,
+	).Scan(&linkUserID, &verifier, &nonce, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !s.now().Before(expiresAt)) {
+		return nil, ErrInvalidState
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rawIDToken, err := p.Exchange(ctx, code, verifier)
+	if err != nil {
+		return nil, err
+	}
+	id, err := p.Verify(ctx, rawIDToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+	return s.resolve(ctx, provider, id, linkUserID.Int64)
+}
+
+// resolve maps a verified identity to a local user
+func (s *Service) resolve(ctx context.Context, provider string, id *Identity, linkUserID int64) (*users.User, error) {
+	var ownerID int64
+	err := s.db.QueryRowContext(ctx,
+		`SELECT user_id FROM oidc_identities WHERE provider = $1 AND subject = $2`,
+		provider, id.Subject,
+	).Scan(&ownerID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	switch {
+	case ownerID != 0 && linkUserID != 0 && ownerID != linkUserID:
+		return nil, ErrIdentityTaken
+	case ownerID != 0:
+		return s.activeUser(ctx, ownerID)
+	case linkUserID != 0:
+		user, err := s.activeUser(ctx, linkUserID)
+		if err != nil {
+			return nil, err
+		}
+		return user, s.link(ctx, user.ID, provider, id)
+	}
+
+	// Matching by email is only safe when the provider vouches for it
+	if id.Email == "" || !id.EmailVerified {
+		return nil, ErrEmailNotVerified
+	}
+	user, err := s.users.GetByEmail(ctx, id.Email)
+	if errors.Is(err, users.ErrNotFound) {
+		user = &users.User{Email: id.Email, Name: id.Name}
+		err = s.users.Create(ctx, user)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.DeletedAt != nil {
+		return nil, ErrAccountDeleted
+	}
+	// Local emails are never verified, so whoever registered the address
+	// first may not own it. Linking would let them keep a password into the
+	// real owner's account; its owner links explicitly after signing in.
+	if user.PasswordHash != "" {
+		return nil, ErrLinkRequired
+	}
+	return user, s.link(ctx, user.ID, provider, id)
+}
+
+func (s *Service) activeUser(ctx context.Context, userID int64) (*users.User, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.DeletedAt != nil {
+		return nil, ErrAccountDeleted
+	}
+	return user, nil
+}
+
+func (s *Service) link(ctx context.Context, userID int64, provider string, id *Identity) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO oidc_identities (user_id, provider, subject, email, created_at) VALUES ($1, $2, $3, $4, $5)`,
+		userID, provider, id.Subject, id.Email, s.now())
+	return err
+}
+
+// Identities returns the provider accounts linked to the user
+func (s *Service) Identities(ctx context.Context, userID int64) ([]LinkedIdentity, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT provider, subject, email, created_at FROM oidc_identities WHERE user_id = $1 ORDER BY provider, id`,
+		userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []LinkedIdentity{}
+	for rows.Next() {
+		var li LinkedIdentity
+		if err := rows.Scan(&li.Provider, &li.Subject, &li.Email, &li.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, li)
+	}
+	return list, rows.Err()
+}
+
+// Unlink removes the user's identities at the provider. Accounts without a
+// password must keep at least one linked identity.
+func (s *Service) Unlink(ctx context.Context, userID int64, provider string) error {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	var linked, atProvider int
+	err = s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*), COALESCE(SUM(CASE WHEN provider = $2 THEN 1 ELSE 0 END), 0)
+		 FROM oidc_identities WHERE user_id = $1`,
+		userID, provider,
+	).Scan(&linked, &atProvider)
+	if err != nil {
+		return err
+	}
+	if atProvider == 0 {
+		return ErrIdentityNotFound
+	}
+	if user.PasswordHash == "" && linked == atProvider {
+		return ErrLastSignInMethod
+	}
+	_, err = s.db.ExecContext(ctx, `DELETE FROM oidc_identities WHERE user_id = $1 AND provider = $2`, userID, provider)
+	return err
+}
+
+// randomString returns 32 random bytes, base64url-encoded. At 43 characters
+// it is also a valid PKCE verifier.
+func randomString() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
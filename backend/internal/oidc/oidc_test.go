@@ -0,0 +1,357 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/config"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database/dbtest"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/users"
+)
+
+// mockProvider is an in-process OpenID Connect provider. Authorize plays
+// the part of the user's browser approving the login.
+type mockProvider struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	kid    string
+
+	mu    sync.Mutex
+	codes map[string]grant
+}
+
+type grant struct {
+	challenge string
+	nonce     string
+	claims    jwt.MapClaims
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	m := &mockProvider{t: t, key: key, kid: "key-1", codes: map[string]grant{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.server.URL,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"jwks_uri":               m.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": m.kid,
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(m.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(m.key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("POST /token", m.token)
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+func (m *mockProvider) config() config.OIDCProvider {
+	return config.OIDCProvider{
+		Name:         "mock",
+		Issuer:       m.server.URL,
+		ClientID:     "client-1",
+		ClientSecret: "client-secret",
+		RedirectURL:  "app://callback",
+		Scopes:       []string{"openid", "email"},
+	}
+}
+
+// Authorize approves the login behind authURL for a user with the given
+// claims and returns the code the provider redirects back with
+func (m *mockProvider) Authorize(authURL string, claims jwt.MapClaims) string {
+	m.t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		m.t.Fatalf("Bad auth URL: %v", err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("client_id") != "client-1" {
+		m.t.Fatalf("Unexpected authorization request %v", q)
+	}
+	code := randomString()
+	m.mu.Lock()
+	m.codes[code] = grant{challenge: q.Get("code_challenge"), nonce: q.Get("nonce"), claims: claims}
+	m.mu.Unlock()
+	return code
+}
+
+func (m *mockProvider) token(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	m.mu.Lock()
+	g, ok := m.codes[r.PostForm.Get("code")]
+	delete(m.codes, r.PostForm.Get("code"))
+	m.mu.Unlock()
+	if !ok || codeChallenge(r.PostForm.Get("code_verifier")) != g.challenge ||
+		r.PostForm.Get("client_secret") != "client-secret" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"id_token": m.sign(g.nonce, g.claims)})
+}
+
+func (m *mockProvider) sign(nonce string, extra jwt.MapClaims) string {
+	claims := jwt.MapClaims{
+		"iss":   m.server.URL,
+		"aud":   "client-1",
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"nonce": nonce,
+	}
+	for k, v := range extra {
+		claims[k] = v
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = m.kid
+	signed, err := token.SignedString(m.key)
+	if err != nil {
+		m.t.Fatalf("Sign: %v", err)
+	}
+	return signed
+}
+
+type fixture struct {
+	provider *mockProvider
+	service  *Service
+	users    *users.Repository
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	db := dbtest.New(t)
+	provider := newMockProvider(t)
+	repo := users.NewRepository(db)
+	return &fixture{
+		provider: provider,
+		service:  NewService(db, repo, []config.OIDCProvider{provider.config()}, 10*time.Minute, nil),
+		users:    repo,
+	}
+}
+
+// login runs a whole login for a provider user with the given claims
+func (f *fixture) login(t *testing.T, linkUserID int64, claims jwt.MapClaims) (*users.User, error) {
+	t.Helper()
+	ctx := context.Background()
+	authURL, state, err := f.service.Start(ctx, "mock", linkUserID)
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	code := f.provider.Authorize(authURL, claims)
+	return f.service.Finish(ctx, "mock", state, code)
+}
+
+func TestLoginCreatesAndReusesAccount(t *testing.T) {
+	f := newFixture(t)
+	claims := jwt.MapClaims{"sub": "alice-1", "email": "Alice@Example.com", "email_verified": true, "name": "Alice"}
+
+	user, err := f.login(t, 0, claims)
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	if user.Email != "alice@example.com" || user.Name != "Alice" || user.PasswordHash != "" {
+		t.Errorf("Expected new passwordless account, got %+v", user)
+	}
+
+	// A changed email at the provider still signs into the same account
+	again, err := f.login(t, 0, jwt.MapClaims{"sub": "alice-1", "email": "alice@new.example.com"})
+	if err != nil || again.ID != user.ID {
+		t.Errorf("Expected same account by subject, got %+v, %v", again, err)
+	}
+}
+
+func TestLoginLinksVerifiedEmail(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	existing := &users.User{Email: "bob@example.com"}
+	if err := f.users.Create(ctx, existing); err != nil {
+		t.Fatalf("Create user: %v", err)
+	}
+
+	_, err := f.login(t, 0, jwt.MapClaims{"sub": "bob-1", "email": "bob@example.com", "email_verified": false})
+	if !errors.Is(err, ErrEmailNotVerified) {
+		t.Errorf("Expected ErrEmailNotVerified, got %v", err)
+	}
+
+	// Apple sends email_verified as a string
+	user, err := f.login(t, 0, jwt.MapClaims{"sub": "bob-1", "email": "bob@example.com", "email_verified": "true"})
+	if err != nil || user.ID != existing.ID {
+		t.Fatalf("Expected link to existing account, got %+v, %v", user, err)
+	}
+	list, err := f.service.Identities(ctx, existing.ID)
+	if err != nil || len(list) != 1 || list[0].Subject != "bob-1" {
+		t.Errorf("Expected one linked identity, got %+v, %v", list, err)
+	}
+}
+
+func TestLoginDoesNotLinkPasswordAccount(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	// Anyone could have registered the address first: emails of local
+	// accounts are not verified
+	squatter := &users.User{Email: "frank@example.com", PasswordHash: "hash"}
+	if err := f.users.Create(ctx, squatter); err != nil {
+		t.Fatalf("Create user: %v", err)
+	}
+
+	claims := jwt.MapClaims{"sub": "frank-1", "email": "frank@example.com", "email_verified": true}
+	if _, err := f.login(t, 0, claims); !errors.Is(err, ErrLinkRequired) {
+		t.Fatalf("Expected ErrLinkRequired, got %v", err)
+	}
+	if list, err := f.service.Identities(ctx, squatter.ID); err != nil || len(list) != 0 {
+		t.Errorf("Expected no linked identity, got %+v, %v", list, err)
+	}
+
+	// After signing in with the password the owner links explicitly
+	user, err := f.login(t, squatter.ID, claims)
+	if err != nil || user.ID != squatter.ID {
+		t.Errorf("Expected explicit link, got %+v, %v", user, err)
+	}
+}
+
+func TestExplicitLinkAndUnlink(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	carol := &users.User{Email: "carol@example.com", PasswordHash: "hash"}
+	if err := f.users.Create(ctx, carol); err != nil {
+		t.Fatalf("Create user: %v", err)
+	}
+
+	// Linking ignores the provider's email, which may differ
+	user, err := f.login(t, carol.ID, jwt.MapClaims{"sub": "carol-1", "email": "c@other.example.com"})
+	if err != nil || user.ID != carol.ID {
+		t.Fatalf("Expected link to carol, got %+v, %v", user, err)
+	}
+
+	dave := &users.User{Email: "dave@example.com", PasswordHash: "hash"}
+	if err := f.users.Create(ctx, dave); err != nil {
+		t.Fatalf("Create user: %v", err)
+	}
+	if _, err := f.login(t, dave.ID, jwt.MapClaims{"sub": "carol-1"}); !errors.Is(err, ErrIdentityTaken) {
+		t.Errorf("Expected ErrIdentityTaken, got %v", err)
+	}
+
+	if err := f.service.Unlink(ctx, carol.ID, "mock"); err != nil {
+		t.Fatalf("Unlink failed: %v", err)
+	}
+	if err := f.service.Unlink(ctx, carol.ID, "mock"); !errors.Is(err, ErrIdentityNotFound) {
+		t.Errorf("Expected ErrIdentityNotFound, got %v", err)
+	}
+
+	// A passwordless account keeps its only sign-in method
+	erin, err := f.login(t, 0, jwt.MapClaims{"sub": "erin-1", "email": "erin@example.com", "email_verified": true})
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	if err := f.service.Unlink(ctx, erin.ID, "mock"); !errors.Is(err, ErrLastSignInMethod) {
+		t.Errorf("Expected ErrLastSignInMethod, got %v", err)
+	}
+}
+
+func TestStateIsSingleUse(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	authURL, state, err := f.service.Start(ctx, "mock", 0)
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	code := f.provider.Authorize(authURL, jwt.MapClaims{"sub": "x", "email": "x@example.com", "email_verified": true})
+	if _, err := f.service.Finish(ctx, "mock", state, code); err != nil {
+		t.Fatalf("Finish failed: %v", err)
+	}
+	if _, err := f.service.Finish(ctx, "mock", state, code); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Expected ErrInvalidState on replay, got %v", err)
+	}
+	if _, err := f.service.Finish(ctx, "mock", "forged", code); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Expected ErrInvalidState for unknown state, got %v", err)
+	}
+	if _, _, err := f.service.Start(ctx, "nope", 0); !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("Expected ErrUnknownProvider, got %v", err)
+	}
+
+	// States expire
+	authURL, state, _ = f.service.Start(ctx, "mock", 0)
+	f.service.now = func() time.Time { return time.Now().UTC().Add(time.Hour) }
+	code = f.provider.Authorize(authURL, jwt.MapClaims{"sub": "x"})
+	if _, err := f.service.Finish(ctx, "mock", state, code); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Expected ErrInvalidState after expiry, got %v", err)
+	}
+}
+
+func TestVerifyRejectsBadTokens(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	p := f.service.providers["mock"]
+	other, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	forged := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss": f.provider.server.URL, "aud": "client-1", "sub": "x", "nonce": "n",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	forged.Header["kid"] = f.provider.kid
+	forgedToken, _ := forged.SignedString(other)
+
+	tests := []struct {
+		name  string
+		token string
+		nonce string
+	}{
+		{"valid", f.provider.sign("n", jwt.MapClaims{"sub": "x"}), "n"},
+		{"wrong nonce", f.provider.sign("n", jwt.MapClaims{"sub": "x"}), "other"},
+		{"wrong audience", f.provider.sign("n", jwt.MapClaims{"sub": "x", "aud": "client-2"}), "n"},
+		{"wrong issuer", f.provider.sign("n", jwt.MapClaims{"sub": "x", "iss": "https://evil.example.com"}), "n"},
+		{"expired", f.provider.sign("n", jwt.MapClaims{"sub": "x", "exp": time.Now().Add(-time.Minute).Unix()}), "n"},
+		{"no subject", f.provider.sign("n", nil), "n"},
+		{"bad signature", forgedToken, "n"},
+		{"unsigned", "eyJhbGciOiJub25lIn0.eyJzdWIiOiJ4In0.", "n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := p.Verify(ctx, tt.token, tt.nonce)
+			if tt.name == "valid" {
+				if err != nil || id.Subject != "x" {
+					t.Errorf("Expected valid token, got %+v, %v", id, err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidIDToken) {
+				t.Errorf("Expected ErrInvalidIDToken, got %v", err)
+			}
+		})
+	}
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	f := newFixture(t)
+	cfg := f.provider.config()
+	cfg.Issuer += "/"
+	p := NewProvider(cfg, nil)
+	if _, err := p.AuthURL(context.Background(), "s", "n", "v"); !errors.Is(err, ErrDiscovery) {
+		t.Errorf("Expected ErrDiscovery, got %v", err)
+	}
+}
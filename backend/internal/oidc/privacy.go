@@ -0,0 +1,49 @@
+package oidc
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/privacy"
+)
+
+// PrivacyModule exports and erases a user's linked identities
+type PrivacyModule struct {
+	service *Service
+}
+
+// NewPrivacyModule creates the oidc privacy module
+func NewPrivacyModule(service *Service) *PrivacyModule {
+	return &PrivacyModule{service: service}
+}
+
+// Name implements privacy.Module
+func (m *PrivacyModule) Name() string {
+	return "identities"
+}
+
+// Export implements privacy.Module
+func (m *PrivacyModule) Export(ctx context.Context, userID int64) ([]privacy.Dataset, error) {
+	list, err := m.service.Identities(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	ds := privacy.Dataset{
+		Name:    "identities",
+		Columns: []string{"provider", "subject", "email", "created_at"},
+	}
+	for _, li := range list {
+		ds.Rows = append(ds.Rows, []any{li.Provider, li.Subject, li.Email, li.CreatedAt})
+	}
+	return []privacy.Dataset{ds}, nil
+}
+
+// Delete implements privacy.Module. Identities are removed in both modes so
+// that an anonymised account cannot be signed into through a provider.
+func (m *PrivacyModule) Delete(ctx context.Context, tx *sql.Tx, userID int64, mode privacy.DeleteMode) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM oidc_logins WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `DELETE FROM oidc_identities WHERE user_id = $1`, userID)
+	return err
+}
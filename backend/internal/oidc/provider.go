@@ -0,0 +1,296 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/config"
+)
+
+// jwksRefreshInterval limits how often an unknown key ID triggers a JWKS
+// refetch, so forged tokens cannot make us hammer the provider
+const jwksRefreshInterval = time.Minute
+
+// Identity is what a verified ID token says about the user
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// metadata is the part of the discovery document we use
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider is an OpenID Connect client for one identity provider. The
+// discovery document is fetched on first use and signing keys are cached
+// until a token names a key we have not seen.
+type Provider struct {
+	cfg    config.OIDCProvider
+	client *http.Client
+	now    func() time.Time
+
+	mu          sync.Mutex
+	meta        *metadata
+	keys        map[string]*rsa.PublicKey
+	keysFetched time.Time
+}
+
+// NewProvider creates a client for the configured provider
+func NewProvider(cfg config.OIDCProvider, client *http.Client) *Provider {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Provider{cfg: cfg, client: client, now: time.Now}
+}
+
+// Name returns the provider's configured name
+func (p *Provider) Name() string {
+	return p.cfg.Name
+}
+
+// AuthURL returns the authorization endpoint URL the user is sent to. The
+// PKCE challenge is derived from verifier.
+func (p *Provider) AuthURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(meta.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("%w: bad authorization endpoint", ErrDiscovery)
+	}
+	q := u.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", p.cfg.ClientID)
+	q.Set("redirect_uri", p.cfg.RedirectURL)
+	q.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", codeChallenge(verifier))
+	q.Set("code_challenge_method", "S256")
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// Exchange trades an authorization code for an ID token
+func (p *Provider) Exchange(ctx context.Context, code, verifier string) (string, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"client_id":     {p.cfg.ClientID},
+		"code_verifier": {verifier},
+	}
+	if p.cfg.ClientSecret != "" {
+		form.Set("client_secret", p.cfg.ClientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrExchangeFailed, err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken string `json:"id_token"`
+		Error   string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("%w: status %d", ErrExchangeFailed, resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK || body.IDToken == "" {
+		return "", fmt.Errorf("%w: status %d %s", ErrExchangeFailed, resp.StatusCode, body.Error)
+	}
+	return body.IDToken, nil
+}
+
+// idClaims are the ID token claims we read
+type idClaims struct {
+	Nonce         string   `json:"nonce"`
+	Email         string   `json:"email"`
+	EmailVerified flexBool `json:"email_verified"`
+	Name          string   `json:"name"`
+	jwt.RegisteredClaims
+}
+
+// flexBool accepts both true and "true"; Apple sends booleans as strings
+type flexBool bool
+
+func (b *flexBool) UnmarshalJSON(data []byte) error {
+	*b = flexBool(strings.Trim(string(data), `"`) == "true")
+	return nil
+}
+
+// Verify checks the ID token's signature against the provider's JWKS, its
+// issuer, audience, expiry and nonce, and returns the identity it asserts
+func (p *Provider) Verify(ctx context.Context, rawIDToken, nonce string) (*Identity, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	claims := &idClaims{}
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{"RS256", "RS384", "RS512"}))
+	_, err = parser.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.key(ctx, meta, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+	switch {
+	case claims.Issuer != meta.Issuer:
+		return nil, fmt.Errorf("%w: issuer %q", ErrInvalidIDToken, claims.Issuer)
+	case !slices.Contains(claims.Audience, p.cfg.ClientID):
+		return nil, fmt.Errorf("%w: audience %v", ErrInvalidIDToken, claims.Audience)
+	case claims.ExpiresAt == nil:
+		return nil, fmt.Errorf("%w: no expiry", ErrInvalidIDToken)
+	case claims.Nonce != nonce:
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	case claims.Subject == "":
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidIDToken)
+	}
+	return &Identity{
+		Subject:       claims.Subject,
+		Email:         strings.ToLower(strings.TrimSpace(claims.Email)),
+		EmailVerified: bool(claims.EmailVerified),
+		Name:          claims.Name,
+	}, nil
+}
+
+// discover fetches and caches the provider's discovery document
+func (p *Provider) discover(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.meta != nil {
+		return p.meta, nil
+	}
+
+	var meta metadata
+	wellKnown := strings.TrimSuffix(p.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := p.getJSON(ctx, wellKnown, &meta); err != nil {
+		return nil, err
+	}
+	// The issuer must match exactly, otherwise a compromised document could
+	// make us accept tokens from someone else
+	if meta.Issuer != p.cfg.Issuer {
+		return nil, fmt.Errorf("%w: issuer %q does not match %q", ErrDiscovery, meta.Issuer, p.cfg.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: incomplete discovery document", ErrDiscovery)
+	}
+	p.meta = &meta
+	return p.meta, nil
+}
+
+// key returns the signing key with the given ID, refetching the JWKS when
+// it is unknown
+func (p *Provider) key(ctx context.Context, meta *metadata, kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if k, ok := p.keys[kid]; ok {
+		return k, nil
+	}
+	if p.keys != nil && p.now().Sub(p.keysFetched) < jwksRefreshInterval {
+		return nil, errors.New("unknown signing key")
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := p.getJSON(ctx, meta.JWKSURI, &set); err != nil {
+		return nil, err
+	}
+	p.keys = make(map[string]*rsa.PublicKey, len(set.Keys))
+	p.keysFetched = p.now()
+	for _, k := range set.Keys {
+		if pub, err := k.rsaKey(); err == nil {
+			p.keys[k.Kid] = pub
+		}
+	}
+	if k, ok := p.keys[kid]; ok {
+		return k, nil
+	}
+	return nil, errors.New("unknown signing key")
+}
+
+func (p *Provider) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDiscovery, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: GET %s: status %d", ErrDiscovery, url, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("%w: GET %s: %v", ErrDiscovery, url, err)
+	}
+	return nil
+}
+
+// jwk is an RSA JSON Web Key
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+func (k jwk) rsaKey() (*rsa.PublicKey, error) {
+	if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+		return nil, errors.New("not an RSA signing key")
+	}
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+	exp := new(big.Int).SetBytes(e)
+	if !exp.IsInt64() || exp.Int64() < 3 || exp.Int64() > 1<<31 {
+		return nil, errors.New("bad RSA exponent")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}, nil
+}
+
+// codeChallenge derives the S256 PKCE challenge from a verifier
+func codeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
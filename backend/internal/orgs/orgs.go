@@ -0,0 +1,449 @@
+package orgs
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/tenant"
+)
+
+// Membership roles, from most to least privileged
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+)
+
+// Predefined errors
+var (
+	ErrNotFound          = errors.New("organization not found")
+	ErrNotMember         = errors.New("not a member of this organization")
+	ErrForbidden         = errors.New("insufficient organization role")
+	ErrInvalidSlug       = errors.New("invalid slug: use 3-40 lowercase letters, digits and dashes")
+	ErrInvalidName       = errors.New("organization name cannot be empty")
+	ErrInvalidRole       = errors.New("invalid organization role")
+	ErrSlugTaken         = errors.New("slug already taken")
+	ErrAlreadyMember     = errors.New("user is already a member")
+	ErrInvitationInvalid = errors.New("invitation is invalid or has expired")
+	ErrEmailMismatch     = errors.New("invitation was sent to a different email address")
+	ErrLastOwner         = errors.New("an organization needs at least one owner")
+)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,38}[a-z0-9]$`)
+
+// Organization is a tenant: a cohort, clinic or team whose data is isolated
+type Organization struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Slug      string    `json:"slug"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Membership links a user to an organization with a role
+type Membership struct {
+	OrgID     int64     `json:"org_id"`
+	UserID    int64     `json:"user_id"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Invitation asks someone, identified by email, to join an organization
+type Invitation struct {
+	ID         int64      `json:"id"`
+	OrgID      int64      `json:"org_id"`
+	Email      string     `json:"email"`
+	Role       string     `json:"role"`
+	InvitedBy  int64      `json:"invited_by"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// ValidRole reports whether role is a membership role
+func ValidRole(role string) bool {
+	return role == RoleOwner || role == RoleAdmin || role == RoleMember
+}
+
+// CanManage reports whether role may invite and manage members
+func CanManage(role string) bool {
+	return role == RoleOwner || role == RoleAdmin
+}
+
+// Service manages organizations, memberships and invitations
+type Service struct {
+	db            *database.DB
+	invitationTTL time.Duration
+	now           func() time.Time
+}
+
+// NewService creates an organization service
+func NewService(db *database.DB, invitationTTL time.Duration) *Service {
+	return &Service{
+		db:            db,
+		invitationTTL: invitationTTL,
+		now:           func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// SetClock replaces the service's time source, for tests that control time
+func (s *Service) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// Create creates an organization owned by ownerID
+func (s *Service) Create(ctx context.Context, name, slug string, ownerID int64) (*Organization, error) {
+	name = strings.TrimSpace(name)
+	slug = strings.ToLower(strings.TrimSpace(slug))
+	if name == "" {
+		return nil, ErrInvalidName
+	}
+	if !slugPattern.MatchString(slug) {
+		return nil, ErrInvalidSlug
+	}
+	if _, err := s.GetBySlug(ctx, slug); err == nil {
+		return nil, ErrSlugTaken
+	} else if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	org := &Organization{Name: name, Slug: slug, CreatedAt: s.now()}
+	err := s.db.WithTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx,
+			`INSERT INTO organizations (name, slug, created_at) VALUES ($1, $2, $3) RETURNING id`,
+			org.Name, org.Slug, org.CreatedAt,
+		).Scan(&org.ID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO memberships (org_id, user_id, role, created_at) VALUES ($1, $2, $3, $4)`,
+			org.ID, ownerID, RoleOwner, org.CreatedAt)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+// GetByID returns the organization with the given ID
+func (s *Service) GetByID(ctx context.Context, id int64) (*Organization, error) {
+	return s.getOne(ctx, `SELECT id, name, slug, created_at FROM organizations WHERE id = $1`, id)
+}
+
+// GetBySlug returns the organization with the given slug
+func (s *Service) GetBySlug(ctx context.Context, slug string) (*Organization, error) {
+	return s.getOne(ctx, `SELECT id, name, slug, created_at FROM organizations WHERE slug = $1`, slug)
+}
+
+func (s *Service) getOne(ctx context.Context, query string, arg any) (*Organization, error) {
+	var org Organization
+	err := s.db.QueryRowContext(ctx, query, arg).Scan(&org.ID, &org.Name, &org.Slug, &org.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+// ListForUser returns the organizations the user belongs to
+func (s *Service) ListForUser(ctx context.Context, userID int64) ([]Organization, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT o.id, o.name, o.slug, o.created_at
+		 FROM organizations o JOIN memberships m ON m.org_id = o.id
+		 WHERE m.user_id = $1 ORDER BY o.name`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []Organization{}
+	for rows.Next() {
+		var org Organization
+		if err := rows.Scan(&org.ID, &org.Name, &org.Slug, &org.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, org)
+	}
+	return list, rows.Err()
+}
+
+// Membership returns the user's membership in the organization
+func (s *Service) Membership(ctx context.Context, orgID, userID int64) (*Membership, error) {
+	var m Membership
+	err := s.db.QueryRowContext(ctx,
+		`SELECT org_id, user_id, role, created_at FROM memberships WHERE org_id = $1 AND user_id = $2`,
+		orgID, userID,
+	).Scan(&m.OrgID, &m.UserID, &m.Role, &m.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotMember
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// DefaultOrg returns the ID of the organization the user joined first, or
+// 0 if they belong to none
+func (s *Service) DefaultOrg(ctx context.Context, userID int64) (int64, error) {
+	var orgID int64
+	err := s.db.QueryRowContext(ctx,
+		`SELECT org_id FROM memberships WHERE user_id = $1 ORDER BY created_at, org_id LIMIT 1`,
+		userID,
+	).Scan(&orgID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return orgID, err
+}
+
+// ResolveTenant finds the organization referenced by ref, a slug or numeric
+// ID, and checks that the user is a member of it
+func (s *Service) ResolveTenant(ctx context.Context, ref string, userID int64) (tenant.Tenant, error) {
+	var org *Organization
+	var err error
+	if id, convErr := strconv.ParseInt(ref, 10, 64); convErr == nil {
+		org, err = s.GetByID(ctx, id)
+	} else {
+		org, err = s.GetBySlug(ctx, strings.ToLower(ref))
+	}
+	if err != nil {
+		return tenant.Tenant{}, err
+	}
+
+	m, err := s.Membership(ctx, org.ID, userID)
+	if err != nil {
+		return tenant.Tenant{}, err
+	}
+	return tenant.Tenant{OrgID: org.ID, Slug: org.Slug, Role: m.Role}, nil
+}
+
+// ListMembers returns the members of the organization
+func (s *Service) ListMembers(ctx context.Context, orgID int64) ([]Membership, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT org_id, user_id, role, created_at FROM memberships WHERE org_id = $1 ORDER BY created_at, user_id`,
+		orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []Membership{}
+	for rows.Next() {
+		var m Membership
+		if err := rows.Scan(&m.OrgID, &m.UserID, &m.Role, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, m)
+	}
+	return list, rows.Err()
+}
+
+// Invite creates an invitation on behalf of actorID, who must be an owner
+// or admin. It returns the invitation together with the secret token to
+// send to the invitee; only a hash of the token is stored.
+func (s *Service) Invite(ctx context.Context, orgID, actorID int64, email, role string) (*Invitation, string, error) {
+	if err := s.requireManager(ctx, orgID, actorID); err != nil {
+		return nil, "", err
+	}
+	if !ValidRole(role) {
+		return nil, "", ErrInvalidRole
+	}
+	if role == RoleOwner {
+		if err := s.requireRole(ctx, orgID, actorID, RoleOwner); err != nil {
+			return nil, "", err
+		}
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return nil, "", err
+	}
+	now := s.now()
+	inv := &Invitation{
+		OrgID:     orgID,
+		Email:     strings.ToLower(strings.TrimSpace(email)),
+		Role:      role,
+		InvitedBy: actorID,
+		ExpiresAt: now.Add(s.invitationTTL),
+		CreatedAt: now,
+	}
+	err = s.db.QueryRowContext(ctx,
+		`INSERT INTO invitations (org_id, email, role, token_hash, invited_by, expires_at, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+		inv.OrgID, inv.Email, inv.Role, hashToken(token), inv.InvitedBy, inv.ExpiresAt, inv.CreatedAt,
+	).Scan(&inv.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	return inv, token, nil
+}
+
+// AcceptInvitation adds the user to the organization the token invites them
+// to. Holding the token is what proves the user is the invitee: it was only
+// sent to the invited address, while account emails are not verified. The
+// user's email must still match the invitation, so that a forwarded link
+// does not let someone else join.
+func (s *Service) AcceptInvitation(ctx context.Context, token string, userID int64, email string) (*Membership, error) {
+	var inv Invitation
+	var acceptedAt sql.NullTime
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id, org_id, email, role, expires_at, accepted_at FROM invitations WHERE token_hash = $1`,
+		hashToken(token),
+	).Scan(&inv.ID, &inv.OrgID, &inv.Email, &inv.Role, &inv.ExpiresAt, &acceptedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvitationInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	if acceptedAt.Valid || !now.Before(inv.ExpiresAt) {
+		return nil, ErrInvitationInvalid
+	}
+	if !strings.EqualFold(inv.Email, strings.TrimSpace(email)) {
+		return nil, ErrEmailMismatch
+	}
+	if _, err := s.Membership(ctx, inv.OrgID, userID); err == nil {
+		return nil, ErrAlreadyMember
+	} else if !errors.Is(err, ErrNotMember) {
+		return nil, err
+	}
+
+	m := &Membership{OrgID: inv.OrgID, UserID: userID, Role: inv.Role, CreatedAt: now}
+	err = s.db.WithTx(ctx, func(tx *sql.Tx) error {
+		// Claiming the invitation first makes a concurrent accept of the
+		// same token wait for this one and then find it used
+		res, err := tx.ExecContext(ctx,
+			`UPDATE invitations SET accepted_at = $1 WHERE id = $2 AND accepted_at IS NULL`, now, inv.ID)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrInvitationInvalid
+		}
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO memberships (org_id, user_id, role, created_at) VALUES ($1, $2, $3, $4)`,
+			m.OrgID, m.UserID, m.Role, m.CreatedAt)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// UpdateRole changes a member's role on behalf of actorID. Only owners can
+// grant or revoke ownership.
+func (s *Service) UpdateRole(ctx context.Context, orgID, actorID, userID int64, role string) error {
+	if !ValidRole(role) {
+		return ErrInvalidRole
+	}
+	if err := s.requireManager(ctx, orgID, actorID); err != nil {
+		return err
+	}
+	current, err := s.Membership(ctx, orgID, userID)
+	if err != nil {
+		return err
+	}
+	if role == RoleOwner || current.Role == RoleOwner {
+		if err := s.requireRole(ctx, orgID, actorID, RoleOwner); err != nil {
+			return err
+		}
+	}
+	if current.Role == RoleOwner && role != RoleOwner {
+		if err := s.ensureAnotherOwner(ctx, orgID); err != nil {
+			return err
+		}
+	}
+	_, err = s.db.ExecContext(ctx,
+		`UPDATE memberships SET role = $1 WHERE org_id = $2 AND user_id = $3`, role, orgID, userID)
+	return err
+}
+
+// RemoveMember removes a user from the organization on behalf of actorID.
+// Members may always remove themselves.
+func (s *Service) RemoveMember(ctx context.Context, orgID, actorID, userID int64) error {
+	if actorID != userID {
+		if err := s.requireManager(ctx, orgID, actorID); err != nil {
+			return err
+		}
+	}
+	current, err := s.Membership(ctx, orgID, userID)
+	if err != nil {
+		return err
+	}
+	if current.Role == RoleOwner {
+		if actorID != userID {
+			if err := s.requireRole(ctx, orgID, actorID, RoleOwner); err != nil {
+				return err
+			}
+		}
+		if err := s.ensureAnotherOwner(ctx, orgID); err != nil {
+			return err
+		}
+	}
+	_, err = s.db.ExecContext(ctx, `DELETE FROM memberships WHERE org_id = $1 AND user_id = $2`, orgID, userID)
+	return err
+}
+
+func (s *Service) requireManager(ctx context.Context, orgID, userID int64) error {
+	m, err := s.Membership(ctx, orgID, userID)
+	if err != nil {
+		return err
+	}
+	if !CanManage(m.Role) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func (s *Service) requireRole(ctx context.Context, orgID, userID int64, role string) error {
+	m, err := s.Membership(ctx, orgID, userID)
+	if err != nil {
+		return err
+	}
+	if m.Role != role {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func (s *Service) ensureAnotherOwner(ctx context.Context, orgID int64) error {
+	var owners int
+	err := s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM memberships WHERE org_id = $1 AND role = $2`, orgID, RoleOwner,
+	).Scan(&owners)
+	if err != nil {
+		return err
+	}
+	if owners < 2 {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
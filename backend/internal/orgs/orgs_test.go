@@ -0,0 +1,174 @@
+package orgs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database/dbtest"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/users"
+)
+
+type fixture struct {
+	service *Service
+	users   map[string]*users.User
+}
+
+func newFixture(t *testing.T, emails ...string) *fixture {
+	t.Helper()
+	db := dbtest.New(t)
+	repo := users.NewRepository(db)
+	f := &fixture{service: NewService(db, time.Hour), users: map[string]*users.User{}}
+	for _, email := range emails {
+		u := &users.User{Email: email}
+		if err := repo.Create(context.Background(), u); err != nil {
+			t.Fatalf("Create user: %v", err)
+		}
+		f.users[email] = u
+	}
+	return f
+}
+
+func TestCreateValidation(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, "owner@example.com")
+	owner := f.users["owner@example.com"].ID
+
+	if _, err := f.service.Create(ctx, "", "clinic", owner); !errors.Is(err, ErrInvalidName) {
+		t.Errorf("Expected ErrInvalidName, got %v", err)
+	}
+	if _, err := f.service.Create(ctx, "Clinic", "Bad Slug!", owner); !errors.Is(err, ErrInvalidSlug) {
+		t.Errorf("Expected ErrInvalidSlug, got %v", err)
+	}
+
+	org, err := f.service.Create(ctx, "North Clinic", "north-clinic", owner)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := f.service.Create(ctx, "Other", "north-clinic", owner); !errors.Is(err, ErrSlugTaken) {
+		t.Errorf("Expected ErrSlugTaken, got %v", err)
+	}
+
+	m, err := f.service.Membership(ctx, org.ID, owner)
+	if err != nil || m.Role != RoleOwner {
+		t.Errorf("Expected creator to be owner, got %+v, %v", m, err)
+	}
+}
+
+func TestInvitationFlow(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, "owner@example.com", "bob@example.com", "eve@example.com")
+	owner, bob, eve := f.users["owner@example.com"], f.users["bob@example.com"], f.users["eve@example.com"]
+
+	org, _ := f.service.Create(ctx, "Cohort", "cohort-1", owner.ID)
+
+	if _, _, err := f.service.Invite(ctx, org.ID, eve.ID, "x@example.com", RoleMember); !errors.Is(err, ErrNotMember) {
+		t.Errorf("Expected outsiders to be unable to invite, got %v", err)
+	}
+
+	inv, token, err := f.service.Invite(ctx, org.ID, owner.ID, "Bob@Example.com", RoleMember)
+	if err != nil {
+		t.Fatalf("Invite failed: %v", err)
+	}
+	if inv.Email != "bob@example.com" || token == "" {
+		t.Errorf("Unexpected invitation %+v / token %q", inv, token)
+	}
+
+	if _, err := f.service.AcceptInvitation(ctx, token, eve.ID, eve.Email); !errors.Is(err, ErrEmailMismatch) {
+		t.Errorf("Expected ErrEmailMismatch for someone else, got %v", err)
+	}
+	if _, err := f.service.AcceptInvitation(ctx, "bogus", bob.ID, bob.Email); !errors.Is(err, ErrInvitationInvalid) {
+		t.Errorf("Expected ErrInvitationInvalid for unknown token, got %v", err)
+	}
+
+	m, err := f.service.AcceptInvitation(ctx, token, bob.ID, bob.Email)
+	if err != nil {
+		t.Fatalf("AcceptInvitation failed: %v", err)
+	}
+	if m.Role != RoleMember {
+		t.Errorf("Expected member role, got %s", m.Role)
+	}
+	if _, err := f.service.AcceptInvitation(ctx, token, bob.ID, bob.Email); !errors.Is(err, ErrInvitationInvalid) {
+		t.Errorf("Expected tokens to be single-use, got %v", err)
+	}
+
+	// Members cannot invite or promote
+	if _, _, err := f.service.Invite(ctx, org.ID, bob.ID, "eve@example.com", RoleMember); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Expected ErrForbidden for member inviting, got %v", err)
+	}
+}
+
+func TestExpiredInvitation(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, "owner@example.com", "bob@example.com")
+	owner, bob := f.users["owner@example.com"], f.users["bob@example.com"]
+	org, _ := f.service.Create(ctx, "Cohort", "cohort-1", owner.ID)
+
+	_, token, _ := f.service.Invite(ctx, org.ID, owner.ID, bob.Email, RoleMember)
+	f.service.now = func() time.Time { return time.Now().UTC().Add(2 * time.Hour) }
+
+	if _, err := f.service.AcceptInvitation(ctx, token, bob.ID, bob.Email); !errors.Is(err, ErrInvitationInvalid) {
+		t.Errorf("Expected expired invitation to be rejected, got %v", err)
+	}
+}
+
+func TestRolesAndLastOwner(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, "owner@example.com", "admin@example.com", "member@example.com")
+	owner, admin, member := f.users["owner@example.com"], f.users["admin@example.com"], f.users["member@example.com"]
+	org, _ := f.service.Create(ctx, "Clinic", "clinic", owner.ID)
+
+	join := func(u *users.User, role string) {
+		_, token, err := f.service.Invite(ctx, org.ID, owner.ID, u.Email, role)
+		if err != nil {
+			t.Fatalf("Invite %s: %v", u.Email, err)
+		}
+		if _, err := f.service.AcceptInvitation(ctx, token, u.ID, u.Email); err != nil {
+			t.Fatalf("Accept %s: %v", u.Email, err)
+		}
+	}
+	join(admin, RoleAdmin)
+	join(member, RoleMember)
+
+	if _, _, err := f.service.Invite(ctx, org.ID, admin.ID, "new@example.com", RoleOwner); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Expected admins to be unable to invite owners, got %v", err)
+	}
+	if err := f.service.UpdateRole(ctx, org.ID, admin.ID, owner.ID, RoleMember); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Expected admins to be unable to demote owners, got %v", err)
+	}
+	if err := f.service.UpdateRole(ctx, org.ID, owner.ID, owner.ID, RoleMember); !errors.Is(err, ErrLastOwner) {
+		t.Errorf("Expected ErrLastOwner, got %v", err)
+	}
+	if err := f.service.RemoveMember(ctx, org.ID, owner.ID, owner.ID); !errors.Is(err, ErrLastOwner) {
+		t.Errorf("Expected the last owner to be unable to leave, got %v", err)
+	}
+
+	if err := f.service.UpdateRole(ctx, org.ID, admin.ID, member.ID, RoleAdmin); err != nil {
+		t.Errorf("Expected admin to promote member, got %v", err)
+	}
+	if err := f.service.RemoveMember(ctx, org.ID, member.ID, member.ID); err != nil {
+		t.Errorf("Expected member to be able to leave, got %v", err)
+	}
+	if _, err := f.service.Membership(ctx, org.ID, member.ID); !errors.Is(err, ErrNotMember) {
+		t.Errorf("Expected membership to be gone, got %v", err)
+	}
+}
+
+func TestResolveTenant(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, "a@example.com", "b@example.com")
+	a, b := f.users["a@example.com"], f.users["b@example.com"]
+	org, _ := f.service.Create(ctx, "Team A", "team-a", a.ID)
+
+	tn, err := f.service.ResolveTenant(ctx, "TEAM-A", a.ID)
+	if err != nil || tn.OrgID != org.ID || tn.Role != RoleOwner {
+		t.Errorf("Expected to resolve by slug, got %+v, %v", tn, err)
+	}
+	if _, err := f.service.ResolveTenant(ctx, "team-a", b.ID); !errors.Is(err, ErrNotMember) {
+		t.Errorf("Expected ErrNotMember for outsider, got %v", err)
+	}
+	if _, err := f.service.ResolveTenant(ctx, "nope", a.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
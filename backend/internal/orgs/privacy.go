@@ -0,0 +1,62 @@
+package orgs
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/privacy"
+)
+
+// PrivacyModule exports and erases organization memberships
+type PrivacyModule struct {
+	db *database.DB
+}
+
+// NewPrivacyModule creates the organizations privacy module
+func NewPrivacyModule(db *database.DB) *PrivacyModule {
+	return &PrivacyModule{db: db}
+}
+
+// Name implements privacy.Module
+func (m *PrivacyModule) Name() string {
+	return "organizations"
+}
+
+// Export implements privacy.Module
+func (m *PrivacyModule) Export(ctx context.Context, userID int64) ([]privacy.Dataset, error) {
+	rows, err := m.db.QueryContext(ctx,
+		`SELECT o.id, o.name, o.slug, m.role, m.created_at
+		 FROM memberships m JOIN organizations o ON o.id = m.org_id
+		 WHERE m.user_id = $1 ORDER BY o.id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ds := privacy.Dataset{Name: "memberships", Columns: []string{"org_id", "name", "slug", "role", "joined_at"}}
+	for rows.Next() {
+		var (
+			orgID      int64
+			name, slug string
+			role       string
+			joined     sql.NullTime
+		)
+		if err := rows.Scan(&orgID, &name, &slug, &role, &joined); err != nil {
+			return nil, err
+		}
+		ds.Rows = append(ds.Rows, []any{orgID, name, slug, role, joined.Time})
+	}
+	return []privacy.Dataset{ds}, rows.Err()
+}
+
+// Delete implements privacy.Module. Memberships tie the account to a tenant,
+// so they are removed in both modes, as are invitations sent to the user.
+func (m *PrivacyModule) Delete(ctx context.Context, tx *sql.Tx, userID int64, mode privacy.DeleteMode) error {
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM invitations WHERE email = (SELECT email FROM users WHERE id = $1)`, userID); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `DELETE FROM memberships WHERE user_id = $1`, userID)
+	return err
+}
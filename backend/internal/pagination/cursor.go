@@ -0,0 +1,138 @@
+package pagination
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Codec signs and verifies cursors
+type Codec struct {
+	key []byte
+}
+
+// NewCodec creates a codec signing cursors with secret
+func NewCodec(secret string) *Codec {
+	return &Codec{key: []byte(secret)}
+}
+
+// cursorPayload is the signed content of a cursor: the sort values of the
+// last row of a page and a fingerprint of the query that produced it
+type cursorPayload struct {
+	Query  string `json:"q"`
+	Values []any  `json:"v"`
+}
+
+func (cd *Codec) encode(q *Query, values []any) string {
+	for i, v := range values {
+		if t, ok := v.(time.Time); ok {
+			values[i] = t.UTC().Format(time.RFC3339Nano)
+		}
+	}
+	payload, _ := json.Marshal(cursorPayload{Query: q.fingerprint(), Values: values})
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(cd.sign(payload))
+}
+
+func (cd *Codec) decode(q *Query, raw string) ([]any, error) {
+	encPayload, encSig, ok := strings.Cut(raw, ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encPayload)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encSig)
+	if err != nil || !hmac.Equal(sig, cd.sign(payload)) {
+		return nil, ErrInvalidCursor
+	}
+
+	var c cursorPayload
+	if err := json.Unmarshal(payload, &c); err != nil || c.Query != q.fingerprint() || len(c.Values) != len(q.Sort) {
+		return nil, ErrInvalidCursor
+	}
+
+	values := make([]any, len(c.Values))
+	for i, s := range q.Sort {
+		v, err := cursorValue(q.resource.Fields[s.Field].Type, c.Values[i])
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func cursorValue(t Type, v any) (any, error) {
+	switch t {
+	case Int:
+		if n, ok := v.(float64); ok && n == float64(int64(n)) {
+			return int64(n), nil
+		}
+	case Bool:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	case Time:
+		if s, ok := v.(string); ok {
+			return time.Parse(time.RFC3339Nano, s)
+		}
+	default:
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+	}
+	return nil, ErrInvalidCursor
+}
+
+func (cd *Codec) sign(payload []byte) []byte {
+	h := hmac.New(sha256.New, cd.key)
+	h.Write([]byte("cursor:"))
+	h.Write(payload)
+	return h.Sum(nil)
+}
+
+// fingerprint identifies the resource, sort and filters of a query, so a
+// cursor only continues the listing it was issued for
+func (q *Query) fingerprint() string {
+	var b strings.Builder
+	b.WriteString(q.resource.Name)
+	for _, s := range q.Sort {
+		if s.Desc {
+			b.WriteString("|-" + s.Field)
+		} else {
+			b.WriteString("|" + s.Field)
+		}
+	}
+	for _, f := range q.Filters {
+		fmt.Fprintf(&b, "|%s:%s=%v", f.Field, f.Op, f.Values)
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
+
+// SetLinks adds an RFC 8288 Link header pointing to the first page and, if
+// there is one, the next page of the listing at u
+func SetLinks(h http.Header, u *url.URL, nextCursor string) {
+	link := func(cursor, rel string) string {
+		values := u.Query()
+		values.Del("cursor")
+		if cursor != "" {
+			values.Set("cursor", cursor)
+		}
+		target := url.URL{Path: u.Path, RawQuery: values.Encode()}
+		return "<" + target.String() + `>; rel="` + rel + `"`
+	}
+
+	links := []string{link("", "first")}
+	if nextCursor != "" {
+		links = append(links, link(nextCursor, "next"))
+	}
+	h.Set("Link", strings.Join(links, ", "))
+}
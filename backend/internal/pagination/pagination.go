@@ -0,0 +1,347 @@
+// Package pagination parses the list parameters shared by all collection
+// endpoints and turns them into SQL.
+//
+// A request may carry
+//
+//	limit=20
+//	sort=-created_at,title
+//	filter[title][contains]=run
+//	filter[created_at][gte]=2025-01-01
+//	cursor=<opaque>
+//
+// Only fields whitelisted in the endpoint's Resource can be sorted and
+// filtered on, and only with the operators listed there; values are always
+// bound as arguments and column names come from the whitelist, never from
+// the request. Pages are walked with keyset cursors that are signed, so
+// clients cannot forge them, and bound to the sort and filters they were
+// issued for.
+package pagination
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Predefined errors
+var (
+	ErrInvalidLimit  = errors.New("invalid limit")
+	ErrInvalidSort   = errors.New("invalid sort")
+	ErrInvalidFilter = errors.New("invalid filter")
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// Type is the type of a field's values
+type Type int
+
+// Field types
+const (
+	String Type = iota
+	Int
+	Time
+	Bool
+)
+
+// Op is a filter operator
+type Op string
+
+// Filter operators
+const (
+	OpEq       Op = "eq"
+	OpNe       Op = "ne"
+	OpLt       Op = "lt"
+	OpLte      Op = "lte"
+	OpGt       Op = "gt"
+	OpGte      Op = "gte"
+	OpIn       Op = "in"
+	OpContains Op = "contains"
+)
+
+var opSQL = map[Op]string{
+	OpEq:  "=",
+	OpNe:  "<>",
+	OpLt:  "<",
+	OpLte: "<=",
+	OpGt:  ">",
+	OpGte: ">=",
+}
+
+// maxInValues bounds the number of values of an "in" filter
+const maxInValues = 50
+
+// Field is a whitelisted field of a resource. Sortable fields must not be
+// nullable.
+type Field struct {
+	Column   string
+	Type     Type
+	Sortable bool
+	Filters  []Op
+}
+
+// Resource describes what a list endpoint allows
+type Resource struct {
+	// Name is bound into cursors so they cannot be replayed elsewhere
+	Name   string
+	Fields map[string]Field
+	// Key names a unique sortable field appended to every sort as a
+	// tie-breaker, usually "id"
+	Key string
+	// DefaultSort is used when the request has no sort, e.g. "-created_at"
+	DefaultSort  string
+	DefaultLimit int
+	MaxLimit     int
+}
+
+// Sort is one sort term
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+// Filter is one filter condition
+type Filter struct {
+	Field  string
+	Op     Op
+	Values []any
+}
+
+// Query is a parsed list request
+type Query struct {
+	Limit   int
+	Sort    []Sort
+	Filters []Filter
+
+	resource *Resource
+	codec    *Codec
+	after    []any
+}
+
+// Parse validates list parameters against the resource's whitelist
+func (cd *Codec) Parse(r *Resource, values url.Values) (*Query, error) {
+	q := &Query{Limit: r.DefaultLimit, resource: r, codec: cd}
+
+	if raw := values.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			return nil, ErrInvalidLimit
+		}
+		q.Limit = n
+	}
+	if r.MaxLimit > 0 {
+		q.Limit = min(q.Limit, r.MaxLimit)
+	}
+
+	sort := values.Get("sort")
+	if sort == "" {
+		sort = r.DefaultSort
+	}
+	if err := q.parseSort(sort); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		if !strings.HasPrefix(key, "filter[") {
+			continue
+		}
+		if err := q.parseFilter(key, values.Get(key)); err != nil {
+			return nil, err
+		}
+	}
+
+	if raw := values.Get("cursor"); raw != "" {
+		after, err := cd.decode(q, raw)
+		if err != nil {
+			return nil, err
+		}
+		q.after = after
+	}
+	return q, nil
+}
+
+func (q *Query) parseSort(raw string) error {
+	seen := map[string]bool{}
+	for _, term := range strings.Split(raw, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		s := Sort{Field: term}
+		if rest, ok := strings.CutPrefix(term, "-"); ok {
+			s = Sort{Field: rest, Desc: true}
+		} else {
+			s.Field = strings.TrimPrefix(term, "+")
+		}
+		f, ok := q.resource.Fields[s.Field]
+		if !ok || !f.Sortable || seen[s.Field] {
+			return fmt.Errorf("%w: cannot sort by %q", ErrInvalidSort, s.Field)
+		}
+		seen[s.Field] = true
+		q.Sort = append(q.Sort, s)
+	}
+
+	// The key makes the order total, which keyset cursors rely on
+	if key := q.resource.Key; !seen[key] {
+		desc := len(q.Sort) > 0 && q.Sort[len(q.Sort)-1].Desc
+		q.Sort = append(q.Sort, Sort{Field: key, Desc: desc})
+	}
+	return nil
+}
+
+// parseFilter handles filter[field]=value and filter[field][op]=value
+func (q *Query) parseFilter(key, raw string) error {
+	spec := strings.TrimPrefix(key, "filter[")
+	name, rest, ok := strings.Cut(spec, "]")
+	if !ok {
+		return fmt.Errorf("%w: malformed parameter %q", ErrInvalidFilter, key)
+	}
+	op := OpEq
+	if rest != "" {
+		inner, ok := strings.CutPrefix(rest, "[")
+		if !ok || !strings.HasSuffix(inner, "]") {
+			return fmt.Errorf("%w: malformed parameter %q", ErrInvalidFilter, key)
+		}
+		op = Op(strings.TrimSuffix(inner, "]"))
+	}
+
+	f, ok := q.resource.Fields[name]
+	if !ok || !slices.Contains(f.Filters, op) {
+		return fmt.Errorf("%w: cannot filter %q with %q", ErrInvalidFilter, name, op)
+	}
+
+	raws := []string{raw}
+	if op == OpIn {
+		raws = strings.Split(raw, ",")
+		if len(raws) > maxInValues {
+			return fmt.Errorf("%w: too many values for %q", ErrInvalidFilter, name)
+		}
+	}
+	filter := Filter{Field: name, Op: op}
+	for _, r := range raws {
+		v, err := parseValue(f.Type, r)
+		if err != nil || (op == OpContains && f.Type != String) {
+			return fmt.Errorf("%w: bad value for %q", ErrInvalidFilter, name)
+		}
+		filter.Values = append(filter.Values, v)
+	}
+	q.Filters = append(q.Filters, filter)
+	return nil
+}
+
+func parseValue(t Type, raw string) (any, error) {
+	switch t {
+	case Int:
+		return strconv.ParseInt(raw, 10, 64)
+	case Bool:
+		return strconv.ParseBool(raw)
+	case Time:
+		if ts, err := time.Parse(time.RFC3339Nano, raw); err == nil {
+			return ts.UTC(), nil
+		}
+		return time.Parse(time.DateOnly, raw)
+	default:
+		return raw, nil
+	}
+}
+
+// Apply appends the filters, the cursor condition, the order and the limit
+// to base, which must be a SELECT ending in a WHERE clause. Placeholders
+// continue after args. One row more than the limit is fetched so that
+// Paginate can tell whether there is a next page.
+func (q *Query) Apply(base string, args []any) (string, []any) {
+	var b strings.Builder
+	b.WriteString(base)
+
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	for _, f := range q.Filters {
+		col := q.resource.Fields[f.Field].Column
+		b.WriteString(" AND ")
+		switch f.Op {
+		case OpIn:
+			holders := make([]string, len(f.Values))
+			for i, v := range f.Values {
+				holders[i] = arg(v)
+			}
+			b.WriteString(col + " IN (" + strings.Join(holders, ", ") + ")")
+		case OpContains:
+			b.WriteString("LOWER(" + col + ") LIKE " + arg("%"+escapeLike(strings.ToLower(f.Values[0].(string)))+"%") + ` ESCAPE '\'`)
+		default:
+			b.WriteString(col + " " + opSQL[f.Op] + " " + arg(f.Values[0]))
+		}
+	}
+
+	// (a, b, id) after (x, y, z) in mixed directions expands to
+	// a > x OR (a = x AND b < y) OR (a = x AND b = y AND id > z)
+	if q.after != nil {
+		var alts []string
+		for i, s := range q.Sort {
+			var terms []string
+			for j, prev := range q.Sort[:i] {
+				terms = append(terms, q.resource.Fields[prev.Field].Column+" = "+arg(q.after[j]))
+			}
+			cmp := " > "
+			if s.Desc {
+				cmp = " < "
+			}
+			terms = append(terms, q.resource.Fields[s.Field].Column+cmp+arg(q.after[i]))
+			alts = append(alts, "("+strings.Join(terms, " AND ")+")")
+		}
+		b.WriteString(" AND (" + strings.Join(alts, " OR ") + ")")
+	}
+
+	order := make([]string, len(q.Sort))
+	for i, s := range q.Sort {
+		order[i] = q.resource.Fields[s.Field].Column
+		if s.Desc {
+			order[i] += " DESC"
+		}
+	}
+	b.WriteString(" ORDER BY " + strings.Join(order, ", "))
+	b.WriteString(" LIMIT " + arg(q.Limit+1))
+	return b.String(), args
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// Page is the uniform list response envelope
+type Page[T any] struct {
+	Data       []T    `json:"data"`
+	NextCursor string `json:"next_cursor"`
+	HasMore    bool   `json:"has_more"`
+}
+
+// Paginate trims the rows fetched with Apply to the limit and issues the
+// cursor for the next page. value returns an item's value of a field.
+func Paginate[T any](q *Query, items []T, value func(item T, field string) any) Page[T] {
+	page := Page[T]{Data: items}
+	if page.Data == nil {
+		page.Data = []T{}
+	}
+	if len(items) <= q.Limit {
+		return page
+	}
+
+	page.Data = items[:q.Limit]
+	page.HasMore = true
+	last := page.Data[q.Limit-1]
+	values := make([]any, len(q.Sort))
+	for i, s := range q.Sort {
+		values[i] = value(last, s.Field)
+	}
+	page.NextCursor = q.codec.encode(q, values)
+	return page
+}
@@ -0,0 +1,179 @@
+package pagination
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var notes = &Resource{
+	Name: "notes",
+	Fields: map[string]Field{
+		"id":         {Column: "id", Type: Int, Sortable: true, Filters: []Op{OpEq, OpIn}},
+		"title":      {Column: "title", Type: String, Sortable: true, Filters: []Op{OpEq, OpContains}},
+		"pinned":     {Column: "pinned", Type: Bool, Filters: []Op{OpEq}},
+		"created_at": {Column: "n.created_at", Type: Time, Sortable: true, Filters: []Op{OpGte, OpLt}},
+	},
+	Key:          "id",
+	DefaultSort:  "-created_at",
+	DefaultLimit: 10,
+	MaxLimit:     50,
+}
+
+func parse(t *testing.T, cd *Codec, query string) (*Query, error) {
+	t.Helper()
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		t.Fatalf("ParseQuery: %v", err)
+	}
+	return cd.Parse(notes, values)
+}
+
+func TestParseDefaults(t *testing.T) {
+	q, err := parse(t, NewCodec("secret"), "")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if q.Limit != 10 {
+		t.Errorf("Expected default limit 10, got %d", q.Limit)
+	}
+	want := []Sort{{Field: "created_at", Desc: true}, {Field: "id", Desc: true}}
+	if !reflect.DeepEqual(q.Sort, want) {
+		t.Errorf("Expected sort %v, got %v", want, q.Sort)
+	}
+
+	q, _ = parse(t, NewCodec("secret"), "limit=500&sort=title")
+	if q.Limit != 50 {
+		t.Errorf("Expected limit capped at 50, got %d", q.Limit)
+	}
+	want = []Sort{{Field: "title"}, {Field: "id"}}
+	if !reflect.DeepEqual(q.Sort, want) {
+		t.Errorf("Expected key appended ascending, got %v", q.Sort)
+	}
+}
+
+func TestParseRejectsUnlisted(t *testing.T) {
+	cd := NewCodec("secret")
+	tests := []struct {
+		query string
+		err   error
+	}{
+		{"limit=0", ErrInvalidLimit},
+		{"limit=ten", ErrInvalidLimit},
+		{"sort=password_hash", ErrInvalidSort},
+		{"sort=pinned", ErrInvalidSort},
+		{"sort=title,-title", ErrInvalidSort},
+		{"filter[password_hash]=x", ErrInvalidFilter},
+		{"filter[title][lt]=x", ErrInvalidFilter},
+		{"filter[id]=abc", ErrInvalidFilter},
+		{"filter[created_at][gte]=yesterday", ErrInvalidFilter},
+		{"filter[title][eq", ErrInvalidFilter},
+		{"cursor=garbage", ErrInvalidCursor},
+	}
+	for _, tt := range tests {
+		if _, err := parse(t, cd, tt.query); !errors.Is(err, tt.err) {
+			t.Errorf("%s: expected %v, got %v", tt.query, tt.err, err)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	q, err := parse(t, NewCodec("secret"),
+		"filter[title][contains]=50%25_off&filter[id][in]=1,2,3&filter[pinned]=true&filter[created_at][gte]=2025-01-01&sort=title")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	sql, args := q.Apply("SELECT * FROM notes n WHERE user_id = $1", []any{int64(7)})
+	want := "SELECT * FROM notes n WHERE user_id = $1" +
+		" AND n.created_at >= $2" +
+		" AND id IN ($3, $4, $5)" +
+		" AND pinned = $6" +
+		` AND LOWER(title) LIKE $7 ESCAPE '\'` +
+		" ORDER BY title, id LIMIT $8"
+	if sql != want {
+		t.Errorf("Unexpected SQL\n got: %s\nwant: %s", sql, want)
+	}
+	wantArgs := []any{int64(7), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		int64(1), int64(2), int64(3), true, `%50\%\_off%`, 11}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("Unexpected args\n got: %v\nwant: %v", args, wantArgs)
+	}
+}
+
+type note struct {
+	ID        int64
+	Title     string
+	CreatedAt time.Time
+}
+
+func noteValue(n note, field string) any {
+	switch field {
+	case "id":
+		return n.ID
+	case "title":
+		return n.Title
+	case "created_at":
+		return n.CreatedAt
+	}
+	return nil
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	cd := NewCodec("secret")
+	q, _ := parse(t, cd, "limit=2&sort=-created_at")
+	ts := time.Date(2025, 3, 1, 10, 0, 0, 123456789, time.UTC)
+	items := []note{{3, "c", ts.Add(time.Hour)}, {2, "b", ts}, {1, "a", ts.Add(-time.Hour)}}
+
+	page := Paginate(q, items, noteValue)
+	if len(page.Data) != 2 || !page.HasMore || page.NextCursor == "" {
+		t.Fatalf("Expected a trimmed page with a cursor, got %+v", page)
+	}
+
+	next, err := parse(t, cd, "limit=2&sort=-created_at&cursor="+url.QueryEscape(page.NextCursor))
+	if err != nil {
+		t.Fatalf("Parse with cursor failed: %v", err)
+	}
+	if !reflect.DeepEqual(next.after, []any{ts, int64(2)}) {
+		t.Errorf("Expected cursor values of the last row, got %v", next.after)
+	}
+	sql, _ := next.Apply("SELECT * FROM notes n WHERE true", nil)
+	if !strings.Contains(sql, "AND ((n.created_at < $1) OR (n.created_at = $2 AND id < $3))") {
+		t.Errorf("Unexpected keyset condition in %s", sql)
+	}
+
+	// Cursors are bound to their query and signed
+	if _, err := parse(t, cd, "limit=2&sort=title&cursor="+url.QueryEscape(page.NextCursor)); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Expected cursor rejected for another sort, got %v", err)
+	}
+	if _, err := parse(t, NewCodec("other"), "limit=2&sort=-created_at&cursor="+url.QueryEscape(page.NextCursor)); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Expected cursor rejected with another key, got %v", err)
+	}
+	tampered := "e30" + page.NextCursor[strings.Index(page.NextCursor, "."):]
+	if _, err := parse(t, cd, "cursor="+url.QueryEscape(tampered)); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Expected tampered cursor rejected, got %v", err)
+	}
+
+	last := Paginate(q, items[:1], noteValue)
+	if last.HasMore || last.NextCursor != "" {
+		t.Errorf("Expected last page without cursor, got %+v", last)
+	}
+	if empty := Paginate(q, []note(nil), noteValue); empty.Data == nil {
+		t.Errorf("Expected empty data to encode as []")
+	}
+}
+
+func TestSetLinks(t *testing.T) {
+	h := http.Header{}
+	u, _ := url.Parse("/api/v2/notes?limit=2&cursor=old&sort=title")
+	SetLinks(h, u, "abc")
+
+	want := `</api/v2/notes?limit=2&sort=title>; rel="first", </api/v2/notes?cursor=abc&limit=2&sort=title>; rel="next"`
+	if got := h.Get("Link"); got != want {
+		t.Errorf("Unexpected Link header\n got: %s\nwant: %s", got, want)
+	}
+}
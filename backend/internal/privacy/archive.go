@@ -0,0 +1,129 @@
+package privacy
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// manifest describes the contents of an export archive
+type manifest struct {
+	UserID      int64            `json:"user_id"`
+	GeneratedAt time.Time        `json:"generated_at"`
+	Files       []manifestRecord `json:"files"`
+}
+
+type manifestRecord struct {
+	Module  string `json:"module"`
+	Dataset string `json:"dataset"`
+	Rows    int    `json:"rows"`
+}
+
+// buildArchive collects every module's datasets into a zip containing a JSON
+// and a CSV file per dataset plus a manifest.json
+func buildArchive(ctx context.Context, modules []Module, userID int64, now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	m := manifest{UserID: userID, GeneratedAt: now}
+	for _, module := range modules {
+		datasets, err := module.Export(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("export %s: %w", module.Name(), err)
+		}
+		for _, ds := range datasets {
+			base := module.Name() + "/" + ds.Name
+			if err := writeJSON(zw, base+".json", ds); err != nil {
+				return nil, err
+			}
+			if err := writeCSV(zw, base+".csv", ds); err != nil {
+				return nil, err
+			}
+			m.Files = append(m.Files, manifestRecord{Module: module.Name(), Dataset: ds.Name, Rows: len(ds.Rows)})
+		}
+	}
+
+	w, err := zw.Create("manifest.json")
+	if err != nil {
+		return nil, err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(m); err != nil {
+		return nil, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeJSON writes the dataset as an array of objects keyed by column name
+func writeJSON(zw *zip.Writer, name string, ds Dataset) error {
+	records := make([]map[string]any, 0, len(ds.Rows))
+	for _, row := range ds.Rows {
+		record := make(map[string]any, len(ds.Columns))
+		for i, column := range ds.Columns {
+			if i < len(row) {
+				record[column] = row[i]
+			}
+		}
+		records = append(records, record)
+	}
+
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(records)
+}
+
+// writeCSV writes the dataset with a header row
+func writeCSV(zw *zip.Writer, name string, ds Dataset) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(ds.Columns); err != nil {
+		return err
+	}
+	for _, row := range ds.Rows {
+		record := make([]string, len(ds.Columns))
+		for i := range ds.Columns {
+			if i < len(row) {
+				record[i] = formatCell(row[i])
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatCell(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return val.UTC().Format(time.RFC3339)
+	case *time.Time:
+		if val == nil {
+			return ""
+		}
+		return val.UTC().Format(time.RFC3339)
+	case []byte:
+		return string(val)
+	default:
+		return fmt.Sprint(val)
+	}
+}
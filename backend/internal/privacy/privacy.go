@@ -0,0 +1,78 @@
+// Package privacy implements the user-data lifecycle: exporting everything the
+// backend stores about a user and erasing it after a grace period.
+//
+// Domain packages take part by registering a Module that knows how to export
+// and delete their own tables.
+package privacy
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+)
+
+// DeleteMode selects how a module erases a user's records
+type DeleteMode string
+
+// Supported delete modes
+const (
+	// DeleteHard removes the rows entirely
+	DeleteHard DeleteMode = "delete"
+	// DeleteAnonymize keeps the rows for aggregate statistics but scrubs
+	// everything that identifies the user
+	DeleteAnonymize DeleteMode = "anonymize"
+)
+
+// Valid reports whether the mode is a known delete mode
+func (m DeleteMode) Valid() bool {
+	return m == DeleteHard || m == DeleteAnonymize
+}
+
+// Dataset is one table's worth of exported records
+type Dataset struct {
+	Name    string
+	Columns []string
+	Rows    [][]any
+}
+
+// Module is implemented by every domain package that stores user data
+type Module interface {
+	// Name identifies the module in archives and audit entries
+	Name() string
+	// Export returns every record the module holds for the user
+	Export(ctx context.Context, userID int64) ([]Dataset, error)
+	// Delete erases or anonymises the user's records inside tx
+	Delete(ctx context.Context, tx *sql.Tx, userID int64, mode DeleteMode) error
+}
+
+// Registry holds the modules taking part in exports and deletions
+type Registry struct {
+	mu      sync.RWMutex
+	modules []Module
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds a module. Modules are deleted in reverse registration order,
+// so register a module after the modules its rows reference.
+func (r *Registry) Register(m Module) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.modules {
+		if existing.Name() == m.Name() {
+			panic(fmt.Sprintf("privacy: module %q registered twice", m.Name()))
+		}
+	}
+	r.modules = append(r.modules, m)
+}
+
+// Modules returns the registered modules in registration order
+func (r *Registry) Modules() []Module {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]Module(nil), r.modules...)
+}
@@ -0,0 +1,287 @@
+package privacy_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database/dbtest"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/privacy"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/users"
+)
+
+// notesModule is a stand-in domain module used to check cascading
+type notesModule struct {
+	notes   map[int64][]string
+	deleted map[int64]privacy.DeleteMode
+}
+
+func (m *notesModule) Name() string { return "notes" }
+
+func (m *notesModule) Export(ctx context.Context, userID int64) ([]privacy.Dataset, error) {
+	ds := privacy.Dataset{Name: "notes", Columns: []string{"body"}}
+	for _, n := range m.notes[userID] {
+		ds.Rows = append(ds.Rows, []any{n})
+	}
+	return []privacy.Dataset{ds}, nil
+}
+
+func (m *notesModule) Delete(ctx context.Context, tx *sql.Tx, userID int64, mode privacy.DeleteMode) error {
+	m.deleted[userID] = mode
+	delete(m.notes, userID)
+	return nil
+}
+
+type fixture struct {
+	db      *database.DB
+	repo    *users.Repository
+	notes   *notesModule
+	service *privacy.Service
+	user    *users.User
+}
+
+func newFixture(t *testing.T, grace time.Duration) *fixture {
+	t.Helper()
+	db := dbtest.New(t)
+	repo := users.NewRepository(db)
+
+	user := &users.User{Email: "Anna@Example.com", Name: "Anna"}
+	if err := repo.Create(context.Background(), user); err != nil {
+		t.Fatalf("Create user failed: %v", err)
+	}
+
+	notes := &notesModule{
+		notes:   map[int64][]string{user.ID: {"slept 8h", "ran, 5km"}},
+		deleted: map[int64]privacy.DeleteMode{},
+	}
+	registry := privacy.NewRegistry()
+	registry.Register(users.NewPrivacyModule(repo))
+	registry.Register(notes)
+
+	return &fixture{
+		db:      db,
+		repo:    repo,
+		notes:   notes,
+		service: privacy.NewService(db, registry, grace, time.Hour),
+		user:    user,
+	}
+}
+
+func readZip(t *testing.T, archive []byte) map[string][]byte {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatalf("Invalid zip: %v", err)
+	}
+	files := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("Open %s: %v", f.Name, err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = data
+	}
+	return files
+}
+
+func TestExportLifecycle(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, time.Hour)
+
+	job, err := f.service.RequestExport(ctx, f.user.ID)
+	if err != nil {
+		t.Fatalf("RequestExport failed: %v", err)
+	}
+	if job.Status != privacy.ExportPending {
+		t.Errorf("Expected pending job, got %s", job.Status)
+	}
+	token := job.Token
+	var stored int
+	f.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM data_exports WHERE token_hash = $1`, token).Scan(&stored)
+	if token == "" || stored != 0 {
+		t.Errorf("Expected a token that is only stored hashed, got %q", token)
+	}
+
+	if _, err := f.service.Download(ctx, job.ID, token); !errors.Is(err, privacy.ErrExportNotReady) {
+		t.Errorf("Expected ErrExportNotReady before processing, got %v", err)
+	}
+
+	processed, err := f.service.ProcessExports(ctx)
+	if err != nil || processed != 1 {
+		t.Fatalf("ProcessExports = %d, %v; want 1, nil", processed, err)
+	}
+
+	job, err = f.service.GetExport(ctx, f.user.ID, job.ID)
+	if err != nil {
+		t.Fatalf("GetExport failed: %v", err)
+	}
+	if job.Status != privacy.ExportReady || job.Token != "" {
+		t.Fatalf("Expected ready job without its token, got %+v", job)
+	}
+
+	if _, err := f.service.GetExport(ctx, f.user.ID+1, job.ID); !errors.Is(err, privacy.ErrExportNotFound) {
+		t.Errorf("Expected other users to get ErrExportNotFound, got %v", err)
+	}
+	if _, err := f.service.Download(ctx, job.ID, "wrong"); !errors.Is(err, privacy.ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken, got %v", err)
+	}
+
+	archive, err := f.service.Download(ctx, job.ID, token)
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	files := readZip(t, archive)
+
+	for _, name := range []string{"manifest.json", "users/profile.json", "users/profile.csv", "notes/notes.json", "notes/notes.csv"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("Expected %s in archive", name)
+		}
+	}
+
+	var profile []map[string]any
+	if err := json.Unmarshal(files["users/profile.json"], &profile); err != nil {
+		t.Fatalf("Invalid profile.json: %v", err)
+	}
+	if len(profile) != 1 || profile[0]["email"] != "anna@example.com" {
+		t.Errorf("Unexpected profile export: %v", profile)
+	}
+	if _, leaked := profile[0]["password_hash"]; leaked {
+		t.Error("Password hash must not be exported")
+	}
+
+	records, err := csv.NewReader(bytes.NewReader(files["notes/notes.csv"])).ReadAll()
+	if err != nil {
+		t.Fatalf("Invalid notes.csv: %v", err)
+	}
+	if len(records) != 3 || records[0][0] != "body" || records[2][0] != "ran, 5km" {
+		t.Errorf("Unexpected notes CSV: %v", records)
+	}
+}
+
+func TestDeletionGracePeriodAndCancel(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, time.Hour)
+
+	if _, err := f.service.RequestDeletion(ctx, f.user.ID, "shred"); !errors.Is(err, privacy.ErrInvalidMode) {
+		t.Errorf("Expected ErrInvalidMode, got %v", err)
+	}
+
+	req, err := f.service.RequestDeletion(ctx, f.user.ID, privacy.DeleteHard)
+	if err != nil {
+		t.Fatalf("RequestDeletion failed: %v", err)
+	}
+	if !req.ScheduledAt.After(req.RequestedAt) {
+		t.Error("Expected deletion to be scheduled after the grace period")
+	}
+	if _, err := f.service.RequestDeletion(ctx, f.user.ID, privacy.DeleteHard); !errors.Is(err, privacy.ErrDeletionPending) {
+		t.Errorf("Expected ErrDeletionPending, got %v", err)
+	}
+	// The check above can race; the database has the final word
+	_, err = f.db.ExecContext(ctx,
+		`INSERT INTO deletion_requests (user_id, mode, status, requested_at, scheduled_at) VALUES ($1, $2, $3, $4, $5)`,
+		f.user.ID, privacy.DeleteHard, privacy.DeletionScheduled, req.RequestedAt, req.ScheduledAt)
+	if !database.IsUniqueViolation(err) {
+		t.Errorf("Expected a unique violation for a second scheduled deletion, got %v", err)
+	}
+
+	// Still inside the grace period: nothing happens
+	processed, err := f.service.ProcessDeletions(ctx)
+	if err != nil || processed != 0 {
+		t.Fatalf("ProcessDeletions = %d, %v; want 0, nil", processed, err)
+	}
+
+	if err := f.service.CancelDeletion(ctx, f.user.ID); err != nil {
+		t.Fatalf("CancelDeletion failed: %v", err)
+	}
+	if err := f.service.CancelDeletion(ctx, f.user.ID); !errors.Is(err, privacy.ErrNoDeletionActive) {
+		t.Errorf("Expected ErrNoDeletionActive, got %v", err)
+	}
+	if _, err := f.repo.GetByID(ctx, f.user.ID); err != nil {
+		t.Errorf("Expected user to survive a cancelled deletion, got %v", err)
+	}
+	if _, err := f.service.RequestDeletion(ctx, f.user.ID, privacy.DeleteAnonymize); err != nil {
+		t.Errorf("Expected a new request after cancelling, got %v", err)
+	}
+}
+
+func TestHardDeleteCascades(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, 0)
+
+	job, _ := f.service.RequestExport(ctx, f.user.ID)
+	f.service.ProcessExports(ctx)
+
+	if _, err := f.service.RequestDeletion(ctx, f.user.ID, privacy.DeleteHard); err != nil {
+		t.Fatalf("RequestDeletion failed: %v", err)
+	}
+	processed, err := f.service.ProcessDeletions(ctx)
+	if err != nil || processed != 1 {
+		t.Fatalf("ProcessDeletions = %d, %v; want 1, nil", processed, err)
+	}
+
+	if _, err := f.repo.GetByID(ctx, f.user.ID); !errors.Is(err, users.ErrNotFound) {
+		t.Errorf("Expected user to be deleted, got %v", err)
+	}
+	if f.notes.deleted[f.user.ID] != privacy.DeleteHard {
+		t.Error("Expected notes module to be asked to delete")
+	}
+	if _, err := f.service.Download(ctx, job.ID, job.Token); !errors.Is(err, privacy.ErrExportNotFound) {
+		t.Errorf("Expected export archives to be removed, got %v", err)
+	}
+
+	trail, err := f.service.AuditTrail(ctx, f.user.ID)
+	if err != nil {
+		t.Fatalf("AuditTrail failed: %v", err)
+	}
+	var actions []string
+	for _, e := range trail {
+		actions = append(actions, e.Action)
+	}
+	want := []string{
+		privacy.AuditExportRequested,
+		privacy.AuditExportCompleted,
+		privacy.AuditDeletionRequested,
+		privacy.AuditDeletionCompleted,
+	}
+	if len(actions) != len(want) {
+		t.Fatalf("Expected audit actions %v, got %v", want, actions)
+	}
+	for i := range want {
+		if actions[i] != want[i] {
+			t.Errorf("Audit entry %d: expected %s, got %s", i, want[i], actions[i])
+		}
+	}
+}
+
+func TestAnonymizeKeepsRow(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, 0)
+
+	if _, err := f.service.RequestDeletion(ctx, f.user.ID, privacy.DeleteAnonymize); err != nil {
+		t.Fatalf("RequestDeletion failed: %v", err)
+	}
+	if _, err := f.service.ProcessDeletions(ctx); err != nil {
+		t.Fatalf("ProcessDeletions failed: %v", err)
+	}
+
+	u, err := f.repo.GetByID(ctx, f.user.ID)
+	if err != nil {
+		t.Fatalf("Expected anonymised user row to remain, got %v", err)
+	}
+	if u.Email == f.user.Email || u.Name != "" || u.DeletedAt == nil {
+		t.Errorf("Expected identifying fields to be scrubbed, got %+v", u)
+	}
+	if f.notes.deleted[f.user.ID] != privacy.DeleteAnonymize {
+		t.Error("Expected notes module to be asked to anonymise")
+	}
+}
@@ -0,0 +1,456 @@
+package privacy
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+)
+
+// Predefined errors
+var (
+	ErrExportNotFound   = errors.New("export not found")
+	ErrExportNotReady   = errors.New("export is not ready yet")
+	ErrExportExpired    = errors.New("export download link has expired")
+	ErrInvalidToken     = errors.New("invalid download token")
+	ErrInvalidMode      = errors.New("invalid delete mode")
+	ErrDeletionPending  = errors.New("account deletion already scheduled")
+	ErrNoDeletionActive = errors.New("no scheduled account deletion")
+)
+
+// Export job statuses
+const (
+	ExportPending = "pending"
+	ExportReady   = "ready"
+	ExportFailed  = "failed"
+)
+
+// Deletion request statuses
+const (
+	DeletionScheduled = "scheduled"
+	DeletionCancelled = "cancelled"
+	DeletionCompleted = "completed"
+)
+
+// Audit actions
+const (
+	AuditExportRequested   = "export_requested"
+	AuditExportCompleted   = "export_completed"
+	AuditExportFailed      = "export_failed"
+	AuditExportDownloaded  = "export_downloaded"
+	AuditDeletionRequested = "deletion_requested"
+	AuditDeletionCancelled = "deletion_cancelled"
+	AuditDeletionCompleted = "deletion_completed"
+)
+
+// ExportJob tracks the generation of a user's data archive. Token authorises
+// the download; only RequestExport returns it, as the database keeps a
+// SHA-256 hash.
+type ExportJob struct {
+	ID          int64      `json:"id"`
+	UserID      int64      `json:"user_id"`
+	Status      string     `json:"status"`
+	Token       string     `json:"-"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	tokenHash   string
+}
+
+// DeletionRequest is a scheduled erasure of a user's account
+type DeletionRequest struct {
+	ID          int64      `json:"id"`
+	UserID      int64      `json:"user_id"`
+	Mode        DeleteMode `json:"mode"`
+	Status      string     `json:"status"`
+	RequestedAt time.Time  `json:"requested_at"`
+	ScheduledAt time.Time  `json:"scheduled_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// AuditEntry records a single data-lifecycle event
+type AuditEntry struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	Action    string    `json:"action"`
+	Detail    string    `json:"detail,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Service runs export jobs and account deletions
+type Service struct {
+	db          *database.DB
+	registry    *Registry
+	gracePeriod time.Duration
+	linkTTL     time.Duration
+	now         func() time.Time
+}
+
+// NewService creates a privacy service. gracePeriod is how long a deletion
+// can still be cancelled, linkTTL how long an export stays downloadable.
+func NewService(db *database.DB, registry *Registry, gracePeriod, linkTTL time.Duration) *Service {
+	return &Service{
+		db:          db,
+		registry:    registry,
+		gracePeriod: gracePeriod,
+		linkTTL:     linkTTL,
+		now:         func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// SetClock replaces the service's time source, for tests that control time
+func (s *Service) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// RequestExport queues a new export job for the user. The returned job
+// carries the download token, which cannot be recovered later.
+func (s *Service) RequestExport(ctx context.Context, userID int64) (*ExportJob, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	job := &ExportJob{UserID: userID, Status: ExportPending, Token: token, CreatedAt: s.now()}
+
+	err = s.db.WithTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx,
+			`INSERT INTO data_exports (user_id, status, token_hash, created_at) VALUES ($1, $2, $3, $4) RETURNING id`,
+			job.UserID, job.Status, hashToken(token), job.CreatedAt,
+		).Scan(&job.ID)
+		if err != nil {
+			return err
+		}
+		return s.audit(ctx, tx, userID, AuditExportRequested, fmt.Sprintf("export %d", job.ID))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// GetExport returns one of the user's export jobs
+func (s *Service) GetExport(ctx context.Context, userID, id int64) (*ExportJob, error) {
+	job, err := s.getExport(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.UserID != userID {
+		return nil, ErrExportNotFound
+	}
+	return job, nil
+}
+
+// Download returns the archive of a ready export after checking its token
+func (s *Service) Download(ctx context.Context, id int64, token string) ([]byte, error) {
+	job, err := s.getExport(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(job.tokenHash), []byte(hashToken(token))) != 1 {
+		return nil, ErrInvalidToken
+	}
+	if job.Status != ExportReady {
+		return nil, ErrExportNotReady
+	}
+	if job.ExpiresAt != nil && !s.now().Before(*job.ExpiresAt) {
+		return nil, ErrExportExpired
+	}
+
+	var archive []byte
+	err = s.db.QueryRowContext(ctx, `SELECT archive FROM data_exports WHERE id = $1`, id).Scan(&archive)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.audit(ctx, s.db, job.UserID, AuditExportDownloaded, fmt.Sprintf("export %d", id)); err != nil {
+		return nil, err
+	}
+	return archive, nil
+}
+
+// ProcessExports builds the archives of all pending export jobs and returns
+// how many were processed
+func (s *Service) ProcessExports(ctx context.Context) (int, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, user_id FROM data_exports WHERE status = $1 ORDER BY id`, ExportPending)
+	if err != nil {
+		return 0, err
+	}
+	type pending struct{ id, userID int64 }
+	var jobs []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.id, &p.userID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		jobs = append(jobs, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for i, job := range jobs {
+		now := s.now()
+		archive, buildErr := buildArchive(ctx, s.registry.Modules(), job.userID, now)
+		err := s.db.WithTx(ctx, func(tx *sql.Tx) error {
+			if buildErr != nil {
+				_, err := tx.ExecContext(ctx,
+					`UPDATE data_exports SET status = $1, error = $2, completed_at = $3 WHERE id = $4`,
+					ExportFailed, buildErr.Error(), now, job.id)
+				if err != nil {
+					return err
+				}
+				return s.audit(ctx, tx, job.userID, AuditExportFailed, buildErr.Error())
+			}
+			_, err := tx.ExecContext(ctx,
+				`UPDATE data_exports SET status = $1, archive = $2, completed_at = $3, expires_at = $4 WHERE id = $5`,
+				ExportReady, archive, now, now.Add(s.linkTTL), job.id)
+			if err != nil {
+				return err
+			}
+			return s.audit(ctx, tx, job.userID, AuditExportCompleted, fmt.Sprintf("export %d, %d bytes", job.id, len(archive)))
+		})
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(jobs), nil
+}
+
+// RequestDeletion schedules erasure of the user's data once the grace period
+// has passed
+func (s *Service) RequestDeletion(ctx context.Context, userID int64, mode DeleteMode) (*DeletionRequest, error) {
+	if !mode.Valid() {
+		return nil, ErrInvalidMode
+	}
+	if _, err := s.ActiveDeletion(ctx, userID); err == nil {
+		return nil, ErrDeletionPending
+	} else if !errors.Is(err, ErrNoDeletionActive) {
+		return nil, err
+	}
+
+	now := s.now()
+	req := &DeletionRequest{
+		UserID:      userID,
+		Mode:        mode,
+		Status:      DeletionScheduled,
+		RequestedAt: now,
+		ScheduledAt: now.Add(s.gracePeriod),
+	}
+	err := s.db.WithTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx,
+			`INSERT INTO deletion_requests (user_id, mode, status, requested_at, scheduled_at)
+			 VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+			req.UserID, string(req.Mode), req.Status, req.RequestedAt, req.ScheduledAt,
+		).Scan(&req.ID)
+		if database.IsUniqueViolation(err) {
+			// A concurrent request scheduled one first
+			return ErrDeletionPending
+		}
+		if err != nil {
+			return err
+		}
+		return s.audit(ctx, tx, userID, AuditDeletionRequested,
+			fmt.Sprintf("mode %s, scheduled for %s", mode, req.ScheduledAt.Format(time.RFC3339)))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// ActiveDeletion returns the user's scheduled deletion, if any
+func (s *Service) ActiveDeletion(ctx context.Context, userID int64) (*DeletionRequest, error) {
+	req, err := scanDeletion(s.db.QueryRowContext(ctx,
+		`SELECT id, user_id, mode, status, requested_at, scheduled_at, completed_at
+		 FROM deletion_requests WHERE user_id = $1 AND status = $2`,
+		userID, DeletionScheduled))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNoDeletionActive
+	}
+	return req, err
+}
+
+// CancelDeletion cancels the user's scheduled deletion during the grace period
+func (s *Service) CancelDeletion(ctx context.Context, userID int64) error {
+	return s.db.WithTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx,
+			`UPDATE deletion_requests SET status = $1 WHERE user_id = $2 AND status = $3`,
+			DeletionCancelled, userID, DeletionScheduled)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrNoDeletionActive
+		}
+		return s.audit(ctx, tx, userID, AuditDeletionCancelled, "")
+	})
+}
+
+// ProcessDeletions erases the data of every user whose grace period is over
+// and returns how many accounts were processed
+func (s *Service) ProcessDeletions(ctx context.Context) (int, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, user_id, mode, status, requested_at, scheduled_at, completed_at
+		 FROM deletion_requests WHERE status = $1 AND scheduled_at <= $2 ORDER BY scheduled_at`,
+		DeletionScheduled, s.now())
+	if err != nil {
+		return 0, err
+	}
+	var due []*DeletionRequest
+	for rows.Next() {
+		req, err := scanDeletion(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		due = append(due, req)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	modules := s.registry.Modules()
+	for i, req := range due {
+		err := s.db.WithTx(ctx, func(tx *sql.Tx) error {
+			// Dependents first: modules registered later may reference
+			// rows owned by earlier ones.
+			for j := len(modules) - 1; j >= 0; j-- {
+				if err := modules[j].Delete(ctx, tx, req.UserID, req.Mode); err != nil {
+					return fmt.Errorf("delete %s: %w", modules[j].Name(), err)
+				}
+			}
+			// Archives hold copies of the data being erased.
+			if _, err := tx.ExecContext(ctx, `DELETE FROM data_exports WHERE user_id = $1`, req.UserID); err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx,
+				`UPDATE deletion_requests SET status = $1, completed_at = $2 WHERE id = $3`,
+				DeletionCompleted, s.now(), req.ID); err != nil {
+				return err
+			}
+			return s.audit(ctx, tx, req.UserID, AuditDeletionCompleted, fmt.Sprintf("mode %s", req.Mode))
+		})
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(due), nil
+}
+
+// AuditTrail returns the user's data-lifecycle events, oldest first
+func (s *Service) AuditTrail(ctx context.Context, userID int64) ([]AuditEntry, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, user_id, action, detail, created_at FROM privacy_audit_log WHERE user_id = $1 ORDER BY id`,
+		userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		var e AuditEntry
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Action, &e.Detail, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// Run processes exports and due deletions every interval until ctx is done
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.ProcessExports(ctx); err != nil {
+			log.Printf("privacy: process exports: %v", err)
+		}
+		if _, err := s.ProcessDeletions(ctx); err != nil {
+			log.Printf("privacy: process deletions: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func (s *Service) audit(ctx context.Context, db execer, userID int64, action, detail string) error {
+	_, err := db.ExecContext(ctx,
+		`INSERT INTO privacy_audit_log (user_id, action, detail, created_at) VALUES ($1, $2, $3, $4)`,
+		userID, action, detail, s.now())
+	return err
+}
+
+func (s *Service) getExport(ctx context.Context, id int64) (*ExportJob, error) {
+	var job ExportJob
+	var completedAt, expiresAt sql.NullTime
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id, user_id, status, token_hash, error, created_at, completed_at, expires_at
+		 FROM data_exports WHERE id = $1`, id,
+	).Scan(&job.ID, &job.UserID, &job.Status, &job.tokenHash, &job.Error, &job.CreatedAt, &completedAt, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrExportNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if completedAt.Valid {
+		job.CompletedAt = &completedAt.Time
+	}
+	if expiresAt.Valid {
+		job.ExpiresAt = &expiresAt.Time
+	}
+	return &job, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanDeletion(row scanner) (*DeletionRequest, error) {
+	var req DeletionRequest
+	var mode string
+	var completedAt sql.NullTime
+	if err := row.Scan(&req.ID, &req.UserID, &mode, &req.Status, &req.RequestedAt, &req.ScheduledAt, &completedAt); err != nil {
+		return nil, err
+	}
+	req.Mode = DeleteMode(mode)
+	if completedAt.Valid {
+		req.CompletedAt = &completedAt.Time
+	}
+	return &req, nil
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
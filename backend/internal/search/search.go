@@ -0,0 +1,232 @@
+// Package search provides full-text search over a user's journal entries.
+//
+// On Postgres entries carry a generated, weighted tsvector column indexed
+// with GIN and built with the author's text search configuration, so that
+// stemming follows the user's language. On SQLite, used for local
+// development and tests, an FTS5 table kept in sync by triggers is queried
+// instead; ranking and snippets are equivalent but no stemming is applied.
+package search
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/tenant"
+)
+
+// Predefined errors
+var (
+	ErrEmptyQuery      = errors.New("search query is empty")
+	ErrInvalidCursor   = errors.New("invalid cursor")
+	ErrInvalidLanguage = errors.New("unsupported search language")
+)
+
+// Languages are the supported text search configurations. "simple" only
+// lowercases words, the others also stem them.
+var Languages = []string{"simple", "english", "russian"}
+
+// Result types
+const (
+	TypeEntry = "entry"
+)
+
+// Paging limits
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// maxTerms bounds the number of words taken from a query
+const maxTerms = 16
+
+// Result is a single match. Matched words in Title and Snippet are wrapped
+// in <mark></mark>.
+type Result struct {
+	Type      string    `json:"type"`
+	ID        int64     `json:"id"`
+	Title     string    `json:"title"`
+	Snippet   string    `json:"snippet"`
+	Rank      float64   `json:"rank"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Page is one page of results, best matches first
+type Page struct {
+	Results    []Result `json:"results"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+// Service runs searches in the tenant of the context
+type Service struct {
+	db      *database.DB
+	scoped  *tenant.DB
+	dialect database.Dialect
+}
+
+// NewService creates a search service
+func NewService(db *database.DB) *Service {
+	return &Service{db: db, scoped: tenant.Scoped(db), dialect: db.Dialect}
+}
+
+type cursor struct {
+	Rank float64 `json:"r"`
+	ID   int64   `json:"id"`
+}
+
+// Search returns the user's entries in the current tenant matching every
+// word of query, where the last characters of a word may be missing, so
+// "run morn" finds "Morning run". after is empty for the first page and
+// the NextCursor of the previous page otherwise.
+func (s *Service) Search(ctx context.Context, userID int64, query string, limit int, after string) (*Page, error) {
+	words := terms(query)
+	if len(words) == 0 {
+		return nil, ErrEmptyQuery
+	}
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	limit = min(limit, MaxLimit)
+
+	var c *cursor
+	if after != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(after)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		c = &cursor{}
+		if err := json.Unmarshal(raw, c); err != nil || c.ID <= 0 {
+			return nil, ErrInvalidCursor
+		}
+	}
+
+	var q string
+	var args []any
+	if s.dialect == database.SQLite {
+		q, args = sqliteQuery(userID, words, limit+1, c)
+	} else {
+		q, args = postgresQuery(userID, words, limit+1, c)
+	}
+
+	rows, err := s.scoped.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := &Page{Results: []Result{}}
+	for rows.Next() {
+		r := Result{Type: TypeEntry}
+		if err := rows.Scan(&r.ID, &r.Title, &r.Snippet, &r.Rank, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		page.Results = append(page.Results, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(page.Results) > limit {
+		page.Results = page.Results[:limit]
+		last := page.Results[limit-1]
+		raw, _ := json.Marshal(cursor{Rank: last.Rank, ID: last.ID})
+		page.NextCursor = base64.RawURLEncoding.EncodeToString(raw)
+	}
+	return page, nil
+}
+
+// postgresQuery ranks with ts_rank_cd over the weighted search_vector and
+// builds snippets with ts_headline, only for the rows of the page
+func postgresQuery(userID int64, words []string, limit int, c *cursor) (string, []any) {
+	for i, w := range words {
+		words[i] = w + ":*"
+	}
+	args := []any{userID, strings.Join(words, " & "), limit}
+	after := ""
+	if c != nil {
+		after = `AND (ts_rank_cd(e.search_vector, q) < $4
+		          OR (ts_rank_cd(e.search_vector, q) = $4 AND e.id < $5))`
+		args = append(args, c.Rank, c.ID)
+	}
+	return `SELECT r.id,
+		       ts_headline(r.language, r.title, r.q, 'StartSel=<mark>, StopSel=</mark>, HighlightAll=true'),
+		       ts_headline(r.language, r.body, r.q,
+		           'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=24, MinWords=8, FragmentDelimiter=" … "'),
+		       r.rank, r.created_at
+		FROM (
+		    SELECT e.id, e.language, e.title, e.body, e.created_at, q, ts_rank_cd(e.search_vector, q) AS rank
+		    FROM entries e, to_tsquery((SELECT search_language FROM users WHERE id = $1), $2) AS q
+		    WHERE e.user_id = $1 AND e.org_id = @tenant AND e.search_vector @@ q ` + after + `
+		    ORDER BY rank DESC, e.id DESC
+		    LIMIT $3
+		) r
+		ORDER BY r.rank DESC, r.id DESC`, args
+}
+
+// sqliteQuery matches prefix terms against the FTS5 table. bm25 scores are
+// negated so that, as on Postgres, a higher rank is a better match; title
+// matches weigh ten times as much as body matches.
+func sqliteQuery(userID int64, words []string, limit int, c *cursor) (string, []any) {
+	for i, w := range words {
+		words[i] = `"` + w + `"*`
+	}
+	args := []any{userID, strings.Join(words, " "), limit}
+	after := ""
+	if c != nil {
+		after = `WHERE rank < $4 OR (rank = $4 AND id < $5)`
+		args = append(args, c.Rank, c.ID)
+	}
+	return `SELECT id, title, snippet, rank, created_at FROM (
+		    SELECT e.id,
+		           highlight(entries_fts, 0, '<mark>', '</mark>') AS title,
+		           snippet(entries_fts, 1, '<mark>', '</mark>', '…', 24) AS snippet,
+		           -bm25(entries_fts, 10.0, 1.0) AS rank,
+		           e.created_at
+		    FROM entries_fts JOIN entries e ON e.id = entries_fts.rowid
+		    WHERE entries_fts MATCH $2 AND e.user_id = $1 AND e.org_id = @tenant
+		) ` + after + `
+		ORDER BY rank DESC, id DESC
+		LIMIT $3`, args
+}
+
+// terms splits a query into lowercase words of letters and digits. Any
+// other character separates words, which also keeps query syntax of either
+// backend out of user input.
+func terms(query string) []string {
+	words := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) > maxTerms {
+		words = words[:maxTerms]
+	}
+	return words
+}
+
+// Language returns the user's search language
+func (s *Service) Language(ctx context.Context, userID int64) (string, error) {
+	var lang string
+	err := s.db.QueryRowContext(ctx, `SELECT search_language FROM users WHERE id = $1`, userID).Scan(&lang)
+	return lang, err
+}
+
+// SetLanguage changes the user's search language and re-indexes all of the
+// user's entries with it
+func (s *Service) SetLanguage(ctx context.Context, userID int64, lang string) error {
+	if !slices.Contains(Languages, lang) {
+		return ErrInvalidLanguage
+	}
+	return s.db.WithTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `UPDATE users SET search_language = $1 WHERE id = $2`, lang, userID); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `UPDATE entries SET language = $1 WHERE user_id = $2`, lang, userID)
+		return err
+	})
+}
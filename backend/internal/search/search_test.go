@@ -0,0 +1,200 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database/dbtest"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/entries"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/tenant"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/users"
+)
+
+type fixture struct {
+	service *Service
+	entries *entries.Repository
+	user    *users.User
+	ctxA    context.Context
+	ctxB    context.Context
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	db := dbtest.New(t)
+	ctx := context.Background()
+
+	user := &users.User{Email: "writer@example.com"}
+	if err := users.NewRepository(db).Create(ctx, user); err != nil {
+		t.Fatalf("Create user: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, `INSERT INTO organizations (name, slug) VALUES ('A', 'a'), ('B', 'b')`); err != nil {
+		t.Fatalf("Create orgs: %v", err)
+	}
+	return &fixture{
+		service: NewService(db),
+		entries: entries.NewRepository(db),
+		user:    user,
+		ctxA:    tenant.NewContext(ctx, tenant.Tenant{OrgID: 1}),
+		ctxB:    tenant.NewContext(ctx, tenant.Tenant{OrgID: 2}),
+	}
+}
+
+func (f *fixture) add(t *testing.T, ctx context.Context, title, body string) *entries.Entry {
+	t.Helper()
+	e := &entries.Entry{UserID: f.user.ID, Title: title, Body: body}
+	if err := f.entries.Create(ctx, e); err != nil {
+		t.Fatalf("Create entry: %v", err)
+	}
+	return e
+}
+
+func ids(page *Page) []int64 {
+	var out []int64
+	for _, r := range page.Results {
+		out = append(out, r.ID)
+	}
+	return out
+}
+
+func TestSearchRankingAndSnippets(t *testing.T) {
+	f := newFixture(t)
+	inBody := f.add(t, f.ctxA, "Tuesday", "Long morning run along the river, then breakfast")
+	inTitle := f.add(t, f.ctxA, "Morning run", "Felt slow today")
+	f.add(t, f.ctxA, "Reading", "Finished the novel")
+
+	page, err := f.service.Search(f.ctxA, f.user.ID, "run morn", 10, "")
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if got := ids(page); !reflect.DeepEqual(got, []int64{inTitle.ID, inBody.ID}) {
+		t.Fatalf("Expected title match ranked first, got %v", got)
+	}
+	if page.Results[0].Title != "<mark>Morning</mark> <mark>run</mark>" {
+		t.Errorf("Expected highlighted title, got %q", page.Results[0].Title)
+	}
+	if !strings.Contains(page.Results[1].Snippet, "<mark>morning</mark> <mark>run</mark>") {
+		t.Errorf("Expected highlighted snippet, got %q", page.Results[1].Snippet)
+	}
+	if page.Results[0].Type != TypeEntry || page.NextCursor != "" {
+		t.Errorf("Unexpected page %+v", page)
+	}
+}
+
+func TestSearchUnicodePrefix(t *testing.T) {
+	f := newFixture(t)
+	e := f.add(t, f.ctxA, "Утро", "Пробежка по парку")
+
+	page, err := f.service.Search(f.ctxA, f.user.ID, "ПРОБ", 10, "")
+	if err != nil || !reflect.DeepEqual(ids(page), []int64{e.ID}) {
+		t.Errorf("Expected Cyrillic prefix match, got %+v, %v", page, err)
+	}
+}
+
+func TestSearchFollowsChanges(t *testing.T) {
+	f := newFixture(t)
+	e := f.add(t, f.ctxA, "Draft", "nothing yet")
+
+	e.Title, e.Body = "Swimming", "pool session"
+	if err := f.entries.Update(f.ctxA, e); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if page, _ := f.service.Search(f.ctxA, f.user.ID, "draft", 10, ""); len(page.Results) != 0 {
+		t.Errorf("Expected old text to be gone from the index, got %v", ids(page))
+	}
+	if page, _ := f.service.Search(f.ctxA, f.user.ID, "swim", 10, ""); len(page.Results) != 1 {
+		t.Errorf("Expected new text to be indexed, got %v", ids(page))
+	}
+
+	if err := f.entries.Delete(f.ctxA, f.user.ID, e.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if page, _ := f.service.Search(f.ctxA, f.user.ID, "swim", 10, ""); len(page.Results) != 0 {
+		t.Errorf("Expected deleted entry to be gone, got %v", ids(page))
+	}
+}
+
+func TestSearchIsTenantScoped(t *testing.T) {
+	f := newFixture(t)
+	f.add(t, f.ctxA, "Secret plan", "only in A")
+
+	page, err := f.service.Search(f.ctxB, f.user.ID, "secret", 10, "")
+	if err != nil || len(page.Results) != 0 {
+		t.Errorf("Expected no results in another tenant, got %+v, %v", page, err)
+	}
+	if _, err := f.service.Search(context.Background(), f.user.ID, "secret", 10, ""); !errors.Is(err, tenant.ErrNoTenant) {
+		t.Errorf("Expected ErrNoTenant, got %v", err)
+	}
+}
+
+func TestSearchPagination(t *testing.T) {
+	f := newFixture(t)
+	for i := range 5 {
+		f.add(t, f.ctxA, fmt.Sprintf("Walk %d", i), "walk in the park")
+	}
+
+	seen := map[int64]bool{}
+	cursor := ""
+	pages := 0
+	for {
+		page, err := f.service.Search(f.ctxA, f.user.ID, "walk", 2, cursor)
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		pages++
+		for _, r := range page.Results {
+			if seen[r.ID] {
+				t.Errorf("Entry %d returned twice", r.ID)
+			}
+			seen[r.ID] = true
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	if len(seen) != 5 || pages != 3 {
+		t.Errorf("Expected 5 entries over 3 pages, got %d over %d", len(seen), pages)
+	}
+
+	if _, err := f.service.Search(f.ctxA, f.user.ID, "walk", 2, "not-a-cursor!"); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func TestSearchQuerySyntaxIsIgnored(t *testing.T) {
+	f := newFixture(t)
+	f.add(t, f.ctxA, "Tea", "green tea")
+
+	if _, err := f.service.Search(f.ctxA, f.user.ID, `"*" - (`, 10, ""); !errors.Is(err, ErrEmptyQuery) {
+		t.Errorf("Expected ErrEmptyQuery, got %v", err)
+	}
+	page, err := f.service.Search(f.ctxA, f.user.ID, `green" OR "tea*`, 10, "")
+	if err != nil {
+		t.Fatalf("Expected operators to be treated as separators, got %v", err)
+	}
+	if len(page.Results) != 0 {
+		t.Errorf(`Expected "or" to be a plain word that does not match, got %v`, ids(page))
+	}
+}
+
+func TestSetLanguage(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	if lang, err := f.service.Language(ctx, f.user.ID); err != nil || lang != "simple" {
+		t.Errorf("Expected default language simple, got %q, %v", lang, err)
+	}
+	if err := f.service.SetLanguage(ctx, f.user.ID, "klingon"); !errors.Is(err, ErrInvalidLanguage) {
+		t.Errorf("Expected ErrInvalidLanguage, got %v", err)
+	}
+	if err := f.service.SetLanguage(ctx, f.user.ID, "russian"); err != nil {
+		t.Fatalf("SetLanguage failed: %v", err)
+	}
+	if lang, _ := f.service.Language(ctx, f.user.ID); lang != "russian" {
+		t.Errorf("Expected russian, got %q", lang)
+	}
+}
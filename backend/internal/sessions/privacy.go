@@ -0,0 +1,54 @@
+package sessions
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/privacy"
+)
+
+// PrivacyModule exports and erases a user's sessions
+type PrivacyModule struct {
+	db *database.DB
+}
+
+// NewPrivacyModule creates the sessions privacy module
+func NewPrivacyModule(db *database.DB) *PrivacyModule {
+	return &PrivacyModule{db: db}
+}
+
+// Name implements privacy.Module
+func (m *PrivacyModule) Name() string {
+	return "sessions"
+}
+
+// Export implements privacy.Module
+func (m *PrivacyModule) Export(ctx context.Context, userID int64) ([]privacy.Dataset, error) {
+	rows, err := m.db.QueryContext(ctx,
+		`SELECT `+sessionColumns+` FROM sessions WHERE user_id = $1 ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ds := privacy.Dataset{
+		Name:    "sessions",
+		Columns: []string{"id", "device_name", "platform", "ip", "created_at", "last_seen_at", "revoked_at"},
+	}
+	for rows.Next() {
+		s, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		ds.Rows = append(ds.Rows, []any{s.ID, s.DeviceName, s.Platform, s.IP, s.CreatedAt, s.LastSeenAt, s.RevokedAt})
+	}
+	return []privacy.Dataset{ds}, rows.Err()
+}
+
+// Delete implements privacy.Module. Sessions hold device and IP details, so
+// they are removed in both modes, which also signs the user out everywhere.
+func (m *PrivacyModule) Delete(ctx context.Context, tx *sql.Tx, userID int64, mode privacy.DeleteMode) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM sessions WHERE user_id = $1`, userID)
+	return err
+}
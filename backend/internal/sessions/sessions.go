@@ -0,0 +1,292 @@
+// Package sessions keeps a server-side record of every signed-in device.
+//
+// Signing in creates a session and an opaque refresh token for it; access
+// tokens carry the session ID. Refreshing rotates the refresh token and
+// records when and from where the device was last seen. Revoking a session
+// invalidates its refresh token immediately and its access tokens as soon as
+// the revocation cache entry expires.
+package sessions
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+)
+
+// Predefined errors
+var (
+	ErrNotFound            = errors.New("session not found")
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+)
+
+// Device describes the client a session was created from
+type Device struct {
+	Name     string
+	Platform string
+	IP       string
+}
+
+// Session is a signed-in device
+type Session struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"user_id"`
+	DeviceName string     `json:"device_name"`
+	Platform   string     `json:"platform"`
+	IP         string     `json:"ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Service manages sessions
+type Service struct {
+	db         *database.DB
+	refreshTTL time.Duration
+	cacheTTL   time.Duration
+	now        func() time.Time
+
+	mu    sync.Mutex
+	cache map[int64]cacheEntry
+}
+
+// cacheEntry remembers whether a session was active when last checked
+type cacheEntry struct {
+	userID  int64
+	active  bool
+	expires time.Time
+}
+
+// NewService creates a session service. Refresh tokens live for refreshTTL
+// after their last use; session state is cached for cacheTTL.
+func NewService(db *database.DB, refreshTTL, cacheTTL time.Duration) *Service {
+	return &Service{
+		db:         db,
+		refreshTTL: refreshTTL,
+		cacheTTL:   cacheTTL,
+		now:        func() time.Time { return time.Now().UTC() },
+		cache:      make(map[int64]cacheEntry),
+	}
+}
+
+// SetClock replaces the service's time source, for tests that control time
+func (s *Service) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+const sessionColumns = `id, user_id, device_name, platform, ip, created_at, last_seen_at, expires_at, revoked_at`
+
+// Create starts a session for the user and returns it with its refresh token
+func (s *Service) Create(ctx context.Context, userID int64, device Device) (*Session, string, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, "", err
+	}
+	now := s.now()
+	sess := &Session{
+		UserID:     userID,
+		DeviceName: device.Name,
+		Platform:   device.Platform,
+		IP:         device.IP,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(s.refreshTTL),
+	}
+	err = s.db.QueryRowContext(ctx,
+		`INSERT INTO sessions (user_id, refresh_token_hash, device_name, platform, ip, created_at, last_seen_at, expires_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $6, $7) RETURNING id`,
+		userID, hashToken(token), sess.DeviceName, sess.Platform, sess.IP, now, sess.ExpiresAt,
+	).Scan(&sess.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	return sess, token, nil
+}
+
+// Refresh exchanges a refresh token for a new one, extending the session and
+// recording the caller's IP
+func (s *Service) Refresh(ctx context.Context, token, ip string) (*Session, string, error) {
+	sess, err := scanSession(s.db.QueryRowContext(ctx,
+		`SELECT `+sessionColumns+` FROM sessions WHERE refresh_token_hash = $1`, hashToken(token)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, "", ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	now := s.now()
+	if sess.RevokedAt != nil || !now.Before(sess.ExpiresAt) {
+		return nil, "", ErrInvalidRefreshToken
+	}
+
+	next, err := newToken()
+	if err != nil {
+		return nil, "", err
+	}
+	sess.LastSeenAt = now
+	sess.ExpiresAt = now.Add(s.refreshTTL)
+	if ip != "" {
+		sess.IP = ip
+	}
+
+	// Matching on the old hash makes concurrent refreshes with the same
+	// token fail instead of forking the session
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE sessions SET refresh_token_hash = $1, last_seen_at = $2, expires_at = $3, ip = $4
+		 WHERE id = $5 AND refresh_token_hash = $6 AND revoked_at IS NULL`,
+		hashToken(next), now, sess.ExpiresAt, sess.IP, sess.ID, hashToken(token))
+	if err != nil {
+		return nil, "", err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, "", ErrInvalidRefreshToken
+	}
+	return sess, next, nil
+}
+
+// List returns the user's active sessions, most recently seen first
+func (s *Service) List(ctx context.Context, userID int64) ([]Session, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+sessionColumns+` FROM sessions
+		 WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > $2
+		 ORDER BY last_seen_at DESC, id DESC`,
+		userID, s.now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []Session{}
+	for rows.Next() {
+		sess, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *sess)
+	}
+	return list, rows.Err()
+}
+
+// Revoke ends one of the user's sessions
+func (s *Service) Revoke(ctx context.Context, userID, id int64) error {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE sessions SET revoked_at = $1 WHERE id = $2 AND user_id = $3 AND revoked_at IS NULL`,
+		s.now(), id, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	s.forget(id)
+	return nil
+}
+
+// RevokeOthers ends all of the user's sessions except keepID and returns
+// how many were revoked
+func (s *Service) RevokeOthers(ctx context.Context, userID, keepID int64) (int, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`UPDATE sessions SET revoked_at = $1 WHERE user_id = $2 AND id <> $3 AND revoked_at IS NULL RETURNING id`,
+		s.now(), userID, keepID)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	s.forget(ids...)
+	return len(ids), nil
+}
+
+// Active reports whether the session exists, belongs to the user and is
+// neither revoked nor expired. Answers are cached for the cache TTL, so a
+// revocation made by another server instance takes at most that long to
+// apply. A cache miss also records the session as seen.
+func (s *Service) Active(ctx context.Context, userID, id int64) (bool, error) {
+	now := s.now()
+	s.mu.Lock()
+	entry, ok := s.cache[id]
+	s.mu.Unlock()
+	if ok && entry.userID == userID && now.Before(entry.expires) {
+		return entry.active, nil
+	}
+
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE sessions SET last_seen_at = $1
+		 WHERE id = $2 AND user_id = $3 AND revoked_at IS NULL AND expires_at > $1`,
+		now, id, userID)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	active := n > 0
+
+	s.mu.Lock()
+	s.cache[id] = cacheEntry{userID: userID, active: active, expires: now.Add(s.cacheTTL)}
+	if len(s.cache) > 10000 {
+		s.evictExpired(now)
+	}
+	s.mu.Unlock()
+	return active, nil
+}
+
+func (s *Service) forget(ids ...int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		delete(s.cache, id)
+	}
+}
+
+// evictExpired drops stale cache entries; s.mu must be held
+func (s *Service) evictExpired(now time.Time) {
+	for id, entry := range s.cache {
+		if !now.Before(entry.expires) {
+			delete(s.cache, id)
+		}
+	}
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSession(row scanner) (*Session, error) {
+	var sess Session
+	err := row.Scan(&sess.ID, &sess.UserID, &sess.DeviceName, &sess.Platform, &sess.IP,
+		&sess.CreatedAt, &sess.LastSeenAt, &sess.ExpiresAt, &sess.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &sess, nil
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,135 @@
+package sessions
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database/dbtest"
+)
+
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time { return c.t }
+
+func newService(t *testing.T) (*Service, *database.DB, *clock) {
+	t.Helper()
+	db := dbtest.New(t)
+	if _, err := db.ExecContext(context.Background(),
+		`INSERT INTO users (email, name, password_hash) VALUES ('a@example.com', '', ''), ('b@example.com', '', '')`); err != nil {
+		t.Fatalf("insert users: %v", err)
+	}
+	c := &clock{t: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+	s := NewService(db, 24*time.Hour, 30*time.Second)
+	s.now = c.now
+	return s, db, c
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	s, _, c := newService(t)
+	ctx := context.Background()
+
+	sess, token, err := s.Create(ctx, 1, Device{Name: "Pixel 8", Platform: "android", IP: "10.0.0.1"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	c.t = c.t.Add(time.Hour)
+	refreshed, next, err := s.Refresh(ctx, token, "10.0.0.2")
+	if err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if refreshed.ID != sess.ID || next == token {
+		t.Errorf("Expected same session with a new token, got %d / reused token %v", refreshed.ID, next == token)
+	}
+	if !refreshed.LastSeenAt.Equal(c.t) || refreshed.IP != "10.0.0.2" || !refreshed.ExpiresAt.Equal(c.t.Add(24*time.Hour)) {
+		t.Errorf("Expected last seen, IP and expiry to be updated, got %+v", refreshed)
+	}
+
+	if _, _, err := s.Refresh(ctx, token, ""); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("Expected old refresh token to be rejected, got %v", err)
+	}
+
+	c.t = c.t.Add(25 * time.Hour)
+	if _, _, err := s.Refresh(ctx, next, ""); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("Expected expired refresh token to be rejected, got %v", err)
+	}
+}
+
+func TestListAndRevoke(t *testing.T) {
+	s, _, c := newService(t)
+	ctx := context.Background()
+
+	phone, _, _ := s.Create(ctx, 1, Device{Name: "phone"})
+	c.t = c.t.Add(time.Minute)
+	laptop, laptopToken, _ := s.Create(ctx, 1, Device{Name: "laptop"})
+	c.t = c.t.Add(time.Minute)
+	tablet, _, _ := s.Create(ctx, 1, Device{Name: "tablet"})
+	s.Create(ctx, 2, Device{Name: "someone else"})
+
+	list, err := s.List(ctx, 1)
+	if err != nil || len(list) != 3 || list[0].ID != tablet.ID || list[2].ID != phone.ID {
+		t.Fatalf("Expected 3 sessions most recent first, got %+v, %v", list, err)
+	}
+
+	if err := s.Revoke(ctx, 2, laptop.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected other users' sessions to be unrevokable, got %v", err)
+	}
+	if err := s.Revoke(ctx, 1, laptop.ID); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	if err := s.Revoke(ctx, 1, laptop.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected revoking twice to fail, got %v", err)
+	}
+	if _, _, err := s.Refresh(ctx, laptopToken, ""); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("Expected revoked session's refresh token to be rejected, got %v", err)
+	}
+
+	n, err := s.RevokeOthers(ctx, 1, tablet.ID)
+	if err != nil || n != 1 {
+		t.Errorf("Expected to revoke 1 other session, got %d, %v", n, err)
+	}
+	if list, _ := s.List(ctx, 1); len(list) != 1 || list[0].ID != tablet.ID {
+		t.Errorf("Expected only the kept session, got %+v", list)
+	}
+	if list, _ := s.List(ctx, 2); len(list) != 1 {
+		t.Errorf("Expected other users to be unaffected, got %+v", list)
+	}
+}
+
+func TestActiveUsesRevocationCache(t *testing.T) {
+	s, db, c := newService(t)
+	ctx := context.Background()
+	sess, _, _ := s.Create(ctx, 1, Device{})
+
+	if ok, err := s.Active(ctx, 2, sess.ID); err != nil || ok {
+		t.Errorf("Expected session to be inactive for another user, got %v, %v", ok, err)
+	}
+	if ok, err := s.Active(ctx, 1, sess.ID); err != nil || !ok {
+		t.Fatalf("Expected session to be active, got %v, %v", ok, err)
+	}
+
+	// Revoked by another server instance: this one notices once the
+	// cached answer expires
+	other := NewService(db, 24*time.Hour, 30*time.Second)
+	if err := other.Revoke(ctx, 1, sess.ID); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	if ok, _ := s.Active(ctx, 1, sess.ID); !ok {
+		t.Errorf("Expected cached answer within the cache TTL")
+	}
+	c.t = c.t.Add(31 * time.Second)
+	if ok, _ := s.Active(ctx, 1, sess.ID); ok {
+		t.Errorf("Expected revocation to apply after the cache TTL")
+	}
+
+	// Revoked through this instance: applies immediately
+	sess2, _, _ := s.Create(ctx, 1, Device{})
+	s.Active(ctx, 1, sess2.ID)
+	s.Revoke(ctx, 1, sess2.ID)
+	if ok, _ := s.Active(ctx, 1, sess2.ID); ok {
+		t.Errorf("Expected local revocation to apply immediately")
+	}
+}
@@ -0,0 +1,114 @@
+// Package tenant carries the current organisation through request contexts
+// and scopes repository queries to it.
+//
+// Repositories holding tenant-owned rows query through a tenant.DB instead
+// of the raw connection. Every statement must filter on the @tenant
+// placeholder, which is bound to the organisation in the context; statements
+// without it and contexts without a tenant are rejected, so a forgotten
+// WHERE clause fails loudly instead of leaking another tenant's data.
+package tenant
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+)
+
+// Placeholder is replaced with the current tenant's organisation ID
+const Placeholder = "@tenant"
+
+// Predefined errors
+var (
+	ErrNoTenant      = errors.New("no tenant in context")
+	ErrUnscopedQuery = errors.New("query is not scoped to the tenant")
+)
+
+// Tenant is the organisation a request acts on, together with the caller's
+// role in it
+type Tenant struct {
+	OrgID int64  `json:"org_id"`
+	Slug  string `json:"slug"`
+	Role  string `json:"role"`
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying t
+func NewContext(ctx context.Context, t Tenant) context.Context {
+	return context.WithValue(ctx, contextKey{}, t)
+}
+
+// FromContext returns the tenant stored in ctx
+func FromContext(ctx context.Context) (Tenant, bool) {
+	t, ok := ctx.Value(contextKey{}).(Tenant)
+	return t, ok && t.OrgID != 0
+}
+
+// DB runs tenant-scoped statements
+type DB struct {
+	db *database.DB
+}
+
+// Scoped wraps db so that every statement is bound to the context's tenant
+func Scoped(db *database.DB) *DB {
+	return &DB{db: db}
+}
+
+// Row is the result of QueryRowContext
+type Row struct {
+	row *sql.Row
+	err error
+}
+
+// Scan copies the columns of the row into dest
+func (r *Row) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.row.Scan(dest...)
+}
+
+// QueryContext runs a scoped query returning rows
+func (s *DB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	query, args, err := bind(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return s.db.QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext runs a scoped query returning at most one row
+func (s *DB) QueryRowContext(ctx context.Context, query string, args ...any) *Row {
+	query, args, err := bind(ctx, query, args)
+	if err != nil {
+		return &Row{err: err}
+	}
+	return &Row{row: s.db.QueryRowContext(ctx, query, args...)}
+}
+
+// ExecContext runs a scoped statement
+func (s *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	query, args, err := bind(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return s.db.ExecContext(ctx, query, args...)
+}
+
+// bind replaces the tenant placeholder with the next positional parameter
+// and appends the tenant's organisation ID to args
+func bind(ctx context.Context, query string, args []any) (string, []any, error) {
+	t, ok := FromContext(ctx)
+	if !ok {
+		return "", nil, ErrNoTenant
+	}
+	if !strings.Contains(query, Placeholder) {
+		return "", nil, ErrUnscopedQuery
+	}
+	param := "$" + strconv.Itoa(len(args)+1)
+	return strings.ReplaceAll(query, Placeholder, param), append(args, t.OrgID), nil
+}
@@ -0,0 +1,35 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database/dbtest"
+)
+
+func TestScopedDBRequiresTenantAndPlaceholder(t *testing.T) {
+	db := Scoped(dbtest.New(t))
+	ctx := context.Background()
+
+	if _, err := db.QueryContext(ctx, `SELECT id FROM entries WHERE org_id = @tenant`); !errors.Is(err, ErrNoTenant) {
+		t.Errorf("Expected ErrNoTenant without a tenant, got %v", err)
+	}
+
+	ctx = NewContext(ctx, Tenant{OrgID: 7})
+	if _, err := db.QueryContext(ctx, `SELECT id FROM entries`); !errors.Is(err, ErrUnscopedQuery) {
+		t.Errorf("Expected ErrUnscopedQuery, got %v", err)
+	}
+	if err := db.QueryRowContext(ctx, `SELECT id FROM entries`).Scan(new(int64)); !errors.Is(err, ErrUnscopedQuery) {
+		t.Errorf("Expected ErrUnscopedQuery from QueryRowContext, got %v", err)
+	}
+
+	var orgID, other int64
+	err := db.QueryRowContext(ctx, `SELECT @tenant, $1`, int64(3)).Scan(&orgID, &other)
+	if err != nil {
+		t.Fatalf("Scoped query failed: %v", err)
+	}
+	if orgID != 7 || other != 3 {
+		t.Errorf("Expected tenant bound after positional args, got %d and %d", orgID, other)
+	}
+}
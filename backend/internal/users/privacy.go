@@ -0,0 +1,52 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/privacy"
+)
+
+// PrivacyModule exports and erases the account profile
+type PrivacyModule struct {
+	repo *Repository
+}
+
+// NewPrivacyModule creates the users privacy module
+func NewPrivacyModule(repo *Repository) *PrivacyModule {
+	return &PrivacyModule{repo: repo}
+}
+
+// Name implements privacy.Module
+func (m *PrivacyModule) Name() string {
+	return "users"
+}
+
+// Export implements privacy.Module
+func (m *PrivacyModule) Export(ctx context.Context, userID int64) ([]privacy.Dataset, error) {
+	u, err := m.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return []privacy.Dataset{{
+		Name:    "profile",
+		Columns: []string{"id", "email", "name", "role", "locale", "created_at", "updated_at"},
+		Rows:    [][]any{{u.ID, u.Email, u.Name, u.Role, u.Locale, u.CreatedAt, u.UpdatedAt}},
+	}}, nil
+}
+
+// Delete implements privacy.Module. Anonymisation keeps the row so that
+// foreign keys from aggregate data stay valid, but removes every identifying
+// field and makes the account unusable.
+func (m *PrivacyModule) Delete(ctx context.Context, tx *sql.Tx, userID int64, mode privacy.DeleteMode) error {
+	if mode == privacy.DeleteHard {
+		_, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, userID)
+		return err
+	}
+	now := m.repo.now()
+	_, err := tx.ExecContext(ctx,
+		`UPDATE users SET email = $1, name = '', locale = '', password_hash = '', updated_at = $2, deleted_at = $2 WHERE id = $3`,
+		fmt.Sprintf("deleted-%d@invalid", userID), now, userID)
+	return err
+}
@@ -0,0 +1,142 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+)
+
+// Predefined errors
+var (
+	ErrNotFound   = errors.New("user not found")
+	ErrEmailTaken = errors.New("email already registered")
+)
+
+// Roles
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// User represents an account holder
+type User struct {
+	ID           int64      `json:"id"`
+	Email        string     `json:"email"`
+	Name         string     `json:"name"`
+	Role         string     `json:"role"`
+	Locale       string     `json:"locale"`
+	PasswordHash string     `json:"-"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty"`
+}
+
+// Repository stores users in the database
+type Repository struct {
+	db  *database.DB
+	now func() time.Time
+}
+
+// NewRepository creates a new user repository
+func NewRepository(db *database.DB) *Repository {
+	return &Repository{db: db, now: func() time.Time { return time.Now().UTC() }}
+}
+
+// SetClock replaces the repository's time source, for tests that control
+// time
+func (r *Repository) SetClock(now func() time.Time) {
+	r.now = now
+}
+
+const userColumns = `id, email, name, role, locale, password_hash, created_at, updated_at, deleted_at`
+
+// Create inserts a new user and fills in its ID and timestamps
+func (r *Repository) Create(ctx context.Context, u *User) error {
+	now := r.now()
+	u.Email = strings.ToLower(strings.TrimSpace(u.Email))
+	if u.Role == "" {
+		u.Role = RoleUser
+	}
+
+	if _, err := r.GetByEmail(ctx, u.Email); err == nil {
+		return ErrEmailTaken
+	} else if !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	err := r.db.QueryRowContext(ctx,
+		`INSERT INTO users (email, name, role, password_hash, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		u.Email, u.Name, u.Role, u.PasswordHash, now, now,
+	).Scan(&u.ID)
+	if database.IsUniqueViolation(err) {
+		// Registered concurrently since the check above
+		return ErrEmailTaken
+	}
+	if err != nil {
+		return err
+	}
+	u.CreatedAt = now
+	u.UpdatedAt = now
+	return nil
+}
+
+// GetByID returns the user with the given ID
+func (r *Repository) GetByID(ctx context.Context, id int64) (*User, error) {
+	return r.getOne(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1`, id)
+}
+
+// GetByEmail returns the user with the given email address
+func (r *Repository) GetByEmail(ctx context.Context, email string) (*User, error) {
+	return r.getOne(ctx, `SELECT `+userColumns+` FROM users WHERE email = $1`, strings.ToLower(strings.TrimSpace(email)))
+}
+
+// Locale returns the user's preferred locale, "" when none was chosen
+func (r *Repository) Locale(ctx context.Context, userID int64) (string, error) {
+	var locale string
+	err := r.db.QueryRowContext(ctx, `SELECT locale FROM users WHERE id = $1`, userID).Scan(&locale)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	return locale, err
+}
+
+// SetLocale changes the user's preferred locale; "" clears it
+func (r *Repository) SetLocale(ctx context.Context, userID int64, locale string) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE users SET locale = $1, updated_at = $2 WHERE id = $3`, locale, r.now(), userID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *Repository) getOne(ctx context.Context, query string, args ...any) (*User, error) {
+	u, err := scanUser(r.db.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return u, err
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUser(row scanner) (*User, error) {
+	var u User
+	var deletedAt sql.NullTime
+	if err := row.Scan(&u.ID, &u.Email, &u.Name, &u.Role, &u.Locale, &u.PasswordHash, &u.CreatedAt, &u.UpdatedAt, &deletedAt); err != nil {
+		return nil, err
+	}
+	if deletedAt.Valid {
+		u.DeletedAt = &deletedAt.Time
+	}
+	return &u, nil
+}
@@ -0,0 +1,101 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// blockedPrefixes are ranges that IsPrivate and friends do not cover but
+// that still reach internal hosts
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "this network"
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64, which embeds IPv4
+}
+
+// guard keeps webhooks from reaching the server's own network: endpoints
+// must be public unicast addresses. It is checked when a URL is saved, for
+// early feedback, and again by the dialer of every delivery, which covers
+// DNS rebinding and redirects.
+type guard struct {
+	// allowed are exempt from the check; see Service.AllowAddresses
+	allowed []netip.Prefix
+}
+
+// check returns ErrForbiddenHost unless deliveries may connect to ip
+func (g *guard) check(ip netip.Addr) error {
+	ip = ip.Unmap()
+	for _, p := range g.allowed {
+		if p.Contains(ip) {
+			return nil
+		}
+	}
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return ErrForbiddenHost
+	}
+	for _, p := range blockedPrefixes {
+		if p.Contains(ip) {
+			return ErrForbiddenHost
+		}
+	}
+	return nil
+}
+
+// checkURL checks every address the host of a webhook URL resolves to. A
+// host that does not resolve yet is accepted: the dialer checks it again.
+func (g *guard) checkURL(ctx context.Context, u *url.URL) error {
+	host := u.Hostname()
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return g.check(ip)
+	}
+	ips, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil
+	}
+	for _, ip := range ips {
+		if err := g.check(ip); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// control is a net.Dialer.Control that refuses connections to forbidden
+// addresses; it sees the address after DNS resolution
+func (g *guard) control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if err := g.check(ip); err != nil {
+		return fmt.Errorf("%w: %s", err, ip)
+	}
+	return nil
+}
+
+// client returns an HTTP client whose connections go through the guard.
+// It ignores proxy settings, as a proxy would make the dial address
+// meaningless.
+func (g *guard) client() *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second, Control: g.control}
+	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
+		ForceAttemptHTTP2:   true,
+		MaxIdleConns:        100,
+		IdleConnTimeout:     90 * time.Second,
+		TLSHandshakeTimeout: 5 * time.Second,
+	}
+	return &http.Client{Timeout: 10 * time.Second, Transport: transport}
+}
@@ -0,0 +1,54 @@
+package webhooks
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/privacy"
+)
+
+// PrivacyModule exports and removes the webhooks a user created
+type PrivacyModule struct {
+	db *database.DB
+}
+
+// NewPrivacyModule creates the webhooks privacy module
+func NewPrivacyModule(db *database.DB) *PrivacyModule {
+	return &PrivacyModule{db: db}
+}
+
+// Name implements privacy.Module
+func (m *PrivacyModule) Name() string {
+	return "webhooks"
+}
+
+// Export implements privacy.Module. Signing secrets are left out.
+func (m *PrivacyModule) Export(ctx context.Context, userID int64) ([]privacy.Dataset, error) {
+	rows, err := m.db.QueryContext(ctx,
+		`SELECT `+webhookColumns+` FROM webhooks WHERE user_id = $1 ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ds := privacy.Dataset{
+		Name:    "webhooks",
+		Columns: []string{"id", "org_id", "url", "events", "active", "created_at"},
+	}
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		ds.Rows = append(ds.Rows, []any{w.ID, w.OrgID, w.URL, joinEvents(w.Events), w.Active, w.CreatedAt})
+	}
+	return []privacy.Dataset{ds}, rows.Err()
+}
+
+// Delete implements privacy.Module. Endpoints are personal data of their
+// creator, so they are removed in both modes; deliveries cascade.
+func (m *PrivacyModule) Delete(ctx context.Context, tx *sql.Tx, userID int64, mode privacy.DeleteMode) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM webhooks WHERE user_id = $1`, userID)
+	return err
+}
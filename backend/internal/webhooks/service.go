@@ -0,0 +1,549 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+)
+
+// Delivery policy defaults
+const (
+	DefaultMaxAttempts  = 6
+	DefaultBaseDelay    = 30 * time.Second
+	DefaultMaxDelay     = 6 * time.Hour
+	DefaultDisableAfter = 15
+)
+
+// Scope selects whose subscriptions an operation acts on: the personal ones
+// of UserID, or those of OrgID when it is set
+type Scope struct {
+	UserID int64
+	OrgID  int64
+}
+
+func (sc Scope) filter(n int) (string, []any) {
+	if sc.OrgID != 0 {
+		return fmt.Sprintf("org_id = $%d", n), []any{sc.OrgID}
+	}
+	return fmt.Sprintf("user_id = $%d AND org_id IS NULL", n), []any{sc.UserID}
+}
+
+// Changes is a partial update of a webhook; nil fields are left unchanged
+type Changes struct {
+	URL    *string
+	Events []string
+	Active *bool
+}
+
+// Service manages subscriptions and delivers events to them
+type Service struct {
+	db     *database.DB
+	client *http.Client
+	guard  *guard
+	now    func() time.Time
+
+	maxAttempts  int
+	baseDelay    time.Duration
+	maxDelay     time.Duration
+	disableAfter int
+}
+
+// NewService creates a webhook service. A nil client uses one with a
+// 10 second timeout that only connects to public addresses; a client given
+// is used as is.
+func NewService(db *database.DB, client *http.Client) *Service {
+	g := &guard{}
+	if client == nil {
+		client = g.client()
+	}
+	return &Service{
+		db:           db,
+		client:       client,
+		guard:        g,
+		now:          func() time.Time { return time.Now().UTC() },
+		maxAttempts:  DefaultMaxAttempts,
+		baseDelay:    DefaultBaseDelay,
+		maxDelay:     DefaultMaxDelay,
+		disableAfter: DefaultDisableAfter,
+	}
+}
+
+// AllowAddresses exempts prefixes from the public address check, for tests
+// that deliver to an httptest server on loopback. Call it before using the
+// service.
+func (s *Service) AllowAddresses(prefixes ...netip.Prefix) {
+	s.guard.allowed = append(s.guard.allowed, prefixes...)
+}
+
+// SetClock replaces the service's time source, for tests that control time
+func (s *Service) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+const webhookColumns = `id, user_id, org_id, url, secret, events, active, failure_count, disabled_at, created_at, updated_at`
+
+const deliveryColumns = `id, webhook_id, event_id, event_type, payload, status, attempts, next_attempt_at,
+	response_status, error, created_at, delivered_at`
+
+// Create subscribes a new endpoint in the given scope and generates its
+// signing secret
+func (s *Service) Create(ctx context.Context, scope Scope, rawURL string, events []string) (*Webhook, error) {
+	if err := s.validateURL(ctx, rawURL); err != nil {
+		return nil, err
+	}
+	if !ValidEvents(events) {
+		return nil, ErrInvalidEvent
+	}
+	secret, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	w := &Webhook{
+		UserID:    scope.UserID,
+		OrgID:     scope.OrgID,
+		URL:       rawURL,
+		Secret:    secret,
+		Events:    events,
+		Active:    true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	err = s.db.QueryRowContext(ctx,
+		`INSERT INTO webhooks (user_id, org_id, url, secret, events, active, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $7) RETURNING id`,
+		w.UserID, nullID(w.OrgID), w.URL, w.Secret, joinEvents(w.Events), true, now,
+	).Scan(&w.ID)
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// List returns the subscriptions of the scope
+func (s *Service) List(ctx context.Context, scope Scope) ([]Webhook, error) {
+	where, args := scope.filter(1)
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+webhookColumns+` FROM webhooks WHERE `+where+` ORDER BY id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []Webhook{}
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *w)
+	}
+	return list, rows.Err()
+}
+
+// Get returns one subscription of the scope
+func (s *Service) Get(ctx context.Context, scope Scope, id int64) (*Webhook, error) {
+	where, args := scope.filter(2)
+	w, err := scanWebhook(s.db.QueryRowContext(ctx,
+		`SELECT `+webhookColumns+` FROM webhooks WHERE id = $1 AND `+where, append([]any{id}, args...)...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return w, err
+}
+
+// Update applies changes to a subscription. Re-activating a webhook resets
+// its failure count.
+func (s *Service) Update(ctx context.Context, scope Scope, id int64, changes Changes) (*Webhook, error) {
+	w, err := s.Get(ctx, scope, id)
+	if err != nil {
+		return nil, err
+	}
+	if changes.URL != nil {
+		if err := s.validateURL(ctx, *changes.URL); err != nil {
+			return nil, err
+		}
+		w.URL = *changes.URL
+	}
+	if changes.Events != nil {
+		if !ValidEvents(changes.Events) {
+			return nil, ErrInvalidEvent
+		}
+		w.Events = changes.Events
+	}
+	if changes.Active != nil && *changes.Active != w.Active {
+		w.Active = *changes.Active
+		w.FailureCount = 0
+		w.DisabledAt = nil
+	}
+	w.UpdatedAt = s.now()
+
+	_, err = s.db.ExecContext(ctx,
+		`UPDATE webhooks SET url = $1, events = $2, active = $3, failure_count = $4, disabled_at = $5, updated_at = $6
+		 WHERE id = $7`,
+		w.URL, joinEvents(w.Events), w.Active, w.FailureCount, w.DisabledAt, w.UpdatedAt, w.ID)
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Delete removes a subscription together with its delivery log
+func (s *Service) Delete(ctx context.Context, scope Scope, id int64) error {
+	where, args := scope.filter(2)
+	res, err := s.db.ExecContext(ctx,
+		`DELETE FROM webhooks WHERE id = $1 AND `+where, append([]any{id}, args...)...)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Publish queues a delivery of evt for every active subscription that wants
+// it: the organization's webhooks and the acting user's personal ones
+func (s *Service) Publish(ctx context.Context, evt Event) error {
+	if evt.ID == "" {
+		id, err := newEventID()
+		if err != nil {
+			return err
+		}
+		evt.ID = id
+	}
+	if evt.OccurredAt.IsZero() {
+		evt.OccurredAt = s.now()
+	}
+	payload, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+webhookColumns+` FROM webhooks
+		 WHERE active = $1 AND ((org_id IS NOT NULL AND org_id = $2) OR (org_id IS NULL AND user_id = $3))
+		 ORDER BY id`,
+		true, evt.OrgID, evt.UserID)
+	if err != nil {
+		return err
+	}
+	var targets []int64
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		if w.Subscribed(evt.Type) {
+			targets = append(targets, w.ID)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(targets) == 0 {
+		return err
+	}
+
+	now := s.now()
+	return s.db.WithTx(ctx, func(tx *sql.Tx) error {
+		for _, id := range targets {
+			_, err := tx.ExecContext(ctx,
+				`INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload, status, next_attempt_at, created_at)
+				 VALUES ($1, $2, $3, $4, $5, $6, $6)`,
+				id, evt.ID, evt.Type, string(payload), DeliveryPending, now)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Deliveries returns the delivery log of one of the scope's webhooks,
+// newest first
+func (s *Service) Deliveries(ctx context.Context, scope Scope, webhookID int64) ([]Delivery, error) {
+	if _, err := s.Get(ctx, scope, webhookID); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE webhook_id = $1 ORDER BY id DESC LIMIT 100`,
+		webhookID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []Delivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *d)
+	}
+	return list, rows.Err()
+}
+
+// Redeliver queues a fresh delivery of the same event and payload as an
+// earlier delivery of one of the scope's webhooks
+func (s *Service) Redeliver(ctx context.Context, scope Scope, webhookID, deliveryID int64) (*Delivery, error) {
+	if _, err := s.Get(ctx, scope, webhookID); err != nil {
+		return nil, err
+	}
+	orig, err := scanDelivery(s.db.QueryRowContext(ctx,
+		`SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE id = $1 AND webhook_id = $2`,
+		deliveryID, webhookID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDeliveryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	d := &Delivery{
+		WebhookID:     webhookID,
+		EventID:       orig.EventID,
+		EventType:     orig.EventType,
+		Payload:       orig.Payload,
+		Status:        DeliveryPending,
+		NextAttemptAt: &now,
+		CreatedAt:     now,
+	}
+	err = s.db.QueryRowContext(ctx,
+		`INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload, status, next_attempt_at, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $6) RETURNING id`,
+		d.WebhookID, d.EventID, d.EventType, d.Payload, d.Status, now,
+	).Scan(&d.ID)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+type dueDelivery struct {
+	Delivery
+	url    string
+	secret string
+	active bool
+}
+
+// ProcessDeliveries attempts every pending delivery that is due and returns
+// how many were attempted
+func (s *Service) ProcessDeliveries(ctx context.Context) (int, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT d.id, d.webhook_id, d.event_id, d.event_type, d.payload, d.attempts, w.url, w.secret, w.active
+		 FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+		 WHERE d.status = $1 AND d.next_attempt_at <= $2
+		 ORDER BY d.id LIMIT 100`,
+		DeliveryPending, s.now())
+	if err != nil {
+		return 0, err
+	}
+	var due []dueDelivery
+	for rows.Next() {
+		var d dueDelivery
+		err := rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Payload, &d.Attempts, &d.url, &d.secret, &d.active)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		due = append(due, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for i := range due {
+		if err := s.attempt(ctx, &due[i]); err != nil {
+			return i, err
+		}
+	}
+	return len(due), nil
+}
+
+// attempt sends one delivery and records the outcome on the delivery and
+// its webhook
+func (s *Service) attempt(ctx context.Context, d *dueDelivery) error {
+	now := s.now()
+	if !d.active {
+		_, err := s.db.ExecContext(ctx,
+			`UPDATE webhook_deliveries SET status = $1, error = $2, next_attempt_at = NULL WHERE id = $3`,
+			DeliveryFailed, "webhook disabled", d.ID)
+		return err
+	}
+
+	status, sendErr := s.send(ctx, d, now)
+	d.Attempts++
+
+	return s.db.WithTx(ctx, func(tx *sql.Tx) error {
+		if sendErr == nil {
+			_, err := tx.ExecContext(ctx,
+				`UPDATE webhook_deliveries SET status = $1, attempts = $2, response_status = $3, error = '',
+				 next_attempt_at = NULL, delivered_at = $4 WHERE id = $5`,
+				DeliverySucceeded, d.Attempts, status, now, d.ID)
+			if err != nil {
+				return err
+			}
+			_, err = tx.ExecContext(ctx, `UPDATE webhooks SET failure_count = 0 WHERE id = $1`, d.WebhookID)
+			return err
+		}
+
+		deliveryStatus, next := DeliveryPending, sql.NullTime{Time: now.Add(s.backoff(d.Attempts)), Valid: true}
+		if d.Attempts >= s.maxAttempts {
+			deliveryStatus, next = DeliveryFailed, sql.NullTime{}
+		}
+		_, err := tx.ExecContext(ctx,
+			`UPDATE webhook_deliveries SET status = $1, attempts = $2, response_status = $3, error = $4,
+			 next_attempt_at = $5 WHERE id = $6`,
+			deliveryStatus, d.Attempts, status, sendErr.Error(), next, d.ID)
+		if err != nil {
+			return err
+		}
+
+		var failures int
+		err = tx.QueryRowContext(ctx,
+			`UPDATE webhooks SET failure_count = failure_count + 1 WHERE id = $1 RETURNING failure_count`,
+			d.WebhookID).Scan(&failures)
+		if err != nil {
+			return err
+		}
+		if failures >= s.disableAfter {
+			_, err = tx.ExecContext(ctx,
+				`UPDATE webhooks SET active = $1, disabled_at = $2, updated_at = $2 WHERE id = $3`,
+				false, now, d.WebhookID)
+			if err == nil {
+				log.Printf("webhooks: disabled webhook %d after %d consecutive failures", d.WebhookID, failures)
+			}
+		}
+		return err
+	})
+}
+
+// send POSTs the signed payload and returns the response status. Anything
+// but a 2xx response is an error.
+func (s *Service) send(ctx context.Context, d *dueDelivery, now time.Time) (int, error) {
+	body := []byte(d.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "sum25-webhooks/1")
+	req.Header.Set(EventHeader, d.EventType)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(d.ID, 10))
+	req.Header.Set(SignatureHeader, Sign(d.secret, now, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded with %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff returns the delay before the next attempt after the given number
+// of failed attempts: baseDelay, doubled per attempt, capped at maxDelay
+func (s *Service) backoff(attempts int) time.Duration {
+	delay := s.baseDelay
+	for i := 1; i < attempts && delay < s.maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, s.maxDelay)
+}
+
+// Run processes due deliveries every interval until ctx is cancelled
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.ProcessDeliveries(ctx); err != nil {
+			log.Printf("webhooks: process deliveries: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanWebhook(row scanner) (*Webhook, error) {
+	var w Webhook
+	var orgID sql.NullInt64
+	var events string
+	err := row.Scan(&w.ID, &w.UserID, &orgID, &w.URL, &w.Secret, &events, &w.Active,
+		&w.FailureCount, &w.DisabledAt, &w.CreatedAt, &w.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	w.OrgID = orgID.Int64
+	w.Events = splitEvents(events)
+	return &w, nil
+}
+
+func scanDelivery(row scanner) (*Delivery, error) {
+	var d Delivery
+	err := row.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Payload, &d.Status, &d.Attempts,
+		&d.NextAttemptAt, &d.ResponseStatus, &d.Error, &d.CreatedAt, &d.DeliveredAt)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// validateURL accepts absolute http and https URLs of public hosts
+func (s *Service) validateURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return ErrInvalidURL
+	}
+	return s.guard.checkURL(ctx, u)
+}
+
+func nullID(id int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: id, Valid: id != 0}
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+func newEventID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "evt_" + hex.EncodeToString(b), nil
+}
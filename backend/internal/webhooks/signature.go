@@ -0,0 +1,74 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Delivery request headers
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+// Signature verification errors
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrStaleSignature   = errors.New("webhook signature timestamp outside tolerance")
+)
+
+// Sign returns the X-Webhook-Signature header value for body sent at ts:
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">". Binding the
+// timestamp into the MAC lets receivers reject replayed deliveries.
+func Sign(secret string, ts time.Time, body []byte) string {
+	t := strconv.FormatInt(ts.Unix(), 10)
+	return "t=" + t + ",v1=" + hex.EncodeToString(mac(secret, t, body))
+}
+
+// Verify checks a signature header produced by Sign. Signatures older or
+// newer than tolerance relative to now are rejected.
+func Verify(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var t string
+	var sigs [][]byte
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			t = value
+		case "v1":
+			if sig, err := hex.DecodeString(value); err == nil {
+				sigs = append(sigs, sig)
+			}
+		}
+	}
+
+	unix, err := strconv.ParseInt(t, 10, 64)
+	if err != nil || len(sigs) == 0 {
+		return ErrInvalidSignature
+	}
+	if d := now.Sub(time.Unix(unix, 0)); d > tolerance || d < -tolerance {
+		return ErrStaleSignature
+	}
+
+	expected := mac(secret, t, body)
+	for _, sig := range sigs {
+		if hmac.Equal(sig, expected) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+func mac(secret, t string, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(t))
+	h.Write([]byte("."))
+	h.Write(body)
+	return h.Sum(nil)
+}
@@ -0,0 +1,125 @@
+// Package webhooks notifies external integrations about changes to user
+// content.
+//
+// Users subscribe an endpoint, either for their own events or for all
+// events of an organization they manage, and pick the event types they are
+// interested in. Published events become deliveries that a background
+// worker POSTs to the endpoint, signed with the subscription's secret and
+// retried with exponential backoff. Subscriptions that keep failing are
+// disabled automatically.
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Predefined errors
+var (
+	ErrNotFound         = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("delivery not found")
+	ErrInvalidURL       = errors.New("webhook url must be an absolute http or https url")
+	ErrForbiddenHost    = errors.New("webhook url must not point to a private or local address")
+	ErrInvalidEvent     = errors.New("unknown event type")
+)
+
+// Event types
+const (
+	EventEntryCreated = "entry.created"
+	EventEntryUpdated = "entry.updated"
+	EventEntryDeleted = "entry.deleted"
+
+	// EventAll subscribes to every event type
+	EventAll = "*"
+)
+
+// EventTypes lists the event types that can be subscribed to
+var EventTypes = []string{EventEntryCreated, EventEntryUpdated, EventEntryDeleted}
+
+// Delivery statuses
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// Event is a change that subscribers are notified about. OrgID is zero for
+// events outside of an organization.
+type Event struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	OrgID      int64     `json:"org_id,omitempty"`
+	UserID     int64     `json:"user_id"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Data       any       `json:"data"`
+}
+
+// Publisher accepts events for delivery
+type Publisher interface {
+	Publish(ctx context.Context, evt Event) error
+}
+
+// Webhook is a subscription of an endpoint to events. Subscriptions with an
+// OrgID receive the events of that organization, the others the events of
+// their user.
+type Webhook struct {
+	ID           int64      `json:"id"`
+	UserID       int64      `json:"user_id"`
+	OrgID        int64      `json:"org_id,omitempty"`
+	URL          string     `json:"url"`
+	Secret       string     `json:"-"`
+	Events       []string   `json:"events"`
+	Active       bool       `json:"active"`
+	FailureCount int        `json:"failure_count"`
+	DisabledAt   *time.Time `json:"disabled_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// Subscribed reports whether the webhook wants events of the given type
+func (w *Webhook) Subscribed(eventType string) bool {
+	return slices.Contains(w.Events, EventAll) || slices.Contains(w.Events, eventType)
+}
+
+// Delivery is one event sent, or to be sent, to a webhook
+type Delivery struct {
+	ID             int64      `json:"id"`
+	WebhookID      int64      `json:"webhook_id"`
+	EventID        string     `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Payload        string     `json:"payload"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	ResponseStatus int        `json:"response_status,omitempty"`
+	Error          string     `json:"error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
+
+// ValidEvents reports whether every entry is a known event type or EventAll
+func ValidEvents(events []string) bool {
+	if len(events) == 0 {
+		return false
+	}
+	for _, e := range events {
+		if e != EventAll && !slices.Contains(EventTypes, e) {
+			return false
+		}
+	}
+	return true
+}
+
+func joinEvents(events []string) string {
+	return strings.Join(events, ",")
+}
+
+func splitEvents(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, ",")
+}
@@ -0,0 +1,327 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database/dbtest"
+)
+
+type received struct {
+	headers http.Header
+	body    []byte
+}
+
+// receiver is an httptest endpoint that records requests and answers with
+// a configurable status
+type receiver struct {
+	*httptest.Server
+	status atomic.Int32
+	mu     sync.Mutex
+	got    []received
+}
+
+func newReceiver(t *testing.T) *receiver {
+	r := &receiver{}
+	r.status.Store(http.StatusOK)
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		r.got = append(r.got, received{headers: req.Header.Clone(), body: body})
+		r.mu.Unlock()
+		w.WriteHeader(int(r.status.Load()))
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *receiver) requests() []received {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]received(nil), r.got...)
+}
+
+// loopback lets the tests deliver to httptest servers
+var loopback = []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8"), netip.MustParsePrefix("::1/128")}
+
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time          { return c.t }
+func (c *clock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func setup(t *testing.T) (*Service, *database.DB, *clock) {
+	t.Helper()
+	db := dbtest.New(t)
+	ctx := context.Background()
+	for _, email := range []string{"alice@example.com", "bob@example.com"} {
+		if _, err := db.ExecContext(ctx, `INSERT INTO users (email, name, password_hash) VALUES ($1, '', '')`, email); err != nil {
+			t.Fatalf("insert user: %v", err)
+		}
+	}
+	if _, err := db.ExecContext(ctx, `INSERT INTO organizations (name, slug) VALUES ('Org', 'org')`); err != nil {
+		t.Fatalf("insert org: %v", err)
+	}
+
+	c := &clock{t: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+	s := NewService(db, nil)
+	s.AllowAddresses(loopback...)
+	s.now = c.now
+	return s, db, c
+}
+
+func TestSignAndVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"type":"entry.created"}`)
+	header := Sign("secret", now, body)
+
+	if err := Verify("secret", header, body, 5*time.Minute, now.Add(time.Minute)); err != nil {
+		t.Errorf("Expected valid signature, got %v", err)
+	}
+	if err := Verify("other", header, body, 5*time.Minute, now); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature for wrong secret, got %v", err)
+	}
+	if err := Verify("secret", header, []byte(`{}`), 5*time.Minute, now); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature for tampered body, got %v", err)
+	}
+	if err := Verify("secret", header, body, 5*time.Minute, now.Add(10*time.Minute)); !errors.Is(err, ErrStaleSignature) {
+		t.Errorf("Expected ErrStaleSignature, got %v", err)
+	}
+	if err := Verify("secret", "garbage", body, 5*time.Minute, now); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature for malformed header, got %v", err)
+	}
+}
+
+func TestSignedDelivery(t *testing.T) {
+	s, _, c := setup(t)
+	ctx := context.Background()
+	recv := newReceiver(t)
+
+	w, err := s.Create(ctx, Scope{UserID: 1}, recv.URL, []string{EventEntryCreated})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	s.Publish(ctx, Event{Type: EventEntryCreated, OrgID: 1, UserID: 1, Data: map[string]any{"id": 7}})
+	s.Publish(ctx, Event{Type: EventEntryDeleted, OrgID: 1, UserID: 1, Data: map[string]any{"id": 7}})
+
+	n, err := s.ProcessDeliveries(ctx)
+	if err != nil || n != 1 {
+		t.Fatalf("Expected 1 delivery for the subscribed event, got %d, %v", n, err)
+	}
+
+	reqs := recv.requests()
+	if len(reqs) != 1 {
+		t.Fatalf("Expected 1 request, got %d", len(reqs))
+	}
+	got := reqs[0]
+	if err := Verify(w.Secret, got.headers.Get(SignatureHeader), got.body, time.Minute, c.now()); err != nil {
+		t.Errorf("Expected receiver to verify signature, got %v", err)
+	}
+	if got.headers.Get(EventHeader) != EventEntryCreated {
+		t.Errorf("Expected event header %s, got %s", EventEntryCreated, got.headers.Get(EventHeader))
+	}
+	var evt Event
+	if err := json.Unmarshal(got.body, &evt); err != nil || evt.Type != EventEntryCreated || evt.ID == "" {
+		t.Errorf("Unexpected payload %s: %v", got.body, err)
+	}
+
+	log, err := s.Deliveries(ctx, Scope{UserID: 1}, w.ID)
+	if err != nil || len(log) != 1 || log[0].Status != DeliverySucceeded || log[0].ResponseStatus != http.StatusOK {
+		t.Errorf("Expected one succeeded delivery in the log, got %+v, %v", log, err)
+	}
+}
+
+func TestRetryWithBackoff(t *testing.T) {
+	s, _, c := setup(t)
+	ctx := context.Background()
+	recv := newReceiver(t)
+	recv.status.Store(http.StatusInternalServerError)
+	s.maxAttempts = 3
+
+	w, _ := s.Create(ctx, Scope{UserID: 1}, recv.URL, []string{EventAll})
+	s.Publish(ctx, Event{Type: EventEntryUpdated, UserID: 1})
+
+	if n, _ := s.ProcessDeliveries(ctx); n != 1 {
+		t.Fatalf("Expected first attempt, got %d", n)
+	}
+	if n, _ := s.ProcessDeliveries(ctx); n != 0 {
+		t.Errorf("Expected retry to wait for backoff, got %d attempts", n)
+	}
+
+	c.advance(DefaultBaseDelay)
+	if n, _ := s.ProcessDeliveries(ctx); n != 1 {
+		t.Errorf("Expected second attempt after %v, got %d", DefaultBaseDelay, n)
+	}
+	c.advance(DefaultBaseDelay)
+	if n, _ := s.ProcessDeliveries(ctx); n != 0 {
+		t.Errorf("Expected backoff to double, got %d attempts", n)
+	}
+	c.advance(DefaultBaseDelay)
+	s.ProcessDeliveries(ctx)
+
+	log, _ := s.Deliveries(ctx, Scope{UserID: 1}, w.ID)
+	if len(log) != 1 || log[0].Status != DeliveryFailed || log[0].Attempts != 3 || log[0].ResponseStatus != 500 {
+		t.Fatalf("Expected delivery to fail after 3 attempts, got %+v", log)
+	}
+	if len(recv.requests()) != 3 {
+		t.Errorf("Expected 3 requests, got %d", len(recv.requests()))
+	}
+
+	// Manual redelivery once the endpoint is healthy again
+	recv.status.Store(http.StatusNoContent)
+	d, err := s.Redeliver(ctx, Scope{UserID: 1}, w.ID, log[0].ID)
+	if err != nil {
+		t.Fatalf("Redeliver failed: %v", err)
+	}
+	s.ProcessDeliveries(ctx)
+	log, _ = s.Deliveries(ctx, Scope{UserID: 1}, w.ID)
+	if log[0].ID != d.ID || log[0].Status != DeliverySucceeded || log[0].EventID != log[1].EventID {
+		t.Errorf("Expected redelivery of the same event to succeed, got %+v", log)
+	}
+}
+
+func TestBackoffIsCapped(t *testing.T) {
+	s := NewService(nil, nil)
+	want := []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute, 4 * time.Minute}
+	for i, d := range want {
+		if got := s.backoff(i + 1); got != d {
+			t.Errorf("backoff(%d) = %v, want %v", i+1, got, d)
+		}
+	}
+	if got := s.backoff(50); got != DefaultMaxDelay {
+		t.Errorf("Expected backoff to be capped at %v, got %v", DefaultMaxDelay, got)
+	}
+}
+
+func TestAutoDisable(t *testing.T) {
+	s, _, _ := setup(t)
+	ctx := context.Background()
+	recv := newReceiver(t)
+	recv.status.Store(http.StatusBadGateway)
+	s.disableAfter = 2
+
+	w, _ := s.Create(ctx, Scope{UserID: 1}, recv.URL, []string{EventAll})
+	s.Publish(ctx, Event{Type: EventEntryCreated, UserID: 1})
+	s.Publish(ctx, Event{Type: EventEntryUpdated, UserID: 1})
+	s.ProcessDeliveries(ctx)
+
+	got, _ := s.Get(ctx, Scope{UserID: 1}, w.ID)
+	if got.Active || got.DisabledAt == nil || got.FailureCount != 2 {
+		t.Fatalf("Expected webhook to be disabled, got %+v", got)
+	}
+
+	// Disabled webhooks receive no new events
+	s.Publish(ctx, Event{Type: EventEntryCreated, UserID: 1})
+	if log, _ := s.Deliveries(ctx, Scope{UserID: 1}, w.ID); len(log) != 2 {
+		t.Errorf("Expected no deliveries queued for a disabled webhook, got %d", len(log))
+	}
+
+	active := true
+	got, err := s.Update(ctx, Scope{UserID: 1}, w.ID, Changes{Active: &active})
+	if err != nil || !got.Active || got.FailureCount != 0 || got.DisabledAt != nil {
+		t.Errorf("Expected re-enabling to reset failures, got %+v, %v", got, err)
+	}
+}
+
+func TestScopes(t *testing.T) {
+	s, _, _ := setup(t)
+	ctx := context.Background()
+	recv := newReceiver(t)
+
+	orgHook, _ := s.Create(ctx, Scope{UserID: 1, OrgID: 1}, recv.URL, []string{EventAll})
+	bobHook, _ := s.Create(ctx, Scope{UserID: 2}, recv.URL, []string{EventAll})
+
+	// Alice's change in the organization reaches the org webhook only
+	s.Publish(ctx, Event{Type: EventEntryCreated, OrgID: 1, UserID: 1})
+	if log, _ := s.Deliveries(ctx, Scope{OrgID: 1}, orgHook.ID); len(log) != 1 {
+		t.Errorf("Expected org webhook to get the event, got %d deliveries", len(log))
+	}
+	if log, _ := s.Deliveries(ctx, Scope{UserID: 2}, bobHook.ID); len(log) != 0 {
+		t.Errorf("Expected bob's personal webhook to get nothing, got %d deliveries", len(log))
+	}
+
+	if _, err := s.Get(ctx, Scope{UserID: 1}, orgHook.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected org webhook to be invisible in the personal scope, got %v", err)
+	}
+	if err := s.Delete(ctx, Scope{UserID: 1}, bobHook.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected other users' webhooks to be untouchable, got %v", err)
+	}
+	if list, _ := s.List(ctx, Scope{UserID: 2}); len(list) != 1 || list[0].ID != bobHook.ID {
+		t.Errorf("Expected bob to list his webhook, got %+v", list)
+	}
+}
+
+func TestValidation(t *testing.T) {
+	s, _, _ := setup(t)
+	ctx := context.Background()
+
+	if _, err := s.Create(ctx, Scope{UserID: 1}, "ftp://example.com", []string{EventAll}); !errors.Is(err, ErrInvalidURL) {
+		t.Errorf("Expected ErrInvalidURL, got %v", err)
+	}
+	if _, err := s.Create(ctx, Scope{UserID: 1}, "https://example.com/hook", []string{"goal.exploded"}); !errors.Is(err, ErrInvalidEvent) {
+		t.Errorf("Expected ErrInvalidEvent, got %v", err)
+	}
+}
+
+func TestForbiddenHosts(t *testing.T) {
+	s, db, _ := setup(t)
+	ctx := context.Background()
+	strict := NewService(db, nil)
+
+	for _, u := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://[::1]/hook",
+		"http://localhost/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://10.0.0.1/hook",
+		"https://192.168.1.10/hook",
+		"http://172.16.0.1/hook",
+		"http://100.64.0.1/hook",
+		"http://0.0.0.0/hook",
+		"http://[::ffff:10.0.0.1]/hook",
+		"http://[fd00::1]/hook",
+	} {
+		if _, err := strict.Create(ctx, Scope{UserID: 1}, u, []string{EventAll}); !errors.Is(err, ErrForbiddenHost) {
+			t.Errorf("Create(%q): expected ErrForbiddenHost, got %v", u, err)
+		}
+	}
+	if _, err := strict.Create(ctx, Scope{UserID: 1}, "https://93.184.215.14/hook", []string{EventAll}); err != nil {
+		t.Errorf("Expected a public address to be accepted, got %v", err)
+	}
+	w, _ := strict.Create(ctx, Scope{UserID: 1}, "https://93.184.215.14/hook", []string{EventAll})
+	if _, err := strict.Update(ctx, Scope{UserID: 1}, w.ID, Changes{URL: ptr("http://10.1.2.3/")}); !errors.Is(err, ErrForbiddenHost) {
+		t.Errorf("Update: expected ErrForbiddenHost, got %v", err)
+	}
+
+	// A host that resolved to a public address when saved but points
+	// inside later, as with DNS rebinding, is refused by the dialer
+	recv := newReceiver(t)
+	hook, err := s.Create(ctx, Scope{UserID: 2}, recv.URL, []string{EventAll})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	strict.Publish(ctx, Event{Type: EventEntryCreated, UserID: 2})
+	strict.ProcessDeliveries(ctx)
+	if got := recv.requests(); len(got) != 0 {
+		t.Errorf("Expected no request to reach a loopback receiver, got %d", len(got))
+	}
+	log, _ := strict.Deliveries(ctx, Scope{UserID: 2}, hook.ID)
+	if len(log) != 1 || log[0].Status == DeliverySucceeded || !strings.Contains(log[0].Error, "private or local") {
+		t.Errorf("Expected the delivery to fail at connect time, got %+v", log)
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS feature_flags (
+    key        TEXT PRIMARY KEY,
+    definition TEXT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS entries (
+    id         BIGSERIAL PRIMARY KEY,
+    org_id     BIGINT NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    user_id    BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    title      TEXT NOT NULL,
+    body       TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_entries_org_user ON entries (org_id, user_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_entries_search_vector;
+ALTER TABLE entries DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE entries DROP COLUMN IF EXISTS language;
+ALTER TABLE users DROP COLUMN IF EXISTS search_language;
@@ -0,0 +1,12 @@
+-- Text search configuration used for a user's content, e.g. 'english'
+ALTER TABLE users ADD COLUMN IF NOT EXISTS search_language REGCONFIG NOT NULL DEFAULT 'simple';
+
+ALTER TABLE entries ADD COLUMN IF NOT EXISTS language REGCONFIG NOT NULL DEFAULT 'simple';
+
+ALTER TABLE entries ADD COLUMN IF NOT EXISTS search_vector TSVECTOR
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector(language, coalesce(title, '')), 'A') ||
+        setweight(to_tsvector(language, coalesce(body, '')), 'B')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_entries_search_vector ON entries USING GIN (search_vector);
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS oidc_identities (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    provider   TEXT NOT NULL,
+    subject    TEXT NOT NULL,
+    email      TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_oidc_identities_user_id ON oidc_identities (user_id);
+
+CREATE TABLE IF NOT EXISTS oidc_logins (
+    state         TEXT PRIMARY KEY,
+    provider      TEXT NOT NULL,
+    user_id       BIGINT REFERENCES users (id) ON DELETE CASCADE,
+    code_verifier TEXT NOT NULL,
+    nonce         TEXT NOT NULL,
+    expires_at    TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS mfa_totp (
+    user_id        BIGINT PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    secret         TEXT NOT NULL,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    failures       INTEGER NOT NULL DEFAULT 0,
+    locked_until   TIMESTAMPTZ,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    enabled_at     TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash  TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    used_at    TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_mfa_recovery_codes_user_id ON mfa_recovery_codes (user_id);
+
+CREATE TABLE IF NOT EXISTS mfa_required_roles (
+    role       TEXT PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS email_outbox (
+    id              BIGSERIAL PRIMARY KEY,
+    user_id         BIGINT REFERENCES users (id) ON DELETE CASCADE,
+    template        TEXT NOT NULL DEFAULT '',
+    to_address      TEXT NOT NULL,
+    subject         TEXT NOT NULL,
+    html_body       TEXT NOT NULL DEFAULT '',
+    text_body       TEXT NOT NULL DEFAULT '',
+    status          TEXT NOT NULL DEFAULT 'pending',
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ,
+    error           TEXT NOT NULL DEFAULT '',
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    sent_at         TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_outbox_due ON email_outbox (status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_email_outbox_user_id ON email_outbox (user_id);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_deletion_requests_scheduled;
@@ -0,0 +1,3 @@
+-- Hashes cannot be turned back into tokens: links issued so far stop working
+ALTER TABLE data_exports ADD COLUMN IF NOT EXISTS token TEXT NOT NULL DEFAULT '';
+ALTER TABLE data_exports DROP COLUMN IF EXISTS token_hash;
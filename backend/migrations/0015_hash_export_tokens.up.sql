@@ -0,0 +1,13 @@
+-- Export download tokens are stored as SHA-256 hashes, like invitation
+-- tokens. Tokens already issued are hashed in place so their links keep
+-- working.
+ALTER TABLE data_exports ADD COLUMN IF NOT EXISTS token_hash TEXT NOT NULL DEFAULT '';
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_schema = current_schema() AND table_name = 'data_exports' AND column_name = 'token') THEN
+        UPDATE data_exports SET token_hash = encode(sha256(convert_to(token, 'UTF8')), 'hex');
+        ALTER TABLE data_exports DROP COLUMN token;
+    END IF;
+END $$;
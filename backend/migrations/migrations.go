@@ -0,0 +1,19 @@
+// Package migrations embeds the SQL schema files applied by cmd/migrate.
+//
+// The top-level *.sql files target PostgreSQL and are also mounted into the
+// postgres container's docker-entrypoint-initdb.d by docker-compose, so every
+// up migration must be idempotent. The sqlite directory mirrors them for
+// local development and tests.
+package migrations
+
+import "embed"
+
+// Postgres contains the PostgreSQL migrations
+//
+//go:embed *.sql
+var Postgres embed.FS
+
+// SQLite contains the SQLite migrations, rooted at the sqlite directory
+//
+//go:embed sqlite/*.sql
+var SQLite embed.FS
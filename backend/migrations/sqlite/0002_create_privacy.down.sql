@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS privacy_audit_log;
+DROP TABLE IF EXISTS deletion_requests;
+DROP TABLE IF EXISTS data_exports;
@@ -0,0 +1,37 @@
+CREATE TABLE IF NOT EXISTS data_exports (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id      INTEGER NOT NULL,
+    status       TEXT NOT NULL DEFAULT 'pending',
+    token        TEXT NOT NULL,
+    archive      BLOB,
+    error        TEXT NOT NULL DEFAULT '',
+    created_at   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP,
+    expires_at   TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_data_exports_user_id ON data_exports (user_id);
+CREATE INDEX IF NOT EXISTS idx_data_exports_status ON data_exports (status);
+
+CREATE TABLE IF NOT EXISTS deletion_requests (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id      INTEGER NOT NULL,
+    mode         TEXT NOT NULL,
+    status       TEXT NOT NULL DEFAULT 'scheduled',
+    requested_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    scheduled_at TIMESTAMP NOT NULL,
+    completed_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_deletion_requests_user_id ON deletion_requests (user_id);
+CREATE INDEX IF NOT EXISTS idx_deletion_requests_due ON deletion_requests (status, scheduled_at);
+
+CREATE TABLE IF NOT EXISTS privacy_audit_log (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id    INTEGER NOT NULL,
+    action     TEXT NOT NULL,
+    detail     TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_privacy_audit_log_user_id ON privacy_audit_log (user_id);
@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS entries_fts_update;
+DROP TRIGGER IF EXISTS entries_fts_delete;
+DROP TRIGGER IF EXISTS entries_fts_insert;
+DROP TABLE IF EXISTS entries_fts;
+ALTER TABLE entries DROP COLUMN language;
+ALTER TABLE users DROP COLUMN search_language;
@@ -0,0 +1,28 @@
+-- SQLite has no tsvector; entries are indexed in an FTS5 table kept in sync
+-- by triggers. The language columns are stored but stemming is not applied.
+ALTER TABLE users ADD COLUMN search_language TEXT NOT NULL DEFAULT 'simple';
+
+ALTER TABLE entries ADD COLUMN language TEXT NOT NULL DEFAULT 'simple';
+
+CREATE VIRTUAL TABLE IF NOT EXISTS entries_fts USING fts5(
+    title,
+    body,
+    content = 'entries',
+    content_rowid = 'id',
+    tokenize = 'unicode61 remove_diacritics 2'
+);
+
+INSERT INTO entries_fts (entries_fts) VALUES ('rebuild');
+
+CREATE TRIGGER IF NOT EXISTS entries_fts_insert AFTER INSERT ON entries BEGIN
+    INSERT INTO entries_fts (rowid, title, body) VALUES (new.id, new.title, new.body);
+END;
+
+CREATE TRIGGER IF NOT EXISTS entries_fts_delete AFTER DELETE ON entries BEGIN
+    INSERT INTO entries_fts (entries_fts, rowid, title, body) VALUES ('delete', old.id, old.title, old.body);
+END;
+
+CREATE TRIGGER IF NOT EXISTS entries_fts_update AFTER UPDATE OF title, body ON entries BEGIN
+    INSERT INTO entries_fts (entries_fts, rowid, title, body) VALUES ('delete', old.id, old.title, old.body);
+    INSERT INTO entries_fts (rowid, title, body) VALUES (new.id, new.title, new.body);
+END;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS sessions (
+    id                 INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id            INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    refresh_token_hash TEXT NOT NULL UNIQUE,
+    device_name        TEXT NOT NULL DEFAULT '',
+    platform           TEXT NOT NULL DEFAULT '',
+    ip                 TEXT NOT NULL DEFAULT '',
+    created_at         TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at       TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at         TIMESTAMP NOT NULL,
+    revoked_at         TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
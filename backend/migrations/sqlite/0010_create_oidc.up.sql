@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS oidc_identities (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id    INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    provider   TEXT NOT NULL,
+    subject    TEXT NOT NULL,
+    email      TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_oidc_identities_user_id ON oidc_identities (user_id);
+
+CREATE TABLE IF NOT EXISTS oidc_logins (
+    state         TEXT PRIMARY KEY,
+    provider      TEXT NOT NULL,
+    user_id       INTEGER REFERENCES users (id) ON DELETE CASCADE,
+    code_verifier TEXT NOT NULL,
+    nonce         TEXT NOT NULL,
+    expires_at    TIMESTAMP NOT NULL
+);
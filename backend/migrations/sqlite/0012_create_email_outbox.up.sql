@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS email_outbox (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id         INTEGER REFERENCES users (id) ON DELETE CASCADE,
+    template        TEXT NOT NULL DEFAULT '',
+    to_address      TEXT NOT NULL,
+    subject         TEXT NOT NULL,
+    html_body       TEXT NOT NULL DEFAULT '',
+    text_body       TEXT NOT NULL DEFAULT '',
+    status          TEXT NOT NULL DEFAULT 'pending',
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP,
+    error           TEXT NOT NULL DEFAULT '',
+    created_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    sent_at         TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_outbox_due ON email_outbox (status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_email_outbox_user_id ON email_outbox (user_id);
@@ -0,0 +1,6 @@
+-- Concurrent requests could schedule a second deletion; keep the newest
+UPDATE deletion_requests SET status = 'cancelled'
+WHERE status = 'scheduled'
+  AND id NOT IN (SELECT MAX(id) FROM deletion_requests WHERE status = 'scheduled' GROUP BY user_id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_deletion_requests_scheduled ON deletion_requests (user_id) WHERE status = 'scheduled';
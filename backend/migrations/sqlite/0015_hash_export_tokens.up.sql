@@ -0,0 +1,4 @@
+-- Export download tokens are stored as SHA-256 hashes, like invitation
+-- tokens. SQLite has no sha256(), so links issued so far stop working.
+ALTER TABLE data_exports ADD COLUMN token_hash TEXT NOT NULL DEFAULT '';
+ALTER TABLE data_exports DROP COLUMN token;
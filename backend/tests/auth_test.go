@@ -0,0 +1,48 @@
+//go:build integration
+
+package tests
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/apitest"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/fixtures"
+)
+
+func TestRegisterAndSessions(t *testing.T) {
+	srv := apitest.New(t)
+
+	srv.Client().Post("/api/v2/auth/register", map[string]string{
+		"email": "New.User@Example.com", "name": "New User", "password": "correct horse battery",
+	}).ExpectStatus(http.StatusCreated).Golden("register")
+
+	srv.Client().Post("/api/v2/auth/register", map[string]string{
+		"email": "new.user@example.com", "name": "Again", "password": "correct horse battery",
+	}).ExpectStatus(http.StatusConflict).Golden("duplicate")
+
+	srv.Clock.Advance(90 * time.Minute)
+	user := srv.Login("new.user@example.com", "correct horse battery")
+	user.Get("/api/v2/sessions").ExpectStatus(http.StatusOK).Golden("sessions")
+}
+
+func TestLoginErrorsAreLocalized(t *testing.T) {
+	srv := apitest.New(t)
+	srv.Seed("demo")
+
+	wrong := map[string]string{"email": "anna@example.com", "password": "wrong"}
+	srv.Client().Post("/api/v2/auth/login", wrong).ExpectStatus(http.StatusUnauthorized).Golden("en")
+	srv.Client().WithLanguage("ru-RU,ru;q=0.9").Post("/api/v2/auth/login", wrong).
+		ExpectStatus(http.StatusUnauthorized).Golden("ru")
+}
+
+func TestAccessTokensExpire(t *testing.T) {
+	srv := apitest.New(t)
+	srv.Seed("demo")
+	anna := srv.Login("anna@example.com", fixtures.DefaultPassword)
+
+	anna.Get("/api/v2/orgs").ExpectStatus(http.StatusOK).Golden("orgs")
+	srv.Clock.Advance(srv.App.Config.AccessTokenTTL)
+	anna.Get("/api/v2/orgs").ExpectStatus(http.StatusUnauthorized).Golden("expired")
+}
@@ -0,0 +1,66 @@
+//go:build integration
+
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/apitest"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/fixtures"
+)
+
+func TestEntriesCRUD(t *testing.T) {
+	srv := apitest.New(t)
+	srv.Seed("demo")
+	anna := srv.Login("anna@example.com", fixtures.DefaultPassword).WithOrg("demo")
+
+	var created struct {
+		ID int64 `json:"id"`
+	}
+	anna.Post("/api/v2/entries", map[string]string{"title": "  Evening walk ", "body": "Around the lake."}).
+		ExpectStatus(http.StatusCreated).
+		Golden("create").
+		JSON(&created)
+	path := fmt.Sprintf("/api/v2/entries/%d", created.ID)
+
+	srv.Clock.Advance(5 * time.Minute)
+	anna.Put(path, map[string]string{"title": "Evening walk", "body": "Around the lake, twice."}).
+		ExpectStatus(http.StatusOK).
+		Golden("update")
+	anna.Get("/api/v2/entries?limit=3").ExpectStatus(http.StatusOK).Golden("list")
+	anna.Get("/api/v1/entries?limit=3").ExpectStatus(http.StatusOK).Golden("list_v1")
+
+	anna.Post("/api/v2/entries", map[string]string{"title": " "}).ExpectStatus(http.StatusBadRequest).Golden("empty_title")
+
+	anna.Delete(path).ExpectStatus(http.StatusNoContent)
+	anna.Get(path).ExpectStatus(http.StatusNotFound).Golden("deleted")
+}
+
+func TestEntriesAreScopedToTheOrganization(t *testing.T) {
+	srv := apitest.New(t)
+	srv.Seed("demo")
+	james := srv.Login("james@example.com", fixtures.DefaultPassword)
+	anna := srv.Login("anna@example.com", fixtures.DefaultPassword)
+
+	james.WithOrg("book-circle").Get("/api/v2/entries").ExpectStatus(http.StatusOK).Golden("own_org")
+	anna.WithOrg("book-circle").Get("/api/v2/entries").ExpectStatus(http.StatusForbidden).Golden("other_org")
+	anna.WithOrg("book-circle").WithLanguage("ru").Get("/api/v2/entries").ExpectStatus(http.StatusForbidden).Golden("other_org_ru")
+}
+
+func TestTokenSelectsTheDefaultOrganization(t *testing.T) {
+	srv := apitest.New(t)
+	srv.Seed("demo")
+
+	// James joined demo first; without X-Org or a subdomain his token's
+	// organization applies, and X-Org still overrides it
+	james := srv.Login("james@example.com", fixtures.DefaultPassword)
+	james.Get("/api/v2/entries").ExpectStatus(http.StatusOK).Golden("default_org")
+	james.WithOrg("book-circle").Get("/api/v2/entries").ExpectStatus(http.StatusOK).Golden("selected_org")
+
+	// A user without organizations still has to create or join one
+	srv.Register("nina@example.com", "Nina", fixtures.DefaultPassword).
+		Get("/api/v2/entries").ExpectStatus(http.StatusBadRequest)
+}
@@ -0,0 +1,32 @@
+//go:build integration
+
+package tests
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/apitest"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/fixtures"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/flags"
+)
+
+func TestFlagsIgnoreSignedOutTokens(t *testing.T) {
+	srv := apitest.New(t)
+	srv.Seed("demo")
+	admin := srv.Login("admin@example.com", fixtures.DefaultPassword)
+
+	admin.Put("/api/v2/admin/flags/beta", flags.Flag{
+		Enabled: true,
+		Rules: []flags.Rule{{
+			Conditions: []flags.Condition{{Attribute: flags.AttrRole, Operator: flags.OpIn, Values: []string{"admin"}}},
+			Serve:      flags.Serve{Variant: "on"},
+		}},
+		Fallthrough: flags.Serve{Variant: "off"},
+	}).ExpectStatus(http.StatusOK)
+	admin.Get("/api/v2/flags").ExpectStatus(http.StatusOK).Golden("signed_in")
+
+	// The revoked session's token still parses but no longer identifies the admin
+	admin.Post("/api/v2/auth/logout", nil).ExpectStatus(http.StatusNoContent)
+	admin.Get("/api/v2/flags").ExpectStatus(http.StatusOK).Golden("signed_out")
+}
@@ -0,0 +1,329 @@
+package calculator
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidDecimal is returned when a string is not a decimal number
+var ErrInvalidDecimal = errors.New("invalid decimal")
+
+// maxScale bounds the digits after the decimal point, so that inputs like
+// "1e-999999999" cannot allocate unbounded memory
+const maxScale = 10000
+
+// RoundingMode selects how results are rounded to a number of places
+type RoundingMode int
+
+const (
+	// HalfEven rounds to the nearest neighbour and ties to the even one
+	// (banker's rounding): 2.5 -> 2, 3.5 -> 4
+	HalfEven RoundingMode = iota
+	// HalfUp rounds to the nearest neighbour and ties away from zero:
+	// 2.5 -> 3, -2.5 -> -3
+	HalfUp
+	// Down truncates towards zero: 2.7 -> 2, -2.7 -> -2
+	Down
+)
+
+func (m RoundingMode) String() string {
+	switch m {
+	case HalfEven:
+		return "half-even"
+	case HalfUp:
+		return "half-up"
+	case Down:
+		return "down"
+	}
+	return "RoundingMode(" + strconv.Itoa(int(m)) + ")"
+}
+
+// DecimalContext sets the precision of inexact operations such as
+// division. Addition, subtraction and multiplication are always exact.
+type DecimalContext struct {
+	// Places is the number of digits kept after the decimal point
+	Places   int
+	Rounding RoundingMode
+}
+
+// DefaultDecimalContext keeps 20 places and rounds half-even
+var DefaultDecimalContext = DecimalContext{Places: 20, Rounding: HalfEven}
+
+// Decimal is an exact decimal number. The zero value is 0. Decimals are
+// immutable; operations return new values.
+type Decimal struct {
+	// The value is unscaled / 10^scale
+	unscaled *big.Int
+	scale    int
+}
+
+// NewDecimal returns unscaled / 10^scale, e.g. NewDecimal(125, 2) is 1.25
+func NewDecimal(unscaled int64, scale int) Decimal {
+	return newDecimal(big.NewInt(unscaled), scale)
+}
+
+func newDecimal(unscaled *big.Int, scale int) Decimal {
+	if scale < 0 {
+		unscaled = new(big.Int).Mul(unscaled, pow10(-scale))
+		scale = 0
+	}
+	return Decimal{unscaled: unscaled, scale: scale}
+}
+
+// StringToDecimal parses a decimal such as "12.50", "-0.001" or "1.5e-3"
+// without going through float64, so the value is exactly what was written
+func StringToDecimal(s string) (Decimal, error) {
+	invalid := fmt.Errorf("%w: %q", ErrInvalidDecimal, s)
+	mantissa, exponent := s, 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		e, err := strconv.Atoi(s[i+1:])
+		if err != nil || e > maxScale || e < -maxScale {
+			return Decimal{}, invalid
+		}
+		mantissa, exponent = s[:i], e
+	}
+
+	sign := ""
+	if mantissa != "" && (mantissa[0] == '-' || mantissa[0] == '+') {
+		sign, mantissa = mantissa[:1], mantissa[1:]
+	}
+	intPart, fracPart, _ := strings.Cut(mantissa, ".")
+	digits := intPart + fracPart
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return Decimal{}, invalid
+	}
+
+	unscaled, _ := new(big.Int).SetString(digits, 10)
+	if sign == "-" {
+		unscaled.Neg(unscaled)
+	}
+	scale := len(fracPart) - exponent
+	if scale > maxScale {
+		return Decimal{}, invalid
+	}
+	return newDecimal(unscaled, scale), nil
+}
+
+// DecimalToString formats d with exactly precision digits after the
+// decimal point, rounding with mode; see FloatToString
+func DecimalToString(d Decimal, precision int, mode RoundingMode) string {
+	return d.Round(precision, mode).String()
+}
+
+func (d Decimal) int() *big.Int {
+	if d.unscaled == nil {
+		return new(big.Int)
+	}
+	return d.unscaled
+}
+
+// String formats d exactly, with as many places as its scale, e.g. 0.1
+// times 0.20 is "0.020"
+func (d Decimal) String() string {
+	digits := new(big.Int).Abs(d.int()).String()
+	if d.scale > 0 {
+		if len(digits) <= d.scale {
+			digits = strings.Repeat("0", d.scale-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-d.scale] + "." + digits[len(digits)-d.scale:]
+	}
+	if d.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+// Float64 returns the nearest float64
+func (d Decimal) Float64() float64 {
+	f, _ := new(big.Rat).SetFrac(d.int(), pow10(d.scale)).Float64()
+	return f
+}
+
+// Scale returns the number of digits after the decimal point
+func (d Decimal) Scale() int {
+	return d.scale
+}
+
+// Sign returns -1, 0 or +1
+func (d Decimal) Sign() int {
+	return d.int().Sign()
+}
+
+// IsInteger reports whether d has no fractional part
+func (d Decimal) IsInteger() bool {
+	_, frac := new(big.Int).QuoRem(d.int(), pow10(d.scale), new(big.Int))
+	return frac.Sign() == 0
+}
+
+// Cmp compares d and e, returning -1, 0 or +1
+func (d Decimal) Cmp(e Decimal) int {
+	a, b := align(d, e)
+	return a.Cmp(b)
+}
+
+// Neg returns -d
+func (d Decimal) Neg() Decimal {
+	return Decimal{unscaled: new(big.Int).Neg(d.int()), scale: d.scale}
+}
+
+// Abs returns |d|
+func (d Decimal) Abs() Decimal {
+	return Decimal{unscaled: new(big.Int).Abs(d.int()), scale: d.scale}
+}
+
+// Add returns d + e exactly
+func (d Decimal) Add(e Decimal) Decimal {
+	a, b := align(d, e)
+	return Decimal{unscaled: a.Add(a, b), scale: max(d.scale, e.scale)}
+}
+
+// Sub returns d - e exactly
+func (d Decimal) Sub(e Decimal) Decimal {
+	a, b := align(d, e)
+	return Decimal{unscaled: a.Sub(a, b), scale: max(d.scale, e.scale)}
+}
+
+// Mul returns d * e exactly
+func (d Decimal) Mul(e Decimal) Decimal {
+	return Decimal{unscaled: new(big.Int).Mul(d.int(), e.int()), scale: d.scale + e.scale}
+}
+
+// Round returns d rounded to places digits after the decimal point. A
+// value with fewer places is padded with zeros.
+func (d Decimal) Round(places int, mode RoundingMode) Decimal {
+	places = max(places, 0)
+	if places >= d.scale {
+		return Decimal{unscaled: new(big.Int).Mul(d.int(), pow10(places-d.scale)), scale: places}
+	}
+	return Decimal{unscaled: quoRound(d.int(), pow10(d.scale-places), mode), scale: places}
+}
+
+// Reduce returns d without trailing zeros after the decimal point, e.g.
+// 1.2500 -> 1.25
+func (d Decimal) Reduce() Decimal {
+	unscaled, scale := new(big.Int).Set(d.int()), d.scale
+	ten, digit := big.NewInt(10), new(big.Int)
+	for scale > 0 {
+		q, r := new(big.Int).QuoRem(unscaled, ten, digit)
+		if r.Sign() != 0 {
+			break
+		}
+		unscaled, scale = q, scale-1
+	}
+	return Decimal{unscaled: unscaled, scale: scale}
+}
+
+// Div returns a / b rounded to the context's places
+func (c DecimalContext) Div(a, b Decimal) (Decimal, error) {
+	if b.Sign() == 0 {
+		return Decimal{}, ErrDivisionByZero
+	}
+	// a/b = (ua / 10^sa) / (ub / 10^sb); scaling the numerator by
+	// 10^(places+sb-sa) leaves a quotient with exactly places digits
+	num, den := new(big.Int).Set(a.int()), new(big.Int).Set(b.int())
+	if shift := c.Places + b.scale - a.scale; shift >= 0 {
+		num.Mul(num, pow10(shift))
+	} else {
+		den.Mul(den, pow10(-shift))
+	}
+	return Decimal{unscaled: quoRound(num, den, c.Rounding), scale: c.Places}, nil
+}
+
+// Mod returns the remainder of a / b with the sign of a, exactly
+func (c DecimalContext) Mod(a, b Decimal) (Decimal, error) {
+	if b.Sign() == 0 {
+		return Decimal{}, ErrDivisionByZero
+	}
+	x, y := align(a, b)
+	return Decimal{unscaled: x.Rem(x, y), scale: max(a.scale, b.scale)}, nil
+}
+
+// Pow returns d^n for an integer n; negative powers are divisions rounded
+// to the context's places
+func (c DecimalContext) Pow(d Decimal, n int) (Decimal, error) {
+	if n < 0 {
+		p, err := c.Pow(d, -n)
+		if err != nil {
+			return Decimal{}, err
+		}
+		return c.Div(NewDecimal(1, 0), p)
+	}
+	if n > maxScale || n*d.scale > maxScale {
+		return Decimal{}, fmt.Errorf("%w: power %d too large", ErrDomain, n)
+	}
+	unscaled := new(big.Int).Exp(d.int(), big.NewInt(int64(n)), nil)
+	return Decimal{unscaled: unscaled, scale: d.scale * n}, nil
+}
+
+// Sqrt returns the square root of d rounded to the context's places
+func (c DecimalContext) Sqrt(d Decimal) (Decimal, error) {
+	if d.Sign() < 0 {
+		return Decimal{}, fmt.Errorf("%w: sqrt(%s)", ErrDomain, d)
+	}
+	// With n = d * 10^(2*places) as an integer, sqrt(n) has exactly places
+	// digits after the point once divided by 10^places. Inputs with more
+	// than 2*places digits are worked on at a higher precision.
+	places := max(c.Places, (d.scale+1)/2)
+	n := new(big.Int).Mul(d.int(), pow10(2*places-d.scale))
+	root := new(big.Int).Sqrt(n)
+	rem := new(big.Int).Sub(n, new(big.Int).Mul(root, root))
+
+	if places > c.Places {
+		// Append a sticky digit recording whether the root was inexact, so
+		// that it can never be mistaken for a tie, and round it away
+		root.Mul(root, big.NewInt(10))
+		if rem.Sign() != 0 {
+			root.Add(root, big.NewInt(1))
+		}
+		return Decimal{unscaled: quoRound(root, pow10(places-c.Places+1), c.Rounding), scale: c.Places}, nil
+	}
+	if c.Rounding != Down {
+		// The exact root lies above root + 1/2 iff n > root^2 + root + 1/4,
+		// i.e. 4(n - root^2) > 4root + 1; it never lies exactly on the half
+		rem.Lsh(rem, 2)
+		half := new(big.Int).Lsh(root, 2)
+		if rem.Cmp(half.Add(half, big.NewInt(1))) > 0 {
+			root.Add(root, big.NewInt(1))
+		}
+	}
+	return Decimal{unscaled: root, scale: c.Places}, nil
+}
+
+// align returns the unscaled values of d and e at their common scale
+func align(d, e Decimal) (*big.Int, *big.Int) {
+	a, b := new(big.Int).Set(d.int()), new(big.Int).Set(e.int())
+	switch {
+	case d.scale < e.scale:
+		a.Mul(a, pow10(e.scale-d.scale))
+	case e.scale < d.scale:
+		b.Mul(b, pow10(d.scale-e.scale))
+	}
+	return a, b
+}
+
+// quoRound returns num / den rounded to an integer with mode
+func quoRound(num, den *big.Int, mode RoundingMode) *big.Int {
+	q, r := new(big.Int).QuoRem(num, den, new(big.Int))
+	if r.Sign() == 0 || mode == Down {
+		return q
+	}
+	// Compare the remainder with half the divisor
+	twice := new(big.Int).Abs(r)
+	twice.Lsh(twice, 1)
+	cmp := twice.Cmp(new(big.Int).Abs(den))
+	if cmp > 0 || (cmp == 0 && (mode == HalfUp || q.Bit(0) == 1)) {
+		if num.Sign()*den.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
@@ -0,0 +1,179 @@
+package calculator
+
+import (
+	"errors"
+	"fmt"
+)
+
+// decimalFunctions are the functions available in decimal mode; the
+// transcendental ones have no exact decimal result and are left out
+var decimalFunctions = map[string]struct {
+	minArgs, maxArgs int
+	call             func(c DecimalContext, args []Decimal) (Decimal, error)
+}{
+	"abs": {1, 1, func(c DecimalContext, args []Decimal) (Decimal, error) {
+		return args[0].Abs(), nil
+	}},
+	"sqrt": {1, 1, func(c DecimalContext, args []Decimal) (Decimal, error) {
+		return c.Sqrt(args[0])
+	}},
+	"min": {1, -1, func(c DecimalContext, args []Decimal) (Decimal, error) {
+		m := args[0]
+		for _, a := range args[1:] {
+			if a.Cmp(m) < 0 {
+				m = a
+			}
+		}
+		return m, nil
+	}},
+	"max": {1, -1, func(c DecimalContext, args []Decimal) (Decimal, error) {
+		m := args[0]
+		for _, a := range args[1:] {
+			if a.Cmp(m) > 0 {
+				m = a
+			}
+		}
+		return m, nil
+	}},
+	// round(x) rounds to an integer, round(x, n) to n places, with the
+	// context's rounding mode
+	"round": {1, 2, func(c DecimalContext, args []Decimal) (Decimal, error) {
+		places := 0
+		if len(args) == 2 {
+			n, err := smallInt(args[1])
+			if err != nil {
+				return Decimal{}, err
+			}
+			places = n
+		}
+		return args[0].Round(places, c.Rounding), nil
+	}},
+}
+
+// EvaluateDecimal parses and evaluates an expression in decimal mode: number
+// literals are read exactly, +, - and * are exact, and division, negative
+// powers and sqrt are rounded as set by ctx. Powers take integer exponents.
+//
+//	EvaluateDecimal("0.1 + 0.2", nil, DefaultDecimalContext) // 0.3
+func EvaluateDecimal(expr string, env map[string]Decimal, ctx DecimalContext) (Decimal, error) {
+	e, err := Parse(expr)
+	if err != nil {
+		return Decimal{}, err
+	}
+	return e.EvalDecimal(env, ctx)
+}
+
+// EvalDecimal evaluates the expression in decimal mode with variables bound
+// by env; see EvaluateDecimal
+func (e *Expression) EvalDecimal(env map[string]Decimal, ctx DecimalContext) (Decimal, error) {
+	return evalDecimal(e.Root, env, ctx)
+}
+
+func evalDecimal(n Node, env map[string]Decimal, ctx DecimalContext) (Decimal, error) {
+	switch n := n.(type) {
+	case *Number:
+		d, err := StringToDecimal(n.Text)
+		if err != nil {
+			return Decimal{}, &Error{Column: n.Pos, Msg: err.Error(), Err: ErrSyntax}
+		}
+		return d, nil
+
+	case *Variable:
+		if v, ok := env[n.Name]; ok {
+			return v, nil
+		}
+		return Decimal{}, &Error{Column: n.Pos, Msg: fmt.Sprintf("unknown variable %q", n.Name), Err: ErrUnknownVariable}
+
+	case *Unary:
+		x, err := evalDecimal(n.X, env, ctx)
+		if err != nil {
+			return Decimal{}, err
+		}
+		if n.Op == "-" {
+			return x.Neg(), nil
+		}
+		return x, nil
+
+	case *Binary:
+		x, err := evalDecimal(n.X, env, ctx)
+		if err != nil {
+			return Decimal{}, err
+		}
+		y, err := evalDecimal(n.Y, env, ctx)
+		if err != nil {
+			return Decimal{}, err
+		}
+		v, err := binaryDecimal(n.Op, x, y, ctx)
+		if err != nil {
+			return Decimal{}, positioned(n.Pos, err)
+		}
+		return v, nil
+
+	case *Call:
+		f, ok := decimalFunctions[n.Name]
+		if !ok {
+			msg := fmt.Sprintf("unknown function %q", n.Name)
+			if _, ok := functions[n.Name]; ok {
+				msg = fmt.Sprintf("function %q is not available in decimal mode", n.Name)
+			}
+			return Decimal{}, &Error{Column: n.Pos, Msg: msg, Err: ErrUnknownFunction}
+		}
+		if err := checkArgs(n, f.minArgs, f.maxArgs); err != nil {
+			return Decimal{}, err
+		}
+		args := make([]Decimal, len(n.Args))
+		for i, arg := range n.Args {
+			v, err := evalDecimal(arg, env, ctx)
+			if err != nil {
+				return Decimal{}, err
+			}
+			args[i] = v
+		}
+		v, err := f.call(ctx, args)
+		if err != nil {
+			return Decimal{}, positioned(n.Pos, err)
+		}
+		return v, nil
+	}
+	panic(fmt.Sprintf("calculator: unknown node %T", n))
+}
+
+func binaryDecimal(op string, x, y Decimal, ctx DecimalContext) (Decimal, error) {
+	switch op {
+	case "+":
+		return x.Add(y), nil
+	case "-":
+		return x.Sub(y), nil
+	case "*":
+		return x.Mul(y), nil
+	case "/":
+		return ctx.Div(x, y)
+	case "%":
+		return ctx.Mod(x, y)
+	case "^":
+		n, err := smallInt(y)
+		if err != nil {
+			return Decimal{}, err
+		}
+		return ctx.Pow(x, n)
+	}
+	panic("calculator: unknown operator " + op)
+}
+
+// smallInt converts an integral decimal used as an exponent or a count of
+// places
+func smallInt(d Decimal) (int, error) {
+	if !d.IsInteger() || d.Abs().Cmp(NewDecimal(maxScale, 0)) > 0 {
+		return 0, fmt.Errorf("%w: %s is not a small integer", ErrDomain, d)
+	}
+	return int(d.Round(0, Down).int().Int64()), nil
+}
+
+// positioned attaches a column to an arithmetic error
+func positioned(column int, err error) error {
+	kind := ErrDomain
+	if errors.Is(err, ErrDivisionByZero) {
+		kind = ErrDivisionByZero
+	}
+	return &Error{Column: column, Msg: err.Error(), Err: kind}
+}
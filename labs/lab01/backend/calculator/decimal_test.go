@@ -0,0 +1,237 @@
+package calculator
+
+import (
+	"errors"
+	"testing"
+)
+
+func mustDecimal(t *testing.T, s string) Decimal {
+	t.Helper()
+	d, err := StringToDecimal(s)
+	if err != nil {
+		t.Fatalf("StringToDecimal(%q) failed: %v", s, err)
+	}
+	return d
+}
+
+func TestStringToDecimal(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    string
+		expectError bool
+	}{
+		{"integer", "42", "42", false},
+		{"fraction", "3.14", "3.14", false},
+		{"trailing zeros kept", "12.50", "12.50", false},
+		{"negative", "-123.45", "-123.45", false},
+		{"leading dot", ".5", "0.5", false},
+		{"explicit plus", "+7", "7", false},
+		{"exponent", "1.5e-3", "0.0015", false},
+		{"positive exponent", "2.5E3", "2500", false},
+		{"beyond float64", "123456789012345678901234567890.000000000000000000001", "123456789012345678901234567890.000000000000000000001", false},
+		{"invalid input", "abc", "", true},
+		{"empty string", "", "", true},
+		{"lone dot", ".", "", true},
+		{"two dots", "1.2.3", "", true},
+		{"bad exponent", "1e", "", true},
+		{"huge exponent", "1e-99999999", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := StringToDecimal(tt.input)
+			if tt.expectError {
+				if !errors.Is(err, ErrInvalidDecimal) {
+					t.Errorf("Expected ErrInvalidDecimal, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got.String() != tt.expected {
+				t.Errorf("StringToDecimal(%q) = %v, want %v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDecimalArithmetic(t *testing.T) {
+	tests := []struct {
+		name     string
+		got      func(a, b Decimal) Decimal
+		a, b     string
+		expected string
+	}{
+		{"add tenths", Decimal.Add, "0.1", "0.2", "0.3"},
+		{"add mixed scales", Decimal.Add, "1.005", "2", "3.005"},
+		{"subtract", Decimal.Sub, "0.3", "0.1", "0.2"},
+		{"subtract to negative", Decimal.Sub, "1", "1.01", "-0.01"},
+		{"multiply", Decimal.Mul, "0.1", "0.20", "0.020"},
+		{"multiply large", Decimal.Mul, "12345678901234567890.123", "1000", "12345678901234567890123.000"},
+		{"multiply negative", Decimal.Mul, "-2.5", "0.4", "-1.00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.got(mustDecimal(t, tt.a), mustDecimal(t, tt.b)); got.String() != tt.expected {
+				t.Errorf("%s(%s, %s) = %v, want %v", tt.name, tt.a, tt.b, got, tt.expected)
+			}
+		})
+	}
+
+	for in, want := range map[string]string{"1.2500": "1.25", "100": "100", "3.000": "3", "-0.10": "-0.1", "0.0": "0"} {
+		if got := mustDecimal(t, in).Reduce(); got.String() != want {
+			t.Errorf("Reduce(%s) = %v, want %v", in, got, want)
+		}
+	}
+
+	sum := Decimal{}
+	for range 10 {
+		sum = sum.Add(mustDecimal(t, "0.1"))
+	}
+	if sum.Cmp(NewDecimal(1, 0)) != 0 {
+		t.Errorf("Expected ten times 0.1 to be exactly 1, got %v", sum)
+	}
+}
+
+func TestDecimalDivide(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		places   int
+		mode     RoundingMode
+		expected string
+	}{
+		{"1", "3", 5, HalfEven, "0.33333"},
+		{"2", "3", 5, HalfEven, "0.66667"},
+		{"2", "3", 5, HalfUp, "0.66667"},
+		{"2", "3", 5, Down, "0.66666"},
+		{"-2", "3", 5, Down, "-0.66666"},
+		{"-2", "3", 5, HalfUp, "-0.66667"},
+		{"1", "8", 2, HalfEven, "0.12"},
+		{"1", "8", 2, HalfUp, "0.13"},
+		{"3", "8", 2, HalfEven, "0.38"},
+		{"500", "0.04", 0, HalfEven, "12500"},
+		{"0.000001", "1000", 3, HalfEven, "0.000"},
+	}
+
+	for _, tt := range tests {
+		ctx := DecimalContext{Places: tt.places, Rounding: tt.mode}
+		got, err := ctx.Div(mustDecimal(t, tt.a), mustDecimal(t, tt.b))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got.String() != tt.expected {
+			t.Errorf("Div(%s, %s) at %d places %v = %v, want %v", tt.a, tt.b, tt.places, tt.mode, got, tt.expected)
+		}
+	}
+
+	if _, err := DefaultDecimalContext.Div(NewDecimal(1, 0), Decimal{}); err != ErrDivisionByZero {
+		t.Errorf("Expected ErrDivisionByZero, got %v", err)
+	}
+}
+
+func TestDecimalToString(t *testing.T) {
+	tests := []struct {
+		input     string
+		precision int
+		mode      RoundingMode
+		expected  string
+	}{
+		{"2.5", 0, HalfEven, "2"},
+		{"3.5", 0, HalfEven, "4"},
+		{"-2.5", 0, HalfEven, "-2"},
+		{"2.5", 0, HalfUp, "3"},
+		{"-2.5", 0, HalfUp, "-3"},
+		{"-2.7", 0, Down, "-2"},
+		{"1.005", 2, HalfUp, "1.01"},
+		{"1.005", 2, HalfEven, "1.00"},
+		{"1.015", 2, HalfEven, "1.02"},
+		{"3.14159", 2, HalfEven, "3.14"},
+		{"123456.789", 2, HalfEven, "123456.79"},
+		{"0", 2, HalfEven, "0.00"},
+		{"-0.001", 2, HalfEven, "0.00"},
+		{"7", 3, Down, "7.000"},
+	}
+
+	for _, tt := range tests {
+		if got := DecimalToString(mustDecimal(t, tt.input), tt.precision, tt.mode); got != tt.expected {
+			t.Errorf("DecimalToString(%s, %d, %v) = %v, want %v", tt.input, tt.precision, tt.mode, got, tt.expected)
+		}
+	}
+}
+
+func TestDecimalSqrt(t *testing.T) {
+	tests := []struct {
+		input    string
+		places   int
+		mode     RoundingMode
+		expected string
+	}{
+		{"2", 10, HalfEven, "1.4142135624"},
+		{"2", 10, Down, "1.4142135623"},
+		{"16", 2, HalfEven, "4.00"},
+		{"0.0001", 2, HalfEven, "0.01"},
+		{"0.00000000000000000002", 3, HalfUp, "0.000"},
+		{"6.25", 0, HalfEven, "2"},
+		{"6.25", 0, HalfUp, "3"},
+	}
+
+	for _, tt := range tests {
+		got, err := DecimalContext{Places: tt.places, Rounding: tt.mode}.Sqrt(mustDecimal(t, tt.input))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got.String() != tt.expected {
+			t.Errorf("Sqrt(%s) at %d places %v = %v, want %v", tt.input, tt.places, tt.mode, got, tt.expected)
+		}
+	}
+}
+
+func TestEvaluateDecimal(t *testing.T) {
+	env := map[string]Decimal{"dose": NewDecimal(25, 1)}
+	ctx := DecimalContext{Places: 4, Rounding: HalfUp}
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"0.1 + 0.2", "0.3"},
+		{"dose * 3 / 7", "1.0714"},
+		{"2^10", "1024"},
+		{"2^-2", "0.2500"},
+		{"-1.5^2", "-2.25"},
+		{"10.5 % 3", "1.5"},
+		{"round(2.345, 2) + max(1, 0.5)", "3.35"},
+		{"sqrt(2)", "1.4142"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := EvaluateDecimal(tt.input, env, ctx)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got.String() != tt.expected {
+				t.Errorf("EvaluateDecimal(%q) = %v, want %v", tt.input, got, tt.expected)
+			}
+		})
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+		kind     error
+	}{
+		{"1 / (dose - 2.5)", "division by zero at column 3", ErrDivisionByZero},
+		{"2 ^ 0.5", "argument out of domain: 0.5 is not a small integer at column 3", ErrDomain},
+		{"sin(1)", `function "sin" is not available in decimal mode at column 1`, ErrUnknownFunction},
+		{"pi", `unknown variable "pi" at column 1`, ErrUnknownVariable},
+	}
+	for _, tt := range errorTests {
+		_, err := EvaluateDecimal(tt.input, env, ctx)
+		if err == nil || err.Error() != tt.expected || !errors.Is(err, tt.kind) {
+			t.Errorf("EvaluateDecimal(%q) error = %v, want %q", tt.input, err, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package calculator
+
+import (
+	"fmt"
+	"math"
+)
+
+// constants are variables every expression can use; the environment
+// passed to Eval may shadow them
+var constants = map[string]float64{
+	"pi": math.Pi,
+	"e":  math.E,
+}
+
+// function is an entry of the function table. maxArgs < 0 means any number
+// of arguments from minArgs up.
+type function struct {
+	minArgs, maxArgs int
+	call             func(args []float64) (float64, error)
+}
+
+func unary(f func(float64) float64) function {
+	return function{1, 1, func(args []float64) (float64, error) { return f(args[0]), nil }}
+}
+
+// positive wraps f, whose argument must be greater than zero (or at least
+// zero when zeroOK)
+func positive(name string, zeroOK bool, f func(float64) float64) function {
+	return function{1, 1, func(args []float64) (float64, error) {
+		if args[0] < 0 || (args[0] == 0 && !zeroOK) {
+			return 0, fmt.Errorf("%w: %s(%g)", ErrDomain, name, args[0])
+		}
+		return f(args[0]), nil
+	}}
+}
+
+var functions = map[string]function{
+	"sqrt":  positive("sqrt", true, math.Sqrt),
+	"log":   positive("log", false, math.Log),
+	"ln":    positive("ln", false, math.Log),
+	"log10": positive("log10", false, math.Log10),
+	"log2":  positive("log2", false, math.Log2),
+	"abs":   unary(math.Abs),
+	"exp":   unary(math.Exp),
+	"sin":   unary(math.Sin),
+	"cos":   unary(math.Cos),
+	"tan":   unary(math.Tan),
+	"asin":  unary(math.Asin),
+	"acos":  unary(math.Acos),
+	"atan":  unary(math.Atan),
+	"floor": unary(math.Floor),
+	"ceil":  unary(math.Ceil),
+	"round": unary(math.Round),
+	"min": {1, -1, func(args []float64) (float64, error) {
+		m := args[0]
+		for _, a := range args[1:] {
+			m = math.Min(m, a)
+		}
+		return m, nil
+	}},
+	"max": {1, -1, func(args []float64) (float64, error) {
+		m := args[0]
+		for _, a := range args[1:] {
+			m = math.Max(m, a)
+		}
+		return m, nil
+	}},
+	"pow": {2, 2, func(args []float64) (float64, error) {
+		return math.Pow(args[0], args[1]), nil
+	}},
+}
+
+// Evaluate parses and evaluates an expression, e.g.
+//
+//	Evaluate("2*(3+4)^2 / sqrt(16) - x", map[string]float64{"x": 1}) // 23.5
+func Evaluate(expr string, env map[string]float64) (float64, error) {
+	e, err := Parse(expr)
+	if err != nil {
+		return 0, err
+	}
+	return e.Eval(env)
+}
+
+// Eval evaluates the expression with variables bound by env
+func (e *Expression) Eval(env map[string]float64) (float64, error) {
+	return eval(e.Root, env)
+}
+
+func eval(n Node, env map[string]float64) (float64, error) {
+	switch n := n.(type) {
+	case *Number:
+		return n.Value, nil
+
+	case *Variable:
+		if v, ok := env[n.Name]; ok {
+			return v, nil
+		}
+		if v, ok := constants[n.Name]; ok {
+			return v, nil
+		}
+		return 0, &Error{Column: n.Pos, Msg: fmt.Sprintf("unknown variable %q", n.Name), Err: ErrUnknownVariable}
+
+	case *Unary:
+		x, err := eval(n.X, env)
+		if err != nil {
+			return 0, err
+		}
+		if n.Op == "-" {
+			return -x, nil
+		}
+		return x, nil
+
+	case *Binary:
+		x, err := eval(n.X, env)
+		if err != nil {
+			return 0, err
+		}
+		y, err := eval(n.Y, env)
+		if err != nil {
+			return 0, err
+		}
+		return binary(n, x, y)
+
+	case *Call:
+		f, ok := functions[n.Name]
+		if !ok {
+			return 0, &Error{Column: n.Pos, Msg: fmt.Sprintf("unknown function %q", n.Name), Err: ErrUnknownFunction}
+		}
+		if err := checkArgs(n, f.minArgs, f.maxArgs); err != nil {
+			return 0, err
+		}
+		args := make([]float64, len(n.Args))
+		for i, arg := range n.Args {
+			v, err := eval(arg, env)
+			if err != nil {
+				return 0, err
+			}
+			args[i] = v
+		}
+		v, err := f.call(args)
+		if err != nil {
+			return 0, &Error{Column: n.Pos, Msg: err.Error(), Err: ErrDomain}
+		}
+		return v, nil
+	}
+	panic(fmt.Sprintf("calculator: unknown node %T", n))
+}
+
+func binary(n *Binary, x, y float64) (float64, error) {
+	switch n.Op {
+	case "+":
+		return Add(x, y), nil
+	case "-":
+		return Subtract(x, y), nil
+	case "*":
+		return Multiply(x, y), nil
+	case "/":
+		v, err := Divide(x, y)
+		if err != nil {
+			return 0, &Error{Column: n.Pos, Msg: err.Error(), Err: err}
+		}
+		return v, nil
+	case "%":
+		if y == 0 {
+			return 0, &Error{Column: n.Pos, Msg: ErrDivisionByZero.Error(), Err: ErrDivisionByZero}
+		}
+		return math.Mod(x, y), nil
+	case "^":
+		return math.Pow(x, y), nil
+	}
+	panic("calculator: unknown operator " + n.Op)
+}
+
+// checkArgs reports a wrong number of arguments to a call
+func checkArgs(n *Call, minArgs, maxArgs int) error {
+	got := len(n.Args)
+	if got >= minArgs && (maxArgs < 0 || got <= maxArgs) {
+		return nil
+	}
+	want := fmt.Sprintf("%d", minArgs)
+	switch {
+	case maxArgs < 0:
+		want = fmt.Sprintf("at least %d", minArgs)
+	case maxArgs != minArgs:
+		want = fmt.Sprintf("%d to %d", minArgs, maxArgs)
+	}
+	plural := "s"
+	if want == "1" || want == "at least 1" {
+		plural = ""
+	}
+	return &Error{
+		Column: n.Pos,
+		Msg:    fmt.Sprintf("%s expects %s argument%s, got %d", n.Name, want, plural, got),
+		Err:    ErrArgumentCount,
+	}
+}
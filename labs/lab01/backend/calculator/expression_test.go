@@ -0,0 +1,127 @@
+package calculator
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 + 2 * 3", "(1 + (2 * 3))"},
+		{"(1 + 2) * 3", "((1 + 2) * 3)"},
+		{"10 - 4 - 3", "((10 - 4) - 3)"},
+		{"2 ^ 3 ^ 2", "(2 ^ (3 ^ 2))"},
+		{"2 ** 3", "(2 ^ 3)"},
+		{"-2 ^ 2", "(-(2 ^ 2))"},
+		{"2 ^ -1", "(2 ^ (-1))"},
+		{"-x * 3", "((-x) * 3)"},
+		{"1.5e3 % 7", "(1.5e3 % 7)"},
+		{"max(1, 2 + 3, min(x))", "max(1, (2 + 3), min(x))"},
+		{"2*(3+4)^2 / sqrt(16) - x", "(((2 * ((3 + 4) ^ 2)) / sqrt(16)) - x)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			e, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := e.String(); got != tt.expected {
+				t.Errorf("Parse(%q) = %v, want %v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	env := map[string]float64{"x": 1, "weight_kg": 70, "pi": 3}
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"2*(3+4)^2 / sqrt(16) - x", 23.5},
+		{"-2^2", -4},
+		{"2^3^2", 512},
+		{"10 % 4", 2},
+		{"weight_kg * 0.033", 70 * 0.033},
+		{"min(3, 1, 2) + max(4, 9)", 10},
+		{"log(e)", 1},
+		{"sin(0) + cos(0)", 1},
+		{"pi", 3},
+		{"+.5 * 4", 2},
+		{"abs(-2.5e-1)", 0.25},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := Evaluate(tt.input, env)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if math.Abs(got-tt.expected) > 1e-12 {
+				t.Errorf("Evaluate(%q) = %v, want %v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEvaluateErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		kind     error
+	}{
+		{"(1 + 2))", "unexpected ')' at column 8", ErrSyntax},
+		{"sqrt(4))", "unexpected ')' at column 8", ErrSyntax},
+		{"2 * (3 + 4", "expected ')' but found end of expression at column 11", ErrSyntax},
+		{"2 +", "unexpected end of expression at column 4", ErrSyntax},
+		{"2 # 3", "unexpected character '#' at column 3", ErrSyntax},
+		{"1.2.3 + 1", `invalid number "1.2.3" at column 1`, ErrSyntax},
+		{"max(1,)", "unexpected ')' at column 7", ErrSyntax},
+		{"2 x", "unexpected 'x' at column 3", ErrSyntax},
+		{"1 / (x - 1)", "division by zero at column 3", ErrDivisionByZero},
+		{"5 % 0", "division by zero at column 3", ErrDivisionByZero},
+		{"y + 1", `unknown variable "y" at column 1`, ErrUnknownVariable},
+		{"1 + foo(2)", `unknown function "foo" at column 5`, ErrUnknownFunction},
+		{"sqrt(1, 2)", "sqrt expects 1 argument, got 2 at column 1", ErrArgumentCount},
+		{"max()", "max expects at least 1 argument, got 0 at column 1", ErrArgumentCount},
+		{"2 * sqrt(-4)", "argument out of domain: sqrt(-4) at column 5", ErrDomain},
+		{"log(0)", "argument out of domain: log(0) at column 1", ErrDomain},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := Evaluate(tt.input, map[string]float64{"x": 1})
+			if err == nil {
+				t.Fatal("Expected error, got none")
+			}
+			if err.Error() != tt.expected {
+				t.Errorf("Evaluate(%q) error = %q, want %q", tt.input, err, tt.expected)
+			}
+			if !errors.Is(err, tt.kind) {
+				t.Errorf("Expected %v, got %v", tt.kind, err)
+			}
+			var posErr *Error
+			if !errors.As(err, &posErr) || posErr.Column == 0 {
+				t.Errorf("Expected a positioned *Error, got %T", err)
+			}
+		})
+	}
+}
+
+func TestExpressionReuse(t *testing.T) {
+	e, err := Parse("x^2 + 1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for x, expected := range map[float64]float64{0: 1, 2: 5, -3: 10} {
+		got, err := e.Eval(map[string]float64{"x": x})
+		if err != nil || got != expected {
+			t.Errorf("Eval(x=%v) = %v, %v, want %v", x, got, err, expected)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package calculator
+
+import (
+	"fmt"
+	"strconv"
+	"unicode"
+)
+
+// tokenKind classifies tokens
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenIdent
+	tokenOperator
+	tokenLParen
+	tokenRParen
+	tokenComma
+)
+
+// token is a lexical token with the 1-based column of its first character
+type token struct {
+	kind   tokenKind
+	text   string
+	column int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of expression"
+	}
+	return fmt.Sprintf("'%s'", t.text)
+}
+
+// tokenize splits an expression into tokens, ending with tokenEOF
+func tokenize(expr string) ([]token, error) {
+	runes := []rune(expr)
+	var tokens []token
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		column := i + 1
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case isDigit(r) || r == '.':
+			start := i
+			i = scanNumber(runes, i)
+			text := string(runes[start:i])
+			if _, err := strconv.ParseFloat(text, 64); err != nil {
+				return nil, syntaxError(column, fmt.Sprintf("invalid number %q", text))
+			}
+			tokens = append(tokens, token{tokenNumber, text, column})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || isDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{tokenIdent, string(runes[start:i]), column})
+		case r == '(':
+			tokens = append(tokens, token{tokenLParen, "(", column})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{tokenRParen, ")", column})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{tokenComma, ",", column})
+			i++
+		case r == '*' && i+1 < len(runes) && runes[i+1] == '*':
+			// ** is accepted as a synonym for ^
+			tokens = append(tokens, token{tokenOperator, "^", column})
+			i += 2
+		case isOperator(r):
+			tokens = append(tokens, token{tokenOperator, string(r), column})
+			i++
+		default:
+			return nil, syntaxError(column, fmt.Sprintf("unexpected character '%c'", r))
+		}
+	}
+	return append(tokens, token{tokenEOF, "", len(runes) + 1}), nil
+}
+
+// scanNumber returns the end of the number starting at i: digits with an
+// optional fraction and exponent, e.g. 12, .5, 1.25e-3
+func scanNumber(runes []rune, i int) int {
+	for i < len(runes) && (isDigit(runes[i]) || runes[i] == '.') {
+		i++
+	}
+	if i < len(runes) && (runes[i] == 'e' || runes[i] == 'E') {
+		j := i + 1
+		if j < len(runes) && (runes[j] == '+' || runes[j] == '-') {
+			j++
+		}
+		// Only an exponent with digits belongs to the number; otherwise
+		// the e starts an identifier
+		if j < len(runes) && isDigit(runes[j]) {
+			for j < len(runes) && isDigit(runes[j]) {
+				j++
+			}
+			i = j
+		}
+	}
+	return i
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isOperator(r rune) bool {
+	switch r {
+	case '+', '-', '*', '/', '%', '^':
+		return true
+	}
+	return false
+}
@@ -0,0 +1,261 @@
+package calculator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Expression errors; every error returned by Parse and Evaluate is an
+// *Error wrapping one of these or ErrDivisionByZero
+var (
+	ErrSyntax          = errors.New("syntax error")
+	ErrUnknownVariable = errors.New("unknown variable")
+	ErrUnknownFunction = errors.New("unknown function")
+	ErrArgumentCount   = errors.New("wrong number of arguments")
+	ErrDomain          = errors.New("argument out of domain")
+)
+
+// Error is an error at a position of an expression
+type Error struct {
+	// Column is the 1-based position of the offending character
+	Column int
+	Msg    string
+	Err    error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s at column %d", e.Msg, e.Column)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func syntaxError(column int, msg string) *Error {
+	return &Error{Column: column, Msg: msg, Err: ErrSyntax}
+}
+
+// Node is a node of the syntax tree of an expression
+type Node interface {
+	// Column returns the 1-based position of the node in the expression
+	Column() int
+	// String formats the node with every operation in parentheses
+	String() string
+}
+
+// Number is a numeric literal; Text is kept as written
+type Number struct {
+	Pos   int
+	Text  string
+	Value float64
+}
+
+// Variable is a name bound by the environment
+type Variable struct {
+	Pos  int
+	Name string
+}
+
+// Unary is a sign applied to an operand
+type Unary struct {
+	Pos int
+	Op  string
+	X   Node
+}
+
+// Binary is an arithmetic operation: + - * / % or ^
+type Binary struct {
+	Pos  int
+	Op   string
+	X, Y Node
+}
+
+// Call is a function call
+type Call struct {
+	Pos  int
+	Name string
+	Args []Node
+}
+
+func (n *Number) Column() int   { return n.Pos }
+func (n *Variable) Column() int { return n.Pos }
+func (n *Unary) Column() int    { return n.Pos }
+func (n *Binary) Column() int   { return n.Pos }
+func (n *Call) Column() int     { return n.Pos }
+
+func (n *Number) String() string   { return n.Text }
+func (n *Variable) String() string { return n.Name }
+func (n *Unary) String() string    { return "(" + n.Op + n.X.String() + ")" }
+func (n *Binary) String() string {
+	return "(" + n.X.String() + " " + n.Op + " " + n.Y.String() + ")"
+}
+func (n *Call) String() string {
+	args := make([]string, len(n.Args))
+	for i, a := range n.Args {
+		args[i] = a.String()
+	}
+	return n.Name + "(" + strings.Join(args, ", ") + ")"
+}
+
+// Operator precedence, loosest first. Unary signs bind tighter than
+// multiplication but looser than powers, so -2^2 is -(2^2).
+const (
+	precAdditive = iota + 1
+	precMultiplicative
+	precUnary
+	precPower
+)
+
+var binaryPrecedence = map[string]int{
+	"+": precAdditive, "-": precAdditive,
+	"*": precMultiplicative, "/": precMultiplicative, "%": precMultiplicative,
+	"^": precPower,
+}
+
+// Expression is a parsed expression that can be evaluated repeatedly
+type Expression struct {
+	Root Node
+}
+
+// String formats the expression with every operation in parentheses
+func (e *Expression) String() string {
+	return e.Root.String()
+}
+
+// Parse parses an expression such as "2*(3+4)^2 / sqrt(16) - x". Powers
+// are right-associative; the other operators are left-associative.
+func Parse(expr string) (*Expression, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseExpr(precAdditive)
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, syntaxError(tok.column, "unexpected "+tok.String())
+	}
+	return &Expression{Root: root}, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// parseExpr parses operands joined by binary operators of at least
+// minPrec, by precedence climbing
+func (p *parser) parseExpr(minPrec int) (Node, error) {
+	lhs, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		prec, ok := binaryPrecedence[tok.text]
+		if tok.kind != tokenOperator || !ok || prec < minPrec {
+			return lhs, nil
+		}
+		p.next()
+		nextMin := prec + 1
+		if tok.text == "^" {
+			nextMin = prec
+		}
+		rhs, err := p.parseExpr(nextMin)
+		if err != nil {
+			return nil, err
+		}
+		lhs = &Binary{Pos: tok.column, Op: tok.text, X: lhs, Y: rhs}
+	}
+}
+
+func (p *parser) parseUnary() (Node, error) {
+	tok := p.peek()
+	if tok.kind == tokenOperator && (tok.text == "-" || tok.text == "+") {
+		p.next()
+		x, err := p.parseExpr(precUnary)
+		if err != nil {
+			return nil, err
+		}
+		return &Unary{Pos: tok.column, Op: tok.text, X: x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenNumber:
+		n := &Number{Pos: tok.column, Text: tok.text}
+		n.Value, _ = StringToFloat(tok.text)
+		return n, nil
+	case tokenIdent:
+		if p.peek().kind != tokenLParen {
+			return &Variable{Pos: tok.column, Name: tok.text}, nil
+		}
+		p.next()
+		args, err := p.parseArgs()
+		if err != nil {
+			return nil, err
+		}
+		return &Call{Pos: tok.column, Name: tok.text, Args: args}, nil
+	case tokenLParen:
+		x, err := p.parseExpr(precAdditive)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectRParen(); err != nil {
+			return nil, err
+		}
+		return x, nil
+	}
+	return nil, syntaxError(tok.column, "unexpected "+tok.String())
+}
+
+// parseArgs parses a call's arguments after the opening parenthesis
+func (p *parser) parseArgs() ([]Node, error) {
+	var args []Node
+	if p.peek().kind == tokenRParen {
+		p.next()
+		return args, nil
+	}
+	for {
+		arg, err := p.parseExpr(precAdditive)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.peek().kind != tokenComma {
+			break
+		}
+		p.next()
+	}
+	if err := p.expectRParen(); err != nil {
+		return nil, err
+	}
+	return args, nil
+}
+
+func (p *parser) expectRParen() error {
+	tok := p.peek()
+	if tok.kind != tokenRParen {
+		return syntaxError(tok.column, fmt.Sprintf("expected ')' but found %s", tok))
+	}
+	p.next()
+	return nil
+}
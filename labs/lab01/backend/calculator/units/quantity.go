@@ -0,0 +1,171 @@
+package units
+
+import (
+	"fmt"
+	"strings"
+
+	"lab01/calculator"
+)
+
+// Quantity is a value in a unit, e.g. 2.5 l
+type Quantity struct {
+	Value calculator.Decimal
+	Unit  Unit
+}
+
+// String formats the quantity exactly, e.g. "2.5 l"
+func (q Quantity) String() string {
+	return q.format(q.Value.String())
+}
+
+// Format formats the quantity with places digits after the decimal point
+func (q Quantity) Format(places int, mode calculator.RoundingMode) string {
+	return q.format(calculator.DecimalToString(q.Value, places, mode))
+}
+
+func (q Quantity) format(value string) string {
+	if u := q.Unit.String(); u != "" {
+		return value + " " + u
+	}
+	return value
+}
+
+// Parse reads a quantity such as "2.5 l", "150 lb", "9.81 m/s^2" or "3"
+// with the Default registry
+func Parse(s string) (Quantity, error) {
+	return Default.Parse(s)
+}
+
+// Convert converts q to the unit written as unit with the Default registry
+func Convert(q Quantity, unit string) (Quantity, error) {
+	return Default.Convert(q, unit)
+}
+
+// Parse reads a quantity: a number, optionally followed by a unit
+// expression as accepted by Lookup. The space between them is optional.
+func (r *Registry) Parse(s string) (Quantity, error) {
+	s = strings.TrimSpace(s)
+	// The number is the longest prefix that parses as one, so that
+	// "1e3 m" keeps its exponent but "2 eV" does not swallow the e
+	var value calculator.Decimal
+	end := 0
+	for i := len(s); i > 0; i-- {
+		if d, err := calculator.StringToDecimal(s[:i]); err == nil {
+			value, end = d, i
+			break
+		}
+	}
+	if end == 0 {
+		return Quantity{}, fmt.Errorf("%w: %q has no number", ErrInvalidQuantity, s)
+	}
+	u, err := r.Lookup(s[end:])
+	if err != nil {
+		return Quantity{}, err
+	}
+	return Quantity{Value: value, Unit: u}, nil
+}
+
+// Convert converts q to the unit written as unit, e.g. 150 lb to kg
+func (r *Registry) Convert(q Quantity, unit string) (Quantity, error) {
+	u, err := r.Lookup(unit)
+	if err != nil {
+		return Quantity{}, err
+	}
+	return r.ConvertTo(q, u)
+}
+
+// ConvertTo converts q to u. The result is exact when it has no more
+// places than the registry's Context keeps, and rounded otherwise.
+func (r *Registry) ConvertTo(q Quantity, u Unit) (Quantity, error) {
+	if !q.Unit.Compatible(u) {
+		return Quantity{}, incompatible("convert", "to", q.Unit, u)
+	}
+	// q in u is q.Value * from / to, with the factors as fractions
+	fn, fd := q.Unit.factors()
+	tn, td := u.factors()
+	num, den := fn.Mul(td), fd.Mul(tn)
+	if num.Cmp(den) == 0 {
+		return Quantity{Value: q.Value, Unit: u}, nil
+	}
+	v, err := r.Context.Div(q.Value.Mul(num), den)
+	if err != nil {
+		return Quantity{}, err
+	}
+	return Quantity{Value: v.Reduce(), Unit: u}, nil
+}
+
+// Add returns a + b in the unit of a; adding kg to ml is an
+// ErrIncompatibleUnits
+func (r *Registry) Add(a, b Quantity) (Quantity, error) {
+	if !a.Unit.Compatible(b.Unit) {
+		return Quantity{}, incompatible("add", "and", a.Unit, b.Unit)
+	}
+	b, err := r.ConvertTo(b, a.Unit)
+	if err != nil {
+		return Quantity{}, err
+	}
+	return Quantity{Value: a.Value.Add(b.Value), Unit: a.Unit}, nil
+}
+
+// Sub returns a - b in the unit of a
+func (r *Registry) Sub(a, b Quantity) (Quantity, error) {
+	if !a.Unit.Compatible(b.Unit) {
+		return Quantity{}, incompatible("subtract", "from", b.Unit, a.Unit)
+	}
+	return r.Add(a, b.Neg())
+}
+
+// Mul returns a * b in the product of their units, e.g. 2 m * 3 m is 6 m^2
+// and 70 kg * 2 kcal/kg is 140 kcal
+func (r *Registry) Mul(a, b Quantity) (Quantity, error) {
+	return r.simplify(Quantity{Value: a.Value.Mul(b.Value), Unit: combine(a.Unit, b.Unit, 1)})
+}
+
+// Div returns a / b in the quotient of their units, e.g. 10 km / 2 h is
+// 5 km/h; units that cancel out leave a pure number, so 1 km / 250 m is 4
+func (r *Registry) Div(a, b Quantity) (Quantity, error) {
+	u, num, den := combine(a.Unit, b.Unit, -1), a.Value, b.Value
+	if u.Dimensionless() {
+		// Fold the factor into the one division, so it is rounded once
+		n, d := u.factors()
+		u, num, den = Unit{}, num.Mul(n), den.Mul(d)
+	}
+	v, err := r.Context.Div(num, den)
+	if err != nil {
+		return Quantity{}, err
+	}
+	return Quantity{Value: v.Reduce(), Unit: u}, nil
+}
+
+// Cmp compares a and b, returning -1, 0 or +1
+func (r *Registry) Cmp(a, b Quantity) (int, error) {
+	d, err := r.Sub(a, b)
+	if err != nil {
+		return 0, err
+	}
+	return d.Value.Sign(), nil
+}
+
+// simplify turns a quantity whose dimensions cancel out into a pure number
+func (r *Registry) simplify(q Quantity) (Quantity, error) {
+	if q.Unit.Dimensionless() {
+		return r.ConvertTo(q, Unit{})
+	}
+	return q, nil
+}
+
+// Neg returns -q
+func (q Quantity) Neg() Quantity {
+	return Quantity{Value: q.Value.Neg(), Unit: q.Unit}
+}
+
+func incompatible(verb, prep string, u, v Unit) error {
+	return fmt.Errorf("%w: cannot %s %s %s %s (%s vs %s)", ErrIncompatibleUnits, verb, name(u), prep, name(v), u.Dimension(), v.Dimension())
+}
+
+func name(u Unit) string {
+	if s := u.String(); s != "" {
+		return s
+	}
+	return "a number"
+}
@@ -0,0 +1,328 @@
+package units
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"lab01/calculator"
+)
+
+// Unit errors
+var (
+	ErrUnknownUnit       = errors.New("unknown unit")
+	ErrIncompatibleUnits = errors.New("incompatible units")
+	ErrInvalidQuantity   = errors.New("invalid quantity")
+	ErrUnitExists        = errors.New("unit already defined")
+)
+
+// Unit is a named or compound unit such as kg, ml or km/h. The zero value
+// is the dimensionless unit.
+type Unit struct {
+	// The unit is num/den base units; keeping the factor as a fraction
+	// lets units like km/h stay exact
+	num, den calculator.Decimal
+	// terms are the named units the unit is written with and their
+	// powers, e.g. m:1 s:-2
+	terms map[string]int
+	// dims are the base units it measures and their powers; units with
+	// equal dims are convertible
+	dims map[string]int
+}
+
+var one = calculator.NewDecimal(1, 0)
+
+func baseUnit(name string) Unit {
+	return Unit{num: one, den: one, terms: map[string]int{name: 1}, dims: map[string]int{name: 1}}
+}
+
+// String returns the unit as written, e.g. "kg*m^2/s^2", or "" when it is
+// dimensionless
+func (u Unit) String() string {
+	return formatPowers(u.terms)
+}
+
+// Dimension returns the unit in base units, e.g. "m^3" for ml, or "1" when
+// it is dimensionless
+func (u Unit) Dimension() string {
+	if len(u.dims) == 0 {
+		return "1"
+	}
+	return formatPowers(u.dims)
+}
+
+// Dimensionless reports whether u measures a pure number
+func (u Unit) Dimensionless() bool {
+	return len(u.dims) == 0
+}
+
+// Compatible reports whether quantities in u and v can be converted into
+// each other, added and compared
+func (u Unit) Compatible(v Unit) bool {
+	return maps.Equal(u.dims, v.dims)
+}
+
+func (u Unit) factors() (num, den calculator.Decimal) {
+	if len(u.terms) == 0 {
+		return one, one
+	}
+	return u.num, u.den
+}
+
+// combine returns u*v, or u/v when sign is -1
+func combine(u, v Unit, sign int) Unit {
+	un, ud := u.factors()
+	vn, vd := v.factors()
+	if sign < 0 {
+		vn, vd = vd, vn
+	}
+	return Unit{
+		num:   un.Mul(vn),
+		den:   ud.Mul(vd),
+		terms: addPowers(u.terms, v.terms, sign),
+		dims:  addPowers(u.dims, v.dims, sign),
+	}
+}
+
+// power returns u^n
+func power(u Unit, n int) Unit {
+	p := Unit{}
+	for range max(n, -n) {
+		p = combine(p, u, 1)
+	}
+	if n < 0 {
+		return combine(Unit{}, p, -1)
+	}
+	return p
+}
+
+// addPowers returns a + sign*b, dropping zero powers
+func addPowers(a, b map[string]int, sign int) map[string]int {
+	sum := maps.Clone(a)
+	if sum == nil {
+		sum = map[string]int{}
+	}
+	for name, p := range b {
+		sum[name] += sign * p
+		if sum[name] == 0 {
+			delete(sum, name)
+		}
+	}
+	return sum
+}
+
+// formatPowers writes the positive powers sorted by name, then the
+// negative ones after a slash: kg*m^2/s^2
+func formatPowers(powers map[string]int) string {
+	var num, den []string
+	for _, name := range slices.Sorted(maps.Keys(powers)) {
+		p := powers[name]
+		term := name
+		if p > 1 || p < -1 {
+			term = fmt.Sprintf("%s^%d", name, max(p, -p))
+		}
+		if p > 0 {
+			num = append(num, term)
+		} else {
+			den = append(den, term)
+		}
+	}
+	s := strings.Join(num, "*")
+	if len(den) > 0 {
+		if s == "" {
+			s = "1"
+		}
+		s += "/" + strings.Join(den, "/")
+	}
+	return s
+}
+
+// Registry is a set of named units. Custom units are added with Define and
+// DefineBase. A Registry is safe for concurrent use.
+type Registry struct {
+	// Context rounds conversions and divisions, which may be inexact
+	Context calculator.DecimalContext
+
+	mu    sync.RWMutex
+	units map[string]Unit
+}
+
+// NewRegistry returns a registry with the built-in units:
+//
+//	length  m (base), km, cm, mm, mi, yd, ft, in
+//	mass    kg (base), g, mg, lb
+//	time    s (base), min, h, d
+//	volume  l, L, ml, mL, oz (US fluid ounce), cup, gal
+//	energy  J, kJ, cal, kcal, Cal (= kcal)
+func NewRegistry() *Registry {
+	r := &Registry{Context: calculator.DefaultDecimalContext, units: map[string]Unit{}}
+	for _, name := range []string{"m", "kg", "s"} {
+		r.units[name] = baseUnit(name)
+	}
+	for _, def := range builtin {
+		if err := r.Define(def[0], def[1]); err != nil {
+			panic("units: " + err.Error())
+		}
+	}
+	return r
+}
+
+// builtin are the derived units of NewRegistry, each defined in terms of
+// the ones before it; the factors are exact by definition
+var builtin = [][2]string{
+	{"km", "1000 m"},
+	{"cm", "0.01 m"},
+	{"mm", "0.001 m"},
+	{"in", "0.0254 m"},
+	{"ft", "0.3048 m"},
+	{"yd", "0.9144 m"},
+	{"mi", "1609.344 m"},
+	{"g", "0.001 kg"},
+	{"mg", "0.001 g"},
+	{"lb", "0.45359237 kg"},
+	{"min", "60 s"},
+	{"h", "3600 s"},
+	{"d", "86400 s"},
+	{"l", "0.001 m^3"},
+	{"L", "1 l"},
+	{"ml", "0.001 l"},
+	{"mL", "1 ml"},
+	{"oz", "29.5735295625 ml"},
+	{"cup", "8 oz"},
+	{"gal", "128 oz"},
+	{"J", "1 kg*m^2/s^2"},
+	{"kJ", "1000 J"},
+	{"cal", "4.184 J"},
+	{"kcal", "1000 cal"},
+	{"Cal", "1 kcal"},
+}
+
+// Default is the registry used by the package-level functions
+var Default = NewRegistry()
+
+// DefineBase adds a new base unit, measuring a dimension of its own, e.g.
+// "step" for a step counter
+func (r *Registry) DefineBase(name string) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	return r.add(name, baseUnit(name))
+}
+
+// Define adds a unit equal to a quantity of existing units, e.g.
+//
+//	r.Define("stone", "14 lb")
+//	r.Define("kmh", "1 km/h")
+func (r *Registry) Define(name, definition string) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	q, err := r.Parse(definition)
+	if err != nil {
+		return err
+	}
+	if q.Value.Sign() <= 0 {
+		return fmt.Errorf("%w: unit %s must be positive, got %s", ErrInvalidQuantity, name, q)
+	}
+	num, den := q.Unit.factors()
+	u := Unit{num: q.Value.Mul(num), den: den, terms: map[string]int{name: 1}, dims: q.Unit.dims}
+	return r.add(name, u)
+}
+
+func (r *Registry) add(name string, u Unit) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.units[name]; ok {
+		return fmt.Errorf("%w: %q", ErrUnitExists, name)
+	}
+	if r.units == nil {
+		r.units = map[string]Unit{}
+	}
+	r.units[name] = u
+	return nil
+}
+
+// checkName accepts the names the expression parser reads as variables
+func checkName(name string) error {
+	e, err := calculator.Parse(name)
+	if err == nil {
+		if v, ok := e.Root.(*calculator.Variable); ok && v.Name == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q is not a valid unit name", ErrInvalidQuantity, name)
+}
+
+// Lookup returns the unit written as expr, which may combine named units
+// with *, / and integer powers: "kg", "km/h", "kg*m^2/s^2", "1/s". The
+// empty string is the dimensionless unit.
+func (r *Registry) Lookup(expr string) (Unit, error) {
+	if strings.TrimSpace(expr) == "" {
+		return Unit{}, nil
+	}
+	e, err := calculator.Parse(expr)
+	if err != nil {
+		return Unit{}, fmt.Errorf("%w: unit %q: %v", ErrInvalidQuantity, expr, err)
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.unit(e.Root)
+}
+
+func (r *Registry) unit(n calculator.Node) (Unit, error) {
+	switch n := n.(type) {
+	case *calculator.Variable:
+		if u, ok := r.units[n.Name]; ok {
+			return u, nil
+		}
+		return Unit{}, fmt.Errorf("%w %q", ErrUnknownUnit, n.Name)
+
+	case *calculator.Number:
+		if d, err := calculator.StringToDecimal(n.Text); err == nil && d.Cmp(one) == 0 {
+			return Unit{}, nil
+		}
+
+	case *calculator.Binary:
+		u, err := r.unit(n.X)
+		if err != nil {
+			return Unit{}, err
+		}
+		switch n.Op {
+		case "^":
+			p, ok := exponent(n.Y)
+			if !ok {
+				return Unit{}, fmt.Errorf("%w: unit power %s must be an integer", ErrInvalidQuantity, n.Y)
+			}
+			return power(u, p), nil
+		case "*", "/":
+			v, err := r.unit(n.Y)
+			if err != nil {
+				return Unit{}, err
+			}
+			if n.Op == "/" {
+				return combine(u, v, -1), nil
+			}
+			return combine(u, v, 1), nil
+		}
+	}
+	return Unit{}, fmt.Errorf("%w: unexpected %s in unit", ErrInvalidQuantity, n)
+}
+
+// exponent reads an integer power such as 2 or -2
+func exponent(n calculator.Node) (int, bool) {
+	sign := 1
+	if u, ok := n.(*calculator.Unary); ok {
+		if u.Op == "-" {
+			sign = -1
+		}
+		n = u.X
+	}
+	num, ok := n.(*calculator.Number)
+	if !ok || num.Value != float64(int(num.Value)) || num.Value > 100 {
+		return 0, false
+	}
+	return sign * int(num.Value), true
+}
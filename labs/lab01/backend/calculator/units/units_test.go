@@ -0,0 +1,238 @@
+package units
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"lab01/calculator"
+)
+
+func mustParse(t *testing.T, r *Registry, s string) Quantity {
+	t.Helper()
+	q, err := r.Parse(s)
+	if err != nil {
+		t.Fatalf("Parse(%q) failed: %v", s, err)
+	}
+	return q
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  string
+		dimension string
+		err       error
+	}{
+		{"2.5 l", "2.5 l", "m^3", nil},
+		{"150 lb", "150 lb", "kg", nil},
+		{"150lb", "150 lb", "kg", nil},
+		{"  -3 kg ", "-3 kg", "kg", nil},
+		{"1e3 m", "1000 m", "m", nil},
+		{"9.81 m/s^2", "9.81 m/s^2", "m/s^2", nil},
+		{"9.81 m*s**-2", "9.81 m/s^2", "m/s^2", nil},
+		{"1 kg*m^2/s^2", "1 kg*m^2/s^2", "kg*m^2/s^2", nil},
+		{"60 1/min", "60 1/min", "1/s", nil},
+		{"2000 kcal", "2000 kcal", "kg*m^2/s^2", nil},
+		{"3", "3", "1", nil},
+		{"kg", "", "", ErrInvalidQuantity},
+		{"", "", "", ErrInvalidQuantity},
+		{"5 lbs", "", "", ErrUnknownUnit},
+		{"5 kg+g", "", "", ErrInvalidQuantity},
+		{"5 m^0.5", "", "", ErrInvalidQuantity},
+		{"5 m^", "", "", ErrInvalidQuantity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			q, err := Parse(tt.input)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Errorf("Expected %v, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if q.String() != tt.expected {
+				t.Errorf("Parse(%q) = %v, want %v", tt.input, q, tt.expected)
+			}
+			if q.Unit.Dimension() != tt.dimension {
+				t.Errorf("Parse(%q) dimension = %v, want %v", tt.input, q.Unit.Dimension(), tt.dimension)
+			}
+		})
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		from, to string
+		expected string
+	}{
+		{"250 ml", "oz", "8.45350567546074929216 oz"},
+		{"8 oz", "ml", "236.5882365 ml"},
+		{"2 l", "ml", "2000 ml"},
+		{"1 cup", "L", "0.2365882365 L"},
+		{"150 lb", "kg", "68.0388555 kg"},
+		{"70 kg", "lb", "154.32358352941430650608 lb"},
+		{"10 km", "mi", "6.21371192237333969617 mi"},
+		{"26.2 mi", "km", "42.1648128 km"},
+		{"2000 kcal", "kJ", "8368 kJ"},
+		{"1000 kJ", "kcal", "239.0057361376673040153 kcal"},
+		{"36 km/h", "m/s", "10 m/s"},
+		{"1 h", "min", "60 min"},
+		{"1 J", "kg*m^2/s^2", "1 kg*m^2/s^2"},
+		{"5 L", "l", "5 l"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from+" to "+tt.to, func(t *testing.T) {
+			got, err := Convert(mustParse(t, Default, tt.from), tt.to)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got.String() != tt.expected {
+				t.Errorf("Convert(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.expected)
+			}
+		})
+	}
+
+	_, err := Convert(mustParse(t, Default, "1 kg"), "ml")
+	if !errors.Is(err, ErrIncompatibleUnits) || err.Error() != "incompatible units: cannot convert kg to ml (kg vs m^3)" {
+		t.Errorf("Expected ErrIncompatibleUnits, got %v", err)
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	r := Default
+	tests := []struct {
+		name     string
+		op       func(a, b Quantity) (Quantity, error)
+		a, b     string
+		expected string
+	}{
+		{"add same unit", r.Add, "1.5 l", "0.25 l", "1.75 l"},
+		{"add converts to the first unit", r.Add, "1 l", "250 ml", "1.25 l"},
+		{"add imperial", r.Add, "1 lb", "1 kg", "3.20462262184877580723 lb"},
+		{"subtract", r.Sub, "2 km", "500 m", "1.5 km"},
+		{"multiply lengths", r.Mul, "2 m", "3 m", "6 m^2"},
+		{"multiply rate", r.Mul, "70 kg", "2 kcal/kg", "140 kcal"},
+		{"multiply speed by time", r.Mul, "12 km/h", "0.5 h", "6.0 km"},
+		{"multiply by number", r.Mul, "3", "250 ml", "750 ml"},
+		{"divide to a rate", r.Div, "10 km", "2 h", "5 km/h"},
+		{"divide to a number", r.Div, "1 km", "250 m", "4"},
+		{"divide to an inexact number", r.Div, "1 kcal", "1 kJ", "4.184"},
+		{"divide by number", r.Div, "2 l", "8", "0.25 l"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.op(mustParse(t, r, tt.a), mustParse(t, r, tt.b))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got.String() != tt.expected {
+				t.Errorf("%s(%s, %s) = %v, want %v", tt.name, tt.a, tt.b, got, tt.expected)
+			}
+		})
+	}
+
+	errorTests := []struct {
+		name string
+		op   func(a, b Quantity) (Quantity, error)
+		a, b string
+		err  error
+		msg  string
+	}{
+		{"add mass to volume", r.Add, "1 kg", "500 ml", ErrIncompatibleUnits, "incompatible units: cannot add kg and ml (kg vs m^3)"},
+		{"subtract number", r.Sub, "1 kg", "1", ErrIncompatibleUnits, "incompatible units: cannot subtract a number from kg (1 vs kg)"},
+		{"divide by zero", r.Div, "1 km", "0 h", calculator.ErrDivisionByZero, "division by zero"},
+	}
+	for _, tt := range errorTests {
+		_, err := tt.op(mustParse(t, r, tt.a), mustParse(t, r, tt.b))
+		if !errors.Is(err, tt.err) || err.Error() != tt.msg {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.msg)
+		}
+	}
+
+	if c, err := r.Cmp(mustParse(t, r, "1 mi"), mustParse(t, r, "1.6 km")); err != nil || c != 1 {
+		t.Errorf("Cmp(1 mi, 1.6 km) = %v, %v, want 1", c, err)
+	}
+}
+
+func TestCustomUnits(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Define("stone", "14 lb"); err != nil {
+		t.Fatalf("Define failed: %v", err)
+	}
+	if err := r.DefineBase("step"); err != nil {
+		t.Fatalf("DefineBase failed: %v", err)
+	}
+
+	got, err := r.Convert(mustParse(t, r, "11 stone"), "kg")
+	if err != nil || got.String() != "69.85322498 kg" {
+		t.Errorf("Convert(11 stone, kg) = %v, %v, want 69.85322498 kg", got, err)
+	}
+	rate, err := r.Div(mustParse(t, r, "12000 step"), mustParse(t, r, "2 h"))
+	if err != nil || rate.String() != "6000 step/h" {
+		t.Errorf("Div(12000 step, 2 h) = %v, %v, want 6000 step/h", rate, err)
+	}
+	if _, err := r.Add(mustParse(t, r, "1 step"), mustParse(t, r, "1 m")); !errors.Is(err, ErrIncompatibleUnits) {
+		t.Errorf("Expected ErrIncompatibleUnits, got %v", err)
+	}
+	if _, err := Parse("1 stone"); !errors.Is(err, ErrUnknownUnit) {
+		t.Errorf("Expected custom units to stay in their registry, got %v", err)
+	}
+
+	invalid := []struct {
+		name, definition string
+		err              error
+	}{
+		{"kg", "1000 g", ErrUnitExists},
+		{"2x", "1 m", ErrInvalidQuantity},
+		{"fl oz", "1 oz", ErrInvalidQuantity},
+		{"nothing", "0 m", ErrInvalidQuantity},
+		{"league", "3 miles", ErrUnknownUnit},
+	}
+	for _, tt := range invalid {
+		if err := r.Define(tt.name, tt.definition); !errors.Is(err, tt.err) {
+			t.Errorf("Define(%q, %q) error = %v, want %v", tt.name, tt.definition, err, tt.err)
+		}
+	}
+}
+
+func TestRegistryConcurrency(t *testing.T) {
+	r := NewRegistry()
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := r.Define("u"+string(rune('a'+i)), "1 m"); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			q, err := r.Parse("1 mi")
+			if err == nil {
+				_, err = r.Convert(q, "km")
+			}
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestFormat(t *testing.T) {
+	q := mustParse(t, Default, "68.0388555 kg")
+	if got := q.Format(1, calculator.HalfEven); got != "68.0 kg" {
+		t.Errorf("Format(1) = %v, want 68.0 kg", got)
+	}
+	if got := mustParse(t, Default, "2.345").Format(2, calculator.HalfUp); got != "2.35" {
+		t.Errorf("Format(2) = %v, want 2.35", got)
+	}
+}
@@ -0,0 +1,724 @@
+// Command tasks manages tasks in a local store or through the REST API.
+//
+// Usage:
+//
+//	tasks [-store path | -api url] [-o table|json] command [flags] [args]
+//
+// The commands are:
+//
+//	add [-d text] [-due date] [-p priority] [-tag tag]... [-parent id] title...
+//	list [-done | -pending] [-p priority] [-tag tag]... [-q text] [-sort fields]
+//	done id...
+//	rm id...
+//	edit [-title text] [-d text] [-due date|none] [-p priority] [-tag tag]... [-parent id] [-reopen] id
+//	export [-format format] [file]
+//	import [-format format] [-dry-run] file
+//	undo
+//	redo
+//	serve [-addr host:port]
+//
+// The store is a JSON file, or an SQLite database if its name ends in .db,
+// .sqlite or .sqlite3. Dates are "2006-01-02", "2006-01-02 15:04" in local
+// time or RFC 3339. Import and export formats are json, csv, ics and
+// todo.txt, guessed from the file name unless -format is given; "-" is
+// standard input or output.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"lab01/taskmanager"
+	"lab01/taskmanager/httpapi"
+	"lab01/taskmanager/taskio"
+)
+
+// service is what the commands need of a TaskManager; an httpapi.Client
+// provides the same over the REST API
+type service interface {
+	CreateTask(task taskmanager.Task) (taskmanager.Task, error)
+	GetTask(id int) (taskmanager.Task, error)
+	SaveTask(task taskmanager.Task) (taskmanager.Task, error)
+	DeleteTask(id int) error
+	ListTasks(q taskmanager.Query) ([]taskmanager.Task, error)
+	Undo() (string, error)
+	Redo() (string, error)
+	Close() error
+}
+
+// errUsage is returned for bad command lines; the usage is already printed
+var errUsage = errors.New("usage")
+
+const usage = `usage: tasks [-store path | -api url] [-o table|json] command [flags] [args]
+
+commands:
+  add     add a task
+  list    list tasks
+  done    complete tasks
+  rm      delete tasks and their subtasks
+  edit    change a task
+  export  write every task to a file
+  import  read tasks from a file
+  undo    undo the last change
+  redo    redo the last undone change
+  serve   serve the store over the REST API
+
+Run "tasks command -h" for the flags of a command.
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// cli holds the global flags of a run
+type cli struct {
+	store  string
+	api    string
+	output string
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// run runs the command line args and returns the exit status
+func run(args []string, stdout, stderr io.Writer) int {
+	c := &cli{stdout: stdout, stderr: stderr}
+	fs := flag.NewFlagSet("tasks", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { fmt.Fprint(stderr, usage) }
+	fs.StringVar(&c.store, "store", envOr("TASKS_STORE", "tasks.json"), "task store `path`")
+	fs.StringVar(&c.api, "api", os.Getenv("TASKS_API"), "REST API base `url`, used instead of the store")
+	fs.StringVar(&c.output, "o", "table", "output `format`: table or json")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if c.output != "table" && c.output != "json" {
+		fmt.Fprintf(stderr, "tasks: unknown output format %q\n", c.output)
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	commands := map[string]func(args []string) error{
+		"add":    c.add,
+		"list":   c.list,
+		"done":   c.done,
+		"rm":     c.rm,
+		"edit":   c.edit,
+		"export": c.exportTasks,
+		"import": c.importTasks,
+		"undo":   c.undo,
+		"redo":   c.redo,
+		"serve":  c.serve,
+	}
+	name := fs.Arg(0)
+	cmd := commands[name]
+	if cmd == nil {
+		fmt.Fprintf(stderr, "tasks: unknown command %q\n", name)
+		fs.Usage()
+		return 2
+	}
+	err := cmd(fs.Args()[1:])
+	switch {
+	case errors.Is(err, errUsage):
+		return 2
+	case err != nil:
+		fmt.Fprintf(stderr, "tasks %s: %v\n", name, err)
+		return 1
+	}
+	return 0
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// open returns the REST API client if -api is set, or a TaskManager on the
+// store otherwise
+func (c *cli) open() (service, error) {
+	if c.api != "" {
+		return httpapi.NewClient(c.api, &http.Client{Timeout: 30 * time.Second}), nil
+	}
+	return c.openManager()
+}
+
+func (c *cli) openManager() (*taskmanager.TaskManager, error) {
+	var store taskmanager.Store
+	var err error
+	switch strings.ToLower(filepath.Ext(c.store)) {
+	case ".db", ".sqlite", ".sqlite3":
+		store, err = taskmanager.OpenSQLiteStore(c.store)
+	default:
+		store, err = taskmanager.OpenJSONStore(c.store)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return taskmanager.NewTaskManagerWithStore(store), nil
+}
+
+// flagSet returns the flag set of a command, printing its usage to stderr
+func (c *cli) flagSet(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "usage: tasks %s [flags] %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse parses the flags of a command and checks that at least minArgs
+// arguments follow them
+func parse(fs *flag.FlagSet, args []string, minArgs int) error {
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() < minArgs {
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+func (c *cli) add(args []string) error {
+	fs := c.flagSet("add", "title...")
+	description := fs.String("d", "", "`description`")
+	due := fs.String("due", "", "due `date`")
+	priority := fs.String("p", "none", "`priority`: none, low, medium or high")
+	var tags tagList
+	fs.Var(&tags, "tag", "`tag`, repeated or comma-separated")
+	parent := fs.Int("parent", 0, "`id` of the parent task")
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+
+	task := taskmanager.Task{
+		Title:       strings.Join(fs.Args(), " "),
+		Description: *description,
+		Tags:        tags,
+		ParentID:    *parent,
+	}
+	var err error
+	if task.Due, err = parseDue(*due); err != nil {
+		return err
+	}
+	if task.Priority, err = taskmanager.ParsePriority(*priority); err != nil {
+		return err
+	}
+
+	svc, err := c.open()
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+	task, err = svc.CreateTask(task)
+	if err != nil {
+		return err
+	}
+	return c.printTask(task)
+}
+
+func (c *cli) list(args []string) error {
+	fs := c.flagSet("list", "")
+	done := fs.Bool("done", false, "only completed tasks")
+	pending := fs.Bool("pending", false, "only pending tasks")
+	priority := fs.String("p", "none", "minimum `priority`")
+	var tags tagList
+	fs.Var(&tags, "tag", "only tasks with this `tag`, repeated or comma-separated")
+	text := fs.String("q", "", "only tasks whose title or description contains `text`")
+	sort := fs.String("sort", "id", "`fields` to sort by, e.g. -priority,due")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	if *done && *pending {
+		return errors.New("-done and -pending cannot be combined")
+	}
+
+	q := taskmanager.Query{Tags: tags, Text: *text}
+	if *done || *pending {
+		q.Done = done
+	}
+	var err error
+	if q.MinPriority, err = taskmanager.ParsePriority(*priority); err != nil {
+		return err
+	}
+
+	svc, err := c.open()
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+	tasks, err := svc.ListTasks(q)
+	if err != nil {
+		return err
+	}
+	if err := taskmanager.SortTasks(tasks, *sort); err != nil {
+		return err
+	}
+	return c.printTasks(tasks)
+}
+
+func (c *cli) done(args []string) error {
+	fs := c.flagSet("done", "id...")
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+	ids, err := parseIDs(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	svc, err := c.open()
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+	var tasks []taskmanager.Task
+	for _, id := range ids {
+		task, err := update(svc, id, func(task *taskmanager.Task) { task.Done = true })
+		if err != nil {
+			return fmt.Errorf("task %d: %w", id, err)
+		}
+		tasks = append(tasks, task)
+	}
+	return c.printTasks(tasks)
+}
+
+func (c *cli) rm(args []string) error {
+	fs := c.flagSet("rm", "id...")
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+	ids, err := parseIDs(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	svc, err := c.open()
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+	for _, id := range ids {
+		if err := svc.DeleteTask(id); err != nil {
+			return fmt.Errorf("task %d: %w", id, err)
+		}
+		if c.output == "table" {
+			fmt.Fprintf(c.stdout, "Deleted task %d\n", id)
+		}
+	}
+	return nil
+}
+
+func (c *cli) edit(args []string) error {
+	fs := c.flagSet("edit", "id")
+	title := fs.String("title", "", "new `title`")
+	description := fs.String("d", "", "new `description`")
+	due := fs.String("due", "", `new due date, or "none"`)
+	priority := fs.String("p", "", "new `priority`")
+	var tags tagList
+	fs.Var(&tags, "tag", "new `tag`s, repeated or comma-separated; replace the old ones")
+	parent := fs.Int("parent", 0, "`id` of the new parent task, or 0 for none")
+	reopen := fs.Bool("reopen", false, "mark the task as pending")
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+	ids, err := parseIDs(fs.Args())
+	if err != nil {
+		return err
+	}
+	if len(ids) != 1 {
+		fs.Usage()
+		return errUsage
+	}
+
+	// Only the flags given change the task
+	var changes []func(task *taskmanager.Task)
+	var parseErr error
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "title":
+			changes = append(changes, func(task *taskmanager.Task) { task.Title = *title })
+		case "d":
+			changes = append(changes, func(task *taskmanager.Task) { task.Description = *description })
+		case "due":
+			t, err := parseDue(*due)
+			parseErr = errors.Join(parseErr, err)
+			changes = append(changes, func(task *taskmanager.Task) { task.Due = t })
+		case "p":
+			p, err := taskmanager.ParsePriority(*priority)
+			parseErr = errors.Join(parseErr, err)
+			changes = append(changes, func(task *taskmanager.Task) { task.Priority = p })
+		case "tag":
+			changes = append(changes, func(task *taskmanager.Task) { task.Tags = tags })
+		case "parent":
+			changes = append(changes, func(task *taskmanager.Task) { task.ParentID = *parent })
+		case "reopen":
+			changes = append(changes, func(task *taskmanager.Task) { task.Done = task.Done && !*reopen })
+		}
+	})
+	if parseErr != nil {
+		return parseErr
+	}
+	if len(changes) == 0 {
+		return errors.New("nothing to change")
+	}
+
+	svc, err := c.open()
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+	task, err := update(svc, ids[0], func(task *taskmanager.Task) {
+		for _, change := range changes {
+			change(task)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return c.printTask(task)
+}
+
+func (c *cli) exportTasks(args []string) error {
+	fs := c.flagSet("export", "[file]")
+	format := fs.String("format", "", "`format`: json, csv, ics or todo.txt")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	path := fs.Arg(0)
+	if path == "" {
+		path = "-"
+	}
+	f, err := fileFormat(path, *format, taskio.JSON)
+	if err != nil {
+		return err
+	}
+
+	svc, err := c.open()
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+	tasks, err := svc.ListTasks(taskmanager.Query{})
+	if err != nil {
+		return err
+	}
+	if path == "-" {
+		return taskio.Export(c.stdout, f, tasks)
+	}
+	// Write next to the file and rename, so that a failed export leaves
+	// an existing file alone
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := taskio.Export(tmp, f, tasks); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stderr, "Exported %d tasks to %s\n", len(tasks), path)
+	return nil
+}
+
+func (c *cli) importTasks(args []string) error {
+	fs := c.flagSet("import", "file")
+	format := fs.String("format", "", "`format`: json, csv, ics or todo.txt")
+	dryRun := fs.Bool("dry-run", false, "check the file without importing it")
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+	path := fs.Arg(0)
+	f, err := fileFormat(path, *format, "")
+	if err != nil {
+		return err
+	}
+	in := io.Reader(os.Stdin)
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		in = file
+	}
+
+	svc, err := c.open()
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+	var report taskio.Report
+	importInto := func(target taskio.Target) (err error) {
+		report, err = taskio.Import(target, in, f, taskio.Options{DryRun: *dryRun})
+		return err
+	}
+	if tm, ok := svc.(*taskmanager.TaskManager); ok && !*dryRun {
+		// A single undo reverts the whole import
+		err = tm.Transaction("import "+path, func(tx *taskmanager.Tx) error { return importInto(tx) })
+	} else {
+		err = importInto(svc)
+	}
+	if err != nil {
+		return err
+	}
+
+	if c.output == "json" {
+		type rowError struct {
+			Row   int    `json:"row"`
+			Error string `json:"error"`
+		}
+		out := struct {
+			DryRun   bool               `json:"dry_run"`
+			Imported []taskmanager.Task `json:"imported"`
+			Errors   []rowError         `json:"errors"`
+		}{DryRun: *dryRun, Imported: report.Imported, Errors: []rowError{}}
+		if out.Imported == nil {
+			out.Imported = []taskmanager.Task{}
+		}
+		for _, e := range report.Errors {
+			out.Errors = append(out.Errors, rowError{Row: e.Row, Error: e.Err.Error()})
+		}
+		if err := c.printJSON(out); err != nil {
+			return err
+		}
+	} else {
+		verb := "Imported"
+		if *dryRun {
+			verb = "Would import"
+		}
+		fmt.Fprintf(c.stdout, "%s %d tasks\n", verb, len(report.Imported))
+		for _, e := range report.Errors {
+			fmt.Fprintf(c.stdout, "%s: %v\n", path, e)
+		}
+	}
+	if len(report.Errors) > 0 {
+		return fmt.Errorf("%d rows not imported", len(report.Errors))
+	}
+	return nil
+}
+
+// undo undoes the last change
+func (c *cli) undo(args []string) error {
+	return c.step("undo", "Undid", args)
+}
+
+// redo redoes the last undone change
+func (c *cli) redo(args []string) error {
+	return c.step("redo", "Redid", args)
+}
+
+// step runs the undo or redo command, which report what they did as
+// "verb name"
+func (c *cli) step(name, verb string, args []string) error {
+	fs := c.flagSet(name, "")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	svc, err := c.open()
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+	fn := svc.Undo
+	if name == "redo" {
+		fn = svc.Redo
+	}
+	done, err := fn()
+	if err != nil {
+		return err
+	}
+	if c.output == "json" {
+		return c.printJSON(map[string]string{name: done})
+	}
+	fmt.Fprintf(c.stdout, "%s %s\n", verb, done)
+	return nil
+}
+
+// fileFormat returns the format given by name, or else guessed from the
+// path, or else fallback for standard input or output
+func fileFormat(path, name string, fallback taskio.Format) (taskio.Format, error) {
+	switch {
+	case name != "":
+		return taskio.ParseFormat(name)
+	case path == "-" && fallback != "":
+		return fallback, nil
+	case path == "-":
+		return "", errors.New("-format is needed to read standard input")
+	case strings.HasSuffix(strings.ToLower(path), "todo.txt"):
+		return taskio.TodoTxt, nil
+	}
+	return taskio.FormatOf(path)
+}
+
+func (c *cli) serve(args []string) error {
+	fs := c.flagSet("serve", "")
+	addr := fs.String("addr", "localhost:8080", "`address` to listen on")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	if c.api != "" {
+		return errors.New("serve works on a local store, not on -api")
+	}
+
+	tm, err := c.openManager()
+	if err != nil {
+		return err
+	}
+	defer tm.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           httpapi.NewHandler(tm),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+	fmt.Fprintf(c.stderr, "Serving %s on http://%s\n", c.store, *addr)
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// update applies change to a task and saves it, starting over if the task
+// is changed by someone else in between
+func update(svc service, id int, change func(task *taskmanager.Task)) (taskmanager.Task, error) {
+	for {
+		task, err := svc.GetTask(id)
+		if err != nil {
+			return taskmanager.Task{}, err
+		}
+		change(&task)
+		saved, err := svc.SaveTask(task)
+		if !errors.Is(err, taskmanager.ErrVersionConflict) {
+			return saved, err
+		}
+	}
+}
+
+// printTask writes a task in the output format, as an object in JSON
+func (c *cli) printTask(task taskmanager.Task) error {
+	if c.output == "json" {
+		return c.printJSON(task)
+	}
+	return c.printTable([]taskmanager.Task{task})
+}
+
+// printTasks writes tasks in the output format, as a list in JSON
+func (c *cli) printTasks(tasks []taskmanager.Task) error {
+	if c.output == "json" {
+		if tasks == nil {
+			tasks = []taskmanager.Task{}
+		}
+		return c.printJSON(tasks)
+	}
+	return c.printTable(tasks)
+}
+
+func (c *cli) printJSON(v any) error {
+	enc := json.NewEncoder(c.stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func (c *cli) printTable(tasks []taskmanager.Task) error {
+	w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tDONE\tPRIORITY\tDUE\tTAGS\tTITLE")
+	for _, task := range tasks {
+		done, priority, due, tags := "", "-", "-", "-"
+		if task.Done {
+			done = "x"
+		}
+		if task.Priority != taskmanager.PriorityNone {
+			priority = task.Priority.String()
+		}
+		if !task.Due.IsZero() {
+			due = task.Due.Local().Format("2006-01-02 15:04")
+		}
+		if len(task.Tags) > 0 {
+			tags = strings.Join(task.Tags, ",")
+		}
+		title := task.Title
+		if task.ParentID != 0 {
+			title = fmt.Sprintf("%s (subtask of %d)", title, task.ParentID)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", task.ID, done, priority, due, tags, title)
+	}
+	return w.Flush()
+}
+
+// parseDue reads a due date; "" and "none" mean no due date
+func parseDue(s string) (time.Time, error) {
+	if s == "" || s == "none" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q: use 2006-01-02, 2006-01-02 15:04 or RFC 3339", s)
+}
+
+func parseIDs(args []string) ([]int, error) {
+	ids := make([]int, len(args))
+	for i, arg := range args {
+		id, err := strconv.Atoi(arg)
+		if err != nil || id < 1 {
+			return nil, fmt.Errorf("invalid task ID %q", arg)
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+// tagList collects the values of a repeated, comma-separated flag
+type tagList []string
+
+func (l *tagList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *tagList) Set(s string) error {
+	*l = append(*l, strings.Split(s, ",")...)
+	return nil
+}
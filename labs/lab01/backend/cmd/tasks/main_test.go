@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"lab01/taskmanager"
+	"lab01/taskmanager/httpapi"
+)
+
+// tasks runs the command line and returns its output, failing the test if
+// the exit status is not want
+func tasks(t *testing.T, want int, args ...string) string {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	if code := run(args, &stdout, &stderr); code != want {
+		t.Fatalf("tasks %s exited with %d, want %d: %s", strings.Join(args, " "), code, want, stderr.String())
+	}
+	return stdout.String() + stderr.String()
+}
+
+func TestCommands(t *testing.T) {
+	tm := taskmanager.NewTaskManager()
+	server := httptest.NewServer(httpapi.NewHandler(tm))
+	defer server.Close()
+
+	targets := map[string][]string{
+		"json store":   {"-store", filepath.Join(t.TempDir(), "tasks.json")},
+		"sqlite store": {"-store", filepath.Join(t.TempDir(), "tasks.db")},
+		"api":          {"-api", server.URL},
+	}
+	for name, target := range targets {
+		t.Run(name, func(t *testing.T) {
+			asJSON := append(target, "-o", "json")
+
+			out := tasks(t, 0, append(target, "add", "-p", "high", "-tag", "Health,water", "-due", "2025-06-02", "Drink", "water")...)
+			if !strings.Contains(out, "Drink water") || !strings.Contains(out, "2025-06-02") || !strings.Contains(out, "health,water") {
+				t.Errorf("Unexpected output of add:\n%s", out)
+			}
+			tasks(t, 0, append(target, "add", "-parent", "1", "Fill the bottle")...)
+			tasks(t, 0, append(target, "add", "-d", "Chapter 3", "Read")...)
+
+			var listed []taskmanager.Task
+			decode(t, tasks(t, 0, append(asJSON, "list", "-sort", "-priority,id")...), &listed)
+			if len(listed) != 3 || listed[0].Title != "Drink water" || listed[1].ParentID != 1 {
+				t.Fatalf("Unexpected tasks listed: %+v", listed)
+			}
+
+			tasks(t, 0, append(target, "done", "1")...)
+			decode(t, tasks(t, 0, append(asJSON, "list", "-pending")...), &listed)
+			if len(listed) != 1 || listed[0].Title != "Read" {
+				t.Errorf("Expected completing a task to complete its subtask, got pending %+v", listed)
+			}
+
+			var edited taskmanager.Task
+			decode(t, tasks(t, 0, append(asJSON, "edit", "-title", "Read a book", "-p", "low", "3")...), &edited)
+			if edited.Title != "Read a book" || edited.Description != "Chapter 3" || edited.Priority != taskmanager.PriorityLow {
+				t.Errorf("Unexpected task after edit: %+v", edited)
+			}
+
+			out = tasks(t, 0, append(target, "rm", "1")...)
+			if !strings.Contains(out, "Deleted task 1") {
+				t.Errorf("Unexpected output of rm: %s", out)
+			}
+			out = tasks(t, 0, append(target, "list")...)
+			if lines := strings.Split(strings.TrimSpace(out), "\n"); len(lines) != 2 || !strings.HasPrefix(lines[1], "3 ") {
+				t.Errorf("Expected the header and task 3 only, got:\n%s", out)
+			}
+
+			if out := tasks(t, 1, append(target, "done", "1")...); !strings.Contains(out, "task not found") {
+				t.Errorf("Expected a not found error, got: %s", out)
+			}
+			if out := tasks(t, 1, append(target, "edit", "-title", "", "3")...); !strings.Contains(out, "title cannot be empty") {
+				t.Errorf("Expected an empty title error, got: %s", out)
+			}
+
+			// Undo brings back the deleted task and its subtask
+			if out := tasks(t, 0, append(target, "undo")...); !strings.Contains(out, "Undid delete task 1") {
+				t.Errorf("Unexpected output of undo: %s", out)
+			}
+			decode(t, tasks(t, 0, append(asJSON, "list")...), &listed)
+			if len(listed) != 3 || listed[0].ID != 1 || listed[1].ParentID != 1 {
+				t.Errorf("Expected tasks 1 to 3 after undo, got %+v", listed)
+			}
+			tasks(t, 0, append(target, "redo")...)
+			if out := tasks(t, 1, append(target, "redo")...); !strings.Contains(out, "nothing to redo") {
+				t.Errorf("Expected nothing to redo, got: %s", out)
+			}
+		})
+	}
+}
+
+func TestImportExport(t *testing.T) {
+	dir := t.TempDir()
+	from := []string{"-store", filepath.Join(dir, "from.json")}
+	to := []string{"-store", filepath.Join(dir, "to.db")}
+	tasks(t, 0, append(from, "add", "-p", "high", "-tag", "health", "Drink water")...)
+	tasks(t, 0, append(from, "add", "-parent", "1", "Fill the bottle")...)
+
+	for _, name := range []string{"tasks.csv", "tasks.ics", "todo.txt"} {
+		path := filepath.Join(dir, name)
+		tasks(t, 0, append(from, "export", path)...)
+
+		out := tasks(t, 0, append(to, "import", "-dry-run", path)...)
+		if !strings.Contains(out, "Would import 2 tasks") {
+			t.Errorf("Unexpected output of a dry run of %s: %s", name, out)
+		}
+		var report struct {
+			Imported []taskmanager.Task
+			Errors   []struct{ Row int }
+		}
+		decode(t, tasks(t, 0, append(to, "-o", "json", "import", path)...), &report)
+		if len(report.Imported) != 2 || len(report.Errors) != 0 || report.Imported[1].ParentID != report.Imported[0].ID {
+			t.Errorf("Unexpected import of %s: %+v", name, report)
+		}
+	}
+	var imported []taskmanager.Task
+	decode(t, tasks(t, 0, append(to, "-o", "json", "list")...), &imported)
+	if len(imported) != 6 {
+		t.Errorf("Expected 6 tasks after importing 3 files, got %d", len(imported))
+	}
+
+	// Standard output is JSON unless a format is given
+	decode(t, tasks(t, 0, append(from, "export")...), &imported)
+	if len(imported) != 2 || imported[0].Priority != taskmanager.PriorityHigh {
+		t.Errorf("Unexpected JSON export: %+v", imported)
+	}
+	if out := tasks(t, 0, append(from, "export", "-format", "todo.txt", "-")...); !strings.HasPrefix(out, "(A) ") {
+		t.Errorf("Unexpected todo.txt export: %s", out)
+	}
+
+	bad := filepath.Join(dir, "bad.csv")
+	os.WriteFile(bad, []byte("title,priority\nGood,low\nBad,urgent\n"), 0o644)
+	out := tasks(t, 1, append(to, "import", bad)...)
+	if !strings.Contains(out, "Imported 1 tasks") || !strings.Contains(out, "row 3: invalid priority") {
+		t.Errorf("Expected the bad row to be reported, got: %s", out)
+	}
+
+	// An import is undone at once
+	tasks(t, 0, append(to, "undo")...)
+	tasks(t, 0, append(to, "undo")...)
+	decode(t, tasks(t, 0, append(to, "-o", "json", "list")...), &imported)
+	if len(imported) != 4 {
+		t.Errorf("Expected 4 tasks after undoing 2 imports, got %d", len(imported))
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+	store := filepath.Join(t.TempDir(), "tasks.json")
+	for _, args := range [][]string{
+		{},
+		{"fly"},
+		{"-o", "xml", "list"},
+		{"-store", store, "add"},
+		{"-store", store, "done", "-x"},
+		{"-store", store, "edit", "1", "2"},
+		{"-store", store, "undo", "1"},
+	} {
+		tasks(t, 2, args...)
+	}
+	for _, args := range [][]string{
+		{"-store", store, "done", "one"},
+		{"-store", store, "add", "-due", "soon", "x"},
+		{"-store", store, "add", "-p", "urgent", "x"},
+		{"-store", store, "edit", "1"},
+		{"-store", store, "list", "-sort", "owner"},
+		{"-store", store, "import", "-", "x"},
+		{"-store", store, "export", "tasks.xml"},
+	} {
+		tasks(t, 1, args...)
+	}
+}
+
+func decode(t *testing.T, out string, v any) {
+	t.Helper()
+	if err := json.Unmarshal([]byte(out), v); err != nil {
+		t.Fatalf("Decoding %q failed: %v", out, err)
+	}
+}
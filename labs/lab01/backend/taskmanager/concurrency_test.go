@@ -0,0 +1,273 @@
+package taskmanager
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSaveTask(t *testing.T) {
+	tm := NewTaskManager()
+	task, err := tm.AddTask("Drink water", "")
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+
+	first, second := task, task
+	first.Done = true
+	saved, err := tm.SaveTask(first)
+	if err != nil {
+		t.Fatalf("SaveTask failed: %v", err)
+	}
+	if saved.Version != 2 || !saved.Done {
+		t.Errorf("Expected done task at version 2, got %+v", saved)
+	}
+
+	second.Title = "Drink 2 l of water"
+	if _, err := tm.SaveTask(second); err != ErrVersionConflict {
+		t.Errorf("Expected ErrVersionConflict, got %v", err)
+	}
+	second.Version = saved.Version
+	if _, err := tm.SaveTask(second); err != nil {
+		t.Errorf("Expected the retried save to succeed, got %v", err)
+	}
+
+	tests := []struct {
+		name string
+		task Task
+		err  error
+	}{
+		{"empty title", Task{ID: task.ID, Version: 3}, ErrEmptyTitle},
+		{"missing task", Task{ID: 999, Title: "x", Version: 1}, ErrTaskNotFound},
+	}
+	for _, tt := range tests {
+		if _, err := tm.SaveTask(tt.task); err != tt.err {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.err, err)
+		}
+	}
+}
+
+func TestUpdateTaskBumpsVersion(t *testing.T) {
+	tm := NewTaskManager()
+	task, _ := tm.AddTask("Stretch", "")
+	for range 3 {
+		if err := tm.UpdateTask(task.ID, 0, "Stretch", "", true); err != nil {
+			t.Fatalf("UpdateTask failed: %v", err)
+		}
+	}
+	got, _ := tm.GetTask(task.ID)
+	if got.Version != 4 {
+		t.Errorf("Expected version 4 after three updates, got %d", got.Version)
+	}
+}
+
+func receive(t *testing.T, events <-chan Event) Event {
+	t.Helper()
+	select {
+	case e, ok := <-events:
+		if !ok {
+			t.Fatal("Events channel closed unexpectedly")
+		}
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for an event")
+	}
+	return Event{}
+}
+
+func TestSubscribe(t *testing.T) {
+	tm := NewTaskManager()
+	ctx, cancel := context.WithCancel(context.Background())
+	events := tm.Subscribe(ctx)
+
+	task, _ := tm.AddTask("Walk", "")
+	tm.UpdateTask(task.ID, 0, "Walk 10k steps", "", false)
+	tm.DeleteTask(task.ID)
+	tm.DeleteTask(task.ID) // fails, so no event
+
+	want := []struct {
+		typ     EventType
+		title   string
+		version int
+	}{
+		{EventCreated, "Walk", 1},
+		{EventUpdated, "Walk 10k steps", 2},
+		{EventDeleted, "Walk 10k steps", 2},
+	}
+	for _, w := range want {
+		e := receive(t, events)
+		if e.Type != w.typ || e.Task.Title != w.title || e.Task.Version != w.version {
+			t.Errorf("Got event %s %q v%d, want %s %q v%d", e.Type, e.Task.Title, e.Task.Version, w.typ, w.title, w.version)
+		}
+	}
+
+	cancel()
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("Expected no more events")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the channel to close after cancel")
+	}
+	// Changes after unsubscribing do not block or panic
+	tm.AddTask("After", "")
+}
+
+func TestSubscribeSlowConsumer(t *testing.T) {
+	tm := NewTaskManager()
+	slow := tm.Subscribe(context.Background())
+	fast := tm.Subscribe(context.Background())
+
+	// The slow subscriber reads nothing while its backlog overflows, and
+	// is dropped; the fast one reads every change as it happens
+	n := subscriberBacklog + 10
+	for i := range n {
+		if _, err := tm.AddTask(fmt.Sprintf("Task %d", i), ""); err != nil {
+			t.Fatalf("AddTask failed: %v", err)
+		}
+		if e := receive(t, fast); e.Task.ID != i+1 {
+			t.Fatalf("Expected event for task %d, got %d", i+1, e.Task.ID)
+		}
+	}
+	count := 0
+	for range slow {
+		count++
+	}
+	if count >= n {
+		t.Errorf("Expected the slow subscriber to be dropped, got all %d events", count)
+	}
+
+	tm.Close()
+	if _, ok := <-fast; ok {
+		t.Error("Expected Close to end the subscription")
+	}
+}
+
+// TestConcurrentStress hammers one manager from many goroutines; run it
+// with go test -race
+func TestConcurrentStress(t *testing.T) {
+	stores := map[string]func(t *testing.T) Store{
+		"memory": func(t *testing.T) Store { return NewMemoryStore() },
+		"sqlite": func(t *testing.T) Store {
+			s, err := OpenSQLiteStore(filepath.Join(t.TempDir(), "tasks.db"))
+			if err != nil {
+				t.Fatalf("OpenSQLiteStore failed: %v", err)
+			}
+			return s
+		},
+	}
+	for name, open := range stores {
+		t.Run(name, func(t *testing.T) {
+			tm := NewTaskManagerWithStore(open(t))
+			events := tm.Subscribe(context.Background())
+
+			// Count events and check that each task's versions only go up
+			var (
+				counts   = map[EventType]int{}
+				versions = map[int]int{}
+				bad      []string
+			)
+			consumed := make(chan struct{})
+			go func() {
+				defer close(consumed)
+				for e := range events {
+					counts[e.Type]++
+					if e.Type != EventDeleted && e.Task.Version <= versions[e.Task.ID] {
+						bad = append(bad, fmt.Sprintf("task %d went from v%d to v%d", e.Task.ID, versions[e.Task.ID], e.Task.Version))
+					}
+					versions[e.Task.ID] = e.Task.Version
+				}
+			}()
+
+			const workers, rounds = 8, 20
+			var (
+				wg                      sync.WaitGroup
+				mu                      sync.Mutex
+				created, saved, deleted int
+				conflicts               int
+			)
+			for w := range workers {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := range rounds {
+						task, err := tm.AddTask(fmt.Sprintf("w%d-%d", w, i), "")
+						if err != nil {
+							t.Errorf("AddTask failed: %v", err)
+							return
+						}
+						if err := tm.UpdateTask(task.ID, 0, task.Title, "updated", false); err != nil {
+							t.Errorf("UpdateTask failed: %v", err)
+							return
+						}
+						// Everyone races to complete task 1 from the same
+						// stale read; at most one save per version wins
+						shared, err := tm.GetTask(1)
+						if err != nil {
+							t.Errorf("GetTask failed: %v", err)
+							return
+						}
+						shared.Done = !shared.Done
+						_, err = tm.SaveTask(shared)
+						mu.Lock()
+						created++
+						switch err {
+						case nil:
+							saved++
+						case ErrVersionConflict:
+							conflicts++
+						default:
+							t.Errorf("SaveTask failed: %v", err)
+						}
+						mu.Unlock()
+						if i%4 == 3 {
+							if err := tm.DeleteTask(task.ID); err != nil {
+								t.Errorf("DeleteTask failed: %v", err)
+							}
+							mu.Lock()
+							deleted++
+							mu.Unlock()
+						}
+						if _, err := tm.ListTasks(Query{}); err != nil {
+							t.Errorf("ListTasks failed: %v", err)
+						}
+					}
+				}()
+			}
+			wg.Wait()
+			// Close delivers the remaining events before ending the
+			// subscription
+			tasks, err := tm.ListTasks(Query{})
+			if err != nil {
+				t.Fatalf("ListTasks failed: %v", err)
+			}
+			shared, _ := tm.GetTask(1)
+			tm.Close()
+			<-consumed
+
+			for _, b := range bad {
+				t.Error(b)
+			}
+			if counts[EventCreated] != created {
+				t.Errorf("Got %d created events, want %d", counts[EventCreated], created)
+			}
+			// Every task got one UpdateTask, plus the successful saves
+			if counts[EventUpdated] != created+saved {
+				t.Errorf("Got %d updated events, want %d", counts[EventUpdated], created+saved)
+			}
+			if counts[EventDeleted] != deleted {
+				t.Errorf("Got %d deleted events, want %d", counts[EventDeleted], deleted)
+			}
+			if len(tasks) != created-deleted {
+				t.Errorf("Got %d tasks, want %d", len(tasks), created-deleted)
+			}
+			if shared.Version != 2+saved {
+				t.Errorf("Task 1 is at version %d, want %d after %d saves (%d conflicts)", shared.Version, 2+saved, saved, conflicts)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package taskmanager
+
+import (
+	"context"
+	"sync"
+)
+
+// EventType says what happened to a task
+type EventType string
+
+const (
+	EventCreated EventType = "created"
+	EventUpdated EventType = "updated"
+	EventDeleted EventType = "deleted"
+)
+
+// Event is a change made through a TaskManager. Task is the task after the
+// change, or as it was before being deleted.
+type Event struct {
+	Type EventType `json:"type"`
+	Task Task      `json:"task"`
+}
+
+// subscriberBacklog is how many events a subscriber may fall behind by
+// before it is dropped
+const subscriberBacklog = 1024
+
+// subscriber queues events so that a slow reader never blocks changes; a
+// goroutine forwards the queue to ch
+type subscriber struct {
+	ch   chan Event
+	wake chan struct{}
+
+	mu     sync.Mutex
+	queue  []Event
+	closed bool
+}
+
+// Subscribe returns a channel of the changes made from now on, in order.
+// The channel is closed when ctx is done, after the remaining events when
+// the manager is closed, or when the reader falls more than 1024 events
+// behind; in that last case events were missed and the reader should
+// reload its tasks.
+func (tm *TaskManager) Subscribe(ctx context.Context) <-chan Event {
+	s := &subscriber{ch: make(chan Event), wake: make(chan struct{}, 1)}
+	tm.mu.Lock()
+	tm.subs[s] = struct{}{}
+	tm.mu.Unlock()
+	go tm.forward(ctx, s)
+	return s.ch
+}
+
+func (tm *TaskManager) forward(ctx context.Context, s *subscriber) {
+	defer close(s.ch)
+	for {
+		s.mu.Lock()
+		batch, closed := s.queue, s.closed
+		s.queue = nil
+		s.mu.Unlock()
+		if closed && len(batch) == 0 {
+			return
+		}
+		for _, e := range batch {
+			select {
+			case s.ch <- e:
+			case <-ctx.Done():
+				tm.unsubscribe(s, false)
+				return
+			}
+		}
+		if len(batch) > 0 {
+			continue
+		}
+		select {
+		case <-s.wake:
+		case <-ctx.Done():
+			tm.unsubscribe(s, false)
+			return
+		}
+	}
+}
+
+// publish queues e for every subscriber; tm.mu must be held
+func (tm *TaskManager) publish(e Event) {
+	for s := range tm.subs {
+		s.mu.Lock()
+		overflow := len(s.queue) >= subscriberBacklog
+		if !overflow {
+			s.queue = append(s.queue, e)
+		}
+		s.mu.Unlock()
+		if overflow {
+			tm.end(s, false)
+			continue
+		}
+		s.notify()
+	}
+}
+
+// unsubscribe removes s, with pending events delivered first when drain is
+// set
+func (tm *TaskManager) unsubscribe(s *subscriber, drain bool) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.end(s, drain)
+}
+
+// end removes s and tells its goroutine to stop; tm.mu must be held
+func (tm *TaskManager) end(s *subscriber, drain bool) {
+	delete(tm.subs, s)
+	s.mu.Lock()
+	s.closed = true
+	if !drain {
+		s.queue = nil
+	}
+	s.mu.Unlock()
+	s.notify()
+}
+
+func (s *subscriber) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
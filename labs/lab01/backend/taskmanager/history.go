@@ -0,0 +1,416 @@
+package taskmanager
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// DefaultHistoryLimit is how many operations a TaskManager can undo unless
+// SetHistoryLimit says otherwise
+const DefaultHistoryLimit = 100
+
+var (
+	ErrNothingToUndo = errors.New("nothing to undo")
+	ErrNothingToRedo = errors.New("nothing to redo")
+)
+
+// HistoryStore is a Store that also keeps the undo history of a TaskManager
+// next to the tasks, so that Undo works after a restart. The history is
+// JSON that the store saves as is.
+type HistoryStore interface {
+	Store
+	// LoadHistory returns the saved history, or nil if there is none
+	LoadHistory() ([]byte, error)
+	// SaveHistory replaces the saved history
+	SaveHistory(data []byte) error
+}
+
+// change is what an operation did to one task. Before is nil for a task it
+// created and After is nil for one it deleted; the Version of each is the
+// one the task has while in that state.
+type change struct {
+	Before *Task `json:"before,omitempty"`
+	After  *Task `json:"after,omitempty"`
+}
+
+func (c change) id() int {
+	return cmp.Or(c.Before, c.After).ID
+}
+
+// operation is an entry of the history: the changes made by a call such as
+// DeleteTask, subtasks included, or by a Transaction
+type operation struct {
+	Name    string   `json:"name"`
+	Changes []change `json:"changes"`
+}
+
+// add records a change, merging it with an earlier change to the same task
+// so that an operation holds at most one change per task
+func (op *operation) add(before, after *Task) {
+	c := change{clonePtr(before), clonePtr(after)}
+	for i := range op.Changes {
+		if op.Changes[i].id() != c.id() {
+			continue
+		}
+		if op.Changes[i].Before == nil && after == nil {
+			// Created and deleted again: nothing to undo
+			op.Changes = slices.Delete(op.Changes, i, i+1)
+		} else {
+			op.Changes[i].After = c.After
+		}
+		return
+	}
+	op.Changes = append(op.Changes, c)
+}
+
+func clonePtr(task *Task) *Task {
+	if task == nil {
+		return nil
+	}
+	c := task.clone()
+	return &c
+}
+
+// historyFile is the layout of the history saved in a HistoryStore
+type historyFile struct {
+	Undo []*operation `json:"undo"`
+	Redo []*operation `json:"redo"`
+}
+
+// record starts recording the changes made from now on as an operation, and
+// returns the function that adds it to the history; tm.mu must be held
+func (tm *TaskManager) record(name string) (done func()) {
+	tm.op = &operation{Name: name}
+	return func() {
+		op := tm.op
+		tm.op = nil
+		tm.push(op)
+	}
+}
+
+// changed adds a change to the operation being recorded; tm.mu must be held
+func (tm *TaskManager) changed(before, after *Task) {
+	if tm.op != nil {
+		tm.op.add(before, after)
+	}
+}
+
+// push adds an operation to the history, which forgets what was undone
+// before it; tm.mu must be held
+func (tm *TaskManager) push(op *operation) {
+	if len(op.Changes) == 0 || tm.historyLimit <= 0 {
+		return
+	}
+	tm.undo = append(tm.undo, op)
+	tm.redo = nil
+	tm.trimHistory()
+	tm.saveHistory()
+}
+
+// trimHistory drops the oldest operations over the limit; tm.mu must be held
+func (tm *TaskManager) trimHistory() {
+	if extra := len(tm.undo) - max(tm.historyLimit, 0); extra > 0 {
+		tm.undo = slices.Clone(tm.undo[extra:])
+	}
+	if extra := len(tm.redo) - max(tm.historyLimit, 0); extra > 0 {
+		tm.redo = slices.Clone(tm.redo[extra:])
+	}
+}
+
+// loadHistory reads the history of a HistoryStore. A history that cannot
+// be read is dropped: it only matters to Undo, and the tasks are intact.
+func (tm *TaskManager) loadHistory() {
+	hs, ok := tm.store.(HistoryStore)
+	if !ok {
+		return
+	}
+	data, err := hs.LoadHistory()
+	if err != nil || len(data) == 0 {
+		return
+	}
+	var file historyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return
+	}
+	tm.undo, tm.redo = file.Undo, file.Redo
+	tm.trimHistory()
+}
+
+// saveHistory saves the history in a HistoryStore. The error is also kept
+// for Close, as a change whose history failed to save has still been made;
+// tm.mu must be held.
+func (tm *TaskManager) saveHistory() error {
+	hs, ok := tm.store.(HistoryStore)
+	if !ok {
+		return nil
+	}
+	data, err := json.Marshal(historyFile{Undo: tm.undo, Redo: tm.redo})
+	if err == nil {
+		err = hs.SaveHistory(data)
+	}
+	tm.historyErr = nil
+	if err != nil {
+		tm.historyErr = fmt.Errorf("taskmanager: saving history: %w", err)
+	}
+	return tm.historyErr
+}
+
+// SetHistoryLimit sets how many operations can be undone, dropping the
+// oldest ones beyond it; 0 turns the history off
+func (tm *TaskManager) SetHistoryLimit(n int) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.historyLimit = n
+	tm.trimHistory()
+	tm.saveHistory()
+}
+
+// History returns the names of the operations that can be undone and
+// redone, next first, e.g. `delete task 3` or the name of a Transaction
+func (tm *TaskManager) History() (undo, redo []string) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	names := func(ops []*operation) []string {
+		list := make([]string, 0, len(ops))
+		for _, op := range slices.Backward(ops) {
+			list = append(list, op.Name)
+		}
+		return list
+	}
+	return names(tm.undo), names(tm.redo)
+}
+
+// ClearHistory forgets every operation, e.g. after an Undo that conflicts
+func (tm *TaskManager) ClearHistory() error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.undo, tm.redo = nil, nil
+	return tm.saveHistory()
+}
+
+// Undo reverts the last operation and returns its name: a deleted task
+// comes back with its ID and subtasks, a created one is deleted and an
+// updated one gets its old fields back. If another manager on the same
+// store has changed one of its tasks since, Undo returns
+// ErrVersionConflict and changes nothing.
+func (tm *TaskManager) Undo() (string, error) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if len(tm.undo) == 0 {
+		return "", ErrNothingToUndo
+	}
+	op := tm.undo[len(tm.undo)-1]
+	reverted, err := tm.revert(op)
+	if err != nil {
+		return "", err
+	}
+	tm.undo = tm.undo[:len(tm.undo)-1]
+	relink(tm.undo, reverted, true)
+	tm.redo = append(tm.redo, reverted)
+	return op.Name, tm.saveHistory()
+}
+
+// Redo makes the last undone operation again and returns its name. It
+// fails like Undo, and with ErrNothingToRedo once a change has been made
+// after the Undo.
+func (tm *TaskManager) Redo() (string, error) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if len(tm.redo) == 0 {
+		return "", ErrNothingToRedo
+	}
+	op := tm.redo[len(tm.redo)-1]
+	reapplied, err := tm.reapply(op)
+	if err != nil {
+		return "", err
+	}
+	tm.redo = tm.redo[:len(tm.redo)-1]
+	relink(tm.redo, reapplied, false)
+	tm.undo = append(tm.undo, reapplied)
+	return op.Name, tm.saveHistory()
+}
+
+// revert takes the tasks of op back to their Before state, last change
+// first so that parents come back before their subtasks, and returns op
+// with the new versions; tm.mu must be held
+func (tm *TaskManager) revert(op *operation) (*operation, error) {
+	for _, c := range op.Changes {
+		if err := tm.check(c.id(), c.After); err != nil {
+			return nil, err
+		}
+	}
+	reverted := &operation{Name: op.Name, Changes: slices.Clone(op.Changes)}
+	for i := len(reverted.Changes) - 1; i >= 0; i-- {
+		c := &reverted.Changes[i]
+		before, err := tm.transition(c.After, c.Before)
+		if err != nil {
+			return nil, err
+		}
+		c.Before = before
+	}
+	return reverted, nil
+}
+
+// reapply takes the tasks of op from their Before to their After state in
+// the order they first changed, and returns op with the new versions;
+// tm.mu must be held
+func (tm *TaskManager) reapply(op *operation) (*operation, error) {
+	for _, c := range op.Changes {
+		if err := tm.check(c.id(), c.Before); err != nil {
+			return nil, err
+		}
+	}
+	reapplied := &operation{Name: op.Name, Changes: slices.Clone(op.Changes)}
+	for i := range reapplied.Changes {
+		c := &reapplied.Changes[i]
+		after, err := tm.transition(c.Before, c.After)
+		if err != nil {
+			return nil, err
+		}
+		c.After = after
+	}
+	return reapplied, nil
+}
+
+// relink points the nearest operation of ops that changed each task of op
+// at the version the task has now: undoing or redoing op moved it on. ops
+// are searched from the last; undone says op was undone, so that it is the
+// After of earlier operations that must match, and Before otherwise.
+func relink(ops []*operation, op *operation, undone bool) {
+	for _, c := range op.Changes {
+		now := c.After
+		if undone {
+			now = c.Before
+		}
+		if now == nil {
+			continue
+		}
+		for _, other := range slices.Backward(ops) {
+			i := slices.IndexFunc(other.Changes, func(o change) bool { return o.id() == c.id() })
+			if i < 0 {
+				continue
+			}
+			link := &other.Changes[i].Before
+			if undone {
+				link = &other.Changes[i].After
+			}
+			if *link != nil {
+				task := (*link).clone()
+				task.Version = now.Version
+				*link = &task
+			}
+			break
+		}
+	}
+}
+
+// check returns ErrVersionConflict unless the stored task is in state want,
+// nil meaning it does not exist; tm.mu must be held
+func (tm *TaskManager) check(id int, want *Task) error {
+	stored, err := tm.store.Get(id)
+	switch {
+	case err == ErrTaskNotFound && want == nil:
+		return nil
+	case err == ErrTaskNotFound || (err == nil && (want == nil || stored.Version != want.Version)):
+		return fmt.Errorf("%w: task %d", ErrVersionConflict, id)
+	}
+	return err
+}
+
+// transition stores a task in state to, replacing state from, and publishes
+// the change. It returns the new state, whose version is past every earlier
+// one so that stale copies of the task conflict; tm.mu must be held.
+func (tm *TaskManager) transition(from, to *Task) (*Task, error) {
+	switch {
+	case to == nil:
+		if err := tm.store.Delete(from.ID); err != nil {
+			return nil, err
+		}
+		tm.publish(Event{Type: EventDeleted, Task: from.clone()})
+		return nil, nil
+	case from == nil:
+		task := to.clone()
+		task.Version++
+		if err := tm.store.Restore(task); err != nil {
+			return nil, err
+		}
+		tm.publish(Event{Type: EventCreated, Task: task.clone()})
+		return &task, nil
+	default:
+		task := to.clone()
+		task.Version = from.Version
+		if err := tm.store.Update(task); err != nil {
+			return nil, err
+		}
+		task.Version++
+		tm.publish(Event{Type: EventUpdated, Task: task.clone()})
+		return &task, nil
+	}
+}
+
+// Tx makes the changes of a Transaction. Its methods work like those of
+// TaskManager; it must not be used once the transaction is over.
+type Tx struct {
+	tm *TaskManager
+}
+
+// Transaction runs fn and records the changes it makes through tx as one
+// operation named name, which Undo reverts at once. Other changes wait
+// until fn returns. If fn returns an error, its changes are reverted and
+// the error is returned. fn must not call the methods of tm itself, which
+// would wait for the transaction forever.
+func (tm *TaskManager) Transaction(name string, fn func(tx *Tx) error) error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.op = &operation{Name: name}
+	defer func() { tm.op = nil }()
+	if err := fn(&Tx{tm: tm}); err != nil {
+		reverted, rerr := tm.revert(tm.op)
+		if rerr != nil {
+			return errors.Join(err, fmt.Errorf("taskmanager: reverting %q: %w", name, rerr))
+		}
+		relink(tm.undo, reverted, true)
+		return err
+	}
+	tm.push(tm.op)
+	return nil
+}
+
+// AddTask adds a task with a title and description
+func (tx *Tx) AddTask(title, description string) (Task, error) {
+	return tx.tm.createTask(Task{Title: title, Description: description})
+}
+
+// CreateTask adds a task; see TaskManager.CreateTask
+func (tx *Tx) CreateTask(task Task) (Task, error) {
+	return tx.tm.createTask(task)
+}
+
+// UpdateTask updates a task; see TaskManager.UpdateTask
+func (tx *Tx) UpdateTask(id, version int, title, description string, done bool) error {
+	return tx.tm.updateTask(id, version, title, description, done)
+}
+
+// SaveTask writes a task; see TaskManager.SaveTask
+func (tx *Tx) SaveTask(task Task) (Task, error) {
+	return tx.tm.saveTask(task)
+}
+
+// DeleteTask removes a task together with its subtasks
+func (tx *Tx) DeleteTask(id int) error {
+	return tx.tm.deleteTask(id)
+}
+
+// GetTask retrieves a task by ID, seeing the changes made so far
+func (tx *Tx) GetTask(id int) (Task, error) {
+	return tx.tm.GetTask(id)
+}
+
+// ListTasks returns the tasks matching the query, seeing the changes made
+// so far
+func (tx *Tx) ListTasks(q Query) ([]Task, error) {
+	return tx.tm.ListTasks(q)
+}
@@ -0,0 +1,215 @@
+package taskmanager
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+// titles returns the titles of every task in ID order
+func titles(t *testing.T, tm *TaskManager) []string {
+	t.Helper()
+	tasks, err := tm.ListTasks(Query{})
+	if err != nil {
+		t.Fatalf("ListTasks failed: %v", err)
+	}
+	list := []string{}
+	for _, task := range tasks {
+		list = append(list, task.Title)
+	}
+	return list
+}
+
+func mustUndo(t *testing.T, tm *TaskManager, want string) {
+	t.Helper()
+	if name, err := tm.Undo(); err != nil || name != want {
+		t.Fatalf("Undo() = %q, %v, want %q", name, err, want)
+	}
+}
+
+func mustRedo(t *testing.T, tm *TaskManager, want string) {
+	t.Helper()
+	if name, err := tm.Redo(); err != nil || name != want {
+		t.Fatalf("Redo() = %q, %v, want %q", name, err, want)
+	}
+}
+
+func TestUndoRedo(t *testing.T) {
+	tm := newTestManager(t, time.Date(2025, 6, 2, 8, 0, 0, 0, time.UTC))
+	if _, err := tm.Undo(); err != ErrNothingToUndo {
+		t.Errorf("Undo on a new manager: expected ErrNothingToUndo, got %v", err)
+	}
+	parent := mustCreateTask(t, tm, Task{Title: "Morning routine", Tags: []string{"home"}})
+	child := mustCreateTask(t, tm, Task{Title: "Stretch", ParentID: parent.ID})
+	if err := tm.UpdateTask(parent.ID, 0, "Evening routine", "", false); err != nil {
+		t.Fatalf("UpdateTask failed: %v", err)
+	}
+	if err := tm.DeleteTask(parent.ID); err != nil {
+		t.Fatalf("DeleteTask failed: %v", err)
+	}
+
+	undo, redo := tm.History()
+	want := []string{`delete task 1`, `update "Evening routine"`, `create "Stretch"`, `create "Morning routine"`}
+	if !reflect.DeepEqual(undo, want) || len(redo) != 0 {
+		t.Errorf("History() = %q, %q, want %q and nothing to redo", undo, redo, want)
+	}
+
+	// The task and its subtask come back with their IDs
+	mustUndo(t, tm, "delete task 1")
+	if got := titles(t, tm); !reflect.DeepEqual(got, []string{"Evening routine", "Stretch"}) {
+		t.Errorf("After undoing the delete, tasks are %q", got)
+	}
+	if got := mustGetTask(t, tm, child.ID); got.ParentID != parent.ID {
+		t.Errorf("Expected the subtask to keep its parent, got %+v", got)
+	}
+	mustUndo(t, tm, `update "Evening routine"`)
+	got := mustGetTask(t, tm, parent.ID)
+	if got.Title != "Morning routine" || !slices.Equal(got.Tags, []string{"home"}) || got.Version <= parent.Version+1 {
+		t.Errorf("Expected the old fields with a newer version, got %+v", got)
+	}
+	mustUndo(t, tm, `create "Stretch"`)
+	if got := titles(t, tm); !reflect.DeepEqual(got, []string{"Morning routine"}) {
+		t.Errorf("After undoing the create, tasks are %q", got)
+	}
+
+	mustRedo(t, tm, `create "Stretch"`)
+	mustRedo(t, tm, `update "Evening routine"`)
+	if got := titles(t, tm); !reflect.DeepEqual(got, []string{"Evening routine", "Stretch"}) {
+		t.Errorf("After redoing, tasks are %q", got)
+	}
+
+	// A new change forgets what could be redone
+	if _, err := tm.AddTask("Read", ""); err != nil {
+		t.Fatalf("AddTask failed: %v", err)
+	}
+	if _, err := tm.Redo(); err != ErrNothingToRedo {
+		t.Errorf("Redo after a change: expected ErrNothingToRedo, got %v", err)
+	}
+	if next := mustCreateTask(t, tm, Task{Title: "Walk"}); next.ID != 4 {
+		t.Errorf("Expected restored IDs not to be reused, got ID %d", next.ID)
+	}
+}
+
+func TestUndoCompletion(t *testing.T) {
+	now := time.Date(2025, 6, 2, 8, 0, 0, 0, time.UTC)
+	tm := newTestManager(t, now)
+	parent := mustCreateTask(t, tm, Task{Title: "Water plants", Due: now, Recurrence: Daily()})
+	mustCreateTask(t, tm, Task{Title: "Fill the can", ParentID: parent.ID})
+
+	parent.Done = true
+	if _, err := tm.SaveTask(parent); err != nil {
+		t.Fatalf("SaveTask failed: %v", err)
+	}
+	if got := len(titles(t, tm)); got != 3 {
+		t.Fatalf("Expected the next occurrence to be created, got %d tasks", got)
+	}
+
+	// One undo reverts the subtask and removes the next occurrence too
+	mustUndo(t, tm, `update "Water plants"`)
+	tasks, _ := tm.ListTasks(Query{})
+	if len(tasks) != 2 || tasks[0].Done || tasks[1].Done {
+		t.Errorf("Expected both tasks pending and no next occurrence, got %+v", tasks)
+	}
+}
+
+func TestUndoConflict(t *testing.T) {
+	store := NewMemoryStore()
+	tm := NewTaskManagerWithStore(store)
+	other := NewTaskManagerWithStore(store)
+	task, err := tm.AddTask("Stretch", "")
+	if err != nil {
+		t.Fatalf("AddTask failed: %v", err)
+	}
+	if err := tm.UpdateTask(task.ID, 0, "Stretch for 10 minutes", "", false); err != nil {
+		t.Fatalf("UpdateTask failed: %v", err)
+	}
+	if err := other.UpdateTask(task.ID, 0, "Stretch", "Hamstrings", false); err != nil {
+		t.Fatalf("UpdateTask failed: %v", err)
+	}
+
+	if _, err := tm.Undo(); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("Undo after another change: expected ErrVersionConflict, got %v", err)
+	}
+	if got := mustGetTask(t, tm, task.ID); got.Description != "Hamstrings" {
+		t.Errorf("Expected a conflicting undo to change nothing, got %+v", got)
+	}
+	if err := tm.ClearHistory(); err != nil {
+		t.Fatalf("ClearHistory failed: %v", err)
+	}
+	if _, err := tm.Undo(); err != ErrNothingToUndo {
+		t.Errorf("Undo after ClearHistory: expected ErrNothingToUndo, got %v", err)
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	tm := newTestManager(t, time.Date(2025, 6, 2, 8, 0, 0, 0, time.UTC))
+	mustCreateTask(t, tm, Task{Title: "Read"})
+
+	err := tm.Transaction("plan the morning", func(tx *Tx) error {
+		parent, err := tx.AddTask("Morning routine", "")
+		if err != nil {
+			return err
+		}
+		if _, err := tx.CreateTask(Task{Title: "Stretch", ParentID: parent.ID}); err != nil {
+			return err
+		}
+		return tx.DeleteTask(1)
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+	if got := titles(t, tm); !reflect.DeepEqual(got, []string{"Morning routine", "Stretch"}) {
+		t.Errorf("After the transaction, tasks are %q", got)
+	}
+	mustUndo(t, tm, "plan the morning")
+	if got := titles(t, tm); !reflect.DeepEqual(got, []string{"Read"}) {
+		t.Errorf("Expected one undo to revert the transaction, got %q", got)
+	}
+	mustRedo(t, tm, "plan the morning")
+
+	// A failing transaction is reverted and leaves no history
+	failed := errors.New("failed")
+	err = tm.Transaction("fail", func(tx *Tx) error {
+		if _, err := tx.AddTask("Walk", ""); err != nil {
+			return err
+		}
+		if err := tx.UpdateTask(2, 0, "Evening routine", "", true); err != nil {
+			return err
+		}
+		return failed
+	})
+	if err != failed {
+		t.Errorf("Transaction: expected its error, got %v", err)
+	}
+	if got := titles(t, tm); !reflect.DeepEqual(got, []string{"Morning routine", "Stretch"}) {
+		t.Errorf("Expected the failed transaction to be reverted, got %q", got)
+	}
+	if undo, _ := tm.History(); len(undo) != 2 || undo[0] != "plan the morning" {
+		t.Errorf("Expected no history entry for the failed transaction, got %q", undo)
+	}
+}
+
+func TestHistoryLimit(t *testing.T) {
+	tm := NewTaskManager()
+	tm.SetHistoryLimit(2)
+	for _, title := range []string{"one", "two", "three"} {
+		if _, err := tm.AddTask(title, ""); err != nil {
+			t.Fatalf("AddTask failed: %v", err)
+		}
+	}
+	mustUndo(t, tm, `create "three"`)
+	mustUndo(t, tm, `create "two"`)
+	if _, err := tm.Undo(); err != ErrNothingToUndo {
+		t.Errorf("Expected only 2 operations to be kept, got %v", err)
+	}
+
+	tm.SetHistoryLimit(0)
+	if _, err := tm.AddTask("four", ""); err != nil {
+		t.Fatalf("AddTask failed: %v", err)
+	}
+	if undo, redo := tm.History(); len(undo) != 0 || len(redo) != 0 {
+		t.Errorf("Expected no history with a limit of 0, got %q, %q", undo, redo)
+	}
+}
@@ -0,0 +1,158 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"lab01/taskmanager"
+)
+
+// Client calls the REST API served by a Handler. Its methods mirror those
+// of TaskManager, and its errors unwrap to the task manager's errors.
+type Client struct {
+	baseURL string
+	http    *http.Client
+}
+
+// NewClient returns a client of the API at baseURL, e.g.
+// "http://localhost:8080". A nil httpClient uses http.DefaultClient.
+func NewClient(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{baseURL: strings.TrimSuffix(baseURL, "/"), http: httpClient}
+}
+
+// CreateTask creates a task; see TaskManager.CreateTask
+func (c *Client) CreateTask(task taskmanager.Task) (taskmanager.Task, error) {
+	var created taskmanager.Task
+	err := c.do(http.MethodPost, "/tasks", task, &created)
+	return created, err
+}
+
+// GetTask returns the task with the given ID
+func (c *Client) GetTask(id int) (taskmanager.Task, error) {
+	var task taskmanager.Task
+	err := c.do(http.MethodGet, taskPath(id), nil, &task)
+	return task, err
+}
+
+// SaveTask replaces a task if its version is still the stored one; see
+// TaskManager.SaveTask
+func (c *Client) SaveTask(task taskmanager.Task) (taskmanager.Task, error) {
+	if task.Version == 0 {
+		// The API would take version 0 as "whatever the version"
+		return taskmanager.Task{}, taskmanager.ErrVersionConflict
+	}
+	var saved taskmanager.Task
+	err := c.do(http.MethodPut, taskPath(task.ID), task, &saved)
+	return saved, err
+}
+
+// DeleteTask deletes a task and its subtasks
+func (c *Client) DeleteTask(id int) error {
+	return c.do(http.MethodDelete, taskPath(id), nil, nil)
+}
+
+// ListTasks returns every task matching q ordered by ID, fetching as many
+// pages as needed
+func (c *Client) ListTasks(q taskmanager.Query) ([]taskmanager.Task, error) {
+	tasks := []taskmanager.Task{}
+	params := ListParams{Query: q, Sort: "id", Page: 1, PerPage: MaxPerPage}
+	for {
+		page, err := c.ListPage(params)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, page.Tasks...)
+		if len(page.Tasks) == 0 || len(tasks) >= page.Total {
+			return tasks, nil
+		}
+		params.Page++
+	}
+}
+
+// ListPage returns one page of tasks and the number of tasks on every page
+func (c *Client) ListPage(params ListParams) (Page, error) {
+	var page Page
+	err := c.do(http.MethodGet, "/tasks?"+params.Values().Encode(), nil, &page)
+	return page, err
+}
+
+// History returns the operations that can be undone and redone
+func (c *Client) History() (History, error) {
+	var history History
+	err := c.do(http.MethodGet, "/history", nil, &history)
+	return history, err
+}
+
+// Undo undoes the last operation and returns its name
+func (c *Client) Undo() (string, error) {
+	var history History
+	err := c.do(http.MethodPost, "/history/undo", nil, &history)
+	return history.Done, err
+}
+
+// Redo redoes the last undone operation and returns its name
+func (c *Client) Redo() (string, error) {
+	var history History
+	err := c.do(http.MethodPost, "/history/redo", nil, &history)
+	return history.Done, err
+}
+
+// Close does nothing; it lets a Client stand in for a TaskManager
+func (c *Client) Close() error {
+	return nil
+}
+
+func taskPath(id int) string {
+	return "/tasks/" + strconv.Itoa(id)
+}
+
+// do sends a request with body encoded as JSON, if not nil, and decodes the
+// response into out, if not nil. Error responses are returned as *Error.
+func (c *Client) do(method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		var errResp errorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil || errResp.Error == nil {
+			return &Error{Status: resp.StatusCode, Code: CodeInternal, Message: fmt.Sprintf("%s %s: %s", method, path, resp.Status)}
+		}
+		errResp.Error.Status = resp.StatusCode
+		return errResp.Error
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding response of %s %s: %w", method, path, err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"lab01/taskmanager"
+)
+
+func TestClient(t *testing.T) {
+	h, _ := newTestHandler(t)
+	server := httptest.NewServer(h)
+	defer server.Close()
+	c := NewClient(server.URL+"/", nil)
+
+	task, err := c.CreateTask(taskmanager.Task{Title: "Water", Priority: taskmanager.PriorityMedium, Tags: []string{"health"}})
+	if err != nil {
+		t.Fatalf("CreateTask failed: %v", err)
+	}
+	if task.ID != 1 || task.Version != 1 {
+		t.Errorf("Unexpected task created: %+v", task)
+	}
+
+	if _, err := c.CreateTask(taskmanager.Task{}); !errors.Is(err, taskmanager.ErrEmptyTitle) {
+		t.Errorf("Expected ErrEmptyTitle, got %v", err)
+	}
+	if _, err := c.GetTask(42); !errors.Is(err, taskmanager.ErrTaskNotFound) {
+		t.Errorf("Expected ErrTaskNotFound, got %v", err)
+	}
+	var apiErr *Error
+	if _, err := c.CreateTask(taskmanager.Task{Title: "x", ParentID: 42}); !errors.As(err, &apiErr) || apiErr.Status != 422 || apiErr.Field != "parent_id" {
+		t.Errorf("Expected a 422 error on parent_id, got %#v", err)
+	}
+
+	task.Done = true
+	saved, err := c.SaveTask(task)
+	if err != nil || !saved.Done || saved.Version != 2 {
+		t.Fatalf("SaveTask() = %+v, %v", saved, err)
+	}
+	if _, err := c.SaveTask(task); !errors.Is(err, taskmanager.ErrVersionConflict) {
+		t.Errorf("Expected ErrVersionConflict saving a stale task, got %v", err)
+	}
+
+	// ListTasks follows the pages
+	for i := 0; i < MaxPerPage+10; i++ {
+		if _, err := c.CreateTask(taskmanager.Task{Title: "Bulk"}); err != nil {
+			t.Fatalf("CreateTask failed: %v", err)
+		}
+	}
+	pending, err := c.ListTasks(taskmanager.Query{Done: new(bool)})
+	if err != nil {
+		t.Fatalf("ListTasks failed: %v", err)
+	}
+	if len(pending) != MaxPerPage+10 || pending[0].ID != 2 || pending[len(pending)-1].ID != MaxPerPage+11 {
+		t.Errorf("ListTasks returned %d tasks from %d, want %d from 2", len(pending), pending[0].ID, MaxPerPage+10)
+	}
+
+	if err := c.DeleteTask(1); err != nil {
+		t.Errorf("DeleteTask failed: %v", err)
+	}
+	if err := c.DeleteTask(1); !errors.Is(err, taskmanager.ErrTaskNotFound) {
+		t.Errorf("Expected ErrTaskNotFound deleting twice, got %v", err)
+	}
+}
+
+func TestListParamsRoundTrip(t *testing.T) {
+	yes, parent := true, 3
+	params := ListParams{
+		Query: taskmanager.Query{
+			Done:        &yes,
+			MinPriority: taskmanager.PriorityLow,
+			Tags:        []string{"health", "sleep"},
+			ParentID:    &parent,
+			Text:        "water",
+		},
+		Sort:    "-due",
+		Page:    2,
+		PerPage: 10,
+	}
+	got, err := ParseListParams(params.Values())
+	if err != nil {
+		t.Fatalf("ParseListParams failed: %v", err)
+	}
+	if *got.Query.Done != yes || got.Query.MinPriority != params.Query.MinPriority || len(got.Query.Tags) != 2 ||
+		*got.Query.ParentID != parent || got.Query.Text != "water" || got.Sort != "-due" || got.Page != 2 || got.PerPage != 10 {
+		t.Errorf("ParseListParams(%v) = %+v, want %+v", params.Values(), got, params)
+	}
+}
@@ -0,0 +1,113 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"lab01/taskmanager"
+)
+
+// Error codes of an error response
+const (
+	CodeInvalidRequest    = "invalid_request"
+	CodeEmptyTitle        = "empty_title"
+	CodeInvalidPriority   = "invalid_priority"
+	CodeInvalidRecurrence = "invalid_recurrence"
+	CodeInvalidParent     = "invalid_parent"
+	CodeInvalidSort       = "invalid_sort"
+	CodeNotFound          = "not_found"
+	CodeVersionConflict   = "version_conflict"
+	CodeNothingToUndo     = "nothing_to_undo"
+	CodeNothingToRedo     = "nothing_to_redo"
+	CodeInternal          = "internal"
+)
+
+// errorKind is how an error of the task manager is reported
+type errorKind struct {
+	err    error
+	status int
+	code   string
+	field  string
+}
+
+// errorKinds maps the task manager's errors to responses, and codes back to
+// errors in the Client
+var errorKinds = []errorKind{
+	{taskmanager.ErrEmptyTitle, http.StatusUnprocessableEntity, CodeEmptyTitle, "title"},
+	{taskmanager.ErrInvalidPriority, http.StatusUnprocessableEntity, CodeInvalidPriority, "priority"},
+	{taskmanager.ErrInvalidRecurrence, http.StatusUnprocessableEntity, CodeInvalidRecurrence, "recurrence"},
+	{taskmanager.ErrInvalidParent, http.StatusUnprocessableEntity, CodeInvalidParent, "parent_id"},
+	{taskmanager.ErrInvalidSort, http.StatusBadRequest, CodeInvalidSort, "sort"},
+	{taskmanager.ErrTaskNotFound, http.StatusNotFound, CodeNotFound, ""},
+	{taskmanager.ErrVersionConflict, http.StatusConflict, CodeVersionConflict, "version"},
+	{taskmanager.ErrNothingToUndo, http.StatusConflict, CodeNothingToUndo, ""},
+	{taskmanager.ErrNothingToRedo, http.StatusConflict, CodeNothingToRedo, ""},
+}
+
+// Error is the body of an error response. The Client returns it for every
+// failed request; it unwraps to the task manager's error for its code, so
+// that errors.Is(err, taskmanager.ErrTaskNotFound) works across the API.
+type Error struct {
+	// Status is the HTTP status of the response
+	Status int    `json:"-"`
+	Code   string `json:"code"`
+	// Field is the request field at fault, if any
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	for _, kind := range errorKinds {
+		if kind.code == e.Code {
+			return kind.err
+		}
+	}
+	return nil
+}
+
+// requestError reports a malformed request
+func requestError(field, format string, args ...any) *Error {
+	return &Error{
+		Status:  http.StatusBadRequest,
+		Code:    CodeInvalidRequest,
+		Field:   field,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// toError turns an error into the response describing it; unknown errors
+// are internal and their message is not shown to the client
+func toError(err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	for _, kind := range errorKinds {
+		if errors.Is(err, kind.err) {
+			return &Error{Status: kind.status, Code: kind.code, Field: kind.field, Message: err.Error()}
+		}
+	}
+	return &Error{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "internal error"}
+}
+
+// errorResponse is the JSON body of every error response
+type errorResponse struct {
+	Error *Error `json:"error"`
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	apiErr := toError(err)
+	writeJSON(w, apiErr.Status, errorResponse{Error: apiErr})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,301 @@
+// Package httpapi serves a TaskManager over HTTP as JSON and provides a
+// client for it
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"lab01/taskmanager"
+)
+
+// maxBodySize limits the size of a request body
+const maxBodySize = 1 << 20
+
+// Handler serves the REST API of a TaskManager:
+//
+//	GET    /tasks       list tasks; see ListParams
+//	POST   /tasks       create a task
+//	GET    /tasks/{id}  get a task
+//	PUT    /tasks/{id}  replace a task
+//	PATCH  /tasks/{id}  change the fields present in the body
+//	DELETE /tasks/{id}  delete a task and its subtasks
+//	GET    /history       list what can be undone and redone; see History
+//	POST   /history/undo  undo the last change
+//	POST   /history/redo  redo the last undone change
+//
+// Tasks are encoded as taskmanager.Task is. A PUT or PATCH with a version
+// only applies to that version of the task and fails with 409 otherwise.
+// Errors are returned as {"error": {"code", "field", "message"}}; see Error.
+type Handler struct {
+	tm  *taskmanager.TaskManager
+	mux *http.ServeMux
+}
+
+// NewHandler returns a handler serving tm
+func NewHandler(tm *taskmanager.TaskManager) *Handler {
+	h := &Handler{tm: tm, mux: http.NewServeMux()}
+	h.mux.HandleFunc("GET /tasks", h.list)
+	h.mux.HandleFunc("POST /tasks", h.create)
+	h.mux.HandleFunc("GET /tasks/{id}", h.get)
+	h.mux.HandleFunc("PUT /tasks/{id}", h.replace)
+	h.mux.HandleFunc("PATCH /tasks/{id}", h.patch)
+	h.mux.HandleFunc("DELETE /tasks/{id}", h.delete)
+	h.mux.HandleFunc("GET /history", h.history)
+	h.mux.HandleFunc("POST /history/undo", h.undo)
+	h.mux.HandleFunc("POST /history/redo", h.redo)
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// Page is the response of GET /tasks; Total counts the tasks on every page
+type Page struct {
+	Tasks   []taskmanager.Task `json:"tasks"`
+	Total   int                `json:"total"`
+	Page    int                `json:"page"`
+	PerPage int                `json:"per_page"`
+}
+
+func (h *Handler) list(w http.ResponseWriter, r *http.Request) {
+	params, err := ParseListParams(r.URL.Query())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	tasks, err := h.tm.ListTasks(params.Query)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := taskmanager.SortTasks(tasks, params.Sort); err != nil {
+		writeError(w, err)
+		return
+	}
+	// Past the last page, without multiplying a huge page number
+	start, end := len(tasks), len(tasks)
+	if params.Page-1 < (len(tasks)+params.PerPage-1)/params.PerPage {
+		start = (params.Page - 1) * params.PerPage
+		end = min(start+params.PerPage, len(tasks))
+	}
+	writeJSON(w, http.StatusOK, Page{
+		Tasks:   tasks[start:end],
+		Total:   len(tasks),
+		Page:    params.Page,
+		PerPage: params.PerPage,
+	})
+}
+
+func (h *Handler) create(w http.ResponseWriter, r *http.Request) {
+	var task taskmanager.Task
+	if err := decodeBody(r, &task); err != nil {
+		writeError(w, err)
+		return
+	}
+	task, err := h.tm.CreateTask(task)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Location", "/tasks/"+strconv.Itoa(task.ID))
+	writeJSON(w, http.StatusCreated, task)
+}
+
+func (h *Handler) get(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	task, err := h.tm.GetTask(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, task)
+}
+
+func (h *Handler) replace(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var body taskmanager.Task
+	if err := decodeBody(r, &body); err != nil {
+		writeError(w, err)
+		return
+	}
+	task, err := h.save(id, body.Version, func(task *taskmanager.Task) error {
+		*task = body
+		return nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, task)
+}
+
+func (h *Handler) patch(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, maxBodySize))
+	if err != nil {
+		writeError(w, requestError("", "request body is larger than %d bytes", maxBodySize))
+		return
+	}
+	var fields map[string]json.RawMessage
+	if err := decodeJSON(bytes.NewReader(body), &fields); err != nil {
+		writeError(w, err)
+		return
+	}
+	var version int
+	if raw, ok := fields["version"]; ok {
+		if err := json.Unmarshal(raw, &version); err != nil {
+			writeError(w, requestError("version", "version must be an integer"))
+			return
+		}
+	}
+	task, err := h.save(id, version, func(task *taskmanager.Task) error {
+		// Decoding onto the stored task changes the fields present only;
+		// null clears a due date, and a recurrence is replaced as a whole
+		if raw, ok := fields["due"]; ok && string(raw) == "null" {
+			task.Due = time.Time{}
+		}
+		if _, ok := fields["recurrence"]; ok {
+			task.Recurrence = nil
+		}
+		return decodeJSON(bytes.NewReader(body), task)
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, task)
+}
+
+// save applies change to the stored task and saves it. With a version of 0
+// the change is applied to the latest version, retrying on conflicts;
+// otherwise only to that version.
+func (h *Handler) save(id, version int, change func(task *taskmanager.Task) error) (taskmanager.Task, error) {
+	for {
+		stored, err := h.tm.GetTask(id)
+		if err != nil {
+			return taskmanager.Task{}, err
+		}
+		if version != 0 && version != stored.Version {
+			return taskmanager.Task{}, taskmanager.ErrVersionConflict
+		}
+		task := stored
+		if err := change(&task); err != nil {
+			return taskmanager.Task{}, err
+		}
+		task.ID, task.Version = id, stored.Version
+		task, err = h.tm.SaveTask(task)
+		if version != 0 || !errors.Is(err, taskmanager.ErrVersionConflict) {
+			return task, err
+		}
+	}
+}
+
+func (h *Handler) delete(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := h.tm.DeleteTask(id); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// History is the response of the history endpoints: the operations that
+// can be undone and redone, next first, and the one just undone or redone
+type History struct {
+	Done string   `json:"done,omitempty"`
+	Undo []string `json:"undo"`
+	Redo []string `json:"redo"`
+}
+
+func (h *Handler) history(w http.ResponseWriter, r *http.Request) {
+	var history History
+	history.Undo, history.Redo = h.tm.History()
+	writeJSON(w, http.StatusOK, history)
+}
+
+func (h *Handler) undo(w http.ResponseWriter, r *http.Request) {
+	h.step(w, h.tm.Undo)
+}
+
+func (h *Handler) redo(w http.ResponseWriter, r *http.Request) {
+	h.step(w, h.tm.Redo)
+}
+
+// step undoes or redoes an operation and responds with the history
+func (h *Handler) step(w http.ResponseWriter, fn func() (string, error)) {
+	done, err := fn()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	history := History{Done: done}
+	history.Undo, history.Redo = h.tm.History()
+	writeJSON(w, http.StatusOK, history)
+}
+
+func pathID(r *http.Request) (int, error) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		return 0, requestError("id", "invalid task ID %q", r.PathValue("id"))
+	}
+	return id, nil
+}
+
+// decodeBody decodes the JSON request body into v
+func decodeBody(r *http.Request, v any) error {
+	return decodeJSON(http.MaxBytesReader(nil, r.Body, maxBodySize), v)
+}
+
+// decodeJSON decodes a single JSON value, rejecting unknown fields and
+// reporting the field at fault
+func decodeJSON(body io.Reader, v any) error {
+	dec := json.NewDecoder(body)
+	dec.DisallowUnknownFields()
+	err := dec.Decode(v)
+	if err == nil && dec.More() {
+		err = errors.New("unexpected data after the JSON value")
+	}
+	if err == nil {
+		return nil
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	var timeErr *time.ParseError
+	var sizeErr *http.MaxBytesError
+	switch {
+	case errors.Is(err, taskmanager.ErrInvalidPriority):
+		return err
+	case errors.As(err, &typeErr):
+		return requestError(typeErr.Field, "%s has the wrong type", typeErr.Field)
+	case errors.As(err, &timeErr):
+		return requestError("due", "due must be an RFC 3339 time")
+	case errors.As(err, &sizeErr):
+		return requestError("", "request body is larger than %d bytes", sizeErr.Limit)
+	case errors.Is(err, io.EOF):
+		return requestError("", "request body is empty")
+	}
+	return requestError("", "invalid JSON body: %v", err)
+}
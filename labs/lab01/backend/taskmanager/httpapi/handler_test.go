@@ -0,0 +1,226 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"lab01/taskmanager"
+)
+
+func newTestHandler(t *testing.T) (*Handler, *taskmanager.TaskManager) {
+	t.Helper()
+	tm := taskmanager.NewTaskManager()
+	tm.SetClock(func() time.Time { return time.Date(2025, 6, 2, 8, 0, 0, 0, time.UTC) })
+	t.Cleanup(func() { tm.Close() })
+	return NewHandler(tm), tm
+}
+
+// serve sends a request to h and decodes the JSON response into out
+func serve(t *testing.T, h http.Handler, method, target, body string, out any) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if out != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: decoding %q failed: %v", method, target, rec.Body.String(), err)
+		}
+	}
+	return rec
+}
+
+func TestCreateAndGetTask(t *testing.T) {
+	h, _ := newTestHandler(t)
+
+	var created taskmanager.Task
+	rec := serve(t, h, "POST", "/tasks", `{"title": "Gym", "priority": "high", "tags": ["Fitness"], "due": "2025-06-02T18:00:00Z"}`, &created)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST /tasks = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body)
+	}
+	if loc := rec.Header().Get("Location"); loc != "/tasks/1" {
+		t.Errorf("Location = %q, want /tasks/1", loc)
+	}
+	if created.ID != 1 || created.Version != 1 || created.Priority != taskmanager.PriorityHigh || !created.HasTag("fitness") {
+		t.Errorf("Unexpected task created: %+v", created)
+	}
+
+	var got taskmanager.Task
+	if rec := serve(t, h, "GET", "/tasks/1", "", &got); rec.Code != http.StatusOK {
+		t.Fatalf("GET /tasks/1 = %d: %s", rec.Code, rec.Body)
+	}
+	if got.Title != "Gym" || !got.Due.Equal(created.Due) {
+		t.Errorf("GET /tasks/1 = %+v, want %+v", got, created)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	h, tm := newTestHandler(t)
+	tm.AddTask("Existing", "")
+	tm.UpdateTask(1, 0, "Existing", "changed", false)
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		status int
+		code   string
+		field  string
+	}{
+		{"empty title", "POST", "/tasks", `{"title": ""}`, 422, CodeEmptyTitle, "title"},
+		{"bad priority", "POST", "/tasks", `{"title": "x", "priority": "urgent"}`, 422, CodeInvalidPriority, "priority"},
+		{"bad recurrence", "POST", "/tasks", `{"title": "x", "recurrence": {"kind": "hourly"}}`, 422, CodeInvalidRecurrence, "recurrence"},
+		{"missing parent", "POST", "/tasks", `{"title": "x", "parent_id": 42}`, 422, CodeInvalidParent, "parent_id"},
+		{"wrong type", "POST", "/tasks", `{"title": 3}`, 400, CodeInvalidRequest, "title"},
+		{"bad due date", "POST", "/tasks", `{"title": "x", "due": "tomorrow"}`, 400, CodeInvalidRequest, "due"},
+		{"unknown field", "POST", "/tasks", `{"title": "x", "owner": "me"}`, 400, CodeInvalidRequest, ""},
+		{"malformed JSON", "POST", "/tasks", `{"title": `, 400, CodeInvalidRequest, ""},
+		{"empty body", "POST", "/tasks", ``, 400, CodeInvalidRequest, ""},
+		{"not found", "GET", "/tasks/99", "", 404, CodeNotFound, ""},
+		{"bad ID", "GET", "/tasks/abc", "", 400, CodeInvalidRequest, "id"},
+		{"delete missing", "DELETE", "/tasks/99", "", 404, CodeNotFound, ""},
+		{"stale version", "PUT", "/tasks/1", `{"title": "x", "version": 1}`, 409, CodeVersionConflict, "version"},
+		{"empty title on update", "PATCH", "/tasks/1", `{"title": ""}`, 422, CodeEmptyTitle, "title"},
+		{"bad filter", "GET", "/tasks?done=maybe", "", 400, CodeInvalidRequest, "done"},
+		{"bad sort", "GET", "/tasks?sort=owner", "", 400, CodeInvalidSort, "sort"},
+		{"bad page", "GET", "/tasks?page=0", "", 400, CodeInvalidRequest, "page"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp errorResponse
+			rec := serve(t, h, tt.method, tt.target, tt.body, &resp)
+			if rec.Code != tt.status {
+				t.Errorf("%s %s = %d, want %d", tt.method, tt.target, rec.Code, tt.status)
+			}
+			if resp.Error == nil || resp.Error.Code != tt.code || resp.Error.Field != tt.field || resp.Error.Message == "" {
+				t.Errorf("%s %s: error = %+v, want code %q and field %q", tt.method, tt.target, resp.Error, tt.code, tt.field)
+			}
+		})
+	}
+}
+
+func TestListTasks(t *testing.T) {
+	h, tm := newTestHandler(t)
+	due := time.Date(2025, 6, 2, 18, 0, 0, 0, time.UTC)
+	for _, task := range []taskmanager.Task{
+		{Title: "Water", Tags: []string{"health"}, Priority: taskmanager.PriorityHigh, Due: due},
+		{Title: "Sleep early", Tags: []string{"health", "sleep"}, Due: due.Add(-time.Hour)},
+		{Title: "Read", Priority: taskmanager.PriorityLow},
+		{Title: "Stretch", Tags: []string{"health"}, Done: true},
+	} {
+		if _, err := tm.CreateTask(task); err != nil {
+			t.Fatalf("CreateTask failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		query    string
+		expected []int
+		total    int
+	}{
+		{"", []int{1, 2, 3, 4}, 4},
+		{"?tag=health&done=false", []int{1, 2}, 2},
+		{"?priority=low&sort=-priority", []int{1, 3}, 2},
+		{"?due_before=2025-06-02T18:00:00Z", []int{2}, 1},
+		{"?has_due=false&q=E", []int{3, 4}, 2},
+		{"?sort=due,-id", []int{2, 1, 4, 3}, 4},
+		{"?sort=title&per_page=2", []int{3, 2}, 4},
+		{"?sort=title&per_page=2&page=2", []int{4, 1}, 4},
+		{"?page=3&per_page=2", []int{}, 4},
+		{"?page=9223372036854775807&per_page=500", []int{}, 4},
+	}
+
+	for _, tt := range tests {
+		var page Page
+		if rec := serve(t, h, "GET", "/tasks"+tt.query, "", &page); rec.Code != http.StatusOK {
+			t.Fatalf("GET /tasks%s = %d: %s", tt.query, rec.Code, rec.Body)
+		}
+		ids := []int{}
+		for _, task := range page.Tasks {
+			ids = append(ids, task.ID)
+		}
+		if !slices.Equal(ids, tt.expected) || page.Total != tt.total {
+			t.Errorf("GET /tasks%s = %v of %d, want %v of %d", tt.query, ids, page.Total, tt.expected, tt.total)
+		}
+	}
+}
+
+func TestUpdateAndDeleteTask(t *testing.T) {
+	h, tm := newTestHandler(t)
+	tm.CreateTask(taskmanager.Task{
+		Title:      "Run",
+		Tags:       []string{"fitness"},
+		Due:        time.Date(2025, 6, 3, 7, 0, 0, 0, time.UTC),
+		Recurrence: taskmanager.Weekly(time.Tuesday, time.Thursday),
+	})
+
+	// PATCH changes only the fields present
+	var task taskmanager.Task
+	if rec := serve(t, h, "PATCH", "/tasks/1", `{"description": "5 km", "due": null, "recurrence": {"kind": "daily"}}`, &task); rec.Code != http.StatusOK {
+		t.Fatalf("PATCH /tasks/1 = %d: %s", rec.Code, rec.Body)
+	}
+	if task.Title != "Run" || task.Description != "5 km" || !task.HasTag("fitness") || !task.Due.IsZero() || task.Version != 2 {
+		t.Errorf("Unexpected task after PATCH: %+v", task)
+	}
+	if task.Recurrence == nil || task.Recurrence.Kind != taskmanager.RecurDaily || task.Recurrence.Weekdays != nil {
+		t.Errorf("Expected the recurrence to be replaced, got %+v", task.Recurrence)
+	}
+
+	// PUT replaces the task, at the given version
+	task = taskmanager.Task{}
+	if rec := serve(t, h, "PUT", "/tasks/1", `{"title": "Walk", "version": 2}`, &task); rec.Code != http.StatusOK {
+		t.Fatalf("PUT /tasks/1 = %d: %s", rec.Code, rec.Body)
+	}
+	if task.Title != "Walk" || task.Description != "" || task.Tags != nil || task.Recurrence != nil || task.Version != 3 {
+		t.Errorf("Unexpected task after PUT: %+v", task)
+	}
+
+	if rec := serve(t, h, "DELETE", "/tasks/1", "", nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("DELETE /tasks/1 = %d: %s", rec.Code, rec.Body)
+	}
+	if _, err := tm.GetTask(1); err != taskmanager.ErrTaskNotFound {
+		t.Errorf("Expected the task to be deleted, got %v", err)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	h, tm := newTestHandler(t)
+	server := httptest.NewServer(h)
+	defer server.Close()
+	c := NewClient(server.URL, nil)
+
+	if _, err := c.Undo(); !errors.Is(err, taskmanager.ErrNothingToUndo) {
+		t.Errorf("Expected ErrNothingToUndo, got %v", err)
+	}
+	tm.AddTask("Gym", "")
+	serve(t, h, "DELETE", "/tasks/1", "", nil)
+
+	var history History
+	serve(t, h, "GET", "/history", "", &history)
+	if !slices.Equal(history.Undo, []string{"delete task 1", `create "Gym"`}) || len(history.Redo) != 0 {
+		t.Errorf("GET /history = %+v", history)
+	}
+	if rec := serve(t, h, "POST", "/history/undo", "", &history); rec.Code != http.StatusOK || history.Done != "delete task 1" {
+		t.Fatalf("POST /history/undo = %d: %s", rec.Code, rec.Body)
+	}
+	if !slices.Equal(history.Redo, []string{"delete task 1"}) {
+		t.Errorf("Expected the delete to be redoable, got %+v", history)
+	}
+	if _, err := tm.GetTask(1); err != nil {
+		t.Errorf("Expected the task back after undo, got %v", err)
+	}
+
+	if done, err := c.Redo(); err != nil || done != "delete task 1" {
+		t.Errorf("Redo() = %q, %v", done, err)
+	}
+	if _, err := c.Redo(); !errors.Is(err, taskmanager.ErrNothingToRedo) {
+		t.Errorf("Expected ErrNothingToRedo, got %v", err)
+	}
+}
@@ -0,0 +1,164 @@
+package httpapi
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"lab01/taskmanager"
+)
+
+// Pagination of GET /tasks
+const (
+	DefaultPerPage = 50
+	MaxPerPage     = 500
+)
+
+// ListParams are the parameters of GET /tasks:
+//
+//	done=true|false          completion
+//	priority=medium          minimum priority
+//	tag=health&tag=sleep     tagged with all of them
+//	due_after, due_before    due in [due_after, due_before), RFC 3339
+//	has_due=true|false       whether a due date is set
+//	parent=3                 subtasks of task 3; parent=0 for top-level tasks
+//	recurring=true|false     whether a recurrence rule is set
+//	q=text                   text in the title or description
+//	sort=-priority,due       see taskmanager.SortTasks; by id by default
+//	page=1&per_page=50       1-based page of at most MaxPerPage tasks
+type ListParams struct {
+	Query   taskmanager.Query
+	Sort    string
+	Page    int
+	PerPage int
+}
+
+// Values encodes the parameters as a URL query
+func (p ListParams) Values() url.Values {
+	v := url.Values{}
+	q := p.Query
+	setBool(v, "done", q.Done)
+	if q.MinPriority != taskmanager.PriorityNone {
+		v.Set("priority", q.MinPriority.String())
+	}
+	for _, tag := range q.Tags {
+		v.Add("tag", tag)
+	}
+	setTime(v, "due_after", q.DueAfter)
+	setTime(v, "due_before", q.DueBefore)
+	setBool(v, "has_due", q.HasDue)
+	if q.ParentID != nil {
+		v.Set("parent", strconv.Itoa(*q.ParentID))
+	}
+	setBool(v, "recurring", q.Recurring)
+	if q.Text != "" {
+		v.Set("q", q.Text)
+	}
+	if p.Sort != "" {
+		v.Set("sort", p.Sort)
+	}
+	if p.Page != 0 {
+		v.Set("page", strconv.Itoa(p.Page))
+	}
+	if p.PerPage != 0 {
+		v.Set("per_page", strconv.Itoa(p.PerPage))
+	}
+	return v
+}
+
+func setBool(v url.Values, key string, b *bool) {
+	if b != nil {
+		v.Set(key, strconv.FormatBool(*b))
+	}
+}
+
+func setTime(v url.Values, key string, t time.Time) {
+	if !t.IsZero() {
+		v.Set(key, t.Format(time.RFC3339Nano))
+	}
+}
+
+// ParseListParams reads the parameters of GET /tasks, filling in the
+// default sort and pagination
+func ParseListParams(v url.Values) (ListParams, error) {
+	p := ListParams{Sort: "id", Page: 1, PerPage: DefaultPerPage}
+	var err error
+	q := &p.Query
+	if q.Done, err = parseBool(v, "done"); err != nil {
+		return p, err
+	}
+	if s := v.Get("priority"); s != "" {
+		if q.MinPriority, err = taskmanager.ParsePriority(s); err != nil {
+			return p, requestError("priority", "priority must be none, low, medium or high")
+		}
+	}
+	q.Tags = v["tag"]
+	if q.DueAfter, err = parseTime(v, "due_after"); err != nil {
+		return p, err
+	}
+	if q.DueBefore, err = parseTime(v, "due_before"); err != nil {
+		return p, err
+	}
+	if q.HasDue, err = parseBool(v, "has_due"); err != nil {
+		return p, err
+	}
+	if s := v.Get("parent"); s != "" {
+		id, err := strconv.Atoi(s)
+		if err != nil || id < 0 {
+			return p, requestError("parent", "parent must be a task ID or 0")
+		}
+		q.ParentID = &id
+	}
+	if q.Recurring, err = parseBool(v, "recurring"); err != nil {
+		return p, err
+	}
+	q.Text = v.Get("q")
+	if s := strings.TrimSpace(v.Get("sort")); s != "" {
+		p.Sort = s
+	}
+	if p.Page, err = parseInt(v, "page", p.Page, 1); err != nil {
+		return p, err
+	}
+	if p.PerPage, err = parseInt(v, "per_page", p.PerPage, 1); err != nil {
+		return p, err
+	}
+	p.PerPage = min(p.PerPage, MaxPerPage)
+	return p, nil
+}
+
+func parseBool(v url.Values, key string) (*bool, error) {
+	s := v.Get(key)
+	if s == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return nil, requestError(key, "%s must be true or false", key)
+	}
+	return &b, nil
+}
+
+func parseTime(v url.Values, key string) (time.Time, error) {
+	s := v.Get(key)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, requestError(key, "%s must be an RFC 3339 time", key)
+	}
+	return t, nil
+}
+
+func parseInt(v url.Values, key string, def, least int) (int, error) {
+	s := v.Get(key)
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < least {
+		return 0, requestError(key, "%s must be an integer of at least %d", key, least)
+	}
+	return n, nil
+}
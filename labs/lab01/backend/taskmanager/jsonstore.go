@@ -0,0 +1,184 @@
+package taskmanager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// JSONStore keeps tasks in memory and saves them to a JSON file after every
+// change. The file is replaced atomically: a crash leaves either the old or
+// the new contents, never a partial write.
+type JSONStore struct {
+	// mu serialises changes so that saves happen in order
+	mu   sync.Mutex
+	path string
+	mem  *MemoryStore
+	// history is kept for a TaskManager; see HistoryStore
+	history json.RawMessage
+}
+
+// jsonFile is the layout of the file; NextID is kept so that IDs of deleted
+// tasks are not reused after a restart
+type jsonFile struct {
+	NextID  int             `json:"next_id"`
+	Tasks   []Task          `json:"tasks"`
+	History json.RawMessage `json:"history,omitempty"`
+}
+
+// OpenJSONStore loads the tasks saved at path. A missing file is an empty
+// store; it is created on the first change.
+func OpenJSONStore(path string) (*JSONStore, error) {
+	s := &JSONStore{path: path, mem: NewMemoryStore()}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var file jsonFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("taskmanager: %s: %w", path, err)
+	}
+	for _, task := range file.Tasks {
+		// Files written before tasks had versions start them at 1
+		task.Version = max(task.Version, 1)
+		s.mem.tasks[task.ID] = task
+		s.mem.nextID = max(s.mem.nextID, task.ID+1)
+	}
+	s.mem.nextID = max(s.mem.nextID, file.NextID)
+	s.history = file.History
+	return s, nil
+}
+
+func (s *JSONStore) Create(task Task) (Task, error) {
+	var created Task
+	err := s.change(func() (err error) {
+		created, err = s.mem.Create(task)
+		return err
+	})
+	return created, err
+}
+
+func (s *JSONStore) Get(id int) (Task, error) {
+	return s.mem.Get(id)
+}
+
+func (s *JSONStore) Update(task Task) error {
+	return s.change(func() error { return s.mem.Update(task) })
+}
+
+func (s *JSONStore) Delete(id int) error {
+	return s.change(func() error { return s.mem.Delete(id) })
+}
+
+func (s *JSONStore) Restore(task Task) error {
+	return s.change(func() error { return s.mem.Restore(task) })
+}
+
+func (s *JSONStore) List() ([]Task, error) {
+	return s.mem.List()
+}
+
+// LoadHistory returns the history saved in the file, or nil
+func (s *JSONStore) LoadHistory() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.history), nil
+}
+
+// SaveHistory saves data in the file next to the tasks
+func (s *JSONStore) SaveHistory(data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old := s.history
+	s.history = slices.Clone(data)
+	if err := s.save(); err != nil {
+		s.history = old
+		return err
+	}
+	return nil
+}
+
+func (s *JSONStore) Close() error {
+	return nil
+}
+
+// change applies fn to the tasks in memory and saves them, undoing fn if
+// the save fails
+func (s *JSONStore) change(fn func() error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mem.mu.RLock()
+	tasks, nextID := maps.Clone(s.mem.tasks), s.mem.nextID
+	s.mem.mu.RUnlock()
+
+	if err := fn(); err != nil {
+		return err
+	}
+	if err := s.save(); err != nil {
+		s.mem.mu.Lock()
+		s.mem.tasks, s.mem.nextID = tasks, nextID
+		s.mem.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// save writes the tasks and the history to a temporary file next to path, syncs it and
+// renames it over path. It only fails when path still has the old contents.
+func (s *JSONStore) save() error {
+	tasks, _ := s.mem.List()
+	s.mem.mu.RLock()
+	file := jsonFile{NextID: s.mem.nextID, Tasks: tasks, History: s.history}
+	s.mem.mu.RUnlock()
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(s.path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return err
+	}
+	// The new contents are in place; failing now would roll back memory to
+	// tasks the file no longer has. A crash may still lose the rename.
+	if err := syncDir(dir); err != nil {
+		log.Printf("taskmanager: saved %s but could not sync its directory, the change may not survive a crash: %v", s.path, err)
+	}
+	return nil
+}
+
+// syncDir makes a rename in dir durable; a variable so that tests can fail it
+var syncDir = func(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,258 @@
+package taskmanager
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Validation errors for the task model
+var (
+	ErrInvalidPriority   = errors.New("invalid priority")
+	ErrInvalidRecurrence = errors.New("invalid recurrence")
+	ErrInvalidParent     = errors.New("invalid parent task")
+)
+
+// Priority ranks tasks; the zero value is PriorityNone
+type Priority int
+
+const (
+	PriorityNone Priority = iota
+	PriorityLow
+	PriorityMedium
+	PriorityHigh
+)
+
+var priorityNames = []string{"none", "low", "medium", "high"}
+
+func (p Priority) String() string {
+	if p.valid() {
+		return priorityNames[p]
+	}
+	return fmt.Sprintf("Priority(%d)", int(p))
+}
+
+func (p Priority) valid() bool {
+	return p >= PriorityNone && p <= PriorityHigh
+}
+
+// ParsePriority reads "none", "low", "medium" or "high"
+func ParsePriority(s string) (Priority, error) {
+	i := slices.Index(priorityNames, strings.ToLower(strings.TrimSpace(s)))
+	if i < 0 {
+		return PriorityNone, fmt.Errorf("%w: %q", ErrInvalidPriority, s)
+	}
+	return Priority(i), nil
+}
+
+// MarshalText encodes the priority by name, e.g. in JSON
+func (p Priority) MarshalText() ([]byte, error) {
+	if !p.valid() {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidPriority, int(p))
+	}
+	return []byte(p.String()), nil
+}
+
+func (p *Priority) UnmarshalText(text []byte) error {
+	parsed, err := ParsePriority(string(text))
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
+
+// RecurrenceKind is how a recurring task repeats
+type RecurrenceKind string
+
+const (
+	// RecurDaily repeats every day
+	RecurDaily RecurrenceKind = "daily"
+	// RecurWeekly repeats on the given Weekdays
+	RecurWeekly RecurrenceKind = "weekly"
+	// RecurEveryNDays repeats every Interval days
+	RecurEveryNDays RecurrenceKind = "every_n_days"
+)
+
+// MaxInterval is the longest interval of an every_n_days rule, a century
+const MaxInterval = 36500
+
+// Recurrence is the rule of a recurring task. Completing an occurrence
+// creates the next one, due at the rule's next date.
+type Recurrence struct {
+	Kind     RecurrenceKind `json:"kind"`
+	Weekdays []time.Weekday `json:"weekdays,omitempty"`
+	Interval int            `json:"interval,omitempty"`
+}
+
+// Daily returns a rule repeating every day
+func Daily() *Recurrence {
+	return &Recurrence{Kind: RecurDaily}
+}
+
+// Weekly returns a rule repeating on the given weekdays
+func Weekly(days ...time.Weekday) *Recurrence {
+	return &Recurrence{Kind: RecurWeekly, Weekdays: days}
+}
+
+// EveryNDays returns a rule repeating every n days
+func EveryNDays(n int) *Recurrence {
+	return &Recurrence{Kind: RecurEveryNDays, Interval: n}
+}
+
+// Validate checks that the rule can produce dates
+func (r *Recurrence) Validate() error {
+	switch r.Kind {
+	case RecurDaily:
+		return nil
+	case RecurWeekly:
+		if len(r.Weekdays) == 0 {
+			return fmt.Errorf("%w: weekly needs at least one weekday", ErrInvalidRecurrence)
+		}
+		for _, d := range r.Weekdays {
+			if d < time.Sunday || d > time.Saturday {
+				return fmt.Errorf("%w: weekday %d", ErrInvalidRecurrence, int(d))
+			}
+		}
+		return nil
+	case RecurEveryNDays:
+		if r.Interval < 1 {
+			return fmt.Errorf("%w: interval must be at least 1 day, got %d", ErrInvalidRecurrence, r.Interval)
+		}
+		if r.Interval > MaxInterval {
+			return fmt.Errorf("%w: interval must be at most %d days, got %d", ErrInvalidRecurrence, MaxInterval, r.Interval)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: unknown kind %q", ErrInvalidRecurrence, r.Kind)
+}
+
+// Next returns the first date of the rule after from, at the same time of
+// day. The rule must be valid.
+func (r *Recurrence) Next(from time.Time) time.Time {
+	switch r.Kind {
+	case RecurWeekly:
+		for i := 1; i <= 7; i++ {
+			if d := from.AddDate(0, 0, i); slices.Contains(r.Weekdays, d.Weekday()) {
+				return d
+			}
+		}
+	case RecurEveryNDays:
+		return from.AddDate(0, 0, r.Interval)
+	}
+	return from.AddDate(0, 0, 1)
+}
+
+// nextDue returns when the occurrence after one due at due and completed at
+// now is due: the first date of the rule after due that is still ahead of
+// now, so that completing a task late does not create overdue copies. A task
+// without a due date counts from now.
+func (r *Recurrence) nextDue(due, now time.Time) time.Time {
+	if due.IsZero() {
+		due = now
+	}
+	next := r.Next(due)
+	// Skip whole periods at once, leaving the last ones to Next in case of
+	// daylight saving time changes
+	period := 1
+	switch r.Kind {
+	case RecurWeekly:
+		period = 7
+	case RecurEveryNDays:
+		period = r.Interval
+	}
+	if periods := int((now.Unix()-next.Unix())/(24*60*60))/period - 1; periods > 0 {
+		next = next.AddDate(0, 0, periods*period)
+	}
+	for !next.After(now) {
+		next = r.Next(next)
+	}
+	return next
+}
+
+var weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// String formats the rule as "daily", "weekly:mon,thu" or "every_n_days:3";
+// ParseRecurrence reads it back
+func (r *Recurrence) String() string {
+	switch r.Kind {
+	case RecurWeekly:
+		days := make([]string, len(r.Weekdays))
+		for i, d := range r.Weekdays {
+			if d >= time.Sunday && d <= time.Saturday {
+				days[i] = weekdayNames[d]
+			} else {
+				days[i] = strconv.Itoa(int(d))
+			}
+		}
+		return string(r.Kind) + ":" + strings.Join(days, ",")
+	case RecurEveryNDays:
+		return string(r.Kind) + ":" + strconv.Itoa(r.Interval)
+	}
+	return string(r.Kind)
+}
+
+// ParseRecurrence reads a rule formatted by Recurrence.String and validates
+// it
+func ParseRecurrence(s string) (*Recurrence, error) {
+	kind, arg, _ := strings.Cut(strings.ToLower(strings.TrimSpace(s)), ":")
+	r := &Recurrence{Kind: RecurrenceKind(kind)}
+	switch r.Kind {
+	case RecurWeekly:
+		for _, name := range strings.Split(arg, ",") {
+			i := slices.Index(weekdayNames, strings.TrimSpace(name))
+			if i < 0 {
+				return nil, fmt.Errorf("%w: unknown weekday %q", ErrInvalidRecurrence, name)
+			}
+			r.Weekdays = append(r.Weekdays, time.Weekday(i))
+		}
+	case RecurEveryNDays:
+		n, err := strconv.Atoi(arg)
+		if err != nil {
+			return nil, fmt.Errorf("%w: interval %q", ErrInvalidRecurrence, arg)
+		}
+		r.Interval = n
+	}
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Recurrence) clone() *Recurrence {
+	if r == nil {
+		return nil
+	}
+	c := *r
+	c.Weekdays = slices.Clone(r.Weekdays)
+	return &c
+}
+
+// NormalizeTags lowercases and trims tags, dropping empty ones and
+// duplicates, and sorts them
+func NormalizeTags(tags []string) []string {
+	var out []string
+	for _, tag := range tags {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+			out = append(out, tag)
+		}
+	}
+	slices.Sort(out)
+	return slices.Compact(out)
+}
+
+// HasTag reports whether the task is tagged with tag
+func (t Task) HasTag(tag string) bool {
+	return slices.Contains(t.Tags, strings.ToLower(strings.TrimSpace(tag)))
+}
+
+// clone copies the slices of a task, so that a store's copy cannot be
+// changed through a task it returned
+func (t Task) clone() Task {
+	t.Tags = slices.Clone(t.Tags)
+	t.Recurrence = t.Recurrence.clone()
+	return t
+}
@@ -0,0 +1,241 @@
+package taskmanager
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParsePriority(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    Priority
+		expectError bool
+	}{
+		{"none", PriorityNone, false},
+		{"low", PriorityLow, false},
+		{" Medium ", PriorityMedium, false},
+		{"HIGH", PriorityHigh, false},
+		{"urgent", PriorityNone, true},
+		{"", PriorityNone, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParsePriority(tt.input)
+		if tt.expectError {
+			if !errors.Is(err, ErrInvalidPriority) {
+				t.Errorf("ParsePriority(%q): expected ErrInvalidPriority, got %v", tt.input, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.expected {
+			t.Errorf("ParsePriority(%q) = %v, %v, want %v", tt.input, got, err, tt.expected)
+		}
+	}
+
+	data, err := json.Marshal(Task{Title: "x", Priority: PriorityHigh})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var task Task
+	if err := json.Unmarshal(data, &task); err != nil || task.Priority != PriorityHigh {
+		t.Errorf("Priority did not round-trip through %s: %v, %v", data, task.Priority, err)
+	}
+	if _, err := json.Marshal(Task{Priority: 7}); err == nil {
+		t.Error("Expected error marshalling an invalid priority, got none")
+	}
+}
+
+func TestRecurrenceNext(t *testing.T) {
+	// 2025-06-02 is a Monday
+	monday := time.Date(2025, 6, 2, 7, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		rule     *Recurrence
+		from     time.Time
+		expected time.Time
+	}{
+		{"daily", Daily(), monday, monday.AddDate(0, 0, 1)},
+		{"weekly later this week", Weekly(time.Monday, time.Thursday), monday, monday.AddDate(0, 0, 3)},
+		{"weekly next week", Weekly(time.Monday, time.Thursday), monday.AddDate(0, 0, 3), monday.AddDate(0, 0, 7)},
+		{"weekly single day", Weekly(time.Monday), monday, monday.AddDate(0, 0, 7)},
+		{"every 3 days", EveryNDays(3), monday, monday.AddDate(0, 0, 3)},
+		{"across month end", EveryNDays(2), time.Date(2025, 6, 30, 8, 0, 0, 0, time.UTC), time.Date(2025, 7, 2, 8, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.rule.Validate(); err != nil {
+				t.Fatalf("Validate failed: %v", err)
+			}
+			if got := tt.rule.Next(tt.from); !got.Equal(tt.expected) {
+				t.Errorf("Next(%v) = %v, want %v", tt.from, got, tt.expected)
+			}
+		})
+	}
+
+	// Completed three days late, a daily task is next due tomorrow rather
+	// than on the days already missed
+	late := monday.AddDate(0, 0, 3).Add(2 * time.Hour)
+	if got, want := Daily().nextDue(monday, late), monday.AddDate(0, 0, 4); !got.Equal(want) {
+		t.Errorf("nextDue(late) = %v, want %v", got, want)
+	}
+	// Completed early, the next occurrence follows the due date
+	if got, want := Weekly(time.Thursday).nextDue(monday, monday.Add(-48*time.Hour)), monday.AddDate(0, 0, 3); !got.Equal(want) {
+		t.Errorf("nextDue(early) = %v, want %v", got, want)
+	}
+	// Without a due date, it counts from the completion time
+	if got, want := EveryNDays(2).nextDue(time.Time{}, monday), monday.AddDate(0, 0, 2); !got.Equal(want) {
+		t.Errorf("nextDue(no due date) = %v, want %v", got, want)
+	}
+	// Centuries late, it skips ahead rather than stepping through every day
+	ancient := time.Date(1, 1, 1, 7, 30, 0, 0, time.UTC)
+	if got, want := Daily().nextDue(ancient, late), monday.AddDate(0, 0, 4); !got.Equal(want) {
+		t.Errorf("nextDue(ancient) = %v, want %v", got, want)
+	}
+	if got := EveryNDays(MaxInterval).nextDue(ancient, late); !got.After(late) || got.After(late.AddDate(0, 0, MaxInterval)) {
+		t.Errorf("nextDue(ancient, every %d days) = %v, want within %d days after %v", MaxInterval, got, MaxInterval, late)
+	}
+}
+
+func TestRecurrenceValidate(t *testing.T) {
+	invalid := []*Recurrence{
+		{Kind: "hourly"},
+		Weekly(),
+		Weekly(time.Weekday(7)),
+		EveryNDays(0),
+		EveryNDays(MaxInterval + 1),
+		EveryNDays(math.MaxInt),
+	}
+	for _, r := range invalid {
+		if err := r.Validate(); !errors.Is(err, ErrInvalidRecurrence) {
+			t.Errorf("Validate(%+v): expected ErrInvalidRecurrence, got %v", r, err)
+		}
+	}
+}
+
+func TestParseRecurrence(t *testing.T) {
+	for _, r := range []*Recurrence{Daily(), Weekly(time.Monday, time.Sunday), EveryNDays(3)} {
+		got, err := ParseRecurrence(r.String())
+		if err != nil || !reflect.DeepEqual(got, r) {
+			t.Errorf("ParseRecurrence(%q) = %+v, %v, want %+v", r.String(), got, err, r)
+		}
+	}
+	if got := Weekly(time.Monday, time.Thursday).String(); got != "weekly:mon,thu" {
+		t.Errorf("String() = %q, want weekly:mon,thu", got)
+	}
+	for _, s := range []string{"", "hourly", "weekly", "weekly:funday", "every_n_days:x", "every_n_days:0"} {
+		if _, err := ParseRecurrence(s); !errors.Is(err, ErrInvalidRecurrence) {
+			t.Errorf("ParseRecurrence(%q): expected ErrInvalidRecurrence, got %v", s, err)
+		}
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	got := NormalizeTags([]string{" Fitness", "morning", "", "fitness", "Health "})
+	want := []string{"fitness", "health", "morning"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NormalizeTags() = %v, want %v", got, want)
+	}
+	if got := NormalizeTags(nil); got != nil {
+		t.Errorf("NormalizeTags(nil) = %v, want nil", got)
+	}
+}
+
+func TestQueryMatches(t *testing.T) {
+	due := time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC)
+	task := Task{
+		ID:          2,
+		Title:       "Drink water",
+		Description: "Two litres",
+		Priority:    PriorityMedium,
+		Tags:        []string{"health", "hydration"},
+		Due:         due,
+		ParentID:    1,
+		Recurrence:  Daily(),
+	}
+	yes, no := true, false
+	zero, one := 0, 1
+
+	tests := []struct {
+		name     string
+		query    Query
+		expected bool
+	}{
+		{"empty query", Query{}, true},
+		{"done", Query{Done: &yes}, false},
+		{"pending", Query{Done: &no}, true},
+		{"min priority met", Query{MinPriority: PriorityMedium}, true},
+		{"min priority not met", Query{MinPriority: PriorityHigh}, false},
+		{"all tags", Query{Tags: []string{"Health", "hydration"}}, true},
+		{"missing tag", Query{Tags: []string{"health", "sleep"}}, false},
+		{"due in range", Query{DueAfter: due.Add(-time.Hour), DueBefore: due.Add(time.Hour)}, true},
+		{"due range is half-open", Query{DueBefore: due}, false},
+		{"due after", Query{DueAfter: due}, true},
+		{"due too early", Query{DueAfter: due.Add(time.Minute)}, false},
+		{"has due", Query{HasDue: &yes}, true},
+		{"has no due", Query{HasDue: &no}, false},
+		{"subtask of 1", Query{ParentID: &one}, true},
+		{"top level", Query{ParentID: &zero}, false},
+		{"recurring", Query{Recurring: &yes}, true},
+		{"not recurring", Query{Recurring: &no}, false},
+		{"text in title", Query{Text: "WATER"}, true},
+		{"text in description", Query{Text: "litres"}, true},
+		{"text missing", Query{Text: "coffee"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.query.Matches(task); got != tt.expected {
+			t.Errorf("%s: Matches() = %v, want %v", tt.name, got, tt.expected)
+		}
+	}
+
+	if (Query{DueBefore: due}).Matches(Task{Title: "no due date"}) {
+		t.Error("Expected a due range to drop tasks without a due date")
+	}
+}
+
+func TestSortTasks(t *testing.T) {
+	due := time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC)
+	tasks := []Task{
+		{ID: 1, Title: "water", Priority: PriorityLow, Due: due.Add(time.Hour)},
+		{ID: 2, Title: "Read", Priority: PriorityHigh},
+		{ID: 3, Title: "sleep", Priority: PriorityHigh, Due: due, Done: true},
+		{ID: 4, Title: "Gym", Priority: PriorityLow, Due: due},
+	}
+
+	tests := []struct {
+		by       string
+		expected []int
+	}{
+		{"", []int{1, 2, 3, 4}},
+		{"-id", []int{4, 3, 2, 1}},
+		{"title", []int{4, 2, 3, 1}},
+		{"due", []int{3, 4, 1, 2}},
+		{"-due", []int{1, 3, 4, 2}},
+		{"-priority,due", []int{3, 2, 4, 1}},
+		{"done, -id", []int{4, 2, 1, 3}},
+	}
+
+	for _, tt := range tests {
+		sorted := slices.Clone(tasks)
+		if err := SortTasks(sorted, tt.by); err != nil {
+			t.Fatalf("SortTasks(%q) failed: %v", tt.by, err)
+		}
+		var ids []int
+		for _, task := range sorted {
+			ids = append(ids, task.ID)
+		}
+		if !slices.Equal(ids, tt.expected) {
+			t.Errorf("SortTasks(%q) = %v, want %v", tt.by, ids, tt.expected)
+		}
+	}
+
+	if err := SortTasks(tasks, "-version"); !errors.Is(err, ErrInvalidSort) {
+		t.Errorf("Expected ErrInvalidSort, got %v", err)
+	}
+}
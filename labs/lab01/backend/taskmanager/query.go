@@ -0,0 +1,152 @@
+package taskmanager
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSort is returned by SortTasks for an unknown field
+var ErrInvalidSort = errors.New("invalid sort")
+
+// Query selects tasks for ListTasks. The zero Query matches every task;
+// each set field narrows the result.
+type Query struct {
+	// Done filters on completion
+	Done *bool
+	// MinPriority keeps tasks of at least this priority
+	MinPriority Priority
+	// Tags keeps tasks tagged with all of them
+	Tags []string
+	// DueAfter and DueBefore keep tasks due in [DueAfter, DueBefore);
+	// setting either drops tasks without a due date
+	DueAfter, DueBefore time.Time
+	// HasDue filters on whether a task has a due date
+	HasDue *bool
+	// ParentID keeps the subtasks of a task, or top-level tasks when it
+	// points to 0
+	ParentID *int
+	// Recurring filters on whether a task has a recurrence rule
+	Recurring *bool
+	// Text keeps tasks whose title or description contains it, ignoring
+	// case
+	Text string
+}
+
+// Matches reports whether task satisfies every condition of the query
+func (q Query) Matches(task Task) bool {
+	if q.Done != nil && *q.Done != task.Done {
+		return false
+	}
+	if task.Priority < q.MinPriority {
+		return false
+	}
+	for _, tag := range q.Tags {
+		if !task.HasTag(tag) {
+			return false
+		}
+	}
+	if q.HasDue != nil && *q.HasDue == task.Due.IsZero() {
+		return false
+	}
+	if !q.DueAfter.IsZero() || !q.DueBefore.IsZero() {
+		if task.Due.IsZero() ||
+			(!q.DueAfter.IsZero() && task.Due.Before(q.DueAfter)) ||
+			(!q.DueBefore.IsZero() && !task.Due.Before(q.DueBefore)) {
+			return false
+		}
+	}
+	if q.ParentID != nil && *q.ParentID != task.ParentID {
+		return false
+	}
+	if q.Recurring != nil && *q.Recurring == (task.Recurrence == nil) {
+		return false
+	}
+	if q.Text != "" {
+		text := strings.ToLower(q.Text)
+		if !strings.Contains(strings.ToLower(task.Title), text) &&
+			!strings.Contains(strings.ToLower(task.Description), text) {
+			return false
+		}
+	}
+	return true
+}
+
+// taskOrders compares tasks by each field SortTasks accepts
+var taskOrders = map[string]func(a, b Task) int{
+	"id":         func(a, b Task) int { return cmp.Compare(a.ID, b.ID) },
+	"title":      func(a, b Task) int { return strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title)) },
+	"created_at": func(a, b Task) int { return a.CreatedAt.Compare(b.CreatedAt) },
+	"due":        func(a, b Task) int { return a.Due.Compare(b.Due) },
+	"priority":   func(a, b Task) int { return cmp.Compare(a.Priority, b.Priority) },
+	"done":       func(a, b Task) int { return compareBool(a.Done, b.Done) },
+}
+
+// SortTasks orders tasks by a comma-separated list of fields, each prefixed
+// with "-" for descending order, e.g. "-priority,due". The fields are id,
+// title, created_at, due, priority and done. Tasks without a due date come
+// last when sorting by due either way, and ties keep their order.
+func SortTasks(tasks []Task, by string) error {
+	type order struct {
+		compare func(a, b Task) int
+		desc    bool
+	}
+	var orders []order
+	for _, field := range strings.Split(by, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		o := order{}
+		if o.desc = strings.HasPrefix(field, "-"); o.desc {
+			field = field[1:]
+		}
+		if o.compare = taskOrders[field]; o.compare == nil {
+			return fmt.Errorf("%w: cannot sort by %q", ErrInvalidSort, field)
+		}
+		if field == "due" {
+			o.compare = dueLast(o.compare, o.desc)
+		}
+		orders = append(orders, o)
+	}
+	slices.SortStableFunc(tasks, func(a, b Task) int {
+		for _, o := range orders {
+			c := o.compare(a, b)
+			if o.desc {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+	return nil
+}
+
+// dueLast wraps the due order so that tasks without a due date come after
+// the others once the order is applied in the given direction
+func dueLast(compare func(a, b Task) int, desc bool) func(a, b Task) int {
+	return func(a, b Task) int {
+		if c := compareBool(a.Due.IsZero(), b.Due.IsZero()); c != 0 {
+			if desc {
+				return -c
+			}
+			return c
+		}
+		return compare(a, b)
+	}
+}
+
+func compareBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	}
+	return -1
+}
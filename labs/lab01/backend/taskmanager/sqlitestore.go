@@ -0,0 +1,288 @@
+package taskmanager
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteStore keeps tasks in a SQLite database
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// sqliteMigrations are applied in order; PRAGMA user_version records how
+// many a database has had
+var sqliteMigrations = []string{
+	`CREATE TABLE IF NOT EXISTS tasks (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		title       TEXT    NOT NULL,
+		description TEXT    NOT NULL DEFAULT '',
+		done        INTEGER NOT NULL DEFAULT 0,
+		created_at  TEXT    NOT NULL
+	)`,
+	`ALTER TABLE tasks ADD COLUMN version INTEGER NOT NULL DEFAULT 1`,
+	`ALTER TABLE tasks ADD COLUMN due TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE tasks ADD COLUMN priority INTEGER NOT NULL DEFAULT 0`,
+	// tags and recurrence are JSON, or '' when unset
+	`ALTER TABLE tasks ADD COLUMN tags TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE tasks ADD COLUMN parent_id INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE tasks ADD COLUMN recurrence TEXT NOT NULL DEFAULT ''`,
+	// A single row holding the history of a TaskManager
+	`CREATE TABLE IF NOT EXISTS history (
+		id   INTEGER PRIMARY KEY CHECK (id = 1),
+		data TEXT    NOT NULL
+	)`,
+	`ALTER TABLE tasks ADD COLUMN next_occurrence_id INTEGER NOT NULL DEFAULT 0`,
+}
+
+// writableColumns are set by Create and Update, in the order of taskValues
+var writableColumns = []string{
+	"title", "description", "done", "created_at", "due", "priority", "tags", "parent_id", "recurrence",
+	"next_occurrence_id",
+}
+
+var taskColumns = "id, version, " + strings.Join(writableColumns, ", ")
+
+// OpenSQLiteStore opens or creates the database at path, which may be
+// ":memory:" for a throwaway one
+func OpenSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, err
+	}
+	if path == ":memory:" {
+		// Every connection to :memory: is a separate database
+		db.SetMaxOpenConns(1)
+	}
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("taskmanager: migrate: %w", err)
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+func migrate(db *sql.DB) error {
+	var applied int
+	if err := db.QueryRow(`PRAGMA user_version`).Scan(&applied); err != nil {
+		return err
+	}
+	for i := applied; i < len(sqliteMigrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(sqliteMigrations[i]); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, i+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQLiteStore) Create(task Task) (Task, error) {
+	values, err := taskValues(task)
+	if err != nil {
+		return Task{}, err
+	}
+	placeholders := strings.Repeat(", ?", len(writableColumns))
+	res, err := s.db.Exec(
+		`INSERT INTO tasks (version, `+strings.Join(writableColumns, ", ")+`) VALUES (1`+placeholders+`)`,
+		values...,
+	)
+	if err != nil {
+		return Task{}, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return Task{}, err
+	}
+	task.ID, task.Version = int(id), 1
+	return task.clone(), nil
+}
+
+func (s *SQLiteStore) Get(id int) (Task, error) {
+	row := s.db.QueryRow(`SELECT `+taskColumns+` FROM tasks WHERE id = ?`, id)
+	task, err := scanTask(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Task{}, ErrTaskNotFound
+	}
+	return task, err
+}
+
+func (s *SQLiteStore) Update(task Task) error {
+	values, err := taskValues(task)
+	if err != nil {
+		return err
+	}
+	res, err := s.db.Exec(
+		`UPDATE tasks SET `+strings.Join(writableColumns, " = ?, ")+` = ?, version = version + 1
+		WHERE id = ? AND version = ?`,
+		append(values, task.ID, task.Version)...,
+	)
+	if err := checkAffected(res, err); err != ErrTaskNotFound {
+		return err
+	}
+	// No row matched: either the task is gone or its version moved on
+	if _, err := s.Get(task.ID); err != nil {
+		return err
+	}
+	return ErrVersionConflict
+}
+
+func (s *SQLiteStore) Delete(id int) error {
+	res, err := s.db.Exec(`DELETE FROM tasks WHERE id = ?`, id)
+	return checkAffected(res, err)
+}
+
+func (s *SQLiteStore) Restore(task Task) error {
+	values, err := taskValues(task)
+	if err != nil {
+		return err
+	}
+	placeholders := strings.Repeat(", ?", len(writableColumns))
+	res, err := s.db.Exec(
+		`INSERT INTO tasks (id, version, `+strings.Join(writableColumns, ", ")+`) VALUES (?, ?`+placeholders+`)
+		ON CONFLICT (id) DO NOTHING`,
+		append([]any{task.ID, task.Version}, values...)...,
+	)
+	if err := checkAffected(res, err); err != ErrTaskNotFound {
+		return err
+	}
+	return ErrTaskExists
+}
+
+func (s *SQLiteStore) List() ([]Task, error) {
+	rows, err := s.db.Query(`SELECT ` + taskColumns + ` FROM tasks ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tasks := []Task{}
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rows.Err()
+}
+
+// LoadHistory returns the saved history, or nil
+func (s *SQLiteStore) LoadHistory() ([]byte, error) {
+	var data []byte
+	err := s.db.QueryRow(`SELECT data FROM history WHERE id = 1`).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// SaveHistory replaces the saved history
+func (s *SQLiteStore) SaveHistory(data []byte) error {
+	_, err := s.db.Exec(
+		`INSERT INTO history (id, data) VALUES (1, ?) ON CONFLICT (id) DO UPDATE SET data = excluded.data`,
+		string(data),
+	)
+	return err
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// taskValues returns the values of writableColumns for a task
+func taskValues(task Task) ([]any, error) {
+	var due, tags, recurrence string
+	if !task.Due.IsZero() {
+		due = formatTime(task.Due)
+	}
+	if len(task.Tags) > 0 {
+		data, err := json.Marshal(task.Tags)
+		if err != nil {
+			return nil, err
+		}
+		tags = string(data)
+	}
+	if task.Recurrence != nil {
+		data, err := json.Marshal(task.Recurrence)
+		if err != nil {
+			return nil, err
+		}
+		recurrence = string(data)
+	}
+	return []any{
+		task.Title, task.Description, task.Done, formatTime(task.CreatedAt),
+		due, int(task.Priority), tags, task.ParentID, recurrence,
+		task.NextOccurrenceID,
+	}, nil
+}
+
+func scanTask(row interface{ Scan(...any) error }) (Task, error) {
+	var task Task
+	var createdAt, due, tags, recurrence string
+	var priority int
+	err := row.Scan(
+		&task.ID, &task.Version, &task.Title, &task.Description, &task.Done, &createdAt,
+		&due, &priority, &tags, &task.ParentID, &recurrence,
+		&task.NextOccurrenceID,
+	)
+	if err != nil {
+		return Task{}, err
+	}
+	task.Priority = Priority(priority)
+	if task.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt); err != nil {
+		return Task{}, fmt.Errorf("taskmanager: task %d: %w", task.ID, err)
+	}
+	if due != "" {
+		if task.Due, err = time.Parse(time.RFC3339Nano, due); err != nil {
+			return Task{}, fmt.Errorf("taskmanager: task %d: %w", task.ID, err)
+		}
+	}
+	if tags != "" {
+		if err := json.Unmarshal([]byte(tags), &task.Tags); err != nil {
+			return Task{}, fmt.Errorf("taskmanager: task %d tags: %w", task.ID, err)
+		}
+	}
+	if recurrence != "" {
+		task.Recurrence = new(Recurrence)
+		if err := json.Unmarshal([]byte(recurrence), task.Recurrence); err != nil {
+			return Task{}, fmt.Errorf("taskmanager: task %d recurrence: %w", task.ID, err)
+		}
+	}
+	return task, nil
+}
+
+// checkAffected turns an update or delete that matched no row into
+// ErrTaskNotFound
+func checkAffected(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrTaskNotFound
+	}
+	return nil
+}
+
+// formatTime stores times as UTC text with full precision
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
@@ -0,0 +1,111 @@
+package taskmanager
+
+import (
+	"maps"
+	"slices"
+	"sync"
+)
+
+// Store persists the tasks of a TaskManager. Implementations assign IDs in
+// increasing order and never reuse them, and are safe for concurrent use.
+type Store interface {
+	// Create saves a new task, assigning it the next ID and version 1, and
+	// returns it
+	Create(task Task) (Task, error)
+	// Get returns the task with the given ID or ErrTaskNotFound
+	Get(id int) (Task, error)
+	// Update replaces the task with task.ID, storing it with the next
+	// version, if task.Version is still the stored one. It returns
+	// ErrTaskNotFound or ErrVersionConflict otherwise.
+	Update(task Task) error
+	// Delete removes the task with the given ID or returns ErrTaskNotFound
+	Delete(id int) error
+	// Restore puts back a deleted task with its ID and version, e.g. to
+	// undo the deletion, or returns ErrTaskExists if the ID is in use
+	Restore(task Task) error
+	// List returns every task ordered by ID, i.e. in creation order
+	List() ([]Task, error)
+	// Close releases the store's resources
+	Close() error
+}
+
+// MemoryStore keeps tasks in memory; they are lost when the process exits
+type MemoryStore struct {
+	mu     sync.RWMutex
+	tasks  map[int]Task
+	nextID int
+}
+
+// NewMemoryStore returns an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{tasks: make(map[int]Task), nextID: 1}
+}
+
+func (s *MemoryStore) Create(task Task) (Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	task.ID, task.Version = s.nextID, 1
+	s.tasks[task.ID] = task.clone()
+	s.nextID++
+	return task, nil
+}
+
+func (s *MemoryStore) Get(id int) (Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	task, ok := s.tasks[id]
+	if !ok {
+		return Task{}, ErrTaskNotFound
+	}
+	return task.clone(), nil
+}
+
+func (s *MemoryStore) Update(task Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.tasks[task.ID]
+	if !ok {
+		return ErrTaskNotFound
+	}
+	if stored.Version != task.Version {
+		return ErrVersionConflict
+	}
+	task.Version++
+	s.tasks[task.ID] = task.clone()
+	return nil
+}
+
+func (s *MemoryStore) Delete(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.tasks[id]; !ok {
+		return ErrTaskNotFound
+	}
+	delete(s.tasks, id)
+	return nil
+}
+
+func (s *MemoryStore) Restore(task Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.tasks[task.ID]; ok {
+		return ErrTaskExists
+	}
+	s.tasks[task.ID] = task.clone()
+	s.nextID = max(s.nextID, task.ID+1)
+	return nil
+}
+
+func (s *MemoryStore) List() ([]Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	tasks := make([]Task, 0, len(s.tasks))
+	for _, id := range slices.Sorted(maps.Keys(s.tasks)) {
+		tasks = append(tasks, s.tasks[id].clone())
+	}
+	return tasks, nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
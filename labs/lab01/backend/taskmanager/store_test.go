@@ -0,0 +1,451 @@
+package taskmanager
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// storeBackend opens a store for the conformance suite. reopen, when set,
+// opens a second store on the same data to check that it persists.
+type storeBackend struct {
+	name   string
+	open   func(t *testing.T) Store
+	reopen func(t *testing.T) Store
+}
+
+func storeBackends() []storeBackend {
+	var jsonPath, sqlitePath string
+	openJSON := func(t *testing.T) Store {
+		s, err := OpenJSONStore(jsonPath)
+		if err != nil {
+			t.Fatalf("OpenJSONStore failed: %v", err)
+		}
+		return s
+	}
+	openSQLite := func(t *testing.T) Store {
+		s, err := OpenSQLiteStore(sqlitePath)
+		if err != nil {
+			t.Fatalf("OpenSQLiteStore failed: %v", err)
+		}
+		return s
+	}
+	return []storeBackend{
+		{name: "memory", open: func(t *testing.T) Store { return NewMemoryStore() }},
+		{
+			name: "json",
+			open: func(t *testing.T) Store {
+				jsonPath = filepath.Join(t.TempDir(), "tasks.json")
+				return openJSON(t)
+			},
+			reopen: openJSON,
+		},
+		{
+			name: "sqlite",
+			open: func(t *testing.T) Store {
+				sqlitePath = filepath.Join(t.TempDir(), "tasks.db")
+				return openSQLite(t)
+			},
+			reopen: openSQLite,
+		},
+	}
+}
+
+// TestStoreConformance runs the same checks against every Store
+func TestStoreConformance(t *testing.T) {
+	for _, b := range storeBackends() {
+		t.Run(b.name, func(t *testing.T) {
+			for _, c := range conformanceCases {
+				t.Run(c.name, func(t *testing.T) {
+					s := b.open(t)
+					t.Cleanup(func() { s.Close() })
+					c.run(t, s, b)
+				})
+			}
+		})
+	}
+}
+
+var epoch = time.Date(2025, 6, 1, 9, 0, 0, 123456789, time.UTC)
+
+func mustCreate(t *testing.T, s Store, title string) Task {
+	t.Helper()
+	task, err := s.Create(Task{Title: title, Description: title + " description", CreatedAt: epoch})
+	if err != nil {
+		t.Fatalf("Create(%q) failed: %v", title, err)
+	}
+	return task
+}
+
+// sameTask compares tasks, with times compared as instants
+func sameTask(a, b Task) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) || !a.Due.Equal(b.Due) {
+		return false
+	}
+	a.CreatedAt, a.Due, b.CreatedAt, b.Due = time.Time{}, time.Time{}, time.Time{}, time.Time{}
+	return reflect.DeepEqual(a, b)
+}
+
+func listIDs(t *testing.T, s Store) []int {
+	t.Helper()
+	tasks, err := s.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	ids := []int{}
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+	}
+	return ids
+}
+
+var conformanceCases = []struct {
+	name string
+	run  func(t *testing.T, s Store, b storeBackend)
+}{
+	{"create and get", func(t *testing.T, s Store, b storeBackend) {
+		created := mustCreate(t, s, "Drink water")
+		if created.ID != 1 {
+			t.Errorf("Expected first ID to be 1, got %d", created.ID)
+		}
+		got, err := s.Get(created.ID)
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if !sameTask(got, created) {
+			t.Errorf("Get(%d) = %+v, want %+v", created.ID, got, created)
+		}
+	}},
+	{"missing task", func(t *testing.T, s Store, b storeBackend) {
+		if _, err := s.Get(42); err != ErrTaskNotFound {
+			t.Errorf("Get: expected ErrTaskNotFound, got %v", err)
+		}
+		if err := s.Update(Task{ID: 42, Title: "x"}); err != ErrTaskNotFound {
+			t.Errorf("Update: expected ErrTaskNotFound, got %v", err)
+		}
+		if err := s.Delete(42); err != ErrTaskNotFound {
+			t.Errorf("Delete: expected ErrTaskNotFound, got %v", err)
+		}
+	}},
+	{"update", func(t *testing.T, s Store, b storeBackend) {
+		task := mustCreate(t, s, "Walk")
+		task.Title, task.Description, task.Done = "Walk 10k steps", "", true
+		if err := s.Update(task); err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+		task.Version++
+		got, err := s.Get(task.ID)
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if !sameTask(got, task) {
+			t.Errorf("Get(%d) = %+v, want %+v", task.ID, got, task)
+		}
+	}},
+	{"all fields", func(t *testing.T, s Store, b storeBackend) {
+		parent := mustCreate(t, s, "Morning routine")
+		task, err := s.Create(Task{
+			Title:            "Push-ups",
+			CreatedAt:        epoch,
+			Due:              epoch.Add(26 * time.Hour),
+			Priority:         PriorityHigh,
+			Tags:             []string{"fitness", "morning"},
+			ParentID:         parent.ID,
+			Recurrence:       Weekly(time.Monday, time.Thursday),
+			NextOccurrenceID: parent.ID,
+		})
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		got, err := s.Get(task.ID)
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if !sameTask(got, task) {
+			t.Errorf("Get(%d) = %+v, want %+v", task.ID, got, task)
+		}
+
+		// Returned tasks do not share memory with the store
+		got.Tags[0] = "changed"
+		got.Recurrence.Weekdays[0] = time.Sunday
+		if again, _ := s.Get(task.ID); !sameTask(again, task) {
+			t.Errorf("Changing a returned task changed the store: %+v", again)
+		}
+
+		task.Due, task.Tags, task.Recurrence, task.Priority = time.Time{}, nil, nil, PriorityNone
+		if err := s.Update(task); err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+		task.Version++
+		if got, _ := s.Get(task.ID); !sameTask(got, task) {
+			t.Errorf("After clearing fields, Get(%d) = %+v, want %+v", task.ID, got, task)
+		}
+	}},
+	{"versions", func(t *testing.T, s Store, b storeBackend) {
+		task := mustCreate(t, s, "Meditate")
+		if task.Version != 1 {
+			t.Fatalf("Expected version 1, got %d", task.Version)
+		}
+		stale := task
+		task.Done = true
+		if err := s.Update(task); err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+		got, err := s.Get(task.ID)
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if got.Version != 2 || !got.Done {
+			t.Errorf("Expected done task at version 2, got %+v", got)
+		}
+		stale.Title = "Stale"
+		if err := s.Update(stale); err != ErrVersionConflict {
+			t.Errorf("Expected ErrVersionConflict, got %v", err)
+		}
+		if got, _ := s.Get(task.ID); got.Title != "Meditate" || got.Version != 2 {
+			t.Errorf("Expected the stale update to be rejected, got %+v", got)
+		}
+	}},
+	{"list is ordered by ID", func(t *testing.T, s Store, b storeBackend) {
+		if ids := listIDs(t, s); len(ids) != 0 {
+			t.Errorf("Expected an empty store, got %v", ids)
+		}
+		for _, title := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"} {
+			mustCreate(t, s, title)
+		}
+		want := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+		for range 5 {
+			if ids := listIDs(t, s); !reflect.DeepEqual(ids, want) {
+				t.Fatalf("List() IDs = %v, want %v", ids, want)
+			}
+		}
+	}},
+	{"IDs are not reused", func(t *testing.T, s Store, b storeBackend) {
+		mustCreate(t, s, "a")
+		last := mustCreate(t, s, "b")
+		if err := s.Delete(last.ID); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if _, err := s.Get(last.ID); err != ErrTaskNotFound {
+			t.Errorf("Expected deleted task to be gone, got %v", err)
+		}
+		if next := mustCreate(t, s, "c"); next.ID != 3 {
+			t.Errorf("Expected ID 3 after deleting 2, got %d", next.ID)
+		}
+	}},
+	{"persists", func(t *testing.T, s Store, b storeBackend) {
+		if b.reopen == nil {
+			t.Skip("store is not persistent")
+		}
+		kept := mustCreate(t, s, "kept")
+		deleted := mustCreate(t, s, "deleted")
+		kept.Done = true
+		if err := s.Update(kept); err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+		kept.Version++
+		if err := s.Delete(deleted.ID); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if err := s.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+
+		r := b.reopen(t)
+		defer r.Close()
+		tasks, err := r.List()
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		if len(tasks) != 1 || !sameTask(tasks[0], kept) {
+			t.Errorf("After reopening, List() = %+v, want [%+v]", tasks, kept)
+		}
+		if next := mustCreate(t, r, "next"); next.ID != 3 {
+			t.Errorf("Expected ID 3 after reopening, got %d", next.ID)
+		}
+	}},
+	{"restore", func(t *testing.T, s Store, b storeBackend) {
+		task := mustCreate(t, s, "Meditate")
+		mustCreate(t, s, "Read")
+		if err := s.Restore(task); err != ErrTaskExists {
+			t.Errorf("Restore of a stored task: expected ErrTaskExists, got %v", err)
+		}
+		if err := s.Delete(task.ID); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		task.Version = 3
+		if err := s.Restore(task); err != nil {
+			t.Fatalf("Restore failed: %v", err)
+		}
+		if got, err := s.Get(task.ID); err != nil || !sameTask(got, task) {
+			t.Errorf("Get(%d) = %+v, %v, want %+v", task.ID, got, err, task)
+		}
+		if next := mustCreate(t, s, "Walk"); next.ID != 3 {
+			t.Errorf("Expected IDs to go on from 3 after a restore, got %d", next.ID)
+		}
+	}},
+	{"history", func(t *testing.T, s Store, b storeBackend) {
+		if b.reopen == nil {
+			t.Skip("store is not persistent")
+		}
+		tm := NewTaskManagerWithStore(s)
+		if _, err := tm.AddTask("Stretch", ""); err != nil {
+			t.Fatalf("AddTask failed: %v", err)
+		}
+		if err := tm.DeleteTask(1); err != nil {
+			t.Fatalf("DeleteTask failed: %v", err)
+		}
+		if err := tm.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+
+		tm = NewTaskManagerWithStore(b.reopen(t))
+		defer tm.Close()
+		if undo, _ := tm.History(); len(undo) != 2 {
+			t.Fatalf("Expected 2 operations after reopening, got %q", undo)
+		}
+		if _, err := tm.Undo(); err != nil {
+			t.Fatalf("Undo failed: %v", err)
+		}
+		if task := mustGetTask(t, tm, 1); task.Title != "Stretch" {
+			t.Errorf("Expected the deleted task back, got %+v", task)
+		}
+	}},
+	{"manager", func(t *testing.T, s Store, b storeBackend) {
+		tm := NewTaskManagerWithStore(s)
+		for _, title := range []string{"one", "two", "three"} {
+			if _, err := tm.AddTask(title, ""); err != nil {
+				t.Fatalf("AddTask failed: %v", err)
+			}
+		}
+		if err := tm.UpdateTask(2, 0, "two", "", true); err != nil {
+			t.Fatalf("UpdateTask failed: %v", err)
+		}
+		pending := false
+		tasks, err := tm.ListTasks(Query{Done: &pending})
+		if err != nil {
+			t.Fatalf("ListTasks failed: %v", err)
+		}
+		if len(tasks) != 2 || tasks[0].Title != "one" || tasks[1].Title != "three" {
+			t.Errorf("ListTasks(pending) = %+v, want one and three in order", tasks)
+		}
+	}},
+}
+
+func TestJSONStoreFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tasks.json")
+	s, err := OpenJSONStore(path)
+	if err != nil {
+		t.Fatalf("OpenJSONStore failed: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected no file before the first change, got %v", err)
+	}
+	mustCreate(t, s, "Stretch")
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "tasks.json" {
+		t.Errorf("Expected only tasks.json to be left behind, got %v", entries)
+	}
+
+	if err := os.WriteFile(path, []byte("{not json"), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if _, err := OpenJSONStore(path); err == nil {
+		t.Error("Expected error for a corrupt file, got none")
+	}
+}
+
+func TestJSONStoreFailedSave(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "data")
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		t.Fatalf("Mkdir failed: %v", err)
+	}
+	s, err := OpenJSONStore(filepath.Join(dir, "tasks.json"))
+	if err != nil {
+		t.Fatalf("OpenJSONStore failed: %v", err)
+	}
+	task := mustCreate(t, s, "Stretch")
+
+	// With the directory gone every save fails; the changes are undone
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatalf("RemoveAll failed: %v", err)
+	}
+	if _, err := s.Create(Task{Title: "Lost"}); err == nil {
+		t.Error("Create: expected error, got none")
+	}
+	if err := s.Delete(task.ID); err == nil {
+		t.Error("Delete: expected error, got none")
+	}
+	if ids := listIDs(t, s); !reflect.DeepEqual(ids, []int{1}) {
+		t.Errorf("Expected the failed changes to be undone, got %v", ids)
+	}
+
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		t.Fatalf("Mkdir failed: %v", err)
+	}
+	if next := mustCreate(t, s, "Walk"); next.ID != 2 {
+		t.Errorf("Expected the failed create not to use up an ID, got %d", next.ID)
+	}
+}
+
+func TestJSONStoreDirectorySyncFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	s, err := OpenJSONStore(path)
+	if err != nil {
+		t.Fatalf("OpenJSONStore failed: %v", err)
+	}
+	orig := syncDir
+	syncDir = func(string) error { return errors.New("fsync: input/output error") }
+	t.Cleanup(func() { syncDir = orig })
+
+	// The file already has the task, so memory keeps it too
+	task := mustCreate(t, s, "Stretch")
+	reopened, err := OpenJSONStore(path)
+	if err != nil {
+		t.Fatalf("OpenJSONStore failed: %v", err)
+	}
+	if got, err := reopened.Get(task.ID); err != nil || got.Title != "Stretch" {
+		t.Errorf("Expected the task in the file, got %+v, %v", got, err)
+	}
+	if ids := listIDs(t, s); !reflect.DeepEqual(ids, []int{task.ID}) {
+		t.Errorf("Expected the task to stay in memory, got %v", ids)
+	}
+}
+
+func TestSQLiteStoreMigratesOldSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	// The schema before tasks had versions, without a user_version
+	if _, err := db.Exec(sqliteMigrations[0]); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO tasks (title, created_at) VALUES ('Old', ?)`, formatTime(epoch)); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	db.Close()
+
+	s, err := OpenSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("OpenSQLiteStore failed: %v", err)
+	}
+	defer s.Close()
+	task, err := s.Get(1)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if task.Title != "Old" || task.Version != 1 {
+		t.Errorf("Expected the old task at version 1, got %+v", task)
+	}
+}
@@ -0,0 +1,293 @@
+package taskmanager
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestManager(t *testing.T, now time.Time) *TaskManager {
+	t.Helper()
+	tm := NewTaskManager()
+	tm.SetClock(func() time.Time { return now })
+	return tm
+}
+
+func mustCreateTask(t *testing.T, tm *TaskManager, task Task) Task {
+	t.Helper()
+	created, err := tm.CreateTask(task)
+	if err != nil {
+		t.Fatalf("CreateTask(%q) failed: %v", task.Title, err)
+	}
+	return created
+}
+
+func mustGetTask(t *testing.T, tm *TaskManager, id int) Task {
+	t.Helper()
+	task, err := tm.GetTask(id)
+	if err != nil {
+		t.Fatalf("GetTask(%d) failed: %v", id, err)
+	}
+	return task
+}
+
+func TestCreateTask(t *testing.T) {
+	now := time.Date(2025, 6, 2, 8, 0, 0, 0, time.UTC)
+	tm := newTestManager(t, now)
+	parent := mustCreateTask(t, tm, Task{Title: "Morning routine"})
+
+	task := mustCreateTask(t, tm, Task{
+		ID:       99,
+		Title:    "Stretch",
+		Due:      now.Add(time.Hour),
+		Priority: PriorityHigh,
+		Tags:     []string{"Fitness", "fitness", " morning"},
+		ParentID: parent.ID,
+	})
+	if task.ID != 2 || task.Version != 1 || !task.CreatedAt.Equal(now) {
+		t.Errorf("Expected ID 2, version 1 and the clock's time, got %+v", task)
+	}
+	if len(task.Tags) != 2 || task.Tags[0] != "fitness" || task.Tags[1] != "morning" {
+		t.Errorf("Expected normalised tags, got %v", task.Tags)
+	}
+
+	// A creation time given, e.g. by an import, is kept
+	imported := mustCreateTask(t, tm, Task{Title: "Imported", CreatedAt: now.AddDate(-1, 0, 0)})
+	if !imported.CreatedAt.Equal(now.AddDate(-1, 0, 0)) {
+		t.Errorf("Expected the given creation time to be kept, got %v", imported.CreatedAt)
+	}
+
+	tests := []struct {
+		name string
+		task Task
+		err  error
+	}{
+		{"empty title", Task{}, ErrEmptyTitle},
+		{"bad priority", Task{Title: "x", Priority: 9}, ErrInvalidPriority},
+		{"bad recurrence", Task{Title: "x", Recurrence: Weekly()}, ErrInvalidRecurrence},
+		{"missing parent", Task{Title: "x", ParentID: 42}, ErrInvalidParent},
+		{"negative parent", Task{Title: "x", ParentID: -1}, ErrInvalidParent},
+	}
+	for _, tt := range tests {
+		if _, err := tm.CreateTask(tt.task); !errors.Is(err, tt.err) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.err, err)
+		}
+	}
+}
+
+func TestSubtaskCycles(t *testing.T) {
+	tm := NewTaskManager()
+	a := mustCreateTask(t, tm, Task{Title: "a"})
+	b := mustCreateTask(t, tm, Task{Title: "b", ParentID: a.ID})
+	c := mustCreateTask(t, tm, Task{Title: "c", ParentID: b.ID})
+
+	for _, parent := range []int{a.ID, c.ID} {
+		a := mustGetTask(t, tm, a.ID)
+		a.ParentID = parent
+		if _, err := tm.SaveTask(a); !errors.Is(err, ErrInvalidParent) {
+			t.Errorf("Making a a subtask of %d: expected ErrInvalidParent, got %v", parent, err)
+		}
+	}
+
+	// Moving a subtask to another parent is fine
+	c = mustGetTask(t, tm, c.ID)
+	c.ParentID = a.ID
+	if _, err := tm.SaveTask(c); err != nil {
+		t.Errorf("Moving c under a failed: %v", err)
+	}
+}
+
+func TestCompletingParentCompletesSubtasks(t *testing.T) {
+	tm := NewTaskManager()
+	parent := mustCreateTask(t, tm, Task{Title: "Plan the week"})
+	child := mustCreateTask(t, tm, Task{Title: "Meals", ParentID: parent.ID})
+	grandchild := mustCreateTask(t, tm, Task{Title: "Shopping list", ParentID: child.ID})
+	other := mustCreateTask(t, tm, Task{Title: "Unrelated"})
+
+	if err := tm.UpdateTask(parent.ID, 0, parent.Title, "", true); err != nil {
+		t.Fatalf("UpdateTask failed: %v", err)
+	}
+	for _, id := range []int{parent.ID, child.ID, grandchild.ID} {
+		if !mustGetTask(t, tm, id).Done {
+			t.Errorf("Expected task %d to be done", id)
+		}
+	}
+	if mustGetTask(t, tm, other.ID).Done {
+		t.Error("Expected the unrelated task to stay pending")
+	}
+
+	// Reopening the parent leaves the subtasks alone
+	if err := tm.UpdateTask(parent.ID, 0, parent.Title, "", false); err != nil {
+		t.Fatalf("UpdateTask failed: %v", err)
+	}
+	if !mustGetTask(t, tm, child.ID).Done {
+		t.Error("Expected the subtask to stay done")
+	}
+
+	subtasks, err := tm.ListTasks(Query{ParentID: &parent.ID})
+	if err != nil || len(subtasks) != 1 || subtasks[0].ID != child.ID {
+		t.Errorf("ListTasks(subtasks of %d) = %v, %v", parent.ID, subtasks, err)
+	}
+
+	// Deleting the parent deletes the whole tree
+	if err := tm.DeleteTask(parent.ID); err != nil {
+		t.Fatalf("DeleteTask failed: %v", err)
+	}
+	tasks, _ := tm.ListTasks(Query{})
+	if len(tasks) != 1 || tasks[0].ID != other.ID {
+		t.Errorf("Expected only the unrelated task to remain, got %v", tasks)
+	}
+}
+
+func TestRecurringTaskSpawnsNext(t *testing.T) {
+	// 2025-06-02 is a Monday
+	now := time.Date(2025, 6, 2, 20, 0, 0, 0, time.UTC)
+	tm := newTestManager(t, now)
+	due := time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC)
+	habit := mustCreateTask(t, tm, Task{
+		Title:      "Gym",
+		Due:        due,
+		Priority:   PriorityMedium,
+		Tags:       []string{"fitness"},
+		Recurrence: Weekly(time.Monday, time.Wednesday, time.Friday),
+	})
+	sub := mustCreateTask(t, tm, Task{Title: "Pack bag", ParentID: habit.ID})
+
+	habit.Done = true
+	if _, err := tm.SaveTask(habit); err != nil {
+		t.Fatalf("SaveTask failed: %v", err)
+	}
+	if !mustGetTask(t, tm, sub.ID).Done {
+		t.Error("Expected the subtask to be completed with its parent")
+	}
+
+	yes := true
+	pending, err := tm.ListTasks(Query{Done: new(bool), Recurring: &yes})
+	if err != nil {
+		t.Fatalf("ListTasks failed: %v", err)
+	}
+	if len(pending) != 1 {
+		t.Fatalf("Expected one pending occurrence, got %v", pending)
+	}
+	next := pending[0]
+	if want := due.AddDate(0, 0, 2); !next.Due.Equal(want) {
+		t.Errorf("Expected the next occurrence on Wednesday %v, got %v", want, next.Due)
+	}
+	if next.Title != "Gym" || next.Priority != PriorityMedium || !next.HasTag("fitness") || next.Recurrence == nil {
+		t.Errorf("Expected the next occurrence to copy the task, got %+v", next)
+	}
+	if !next.CreatedAt.Equal(now) || next.Version != 1 || next.ID == habit.ID {
+		t.Errorf("Expected a new task created now, got %+v", next)
+	}
+
+	// Saving an already completed task again does not spawn another one
+	done := mustGetTask(t, tm, habit.ID)
+	done.Description = "Legs"
+	if _, err := tm.SaveTask(done); err != nil {
+		t.Fatalf("SaveTask failed: %v", err)
+	}
+	recurring, _ := tm.ListTasks(Query{Recurring: &yes})
+	if len(recurring) != 2 {
+		t.Errorf("Expected two occurrences in total, got %d", len(recurring))
+	}
+}
+
+func TestRecurringTaskSpawnsNextOnce(t *testing.T) {
+	now := time.Date(2025, 6, 2, 20, 0, 0, 0, time.UTC)
+	tm := newTestManager(t, now)
+	habit := mustCreateTask(t, tm, Task{Title: "Stretch", Due: now, Recurrence: Daily()})
+	yes := true
+	occurrences := func() int {
+		t.Helper()
+		tasks, err := tm.ListTasks(Query{Recurring: &yes})
+		if err != nil {
+			t.Fatalf("ListTasks failed: %v", err)
+		}
+		return len(tasks)
+	}
+	setDone := func(done bool) {
+		t.Helper()
+		task := mustGetTask(t, tm, habit.ID)
+		task.Done = done
+		saved, err := tm.SaveTask(task)
+		if err != nil {
+			t.Fatalf("SaveTask(done=%v) failed: %v", done, err)
+		}
+		if saved.Version != mustGetTask(t, tm, habit.ID).Version {
+			t.Errorf("Expected SaveTask to return the stored version, got %+v", saved)
+		}
+	}
+
+	// Complete, reopen, complete
+	setDone(true)
+	next := mustGetTask(t, tm, habit.ID).NextOccurrenceID
+	if next == 0 || occurrences() != 2 {
+		t.Fatalf("Expected the next occurrence to be recorded, got %d of %d", next, occurrences())
+	}
+	setDone(false)
+	setDone(true)
+	if got := occurrences(); got != 2 {
+		t.Errorf("Expected completing again not to create another occurrence, got %d", got)
+	}
+
+	// Undoing and redoing the reopening neither
+	mustUndo(t, tm, `update "Stretch"`)
+	mustUndo(t, tm, `update "Stretch"`)
+	if _, err := tm.Redo(); err != nil {
+		t.Fatalf("Redo failed: %v", err)
+	}
+	setDone(true)
+	if got := occurrences(); got != 2 {
+		t.Errorf("Expected undo and redo not to create another occurrence, got %d", got)
+	}
+
+	// Once the next occurrence is deleted, completing creates a new one
+	setDone(false)
+	if err := tm.DeleteTask(next); err != nil {
+		t.Fatalf("DeleteTask failed: %v", err)
+	}
+	setDone(true)
+	if got := mustGetTask(t, tm, habit.ID).NextOccurrenceID; got == next || occurrences() != 2 {
+		t.Errorf("Expected a new next occurrence, got %d of %d", got, occurrences())
+	}
+}
+
+func TestListTasksQuery(t *testing.T) {
+	now := time.Date(2025, 6, 2, 8, 0, 0, 0, time.UTC)
+	tm := newTestManager(t, now)
+	mustCreateTask(t, tm, Task{Title: "Water", Tags: []string{"health"}, Priority: PriorityHigh, Due: now.Add(2 * time.Hour)})
+	mustCreateTask(t, tm, Task{Title: "Sleep early", Tags: []string{"health", "sleep"}, Due: now.Add(14 * time.Hour)})
+	mustCreateTask(t, tm, Task{Title: "Read", Priority: PriorityLow})
+
+	tests := []struct {
+		name     string
+		query    Query
+		expected []string
+	}{
+		{"all", Query{}, []string{"Water", "Sleep early", "Read"}},
+		{"tag", Query{Tags: []string{"health"}}, []string{"Water", "Sleep early"}},
+		{"priority", Query{MinPriority: PriorityLow}, []string{"Water", "Read"}},
+		{"due today", Query{DueBefore: now.Add(12 * time.Hour)}, []string{"Water"}},
+		{"tag and text", Query{Tags: []string{"health"}, Text: "sleep"}, []string{"Sleep early"}},
+	}
+	for _, tt := range tests {
+		tasks, err := tm.ListTasks(tt.query)
+		if err != nil {
+			t.Fatalf("%s: ListTasks failed: %v", tt.name, err)
+		}
+		var titles []string
+		for _, task := range tasks {
+			titles = append(titles, task.Title)
+		}
+		if len(titles) != len(tt.expected) {
+			t.Errorf("%s: ListTasks() = %v, want %v", tt.name, titles, tt.expected)
+			continue
+		}
+		for i := range titles {
+			if titles[i] != tt.expected[i] {
+				t.Errorf("%s: ListTasks() = %v, want %v", tt.name, titles, tt.expected)
+				break
+			}
+		}
+	}
+}
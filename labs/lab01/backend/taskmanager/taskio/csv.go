@@ -0,0 +1,178 @@
+package taskio
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"lab01/taskmanager"
+)
+
+// csvColumns are the columns written, in order. On import they are found by
+// the header, in any order, and only title is required.
+var csvColumns = []string{"id", "parent_id", "title", "description", "done", "priority", "due", "tags", "recurrence", "created_at"}
+
+// csvCodec writes a header and a row per task. Times are RFC 3339, tags are
+// comma-separated and recurrence is formatted by Recurrence.String.
+type csvCodec struct{}
+
+func (csvCodec) encode(w io.Writer, tasks []taskmanager.Task) error {
+	cw := csv.NewWriter(w)
+	cw.Write(csvColumns)
+	for _, task := range tasks {
+		recurrence := ""
+		if task.Recurrence != nil {
+			recurrence = task.Recurrence.String()
+		}
+		cw.Write([]string{
+			strconv.Itoa(task.ID),
+			optionalID(task.ParentID),
+			task.Title,
+			task.Description,
+			strconv.FormatBool(task.Done),
+			task.Priority.String(),
+			formatTime(task.Due),
+			strings.Join(task.Tags, ","),
+			recurrence,
+			formatTime(task.CreatedAt),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func optionalID(id int) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.Itoa(id)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+func (csvCodec) decode(r io.Reader) ([]row, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading CSV header: %w", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !slices.Contains(csvColumns, name) {
+			return nil, fmt.Errorf("reading CSV header: unknown column %q", name)
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, errors.New("reading CSV header: no title column")
+	}
+
+	var rows []row
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			// The reader goes on with the next record
+			rows = append(rows, row{line: parseErr.StartLine, err: parseErr.Err})
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading CSV: %w", err)
+		}
+		line, _ := cr.FieldPos(0)
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return record[i]
+			}
+			return ""
+		}
+		task, err := csvTask(field)
+		rows = append(rows, row{line: line, task: task, err: err})
+	}
+}
+
+// csvTask reads a task from the fields of a record
+func csvTask(text func(name string) string) (taskmanager.Task, error) {
+	task := taskmanager.Task{
+		Title:       text("title"),
+		Description: text("description"),
+	}
+	field := func(name string) string {
+		return strings.TrimSpace(text(name))
+	}
+	if s := field("tags"); s != "" {
+		task.Tags = strings.Split(s, ",")
+	}
+	var err error
+	if task.ID, err = parseID(field("id")); err != nil {
+		return task, fmt.Errorf("id: %w", err)
+	}
+	if task.ParentID, err = parseID(field("parent_id")); err != nil {
+		return task, fmt.Errorf("parent_id: %w", err)
+	}
+	if s := field("done"); s != "" {
+		if task.Done, err = strconv.ParseBool(s); err != nil {
+			return task, fmt.Errorf("done: %q is not true or false", s)
+		}
+	}
+	if s := field("priority"); s != "" {
+		if task.Priority, err = taskmanager.ParsePriority(s); err != nil {
+			return task, err
+		}
+	}
+	if task.Due, err = parseTime(field("due")); err != nil {
+		return task, fmt.Errorf("due: %w", err)
+	}
+	if task.CreatedAt, err = parseTime(field("created_at")); err != nil {
+		return task, fmt.Errorf("created_at: %w", err)
+	}
+	if s := field("recurrence"); s != "" {
+		if task.Recurrence, err = taskmanager.ParseRecurrence(s); err != nil {
+			return task, err
+		}
+	}
+	return task, nil
+}
+
+func parseID(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	id, err := strconv.Atoi(s)
+	if err != nil || id < 0 {
+		return 0, fmt.Errorf("%q is not a task ID", s)
+	}
+	return id, nil
+}
+
+// parseTime reads an RFC 3339 time, or a date at midnight in local time
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not an RFC 3339 time or a date", s)
+}
@@ -0,0 +1,446 @@
+package taskio
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"lab01/taskmanager"
+)
+
+// icalCodec writes a VCALENDAR of VTODO components (RFC 5545). Subtasks are
+// linked to their parent with RELATED-TO and recurrence is an RRULE.
+type icalCodec struct{}
+
+const (
+	icalUTC  = "20060102T150405Z"
+	icalDate = "20060102"
+	// icalLineLimit is the length in octets after which lines are folded
+	icalLineLimit = 75
+	icalUIDSuffix = "@lab01.taskmanager"
+)
+
+var icalDays = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+func (icalCodec) encode(w io.Writer, tasks []taskmanager.Task) error {
+	bw := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeFolded(bw, name+":"+value)
+	}
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//lab01//taskmanager//EN")
+	for _, task := range tasks {
+		line("BEGIN", "VTODO")
+		line("UID", icalUID(task.ID))
+		stamp := task.CreatedAt
+		if stamp.IsZero() {
+			stamp = time.Now()
+		}
+		line("DTSTAMP", stamp.UTC().Format(icalUTC))
+		if !task.CreatedAt.IsZero() {
+			line("CREATED", task.CreatedAt.UTC().Format(icalUTC))
+		}
+		line("SUMMARY", icalEscape(task.Title))
+		if task.Description != "" {
+			line("DESCRIPTION", icalEscape(task.Description))
+		}
+		if task.Done {
+			line("STATUS", "COMPLETED")
+		} else {
+			line("STATUS", "NEEDS-ACTION")
+		}
+		if p := icalPriority(task.Priority); p != 0 {
+			line("PRIORITY", strconv.Itoa(p))
+		}
+		if !task.Due.IsZero() {
+			line("DUE", task.Due.UTC().Format(icalUTC))
+		}
+		if len(task.Tags) > 0 {
+			tags := make([]string, len(task.Tags))
+			for i, tag := range task.Tags {
+				tags[i] = icalEscape(tag)
+			}
+			line("CATEGORIES", strings.Join(tags, ","))
+		}
+		if task.ParentID != 0 {
+			line("RELATED-TO;RELTYPE=PARENT", icalUID(task.ParentID))
+		}
+		if task.Recurrence != nil {
+			line("RRULE", icalRRule(task.Recurrence))
+		}
+		line("END", "VTODO")
+	}
+	line("END", "VCALENDAR")
+	return bw.Flush()
+}
+
+func icalUID(id int) string {
+	return "task-" + strconv.Itoa(id) + icalUIDSuffix
+}
+
+// writeFolded writes a content line, folding it into lines of at most
+// icalLineLimit octets without splitting a character
+func writeFolded(w *bufio.Writer, s string) {
+	limit := icalLineLimit
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		w.WriteString(s[:cut])
+		w.WriteString("\r\n ")
+		s = s[cut:]
+		// The leading space of a continuation line counts towards its length
+		limit = icalLineLimit - 1
+	}
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
+
+var icalEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func icalEscape(s string) string {
+	return icalEscaper.Replace(s)
+}
+
+// icalUnescape undoes icalEscape; it also reads \N as a newline
+func icalUnescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// splitEscaped splits a list value on the commas that are not escaped
+func splitEscaped(s string) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case ',':
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// icalPriority maps priorities to the iCalendar scale, where 1 is the
+// highest and 0 undefined
+func icalPriority(p taskmanager.Priority) int {
+	switch p {
+	case taskmanager.PriorityHigh:
+		return 1
+	case taskmanager.PriorityMedium:
+		return 5
+	case taskmanager.PriorityLow:
+		return 9
+	}
+	return 0
+}
+
+func parseICalPriority(s string) (taskmanager.Priority, error) {
+	n, err := strconv.Atoi(s)
+	switch {
+	case err != nil || n < 0 || n > 9:
+		return 0, fmt.Errorf("%w: PRIORITY %q", taskmanager.ErrInvalidPriority, s)
+	case n == 0:
+		return taskmanager.PriorityNone, nil
+	case n <= 4:
+		return taskmanager.PriorityHigh, nil
+	case n == 5:
+		return taskmanager.PriorityMedium, nil
+	}
+	return taskmanager.PriorityLow, nil
+}
+
+func icalRRule(r *taskmanager.Recurrence) string {
+	switch r.Kind {
+	case taskmanager.RecurWeekly:
+		days := make([]string, 0, len(r.Weekdays))
+		for _, d := range r.Weekdays {
+			if d >= time.Sunday && d <= time.Saturday {
+				days = append(days, icalDays[d])
+			}
+		}
+		return "FREQ=WEEKLY;BYDAY=" + strings.Join(days, ",")
+	case taskmanager.RecurEveryNDays:
+		return "FREQ=DAILY;INTERVAL=" + strconv.Itoa(r.Interval)
+	}
+	return "FREQ=DAILY"
+}
+
+// parseRRule reads the rules that a Recurrence can express: daily with an
+// interval, and weekly on given days or on the day the task is due
+func parseRRule(s string, due time.Time) (*taskmanager.Recurrence, error) {
+	parts := make(map[string]string)
+	for _, part := range strings.Split(s, ";") {
+		key, value, _ := strings.Cut(part, "=")
+		parts[strings.ToUpper(key)] = strings.ToUpper(value)
+	}
+	interval := 1
+	if v, ok := parts["INTERVAL"]; ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("%w: RRULE interval %q", taskmanager.ErrInvalidRecurrence, v)
+		}
+		interval = n
+	}
+	for key := range parts {
+		switch key {
+		case "FREQ", "INTERVAL", "BYDAY", "WKST":
+		default:
+			return nil, fmt.Errorf("%w: RRULE %s is not supported", taskmanager.ErrInvalidRecurrence, key)
+		}
+	}
+
+	switch parts["FREQ"] {
+	case "DAILY":
+		if _, ok := parts["BYDAY"]; ok {
+			return nil, fmt.Errorf("%w: daily RRULE with BYDAY is not supported", taskmanager.ErrInvalidRecurrence)
+		}
+		if interval == 1 {
+			return taskmanager.Daily(), nil
+		}
+		return taskmanager.EveryNDays(interval), nil
+	case "WEEKLY":
+		if interval != 1 {
+			return nil, fmt.Errorf("%w: weekly RRULE with an interval is not supported", taskmanager.ErrInvalidRecurrence)
+		}
+		byDay, ok := parts["BYDAY"]
+		if !ok {
+			if due.IsZero() {
+				return nil, fmt.Errorf("%w: weekly RRULE needs BYDAY or DUE", taskmanager.ErrInvalidRecurrence)
+			}
+			return taskmanager.Weekly(due.Weekday()), nil
+		}
+		var days []time.Weekday
+		for _, name := range strings.Split(byDay, ",") {
+			d := slices.Index(icalDays, name)
+			if d < 0 {
+				return nil, fmt.Errorf("%w: RRULE day %q", taskmanager.ErrInvalidRecurrence, name)
+			}
+			days = append(days, time.Weekday(d))
+		}
+		return taskmanager.Weekly(days...), nil
+	}
+	return nil, fmt.Errorf("%w: RRULE frequency %q is not supported", taskmanager.ErrInvalidRecurrence, parts["FREQ"])
+}
+
+// icalLine is an unfolded content line
+type icalLine struct {
+	number int
+	name   string
+	params map[string]string
+	value  string
+}
+
+// readICalLines unfolds the content lines of r, numbering each with the
+// line it starts on
+func readICalLines(r io.Reader) ([]icalLine, error) {
+	var lines []icalLine
+	var raw []string
+	var numbers []int
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for n := 1; scanner.Scan(); n++ {
+		text := strings.TrimSuffix(scanner.Text(), "\r")
+		if n == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		if text != "" && (text[0] == ' ' || text[0] == '\t') && len(raw) > 0 {
+			raw[len(raw)-1] += text[1:]
+			continue
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		raw = append(raw, text)
+		numbers = append(numbers, n)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading iCalendar: %w", err)
+	}
+	for i, text := range raw {
+		lines = append(lines, parseICalLine(numbers[i], text))
+	}
+	return lines, nil
+}
+
+// parseICalLine splits a content line into its name, parameters and
+// value; a line without a value has an empty name
+func parseICalLine(number int, text string) icalLine {
+	l := icalLine{number: number, params: make(map[string]string)}
+	// The value starts at the first colon outside a quoted parameter
+	quoted := false
+	colon := -1
+	for i := 0; i < len(text) && colon < 0; i++ {
+		switch text[i] {
+		case '"':
+			quoted = !quoted
+		case ':':
+			if !quoted {
+				colon = i
+			}
+		}
+	}
+	if colon < 0 {
+		return l
+	}
+	l.value = text[colon+1:]
+	parts := strings.Split(text[:colon], ";")
+	l.name = strings.ToUpper(parts[0])
+	for _, param := range parts[1:] {
+		key, value, _ := strings.Cut(param, "=")
+		l.params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+	}
+	return l
+}
+
+func (icalCodec) decode(r io.Reader) ([]row, error) {
+	lines, err := readICalLines(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) > 0 && (lines[0].name != "BEGIN" || !strings.EqualFold(lines[0].value, "VCALENDAR")) {
+		return nil, errors.New("reading iCalendar: expected BEGIN:VCALENDAR")
+	}
+
+	// UIDs are numbered in order of appearance to link subtasks to parents
+	uids := make(map[string]int)
+	uidID := func(uid string) int {
+		if _, ok := uids[uid]; !ok {
+			uids[uid] = len(uids) + 1
+		}
+		return uids[uid]
+	}
+
+	var rows []row
+	for i := 0; i < len(lines); i++ {
+		if lines[i].name != "BEGIN" || !strings.EqualFold(lines[i].value, "VTODO") {
+			continue
+		}
+		start := i
+		// Gather the properties of the VTODO, skipping nested components
+		// such as VALARM
+		var props []icalLine
+		depth := 0
+		for i++; i < len(lines); i++ {
+			l := lines[i]
+			if l.name == "BEGIN" {
+				depth++
+			} else if l.name == "END" && depth > 0 {
+				depth--
+			} else if l.name == "END" {
+				break
+			} else if depth == 0 {
+				props = append(props, l)
+			}
+		}
+		if i == len(lines) {
+			rows = append(rows, row{line: lines[start].number, err: errors.New("VTODO without END:VTODO")})
+			break
+		}
+		task, err := icalTask(props, uidID)
+		rows = append(rows, row{line: lines[start].number, task: task, err: err})
+	}
+	return rows, nil
+}
+
+// icalTask reads a task from the properties of a VTODO
+func icalTask(props []icalLine, uidID func(uid string) int) (taskmanager.Task, error) {
+	var task taskmanager.Task
+	var rrule string
+	for _, p := range props {
+		var err error
+		switch p.name {
+		case "":
+			err = errors.New("content line without a value")
+		case "UID":
+			task.ID = uidID(p.value)
+		case "SUMMARY":
+			task.Title = icalUnescape(p.value)
+		case "DESCRIPTION":
+			task.Description = icalUnescape(p.value)
+		case "STATUS":
+			task.Done = task.Done || strings.EqualFold(p.value, "COMPLETED")
+		case "COMPLETED":
+			task.Done = true
+		case "PRIORITY":
+			task.Priority, err = parseICalPriority(p.value)
+		case "DUE":
+			task.Due, err = parseICalTime(p)
+		case "CREATED":
+			task.CreatedAt, err = parseICalTime(p)
+		case "CATEGORIES":
+			for _, tag := range splitEscaped(p.value) {
+				task.Tags = append(task.Tags, icalUnescape(tag))
+			}
+		case "RELATED-TO":
+			if reltype := p.params["RELTYPE"]; reltype == "" || strings.EqualFold(reltype, "PARENT") {
+				task.ParentID = uidID(p.value)
+			}
+		case "RRULE":
+			rrule = p.value
+		}
+		if err != nil {
+			return task, fmt.Errorf("line %d: %w", p.number, err)
+		}
+	}
+	if rrule != "" {
+		r, err := parseRRule(rrule, task.Due)
+		if err != nil {
+			return task, err
+		}
+		task.Recurrence = r
+	}
+	return task, nil
+}
+
+// parseICalTime reads a DATE-TIME in UTC, in a TZID or floating in local
+// time, or a DATE at midnight in local time
+func parseICalTime(p icalLine) (time.Time, error) {
+	if strings.HasSuffix(p.value, "Z") {
+		if t, err := time.Parse(icalUTC, p.value); err == nil {
+			return t, nil
+		}
+		return time.Time{}, fmt.Errorf("%s: invalid date-time %q", p.name, p.value)
+	}
+	loc := time.Local
+	if tzid := p.params["TZID"]; tzid != "" {
+		l, err := time.LoadLocation(tzid)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%s: unknown time zone %q", p.name, tzid)
+		}
+		loc = l
+	}
+	for _, layout := range []string{"20060102T150405", icalDate} {
+		if t, err := time.ParseInLocation(layout, p.value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%s: invalid date-time %q", p.name, p.value)
+}
@@ -0,0 +1,35 @@
+package taskio
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"lab01/taskmanager"
+)
+
+// jsonCodec writes an array of tasks encoded as taskmanager.Task is
+type jsonCodec struct{}
+
+func (jsonCodec) encode(w io.Writer, tasks []taskmanager.Task) error {
+	if tasks == nil {
+		tasks = []taskmanager.Task{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(tasks)
+}
+
+func (jsonCodec) decode(r io.Reader) ([]row, error) {
+	// Decode the array first, so that a bad task only fails its own row
+	var items []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&items); err != nil {
+		return nil, fmt.Errorf("reading JSON: expected an array of tasks: %w", err)
+	}
+	rows := make([]row, len(items))
+	for i, item := range items {
+		rows[i].line = i + 1
+		rows[i].err = json.Unmarshal(item, &rows[i].task)
+	}
+	return rows, nil
+}
@@ -0,0 +1,243 @@
+// Package taskio imports and exports tasks as JSON, CSV, iCalendar VTODO
+// and todo.txt
+package taskio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"lab01/taskmanager"
+)
+
+// ErrUnknownFormat is returned for a format taskio does not support
+var ErrUnknownFormat = errors.New("unknown format")
+
+// Format is a file format for tasks. Every format keeps the title, done
+// status, priority, due time, tags, subtasks and recurrence, with these
+// exceptions:
+//
+//   - JSON keeps every field
+//   - CSV cannot tell a tag containing a comma from two tags
+//   - iCalendar keeps times in UTC, and priorities as 1, 5 and 9
+//   - todo.txt has no description, keeps creation dates but not times and
+//     only for pending tasks, collapses spaces in titles and writes those in
+//     tags as "_"; words of a title such as +word or due:date are read back
+//     as a tag or a field
+//
+// IDs and versions are never kept: imported tasks get new IDs, and a
+// subtask is attached to its imported parent.
+type Format string
+
+const (
+	JSON    Format = "json"
+	CSV     Format = "csv"
+	ICal    Format = "ical"
+	TodoTxt Format = "todotxt"
+)
+
+// Formats lists the supported formats
+var Formats = []Format{JSON, CSV, ICal, TodoTxt}
+
+// ParseFormat reads a format name, e.g. "csv", or "ics" and "ical" for
+// iCalendar
+func ParseFormat(name string) (Format, error) {
+	switch name = strings.ToLower(strings.TrimSpace(name)); name {
+	case "ics", "ical", "icalendar":
+		return ICal, nil
+	case "txt", "todo.txt", "todotxt":
+		return TodoTxt, nil
+	}
+	if f := Format(name); slices.Contains(Formats, f) {
+		return f, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownFormat, name)
+}
+
+// FormatOf guesses the format of a file from its extension: .json, .csv,
+// .ics or .txt
+func FormatOf(path string) (Format, error) {
+	ext := strings.TrimPrefix(filepath.Ext(path), ".")
+	if ext == "" {
+		return "", fmt.Errorf("%w: %s has no extension", ErrUnknownFormat, path)
+	}
+	return ParseFormat(ext)
+}
+
+// codec reads and writes one format
+type codec interface {
+	encode(w io.Writer, tasks []taskmanager.Task) error
+	// decode reads every row of the input; an error in a row is kept in
+	// the row, while an error returned stops the import
+	decode(r io.Reader) ([]row, error)
+}
+
+// row is a task read from the input. Its ID and ParentID are those of the
+// input, if any.
+type row struct {
+	line int
+	task taskmanager.Task
+	err  error
+}
+
+func codecOf(f Format) (codec, error) {
+	switch f {
+	case JSON:
+		return jsonCodec{}, nil
+	case CSV:
+		return csvCodec{}, nil
+	case ICal:
+		return icalCodec{}, nil
+	case TodoTxt:
+		return todoTxtCodec{}, nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, f)
+}
+
+// Export writes tasks in the format. Parents should come before their
+// subtasks, as they do in ListTasks.
+func Export(w io.Writer, f Format, tasks []taskmanager.Task) error {
+	c, err := codecOf(f)
+	if err != nil {
+		return err
+	}
+	return c.encode(w, tasks)
+}
+
+// RowError is an error importing one task; the other tasks are imported
+type RowError struct {
+	// Row is where the task is in the input: its line in CSV, todo.txt and
+	// iCalendar, where the line is that of BEGIN:VTODO, and its 1-based
+	// index in JSON
+	Row int
+	Err error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("row %d: %v", e.Row, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// Options change how Import works
+type Options struct {
+	// DryRun checks the input without changing the task manager. The
+	// report is what importing would do, with the tasks created in a
+	// scratch manager.
+	DryRun bool
+}
+
+// Report is the outcome of an import
+type Report struct {
+	// Imported are the tasks created, in the order of the input when
+	// parents come before their subtasks
+	Imported []taskmanager.Task
+	// Errors are the rows that were not imported, ordered by row
+	Errors []*RowError
+}
+
+// Target is where Import creates tasks, e.g. a *taskmanager.TaskManager or
+// an *httpapi.Client
+type Target interface {
+	CreateTask(task taskmanager.Task) (taskmanager.Task, error)
+}
+
+// Import reads tasks in the format and creates them in tm. A row that
+// cannot be read or is invalid, e.g. without a title, is reported in the
+// Report and skipped, as are the subtasks of a skipped task; the error
+// returned is for input that cannot be read at all.
+func Import(tm Target, r io.Reader, f Format, opts Options) (Report, error) {
+	c, err := codecOf(f)
+	if err != nil {
+		return Report{}, err
+	}
+	rows, err := c.decode(r)
+	if err != nil {
+		return Report{}, err
+	}
+	if opts.DryRun {
+		scratch := taskmanager.NewTaskManager()
+		defer scratch.Close()
+		tm = scratch
+	}
+
+	var report Report
+	fail := func(rw *row, err error) {
+		report.Errors = append(report.Errors, &RowError{Row: rw.line, Err: err})
+	}
+
+	// Map the input's IDs to rows, so that subtasks can find their parent,
+	// and keep those of the rows skipped
+	byID := make(map[int]*row)
+	skipped := make(map[int]bool)
+	var pending []*row
+	for i := range rows {
+		rw := &rows[i]
+		if rw.err != nil {
+			fail(rw, rw.err)
+			skipped[rw.task.ID] = true
+			continue
+		}
+		if id := rw.task.ID; id != 0 {
+			if byID[id] != nil {
+				fail(rw, fmt.Errorf("duplicate task ID %d", id))
+				continue
+			}
+			byID[id] = rw
+		}
+		pending = append(pending, rw)
+	}
+
+	// Create the tasks whose parent is created, until none is left or the
+	// rest are waiting on each other
+	created := make(map[int]int)
+	for len(pending) > 0 {
+		var waiting []*row
+		for _, rw := range pending {
+			task := rw.task
+			if parent := task.ParentID; parent != 0 {
+				if skipped[parent] {
+					fail(rw, fmt.Errorf("%w: task %d was not imported", taskmanager.ErrInvalidParent, parent))
+					skipped[rw.task.ID] = true
+					continue
+				}
+				if byID[parent] == nil {
+					fail(rw, fmt.Errorf("%w: task %d is not in the input", taskmanager.ErrInvalidParent, parent))
+					skipped[rw.task.ID] = true
+					continue
+				}
+				newID, ok := created[parent]
+				if !ok {
+					waiting = append(waiting, rw)
+					continue
+				}
+				task.ParentID = newID
+			}
+			task, err := tm.CreateTask(task)
+			if err != nil {
+				fail(rw, err)
+				skipped[rw.task.ID] = true
+				continue
+			}
+			if rw.task.ID != 0 {
+				created[rw.task.ID] = task.ID
+			}
+			report.Imported = append(report.Imported, task)
+		}
+		if len(waiting) == len(pending) {
+			for _, rw := range waiting {
+				fail(rw, fmt.Errorf("%w: task %d is in a cycle of subtasks", taskmanager.ErrInvalidParent, rw.task.ID))
+			}
+			break
+		}
+		pending = waiting
+	}
+
+	slices.SortStableFunc(report.Errors, func(a, b *RowError) int { return a.Row - b.Row })
+	return report, nil
+}
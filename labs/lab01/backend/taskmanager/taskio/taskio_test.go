@@ -0,0 +1,313 @@
+package taskio
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"lab01/taskmanager"
+)
+
+var testNow = time.Date(2025, 6, 2, 8, 0, 0, 0, time.UTC)
+
+func newTestManager(t *testing.T) *taskmanager.TaskManager {
+	t.Helper()
+	tm := taskmanager.NewTaskManager()
+	tm.SetClock(func() time.Time { return testNow })
+	t.Cleanup(func() { tm.Close() })
+	return tm
+}
+
+// sampleTasks creates tasks using every field of the model
+func sampleTasks(t *testing.T) []taskmanager.Task {
+	t.Helper()
+	tm := newTestManager(t)
+	for _, task := range []taskmanager.Task{
+		{
+			Title:       "Drink water",
+			Description: "Two litres; more, if it's hot\nand a \\ backslash",
+			Priority:    taskmanager.PriorityHigh,
+			Tags:        []string{"health", "hydration"},
+			Due:         time.Date(2025, 6, 2, 18, 30, 0, 0, time.UTC),
+			Recurrence:  taskmanager.Daily(),
+		},
+		{Title: "Gym", Priority: taskmanager.PriorityMedium, Recurrence: taskmanager.Weekly(time.Monday, time.Friday), Due: time.Date(2025, 6, 6, 7, 0, 0, 0, time.UTC)},
+		{Title: "Fill the bottle", ParentID: 1, Done: true, Priority: taskmanager.PriorityLow},
+		{Title: "Stretch", ParentID: 2, Recurrence: taskmanager.EveryNDays(3)},
+		{Title: "Очень длинное название задачи, чтобы строка iCalendar была свёрнута"},
+	} {
+		if _, err := tm.CreateTask(task); err != nil {
+			t.Fatalf("CreateTask(%q) failed: %v", task.Title, err)
+		}
+	}
+	tasks, err := tm.ListTasks(taskmanager.Query{})
+	if err != nil {
+		t.Fatalf("ListTasks failed: %v", err)
+	}
+	return tasks
+}
+
+func TestRoundTrip(t *testing.T) {
+	original := sampleTasks(t)
+	for _, f := range Formats {
+		t.Run(string(f), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Export(&buf, f, original); err != nil {
+				t.Fatalf("Export failed: %v", err)
+			}
+			tm := newTestManager(t)
+			// Shift the IDs, so that subtasks must be linked to the new ones
+			tm.AddTask("Already there", "")
+			report, err := Import(tm, bytes.NewReader(buf.Bytes()), f, Options{})
+			if err != nil {
+				t.Fatalf("Import failed: %v\n%s", err, buf.String())
+			}
+			if len(report.Errors) != 0 || len(report.Imported) != len(original) {
+				t.Fatalf("Imported %d tasks with errors %v, want %d\n%s", len(report.Imported), report.Errors, len(original), buf.String())
+			}
+
+			for i, want := range original {
+				got := report.Imported[i]
+				if got.ID != want.ID+1 || got.ParentID != 0 && got.ParentID != want.ParentID+1 || got.ParentID == 0 && want.ParentID != 0 {
+					t.Errorf("%q: got ID %d and parent %d, want %d and %d", want.Title, got.ID, got.ParentID, want.ID+1, want.ParentID+1)
+				}
+				got.ID, got.ParentID, got.Version = want.ID, want.ParentID, want.Version
+				if f == TodoTxt {
+					// Descriptions and the creation time of day are lost
+					want.Description = ""
+					if y, m, d := want.CreatedAt.Local().Date(); !want.Done {
+						want.CreatedAt = time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+					}
+				}
+				if !got.CreatedAt.Equal(want.CreatedAt) || !got.Due.Equal(want.Due) {
+					t.Errorf("%q: got created %v and due %v, want %v and %v", want.Title, got.CreatedAt, got.Due, want.CreatedAt, want.Due)
+				}
+				got.CreatedAt, got.Due = want.CreatedAt, want.Due
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("Round trip changed the task:\ngot  %+v\nwant %+v", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestImportRowErrors(t *testing.T) {
+	input := `id,title,done,priority,due,parent_id
+1,Water,false,high,2025-06-02,
+2,,false,,,
+3,Sleep,maybe,,,
+4,Read,false,urgent,,
+5,Chapter 1,false,,,4
+6,Chapter 2,false,,,1
+7,Orphan,false,,,42
+1,Duplicate,false,,,
+8,"Unclosed,false,,,
+`
+	tm := newTestManager(t)
+	report, err := Import(tm, strings.NewReader(input), CSV, Options{})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	expected := []struct {
+		row int
+		err error
+		msg string
+	}{
+		{3, taskmanager.ErrEmptyTitle, "title cannot be empty"},
+		{4, nil, "done"},
+		{5, taskmanager.ErrInvalidPriority, "urgent"},
+		{6, taskmanager.ErrInvalidParent, "task 4 was not imported"},
+		{8, taskmanager.ErrInvalidParent, "task 42 is not in the input"},
+		{9, nil, "duplicate task ID 1"},
+		{10, nil, "quote"},
+	}
+	if len(report.Errors) != len(expected) {
+		t.Fatalf("Expected %d row errors, got %v", len(expected), report.Errors)
+	}
+	for i, want := range expected {
+		got := report.Errors[i]
+		if got.Row != want.row || (want.err != nil && !errors.Is(got, want.err)) || !strings.Contains(got.Error(), want.msg) {
+			t.Errorf("Error %d = %v, want row %d mentioning %q", i, got, want.row, want.msg)
+		}
+	}
+
+	var titles []string
+	for _, task := range report.Imported {
+		titles = append(titles, task.Title)
+	}
+	if strings.Join(titles, ",") != "Water,Chapter 2" || report.Imported[1].ParentID != report.Imported[0].ID {
+		t.Errorf("Unexpected tasks imported: %+v", report.Imported)
+	}
+}
+
+func TestImportDryRun(t *testing.T) {
+	tm := newTestManager(t)
+	input := "(A) Call mom +family\nx Pay bills\nparent:9 Orphan\n"
+	report, err := Import(tm, strings.NewReader(input), TodoTxt, Options{DryRun: true})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if len(report.Imported) != 2 || len(report.Errors) != 1 || report.Errors[0].Row != 3 {
+		t.Errorf("Unexpected dry run report: %+v, errors %v", report.Imported, report.Errors)
+	}
+	if tasks, _ := tm.ListTasks(taskmanager.Query{}); len(tasks) != 0 {
+		t.Errorf("Expected a dry run to create nothing, got %v", tasks)
+	}
+}
+
+func TestImportICal(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Skip("time zone database not available")
+	}
+	input := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Other app//EN",
+		"BEGIN:VEVENT",
+		"SUMMARY:Not a task",
+		"END:VEVENT",
+		"BEGIN:VTODO",
+		"UID:abc-123",
+		"SUMMARY:Renew pass",
+		"port",
+		"DESCRIPTION:Bring photos\\, form\\nand fee",
+		"DUE;TZID=Europe/Moscow:20250610T090000",
+		"PRIORITY:3",
+		"CATEGORIES:admin,Travel",
+		"CATEGORIES:urgent",
+		"RRULE:FREQ=WEEKLY",
+		"BEGIN:VALARM",
+		"ACTION:DISPLAY",
+		"END:VALARM",
+		"END:VTODO",
+		"BEGIN:VTODO",
+		"UID:def-456",
+		"SUMMARY:Photos",
+		"RELATED-TO:abc-123",
+		"COMPLETED:20250601T100000Z",
+		"DUE;VALUE=DATE:20250605",
+		"END:VTODO",
+		"BEGIN:VTODO",
+		"UID:ghi-789",
+		"SUMMARY:Every month",
+		"RRULE:FREQ=MONTHLY",
+		"END:VTODO",
+		"END:VCALENDAR",
+	}, "\r\n")
+	// Fold the summary of the first task the way RFC 5545 does
+	input = strings.Replace(input, "pass\r\nport", "pass\r\n port", 1)
+
+	tm := newTestManager(t)
+	report, err := Import(tm, strings.NewReader(input), ICal, Options{})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if len(report.Errors) != 1 || report.Errors[0].Row != 28 || !errors.Is(report.Errors[0], taskmanager.ErrInvalidRecurrence) {
+		t.Errorf("Expected an unsupported RRULE on row 28, got %v", report.Errors)
+	}
+	if len(report.Imported) != 2 {
+		t.Fatalf("Expected 2 tasks imported, got %+v", report.Imported)
+	}
+
+	pass, photos := report.Imported[0], report.Imported[1]
+	if pass.Title != "Renew passport" || pass.Description != "Bring photos, form\nand fee" || pass.Priority != taskmanager.PriorityHigh {
+		t.Errorf("Unexpected task: %+v", pass)
+	}
+	if want := time.Date(2025, 6, 10, 9, 0, 0, 0, moscow); !pass.Due.Equal(want) {
+		t.Errorf("Due = %v, want %v", pass.Due, want)
+	}
+	if !reflect.DeepEqual(pass.Tags, []string{"admin", "travel", "urgent"}) {
+		t.Errorf("Tags = %v", pass.Tags)
+	}
+	if pass.Recurrence == nil || !reflect.DeepEqual(pass.Recurrence.Weekdays, []time.Weekday{time.Tuesday}) {
+		t.Errorf("Expected weekly on the due weekday, got %+v", pass.Recurrence)
+	}
+	if !photos.Done || photos.ParentID != pass.ID || !photos.Due.Equal(time.Date(2025, 6, 5, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("Unexpected subtask: %+v", photos)
+	}
+}
+
+func TestImportTodoTxt(t *testing.T) {
+	input := "(B) 2025-06-01 Call mom @phone +family due:2025-06-03 rec:+1w at 10:30\n" +
+		"\n" +
+		"x 2025-06-02 2025-05-30 Pay bills pri:A\n" +
+		"Water plants due:tomorrow\n"
+	tm := newTestManager(t)
+	report, err := Import(tm, strings.NewReader(input), TodoTxt, Options{})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if len(report.Errors) != 1 || report.Errors[0].Row != 4 {
+		t.Errorf("Expected an error on row 4, got %v", report.Errors)
+	}
+	if len(report.Imported) != 2 {
+		t.Fatalf("Expected 2 tasks imported, got %+v", report.Imported)
+	}
+
+	call, bills := report.Imported[0], report.Imported[1]
+	if call.Title != "Call mom at 10:30" || call.Priority != taskmanager.PriorityMedium || !reflect.DeepEqual(call.Tags, []string{"family", "phone"}) {
+		t.Errorf("Unexpected task: %+v", call)
+	}
+	if want := time.Date(2025, 6, 1, 0, 0, 0, 0, time.Local); !call.CreatedAt.Equal(want) {
+		t.Errorf("CreatedAt = %v, want %v", call.CreatedAt, want)
+	}
+	if call.Recurrence == nil || call.Recurrence.String() != "weekly:tue" {
+		t.Errorf("Expected weekly on the due weekday, got %+v", call.Recurrence)
+	}
+	if bills.Title != "Pay bills" || !bills.Done || bills.Priority != taskmanager.PriorityHigh ||
+		!bills.CreatedAt.Equal(time.Date(2025, 5, 30, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("Unexpected task: %+v", bills)
+	}
+}
+
+func TestExportICalFolding(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Export(&buf, ICal, sampleTasks(t)); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	for i, line := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+		if len(line) > icalLineLimit {
+			t.Errorf("Line %d is %d octets long: %q", i+1, len(line), line)
+		}
+		if !utf8.ValidString(line) {
+			t.Errorf("Line %d splits a character: %q", i+1, line)
+		}
+	}
+	if !strings.Contains(buf.String(), `DESCRIPTION:Two litres\; more\, if it's hot\nand a \\ backslash`) {
+		t.Errorf("Expected an escaped description in:\n%s", buf.String())
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Format
+	}{
+		{"json", JSON},
+		{"CSV", CSV},
+		{"ics", ICal},
+		{"ical", ICal},
+		{"todo.txt", TodoTxt},
+		{"txt", TodoTxt},
+	}
+	for _, tt := range tests {
+		if got, err := ParseFormat(tt.input); err != nil || got != tt.expected {
+			t.Errorf("ParseFormat(%q) = %q, %v, want %q", tt.input, got, err, tt.expected)
+		}
+	}
+	if _, err := ParseFormat("xml"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Expected ErrUnknownFormat, got %v", err)
+	}
+	if got, err := FormatOf("backup/tasks.ICS"); err != nil || got != ICal {
+		t.Errorf("FormatOf(tasks.ICS) = %q, %v, want ical", got, err)
+	}
+	if _, err := FormatOf("tasks"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Expected ErrUnknownFormat for a file without extension, got %v", err)
+	}
+}
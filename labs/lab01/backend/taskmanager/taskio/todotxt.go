@@ -0,0 +1,214 @@
+package taskio
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"lab01/taskmanager"
+)
+
+// todoTxtCodec writes a line per task in the todo.txt format:
+//
+//	(A) 2025-06-02 Drink water +health due:2025-06-02 rec:daily id:1
+//	x Fill the bottle +health parent:1 pri:B
+//
+// Tags are +projects, and @contexts are read as tags too. Due dates, the
+// recurrence, the ID and the parent are key:value pairs.
+type todoTxtCodec struct{}
+
+// todoTxtPriorities are the letters of the priorities, from high to low
+var todoTxtPriorities = map[taskmanager.Priority]string{
+	taskmanager.PriorityHigh:   "A",
+	taskmanager.PriorityMedium: "B",
+	taskmanager.PriorityLow:    "C",
+}
+
+func (todoTxtCodec) encode(w io.Writer, tasks []taskmanager.Task) error {
+	bw := bufio.NewWriter(w)
+	for _, task := range tasks {
+		bw.WriteString(todoTxtLine(task))
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+func todoTxtLine(task taskmanager.Task) string {
+	var words []string
+	letter := todoTxtPriorities[task.Priority]
+	if task.Done {
+		// A completion date would have to come before the creation date,
+		// and tasks do not record when they were completed
+		words = append(words, "x")
+	} else {
+		if letter != "" {
+			words = append(words, "("+letter+")")
+		}
+		if !task.CreatedAt.IsZero() {
+			words = append(words, task.CreatedAt.Local().Format(time.DateOnly))
+		}
+	}
+	words = append(words, strings.Fields(task.Title)...)
+	for _, tag := range task.Tags {
+		words = append(words, "+"+strings.Join(strings.Fields(tag), "_"))
+	}
+	if !task.Due.IsZero() {
+		words = append(words, "due:"+todoTxtTime(task.Due))
+	}
+	if task.Recurrence != nil {
+		words = append(words, "rec:"+task.Recurrence.String())
+	}
+	if task.ID != 0 {
+		words = append(words, "id:"+strconv.Itoa(task.ID))
+	}
+	if task.ParentID != 0 {
+		words = append(words, "parent:"+strconv.Itoa(task.ParentID))
+	}
+	if task.Done && letter != "" {
+		words = append(words, "pri:"+letter)
+	}
+	return strings.Join(words, " ")
+}
+
+// todoTxtTime writes a date if t is midnight in local time, and an RFC 3339
+// time otherwise
+func todoTxtTime(t time.Time) string {
+	y, m, d := t.Date()
+	if t.Equal(time.Date(y, m, d, 0, 0, 0, 0, time.Local)) {
+		return t.Format(time.DateOnly)
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+func (todoTxtCodec) decode(r io.Reader) ([]row, error) {
+	var rows []row
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if n == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		task, err := todoTxtTask(line)
+		rows = append(rows, row{line: n, task: task, err: err})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading todo.txt: %w", err)
+	}
+	return rows, nil
+}
+
+// todoTxtTask reads a task from a line
+func todoTxtTask(line string) (taskmanager.Task, error) {
+	var task taskmanager.Task
+	words := strings.Fields(line)
+	isDate := func(i int) bool {
+		if i >= len(words) {
+			return false
+		}
+		_, err := time.Parse(time.DateOnly, words[i])
+		return err == nil
+	}
+
+	// The completion mark, priority and dates come first
+	i := 0
+	if words[0] == "x" {
+		task.Done = true
+		i++
+		// A completion date, then maybe a creation date
+		if isDate(i) {
+			i++
+			if isDate(i) {
+				task.CreatedAt, _ = time.ParseInLocation(time.DateOnly, words[i], time.Local)
+				i++
+			}
+		}
+	} else {
+		if w := words[0]; len(w) == 3 && w[0] == '(' && w[2] == ')' && w[1] >= 'A' && w[1] <= 'Z' {
+			task.Priority = todoTxtPriority(w[1])
+			i++
+		}
+		if isDate(i) {
+			task.CreatedAt, _ = time.ParseInLocation(time.DateOnly, words[i], time.Local)
+			i++
+		}
+	}
+
+	var title []string
+	var rec string
+	for _, w := range words[i:] {
+		if len(w) > 1 && (w[0] == '+' || w[0] == '@') {
+			task.Tags = append(task.Tags, w[1:])
+			continue
+		}
+		key, value, ok := strings.Cut(w, ":")
+		if !ok || value == "" {
+			title = append(title, w)
+			continue
+		}
+		var err error
+		switch key {
+		case "due":
+			task.Due, err = parseTime(value)
+		case "rec":
+			rec = value
+		case "id":
+			task.ID, err = parseID(value)
+		case "parent":
+			task.ParentID, err = parseID(value)
+		case "pri":
+			if len(value) != 1 || value[0] < 'A' || value[0] > 'Z' {
+				err = fmt.Errorf("%w: %q", taskmanager.ErrInvalidPriority, value)
+			}
+			task.Priority = todoTxtPriority(value[0])
+		default:
+			title = append(title, w)
+		}
+		if err != nil {
+			return task, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	task.Title = strings.Join(title, " ")
+	if rec != "" {
+		r, err := parseTodoTxtRecurrence(rec, task.Due)
+		if err != nil {
+			return task, err
+		}
+		task.Recurrence = r
+	}
+	return task, nil
+}
+
+func todoTxtPriority(letter byte) taskmanager.Priority {
+	switch letter {
+	case 'A':
+		return taskmanager.PriorityHigh
+	case 'B':
+		return taskmanager.PriorityMedium
+	}
+	return taskmanager.PriorityLow
+}
+
+// parseTodoTxtRecurrence reads a rule formatted by Recurrence.String, or in
+// the rec: notation of todo.txt tools: "3d" or "+3d" for every 3 days and
+// "1w" for weekly on the day the task is due
+func parseTodoTxtRecurrence(s string, due time.Time) (*taskmanager.Recurrence, error) {
+	short := strings.TrimPrefix(s, "+")
+	if n, err := strconv.Atoi(strings.TrimRight(short, "dw")); err == nil && len(short) > 1 {
+		switch {
+		case strings.HasSuffix(short, "d") && n == 1:
+			return taskmanager.Daily(), nil
+		case strings.HasSuffix(short, "d") && n > 1:
+			return taskmanager.EveryNDays(n), nil
+		case short == "1w" && !due.IsZero():
+			return taskmanager.Weekly(due.Weekday()), nil
+		}
+	}
+	return taskmanager.ParseRecurrence(s)
+}
@@ -2,103 +2,368 @@ package taskmanager
 
 import (
 	"errors"
+	"fmt"
+	"sync"
 	"time"
 )
 
 // Predefined errors
 var (
-	ErrTaskNotFound = errors.New("task not found")
-	ErrEmptyTitle   = errors.New("title cannot be empty")
+	ErrTaskNotFound    = errors.New("task not found")
+	ErrEmptyTitle      = errors.New("title cannot be empty")
+	ErrVersionConflict = errors.New("task was modified by someone else")
+	ErrTaskExists      = errors.New("task already exists")
 )
 
 // Task represents a single task
 type Task struct {
-	ID          int
-	Title       string
-	Description string
-	Done        bool
-	CreatedAt   time.Time
+	ID          int       `json:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Done        bool      `json:"done"`
+	CreatedAt   time.Time `json:"created_at"`
+	// Version starts at 1 and goes up with every update; SaveTask and
+	// UpdateTask only write a task whose Version is still the stored one
+	Version int `json:"version"`
+	// Due is when the task is due; the zero time means no due date
+	Due      time.Time `json:"due,omitzero"`
+	Priority Priority  `json:"priority"`
+	// Tags are kept normalised; see NormalizeTags
+	Tags []string `json:"tags,omitempty"`
+	// ParentID is the task this one is a subtask of, or 0
+	ParentID int `json:"parent_id,omitempty"`
+	// Recurrence makes completing the task create its next occurrence
+	Recurrence *Recurrence `json:"recurrence,omitempty"`
+	// NextOccurrenceID is the occurrence completing this task created, or
+	// 0; completing the task again does not create another one
+	NextOccurrenceID int `json:"next_occurrence_id,omitempty"`
 }
 
-// TaskManager manages a collection of tasks
+// TaskManager manages a collection of tasks kept in a Store. It is safe for
+// concurrent use.
 type TaskManager struct {
-	tasks  map[int]Task
-	nextID int
+	store Store
+	now   func() time.Time
+
+	// mu serialises changes, so that subscribers see events in the order
+	// the changes were made
+	mu   sync.Mutex
+	subs map[*subscriber]struct{}
+
+	// The undo history; see history.go
+	op           *operation
+	undo, redo   []*operation
+	historyLimit int
+	historyErr   error
 }
 
-// NewTaskManager creates a new task manager
+// NewTaskManager creates a new task manager backed by a MemoryStore
 func NewTaskManager() *TaskManager {
-	// TODO: Implement this function
-	return &TaskManager{
-		tasks:  make(map[int]Task),
-		nextID: 1,
+	return NewTaskManagerWithStore(NewMemoryStore())
+}
+
+// NewTaskManagerWithStore creates a task manager that keeps its tasks in
+// store, e.g. a JSONStore or SQLiteStore. A HistoryStore also brings back
+// the history saved by the last manager that used it.
+func NewTaskManagerWithStore(store Store) *TaskManager {
+	tm := &TaskManager{
+		store:        store,
+		now:          time.Now,
+		subs:         make(map[*subscriber]struct{}),
+		historyLimit: DefaultHistoryLimit,
 	}
+	tm.loadHistory()
+	return tm
+}
 
+// SetClock replaces the clock used for creation times and recurrence, e.g.
+// with a fixed time in tests. Call it before using the manager.
+func (tm *TaskManager) SetClock(now func() time.Time) {
+	tm.now = now
 }
 
-// AddTask adds a new task to the manager, returns an error if the title is empty, and increments the nextID
+// AddTask adds a new task to the manager, returns an error if the title is empty; the store assigns the next ID
 func (tm *TaskManager) AddTask(title, description string) (Task, error) {
-	// TODO: Implement this function
-	if title == "" {
-		return Task{}, ErrEmptyTitle
+	return tm.CreateTask(Task{Title: title, Description: description})
+}
+
+// CreateTask adds a task with every field of the model set, e.g. a due
+// date or a parent. ID and Version are assigned, and CreatedAt unless it is
+// set, e.g. by an import; NextOccurrenceID is left unset. The title must not
+// be empty and the parent, priority and recurrence must be valid.
+func (tm *TaskManager) CreateTask(task Task) (Task, error) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	defer tm.record(fmt.Sprintf("create %q", task.Title))()
+	return tm.createTask(task)
+}
+
+// createTask implements CreateTask; tm.mu must be held
+func (tm *TaskManager) createTask(task Task) (Task, error) {
+	task = task.clone()
+	task.ID, task.Version, task.NextOccurrenceID = 0, 0, 0
+	task.Tags = NormalizeTags(task.Tags)
+	if err := tm.validate(task); err != nil {
+		return Task{}, err
 	}
-	tm.tasks[tm.nextID] = Task{
-		ID:          tm.nextID,
-		Title:       title,
-		Description: description,
-		Done:        false,
-		CreatedAt:   time.Now(),
+	if task.CreatedAt.IsZero() {
+		task.CreatedAt = tm.now()
 	}
-	tm.nextID++
-	return tm.tasks[tm.nextID-1], nil
+	return tm.create(task)
 }
 
-// UpdateTask updates an existing task, returns an error if the title is empty or the task is not found
-func (tm *TaskManager) UpdateTask(id int, title, description string, done bool) error {
-	// TODO: Implement this function
+// create stores a new task and publishes it; tm.mu must be held
+func (tm *TaskManager) create(task Task) (Task, error) {
+	task, err := tm.store.Create(task)
+	if err != nil {
+		return Task{}, err
+	}
+	tm.changed(nil, &task)
+	tm.publish(Event{Type: EventCreated, Task: task})
+	return task, nil
+}
+
+// UpdateTask updates an existing task if version is still its stored version,
+// or whatever its version if version is 0. It returns an error if the title is
+// empty or the task is not found, and ErrVersionConflict if the task was
+// changed since version.
+func (tm *TaskManager) UpdateTask(id, version int, title, description string, done bool) error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	defer tm.record(fmt.Sprintf("update %q", title))()
+	return tm.updateTask(id, version, title, description, done)
+}
+
+// updateTask implements UpdateTask; tm.mu must be held
+func (tm *TaskManager) updateTask(id, version int, title, description string, done bool) error {
 	if title == "" {
 		return ErrEmptyTitle
 	}
-	if _, exists := tm.tasks[id]; !exists {
-		return ErrTaskNotFound
+	set := func(task *Task) {
+		task.Title = title
+		task.Description = description
+		task.Done = done
+	}
+	if version == 0 {
+		_, err := tm.modify(id, set)
+		return err
 	}
-	tm.tasks[id] = Task{
-		ID:          id,
-		Title:       title,
-		Description: description,
-		Done:        done,
-		CreatedAt:   tm.tasks[id].CreatedAt,
+	old, err := tm.store.Get(id)
+	if err != nil {
+		return err
+	}
+	if old.Version != version {
+		return ErrVersionConflict
+	}
+	task := old.clone()
+	set(&task)
+	_, err = tm.write(old, task)
+	return err
+}
+
+// SaveTask writes every field of task but its ID, creation time and next
+// occurrence if task.Version is still the stored version, and returns the task with its
+// new version. A task changed since it was read returns ErrVersionConflict;
+// the caller should get it again and reapply its change.
+//
+// Completing a task completes its subtasks, and completing a recurring task
+// creates its next occurrence.
+func (tm *TaskManager) SaveTask(task Task) (Task, error) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	defer tm.record(fmt.Sprintf("update %q", task.Title))()
+	return tm.saveTask(task)
+}
+
+// saveTask implements SaveTask; tm.mu must be held
+func (tm *TaskManager) saveTask(task Task) (Task, error) {
+	task = task.clone()
+	task.Tags = NormalizeTags(task.Tags)
+	stored, err := tm.store.Get(task.ID)
+	if err != nil {
+		return Task{}, err
+	}
+	if stored.Version != task.Version {
+		return Task{}, ErrVersionConflict
+	}
+	if err := tm.validate(task); err != nil {
+		return Task{}, err
+	}
+	task.CreatedAt, task.NextOccurrenceID = stored.CreatedAt, stored.NextOccurrenceID
+	return tm.write(stored, task)
+}
+
+// modify applies fn to the stored task and writes it, retrying when another
+// manager on the same store changes the task in between; tm.mu must be held
+func (tm *TaskManager) modify(id int, fn func(task *Task)) (Task, error) {
+	for {
+		old, err := tm.store.Get(id)
+		if err != nil {
+			return Task{}, err
+		}
+		task := old.clone()
+		fn(&task)
+		task, err = tm.write(old, task)
+		if err != ErrVersionConflict {
+			return task, err
+		}
+	}
+}
+
+// write stores task over old, publishes the change and carries out what
+// completing it implies; tm.mu must be held
+func (tm *TaskManager) write(old, task Task) (Task, error) {
+	if err := tm.store.Update(task); err != nil {
+		return Task{}, err
+	}
+	task.Version++
+	tm.changed(&old, &task)
+	tm.publish(Event{Type: EventUpdated, Task: task})
+	if task.Done && !old.Done {
+		return tm.completed(task)
+	}
+	return task, nil
+}
+
+// completed completes the subtasks of a task that was just completed and
+// creates the next occurrence of a recurring one, unless an earlier
+// completion did and it still exists. It returns the task as stored now;
+// tm.mu must be held.
+func (tm *TaskManager) completed(task Task) (Task, error) {
+	children, err := tm.children(task.ID)
+	if err != nil {
+		return Task{}, err
+	}
+	for _, child := range children {
+		if child.Done {
+			continue
+		}
+		if _, err := tm.modify(child.ID, func(c *Task) { c.Done = true }); err != nil {
+			return Task{}, err
+		}
+	}
+	if task.Recurrence == nil {
+		return task, nil
+	}
+	if task.NextOccurrenceID != 0 {
+		// Reopened and completed again
+		if _, err := tm.store.Get(task.NextOccurrenceID); err == nil {
+			return task, nil
+		} else if err != ErrTaskNotFound {
+			return Task{}, err
+		}
+	}
+	now := tm.now()
+	next := task.clone()
+	next.ID, next.Version, next.Done, next.NextOccurrenceID = 0, 0, false, 0
+	next.CreatedAt = now
+	next.Due = task.Recurrence.nextDue(task.Due, now)
+	next, err = tm.create(next)
+	if err != nil {
+		return Task{}, err
+	}
+	return tm.modify(task.ID, func(t *Task) { t.NextOccurrenceID = next.ID })
+}
+
+// children returns the direct subtasks of a task
+func (tm *TaskManager) children(id int) ([]Task, error) {
+	return tm.ListTasks(Query{ParentID: &id})
+}
+
+// validate checks a task before it is stored; tm.mu must be held
+func (tm *TaskManager) validate(task Task) error {
+	if task.Title == "" {
+		return ErrEmptyTitle
+	}
+	if !task.Priority.valid() {
+		return fmt.Errorf("%w: %d", ErrInvalidPriority, int(task.Priority))
+	}
+	if task.Recurrence != nil {
+		if err := task.Recurrence.Validate(); err != nil {
+			return err
+		}
+	}
+	// Walk up from the parent: it must exist, and the task must not be
+	// among its ancestors
+	for id := task.ParentID; id != 0; {
+		if id < 0 || (task.ID != 0 && id == task.ID) {
+			return fmt.Errorf("%w: task %d cannot be a subtask of %d", ErrInvalidParent, task.ID, task.ParentID)
+		}
+		parent, err := tm.store.Get(id)
+		if err == ErrTaskNotFound {
+			return fmt.Errorf("%w: task %d does not exist", ErrInvalidParent, id)
+		}
+		if err != nil {
+			return err
+		}
+		id = parent.ParentID
 	}
 	return nil
 }
 
-// DeleteTask removes a task from the manager, returns an error if the task is not found
+// DeleteTask removes a task from the manager together with its subtasks, returns an error if the task is not found
 func (tm *TaskManager) DeleteTask(id int) error {
-	// TODO: Implement this function
-	if _, accept := tm.tasks[id]; !accept {
-		return ErrTaskNotFound
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	defer tm.record(fmt.Sprintf("delete task %d", id))()
+	return tm.deleteTask(id)
+}
+
+// deleteTask implements DeleteTask; tm.mu must be held
+func (tm *TaskManager) deleteTask(id int) error {
+	task, err := tm.store.Get(id)
+	if err != nil {
+		return err
+	}
+	return tm.remove(task)
+}
+
+// remove deletes a task after its subtasks; tm.mu must be held
+func (tm *TaskManager) remove(task Task) error {
+	children, err := tm.children(task.ID)
+	if err != nil {
+		return err
 	}
-	delete(tm.tasks, id)
+	for _, child := range children {
+		if err := tm.remove(child); err != nil {
+			return err
+		}
+	}
+	if err := tm.store.Delete(task.ID); err != nil {
+		return err
+	}
+	tm.changed(&task, nil)
+	tm.publish(Event{Type: EventDeleted, Task: task})
 	return nil
 }
 
 // GetTask retrieves a task by ID, returns an error if the task is not found
 func (tm *TaskManager) GetTask(id int) (Task, error) {
-	// TODO: Implement this function
-	if _, exists := tm.tasks[id]; !exists {
-		return Task{}, ErrTaskNotFound
-	}
-	return tm.tasks[id], nil
+	return tm.store.Get(id)
 }
 
-// ListTasks returns all tasks, optionally filtered by done status, returns an empty slice if no tasks are found
-func (tm *TaskManager) ListTasks(filterDone *bool) []Task {
-	// TODO: Implement this function
+// ListTasks returns the tasks matching the query ordered by ID, returns an empty slice if no tasks are found
+func (tm *TaskManager) ListTasks(q Query) ([]Task, error) {
+	tasks, err := tm.store.List()
+	if err != nil {
+		return nil, err
+	}
 	filteredTasks := []Task{}
-	for _, task := range tm.tasks {
-		if filterDone == nil || *filterDone == task.Done {
+	for _, task := range tasks {
+		if q.Matches(task) {
 			filteredTasks = append(filteredTasks, task)
 		}
 	}
-	return filteredTasks
+	return filteredTasks, nil
+}
+
+// Close ends every subscription and closes the underlying store. It also
+// returns the error of the last failed attempt to save the history.
+func (tm *TaskManager) Close() error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	for s := range tm.subs {
+		tm.end(s, true)
+	}
+	return errors.Join(tm.historyErr, tm.store.Close())
 }
@@ -9,11 +9,15 @@ func TestNewTaskManager(t *testing.T) {
 	if tm == nil {
 		t.Error("NewTaskManager() returned nil")
 	}
-	if tm.tasks == nil {
-		t.Error("tasks map is nil")
+	if tm.store == nil {
+		t.Error("store is nil")
 	}
-	if tm.nextID != 1 {
-		t.Errorf("Expected nextID to be 1, got %d", tm.nextID)
+	task, err := tm.AddTask("First", "")
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	if task.ID != 1 {
+		t.Errorf("Expected first ID to be 1, got %d", task.ID)
 	}
 }
 
@@ -134,6 +138,7 @@ func TestUpdateTask(t *testing.T) {
 	tests := []struct {
 		name        string
 		id          int
+		version     int
 		title       string
 		description string
 		done        bool
@@ -142,6 +147,7 @@ func TestUpdateTask(t *testing.T) {
 		{
 			name:        "valid update",
 			id:          task.ID,
+			version:     task.Version,
 			title:       "Updated Task",
 			description: "Updated Description",
 			done:        true,
@@ -163,11 +169,28 @@ func TestUpdateTask(t *testing.T) {
 			done:        true,
 			expectError: true,
 		},
+		{
+			name:        "stale version",
+			id:          task.ID,
+			version:     task.Version,
+			title:       "Stale Task",
+			description: "Stale Description",
+			done:        false,
+			expectError: true,
+		},
+		{
+			name:        "any version",
+			id:          task.ID,
+			title:       "Updated Again",
+			description: "Updated Description",
+			done:        true,
+			expectError: false,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := tm.UpdateTask(tt.id, tt.title, tt.description, tt.done)
+			err := tm.UpdateTask(tt.id, tt.version, tt.title, tt.description, tt.done)
 
 			if tt.expectError {
 				if err == nil {
@@ -262,7 +285,7 @@ func TestListTasks(t *testing.T) {
 	_, _ = tm.AddTask("Task 3", "Description 3")
 
 	// Mark one task as done
-	tm.UpdateTask(task2.ID, task2.Title, task2.Description, true)
+	tm.UpdateTask(task2.ID, task2.Version, task2.Title, task2.Description, true)
 
 	tests := []struct {
 		name     string
@@ -288,7 +311,10 @@ func TestListTasks(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			tasks := tm.ListTasks(tt.filter)
+			tasks, err := tm.ListTasks(Query{Done: tt.filter})
+			if err != nil {
+				t.Fatalf("ListTasks() failed: %v", err)
+			}
 			if len(tasks) != tt.expected {
 				t.Errorf("ListTasks() returned %d tasks, want %d", len(tasks), tt.expected)
 			}
@@ -0,0 +1,232 @@
+package user
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// Codes of a FieldError
+const (
+	CodeRequired          = "required"
+	CodeTooShort          = "too_short"
+	CodeTooLong           = "too_long"
+	CodeOutOfRange        = "out_of_range"
+	CodeInvalidCharacters = "invalid_characters"
+	CodeInvalidFormat     = "invalid_format"
+)
+
+// Rules are the limits the fields of a user must keep to. Lengths of names
+// count characters, not bytes, so "Александр Пушкин" is 16 long. Packages
+// with other users, e.g. names of 2 to 50 characters and no age, can use
+// their own Rules and the checks of the fields they have.
+type Rules struct {
+	NameMinLength int
+	NameMaxLength int
+	AgeMin        int
+	AgeMax        int
+	// EmailMaxLength is in bytes of the ASCII form; RFC 5321 allows 254
+	EmailMaxLength int
+}
+
+// DefaultRules returns the rules of User.Validate and NewUser. Each call
+// returns a new copy, so callers may adjust it without affecting others.
+func DefaultRules() Rules {
+	return Rules{
+		NameMinLength:  1,
+		NameMaxLength:  30,
+		AgeMin:         0,
+		AgeMax:         150,
+		EmailMaxLength: 254,
+	}
+}
+
+// FieldError is a rule broken by a field. It unwraps to the error of the
+// field, e.g. ErrInvalidName.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	err     error
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.err
+}
+
+// ValidationError lists the broken rules of a user, one per field in the
+// order name, age, email. errors.Is matches the error of each field.
+type ValidationError struct {
+	Errors []*FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Field returns the error of a field, or nil if it is valid
+func (e *ValidationError) Field(name string) *FieldError {
+	for _, err := range e.Errors {
+		if err.Field == name {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks every field of u, returns a *ValidationError listing the broken rules or nil
+func (r Rules) Validate(u *User) error {
+	var errs []*FieldError
+	for _, err := range []*FieldError{r.CheckName(u.Name), r.CheckAge(u.Age), r.CheckEmail(u.Email)} {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: errs}
+}
+
+// CheckName returns the rule a name breaks, or nil
+func (r Rules) CheckName(name string) *FieldError {
+	fail := func(code, format string, args ...any) *FieldError {
+		return &FieldError{Field: "name", Code: code, Message: fmt.Sprintf(format, args...), err: ErrInvalidName}
+	}
+	n := utf8.RuneCountInString(name)
+	switch {
+	case strings.TrimSpace(name) == "":
+		return fail(CodeRequired, "is required")
+	case !utf8.ValidString(name) || strings.ContainsFunc(name, unicode.IsControl):
+		return fail(CodeInvalidCharacters, "must not contain control characters")
+	case n < r.NameMinLength:
+		return fail(CodeTooShort, "must be at least %d characters", r.NameMinLength)
+	case n > r.NameMaxLength:
+		return fail(CodeTooLong, "must be at most %d characters", r.NameMaxLength)
+	}
+	return nil
+}
+
+// CheckAge returns the rule an age breaks, or nil
+func (r Rules) CheckAge(age int) *FieldError {
+	if age < r.AgeMin || age > r.AgeMax {
+		return &FieldError{
+			Field:   "age",
+			Code:    CodeOutOfRange,
+			Message: fmt.Sprintf("must be between %d and %d", r.AgeMin, r.AgeMax),
+			err:     ErrInvalidAge,
+		}
+	}
+	return nil
+}
+
+// emailDomain checks domains as DNS resolves them: labels of letters,
+// digits and hyphens once in ASCII form, of at most 63 bytes each
+var emailDomain = idna.New(
+	idna.MapForLookup(),
+	idna.BidiRule(),
+	idna.StrictDomainName(true),
+	idna.VerifyDNSLength(true),
+)
+
+// CheckEmail returns the rule an email address breaks, or nil. The local
+// part is a dot-atom of RFC 5322, i.e. no quoted strings or comments, of
+// at most 64 bytes. The domain may be internationalised, e.g. пример.рф,
+// and needs a top-level domain.
+func (r Rules) CheckEmail(email string) *FieldError {
+	fail := func(code, format string, args ...any) *FieldError {
+		return &FieldError{Field: "email", Code: code, Message: fmt.Sprintf(format, args...), err: ErrInvalidEmail}
+	}
+	if strings.TrimSpace(email) == "" {
+		return fail(CodeRequired, "is required")
+	}
+	at := strings.LastIndexByte(email, '@')
+	if at < 0 {
+		return fail(CodeInvalidFormat, "must look like name@example.com")
+	}
+	local, domain := email[:at], email[at+1:]
+	if !isDotAtom(local) || len(local) > 64 {
+		return fail(CodeInvalidFormat, "has an invalid part before @")
+	}
+	ascii, err := emailDomain.ToASCII(domain)
+	if err != nil || !hasTopLevelDomain(ascii) {
+		return fail(CodeInvalidFormat, "has an invalid domain")
+	}
+	if len(local)+1+len(ascii) > r.EmailMaxLength {
+		return fail(CodeTooLong, "must be at most %d characters", r.EmailMaxLength)
+	}
+	return nil
+}
+
+// isDotAtom reports whether s is atoms of RFC 5322 atext joined by single dots
+func isDotAtom(s string) bool {
+	for _, atom := range strings.Split(s, ".") {
+		if atom == "" {
+			return false
+		}
+		for _, c := range []byte(atom) {
+			if !isAtext(c) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func isAtext(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$%&'*+-/=?^_`{|}~", c) >= 0
+}
+
+// hasTopLevelDomain reports whether an ASCII domain has at least two labels
+// and ends in letters or an internationalised top-level domain
+func hasTopLevelDomain(domain string) bool {
+	dot := strings.LastIndexByte(domain, '.')
+	if dot <= 0 {
+		return false
+	}
+	tld := domain[dot+1:]
+	if strings.HasPrefix(tld, "xn--") {
+		return true
+	}
+	return len(tld) >= 2 && !strings.ContainsFunc(tld, func(c rune) bool { return !unicode.IsLetter(c) })
+}
+
+// NormalizeName trims a name and collapses the spaces inside it
+func NormalizeName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// NormalizeEmail trims an email address and case-folds it, keeping an
+// internationalised domain in Unicode
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Normalize normalises the name and email of the user in place
+func (u *User) Normalize() {
+	u.Name = NormalizeName(u.Name)
+	u.Email = NormalizeEmail(u.Email)
+}
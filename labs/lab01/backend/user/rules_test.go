@@ -0,0 +1,147 @@
+package user
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheckName(t *testing.T) {
+	tests := []struct {
+		name     string
+		userName string
+		code     string
+	}{
+		{"ascii", "John Doe", ""},
+		{"cyrillic over 15 characters", "Александр Пушкин", ""},
+		{"cyrillic at the limit", strings.Repeat("ж", 30), ""},
+		{"too long", strings.Repeat("ж", 31), CodeTooLong},
+		{"empty", "", CodeRequired},
+		{"only spaces", "   ", CodeRequired},
+		{"control character", "John\nDoe", CodeInvalidCharacters},
+		{"invalid utf-8", "John\xffDoe", CodeInvalidCharacters},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := DefaultRules().CheckName(tt.userName)
+			if got := code(err); got != tt.code {
+				t.Errorf("CheckName(%q) = %v, want code %q", tt.userName, err, tt.code)
+			}
+		})
+	}
+}
+
+func TestCheckEmail(t *testing.T) {
+	tests := []struct {
+		email string
+		code  string
+	}{
+		{"john@example.com", ""},
+		{"john.doe+tag@mail.example.co.uk", ""},
+		{"o'brien!#$%&*/=?^_`{|}~-@example.com", ""},
+		{"John@Example.COM", ""},
+		{"ivan@пример.рф", ""},
+		{"ivan@xn--e1afmkfd.xn--p1ai", ""},
+		{"", CodeRequired},
+		{"johnnotvalid", CodeInvalidFormat},
+		{"invalid-email@", CodeInvalidFormat},
+		{"john@notvalid", CodeInvalidFormat},
+		{"john@example.123", CodeInvalidFormat},
+		{"john@example.com.", CodeInvalidFormat},
+		{"john@-example.com", CodeInvalidFormat},
+		{"john@exa_mple.com", CodeInvalidFormat},
+		{"john@" + strings.Repeat("a", 64) + ".com", CodeInvalidFormat},
+		{"john..doe@example.com", CodeInvalidFormat},
+		{".john@example.com", CodeInvalidFormat},
+		{"john doe@example.com", CodeInvalidFormat},
+		{`"john"@example.com`, CodeInvalidFormat},
+		{"a@b@example.com", CodeInvalidFormat},
+		{"иван@example.com", CodeInvalidFormat},
+		{strings.Repeat("a", 65) + "@example.com", CodeInvalidFormat},
+		{strings.Repeat("a", 64) + "@" + strings.Repeat(strings.Repeat("b", 63)+".", 3) + "com", CodeTooLong},
+	}
+	for _, tt := range tests {
+		err := DefaultRules().CheckEmail(tt.email)
+		if got := code(err); got != tt.code {
+			t.Errorf("CheckEmail(%q) = %v, want code %q", tt.email, err, tt.code)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidEmail) {
+			t.Errorf("CheckEmail(%q) = %v, want it to wrap ErrInvalidEmail", tt.email, err)
+		}
+	}
+}
+
+func TestValidateCollectsEveryField(t *testing.T) {
+	u := &User{Name: "", Age: 200, Email: "john@notvalid"}
+	err := u.Validate()
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Validate() = %v, want a *ValidationError", err)
+	}
+	want := []struct{ field, code string }{
+		{"name", CodeRequired},
+		{"age", CodeOutOfRange},
+		{"email", CodeInvalidFormat},
+	}
+	if len(verr.Errors) != len(want) {
+		t.Fatalf("Validate() = %v, want %d errors", err, len(want))
+	}
+	for i, w := range want {
+		if got := verr.Errors[i]; got.Field != w.field || got.Code != w.code || got.Message == "" {
+			t.Errorf("Errors[%d] = %+v, want field %q and code %q", i, got, w.field, w.code)
+		}
+	}
+	for _, sentinel := range []error{ErrInvalidName, ErrInvalidAge, ErrInvalidEmail} {
+		if !errors.Is(err, sentinel) {
+			t.Errorf("Expected errors.Is(err, %v)", sentinel)
+		}
+	}
+	if got := verr.Field("age"); got == nil || got.Error() != "age: must be between 0 and 150" {
+		t.Errorf(`Field("age") = %v`, got)
+	}
+	if got := verr.Field("password"); got != nil {
+		t.Errorf(`Field("password") = %v, want nil`, got)
+	}
+}
+
+func TestNewUserNormalizes(t *testing.T) {
+	u, err := NewUser("  Иван   Петров ", 30, " Ivan.Petrov@Example.COM ")
+	if err != nil {
+		t.Fatalf("NewUser failed: %v", err)
+	}
+	if u.Name != "Иван Петров" || u.Email != "ivan.petrov@example.com" {
+		t.Errorf("Expected a normalised name and email, got %q and %q", u.Name, u.Email)
+	}
+	if got := NormalizeEmail(" Ivan@ПРИМЕР.РФ"); got != "ivan@пример.рф" {
+		t.Errorf("NormalizeEmail() = %q, want ivan@пример.рф", got)
+	}
+}
+
+func TestCustomRules(t *testing.T) {
+	// The limits of the lab05 users: names of 2 to 50 characters
+	rules := Rules{NameMinLength: 2, NameMaxLength: 50, AgeMin: 18, AgeMax: 150, EmailMaxLength: 254}
+	if err := rules.CheckName("J"); code(err) != CodeTooShort || err.Message != "must be at least 2 characters" {
+		t.Errorf(`CheckName("J") = %v, want too short`, err)
+	}
+	if err := rules.CheckName(strings.Repeat("ж", 50)); err != nil {
+		t.Errorf("CheckName(50 characters) = %v, want nil", err)
+	}
+	if err := rules.Validate(&User{Name: "Jo", Age: 17, Email: "jo@example.com"}); !errors.Is(err, ErrInvalidAge) || errors.Is(err, ErrInvalidName) {
+		t.Errorf("Validate() = %v, want only an age error", err)
+	}
+
+	// Changing a copy of the defaults leaves them alone
+	changed := DefaultRules()
+	changed.NameMaxLength = 2
+	if got := DefaultRules().NameMaxLength; got != 30 {
+		t.Errorf("DefaultRules().NameMaxLength = %d, want 30", got)
+	}
+}
+
+// code returns the code of a field error, or "" for nil
+func code(err *FieldError) string {
+	if err == nil {
+		return ""
+	}
+	return err.Code
+}
@@ -3,10 +3,10 @@ package user
 import (
 	"errors"
 	"fmt"
-	"regexp"
 )
 
-// Predefined errors
+// Predefined errors; a FieldError unwraps to the one of its field. The
+// limits in the messages are those of DefaultRules.
 var (
 	ErrInvalidName  = errors.New("invalid name: must be between 1 and 30 characters")
 	ErrInvalidAge   = errors.New("invalid age: must be between 0 and 150")
@@ -20,21 +20,9 @@ type User struct {
 	Email string
 }
 
-// Validate checks if the user data is valid, returns an error for each invalid field
+// Validate checks the user against DefaultRules, returns a *ValidationError with an error for each invalid field
 func (u *User) Validate() error {
-	if !IsValidName(u.Name) {
-		return ErrInvalidName
-	}
-
-	if !IsValidAge(u.Age) {
-		return ErrInvalidAge
-	}
-
-	if !IsValidEmail(u.Email) {
-		return ErrInvalidEmail
-	}
-
-	return nil
+	return DefaultRules().Validate(u)
 }
 
 // String returns a string representation of the user, formatted as "Name: <name>, Age: <age>, Email: <email>"
@@ -42,39 +30,31 @@ func (u *User) String() string {
 	return fmt.Sprintf("Name: %s, Age: %d, Email: %s", u.Name, u.Age, u.Email)
 }
 
-// NewUser creates a new user with validation, returns an error if the user is not valid
+// NewUser creates a new user with its name and email normalised, returns a *ValidationError if the user is not valid
 func NewUser(name string, age int, email string) (*User, error) {
 	user := &User{
 		Name:  name,
 		Age:   age,
 		Email: email,
 	}
+	user.Normalize()
 	if err := user.Validate(); err != nil {
 		return nil, err
 	}
 	return user, nil
 }
 
-// IsValidEmail checks if the email format is valid
-// You can use regexp.MustCompile to compile the email regex
+// IsValidEmail checks if the email format is valid; see Rules.CheckEmail
 func IsValidEmail(email string) bool {
-	pattern := `^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`
-	match, _ := regexp.MatchString(pattern, email)
-	return match
+	return DefaultRules().CheckEmail(email) == nil
 }
 
 // IsValidName checks if the name is valid, returns false if the name is empty or longer than 30 characters
 func IsValidName(name string) bool {
-	if len(name) < 1 || len(name) > 30 {
-		return false
-	}
-	return true
+	return DefaultRules().CheckName(name) == nil
 }
 
 // IsValidAge checks if the age is valid, returns false if the age is not between 0 and 150
 func IsValidAge(age int) bool {
-	if age < 0 || age > 150 {
-		return false
-	}
-	return true
+	return DefaultRules().CheckAge(age) == nil
 }
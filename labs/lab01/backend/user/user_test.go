@@ -1,6 +1,7 @@
 package user
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -78,7 +79,7 @@ func TestNewUser(t *testing.T) {
 				if err == nil {
 					t.Error("Expected error, got none")
 				}
-				if err != tt.errorType {
+				if !errors.Is(err, tt.errorType) {
 					t.Errorf("Expected error %v, got %v", tt.errorType, err)
 				}
 				return
@@ -168,7 +169,7 @@ func TestUserValidate(t *testing.T) {
 				if err == nil {
 					t.Error("Expected error, got none")
 				}
-				if err != tt.errorType {
+				if !errors.Is(err, tt.errorType) {
 					t.Errorf("Expected error %v, got %v", tt.errorType, err)
 				}
 				return